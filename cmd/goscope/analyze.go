@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goscope/internal/cache"
+	"github.com/goscope/internal/config"
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/osv"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/pool"
+	"github.com/goscope/internal/protodiff"
+	"github.com/goscope/internal/scanner"
+)
+
+// logOut receives progress messages. Commands that print machine-readable
+// output to stdout switch it to stderr.
+var logOut io.Writer = os.Stdout
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(logOut, format, args...)
+}
+
+// progressInterval limits how often a progress counter is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// progress redraws a "done/total label" counter in place while parallel work
+// runs. It only draws when logOut is a terminal, so logs and CI output stay
+// clean. It is safe for concurrent use.
+type progress struct {
+	label string
+	tty   bool
+
+	mu    sync.Mutex
+	last  time.Time
+	drawn bool
+}
+
+func newProgress(label string) *progress {
+	p := &progress{label: label}
+	if f, ok := logOut.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			p.tty = true
+		}
+	}
+	return p
+}
+
+// update records that done of total items are finished.
+func (p *progress) update(done, total int) {
+	if !p.tty {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if done < total && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+	p.drawn = true
+	fmt.Fprintf(logOut, "\r   %d/%d %s", done, total, p.label)
+}
+
+// finish erases the counter.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drawn {
+		fmt.Fprint(logOut, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// project is the in-memory result of scanning and parsing a codebase.
+type project struct {
+	Root  string
+	Name  string
+	Cfg   config.Config
+	Scan  *scanner.ScanResult
+	Files []*parser.ParsedFile
+
+	// Resolver maps import paths to directories of the scanned modules.
+	Resolver *gomod.Resolver
+	// Cache holds results of earlier runs; nil when caching is disabled.
+	Cache *cache.Cache
+}
+
+// history holds the git data gathered for a report.
+type history struct {
+	Branch      string
+	AuthorStats map[string]*gitpkg.AuthorStats
+	Churn       []gitpkg.FileChurnStat
+	Tags        gitpkg.TagStats
+	Commits     gitpkg.CommitStats
+	Branches    gitpkg.BranchStats
+}
+
+// loadProject scans root and parses every discovered Go/proto file on
+// cfg.Workers() goroutines. Files keep the order of a sequential run.
+func loadProject(ctx context.Context, root string, cfg config.Config) (*project, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	logf("🔍 Scanning %s\n", abs)
+	res, err := scanner.Scan(ctx, abs, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", abs, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("%s is not a directory", abs)
+	}
+	logf("   Found %d files in %d microservices, %d Go modules, %d git repos\n",
+		len(res.Files), len(res.Microservices)-len(res.Shared), len(res.Modules), len(res.GitRepos))
+	if len(res.Shared) > 0 {
+		logf("   %d shared libraries from the service map\n", len(res.Shared))
+	}
+	if res.Ignored > 0 {
+		logf("   Ignored %d files matched by ignore files or include/exclude globs\n", res.Ignored)
+	}
+	if res.Skipped > 0 {
+		logf("   ⚠️  Skipped %d files beyond maxFilesAnalyze (%d)\n", res.Skipped, cfg.MaxFilesAnalyze)
+	}
+
+	// Parse per microservice so every file gets its service name.
+	msNames := make([]string, 0, len(res.Microservices))
+	for ms := range res.Microservices {
+		msNames = append(msNames, ms)
+	}
+	sort.Strings(msNames)
+
+	c := openCache(cfg)
+
+	type job struct{ path, ms string }
+	var jobs []job
+	for _, ms := range msNames {
+		for _, path := range res.Microservices[ms] {
+			jobs = append(jobs, job{path, ms})
+		}
+	}
+	type parsed struct {
+		pf  *parser.ParsedFile
+		hit bool
+		err error
+	}
+
+	logf("📄 Parsing files...\n")
+	bar := newProgress("files")
+	var done atomic.Int64
+	results, err := pool.Map(ctx, cfg.Workers(), len(jobs), func(i int) parsed {
+		pf, hit, err := parseCached(c, jobs[i].path, jobs[i].ms)
+		bar.update(int(done.Add(1)), len(jobs))
+		return parsed{pf, hit, err}
+	})
+	bar.finish()
+	if err != nil {
+		return nil, err
+	}
+	var files []*parser.ParsedFile
+	failed, cached := 0, 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			continue
+		}
+		if r.hit {
+			cached++
+		}
+		if r.pf != nil {
+			files = append(files, r.pf)
+		}
+	}
+	if failed > 0 {
+		logf("   ⚠️  %d files could not be read\n", failed)
+	}
+	if cfg.ExcludeGenerated {
+		kept := files[:0]
+		for _, f := range files {
+			if !f.Generated {
+				kept = append(kept, f)
+			}
+		}
+		if n := len(files) - len(kept); n > 0 {
+			logf("   Excluded %d generated files\n", n)
+		}
+		files = kept
+	}
+	if c != nil {
+		logf("   Parsed %d files (%d from cache)\n", len(files), cached)
+	} else {
+		logf("   Parsed %d files\n", len(files))
+	}
+
+	name := cfg.ProjectName
+	if name == "" {
+		name = filepath.Base(abs)
+	}
+	return &project{
+		Root:  abs,
+		Name:  name,
+		Cfg:   cfg,
+		Scan:  res,
+		Files: files,
+
+		Resolver: res.Resolver(),
+		Cache:    c,
+	}, nil
+}
+
+// cacheMaxAge is how long an unused cache entry is kept.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// openCache opens the user's goscope cache when enabled and prunes entries
+// no run has used recently. A cache that cannot be created only costs speed.
+func openCache(cfg config.Config) *cache.Cache {
+	if !cfg.EnableCache {
+		return nil
+	}
+	dir, err := cache.DefaultDir()
+	var c *cache.Cache
+	if err == nil {
+		c, err = cache.Open(dir, version)
+	}
+	if err != nil {
+		logf("   ⚠️  Cache disabled: %v\n", err)
+		return nil
+	}
+	c.Prune(cacheMaxAge)
+	return c
+}
+
+// parseCached parses path, or returns the result of an earlier run on the
+// same contents. hit reports whether the cache was used.
+func parseCached(c *cache.Cache, path, microservice string) (pf *parser.ParsedFile, hit bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	hash := cache.Hash(data)
+	key := cache.Key("parse", path, microservice, hash)
+	if c.Get(key, &pf) && pf != nil {
+		return pf, true, nil
+	}
+	pf, err = parser.ParseSource(path, microservice, data)
+	if err != nil || pf == nil {
+		return pf, false, err
+	}
+	pf.ContentHash = hash
+	c.Put(key, pf)
+	return pf, false, nil
+}
+
+// buildGraph builds the file dependency graph and computes PageRank.
+func buildGraph(p *project) *graph.DependencyGraph {
+	logf("🕸️  Building dependency graph...\n")
+	g := graph.New()
+	g.Cache = p.Cache
+	g.MaxTypeRefDecls = p.Cfg.Limits.TypeRefDecls
+	g.Build(p.Files, p.Resolver)
+	g.Analyze()
+	logf("   %d vertices, %d edges\n", len(g.Vertices), len(g.Edges))
+	return g
+}
+
+// buildPackageGraph builds the package-level import graph.
+func buildPackageGraph(p *project) *graph.PackageGraph {
+	pg := graph.BuildPackageGraph(p.Files, p.Resolver)
+	logf("   %d packages, %d package edges (%d modules)\n", len(pg.Packages), len(pg.Edges), p.Resolver.Len())
+	return pg
+}
+
+// buildServiceGraph builds the microservice dependency graph with coupling metrics.
+func buildServiceGraph(p *project, pg *graph.PackageGraph) *graph.ServiceGraph {
+	sg := graph.BuildServiceGraph(p.Files, pg)
+	sg.MarkLibraries(p.Scan.Shared)
+	if libs := sg.Libraries(); libs > 0 {
+		logf("   %d services, %d shared libraries, %d service dependencies\n", len(sg.Vertices)-libs, libs, len(sg.Deps))
+	} else {
+		logf("   %d services, %d service dependencies\n", len(sg.Vertices), len(sg.Deps))
+	}
+	return sg
+}
+
+// buildServiceCalls infers the runtime calls between microservices from
+// gRPC clients and the service addresses in code and configuration.
+func buildServiceCalls(p *project) []graph.ServiceCall {
+	endpoints, aliases := scanner.ScanEndpoints(p.Root, p.Scan)
+	calls := graph.BuildServiceCalls(p.Files, endpoints, aliases)
+	logf("   %d service calls (%d configured endpoints)\n", len(calls), len(endpoints))
+	return calls
+}
+
+// collectHistory runs git analysis across all discovered repos and
+// enriches the parsed files with per-file git metadata. The history is
+// incomplete when ctx is cancelled; callers check ctx.Err().
+func collectHistory(ctx context.Context, p *project, since string) history {
+	repos := p.Scan.GitRepos
+	limit := p.Cfg.GitCommitLimit
+	workers := p.Cfg.Workers()
+	var h history
+	h.Branch = "—"
+	if len(repos) == 0 {
+		return h
+	}
+	logf("🐙 Analyzing git history (%d repos)...\n", len(repos))
+	if b := gitpkg.NewAnalyzer(repos[0], limit).CurrentBranch(); b != "" {
+		h.Branch = b
+	}
+	h.AuthorStats = gitpkg.GetAuthorStatsMultiRepo(ctx, repos, workers, limit, since)
+	n := gitpkg.EnrichFilesMultiRepo(ctx, repos, workers, limit, since, p.Files, h.AuthorStats)
+	logf("   Batch git log parsed (%d file entries from %d repos)\n", n, len(repos))
+	gitpkg.EnrichAuthorLOC(ctx, repos, workers, limit, since, h.AuthorStats)
+	h.Churn = gitpkg.GetChurnStats(ctx, repos, workers, limit, since, 15)
+	h.Tags = gitpkg.GetTagStats(ctx, repos, workers)
+	h.Commits = gitpkg.GetCommitMessageStats(ctx, repos, workers, limit, since)
+	h.Branches = gitpkg.GetBranchStats(ctx, repos, workers, 30)
+	return h
+}
+
+// scanLicenses classifies the licenses of the modules p requires, read
+// from vendor directories and the local module cache.
+func scanLicenses(p *project) *license.Report {
+	modCache := license.ModuleCache()
+	r := license.Scan(p.Scan.Modules, modCache, p.Cfg.Gates.DeniedLicenses, p.Scan.WorkReplaces()...)
+	if len(r.Modules) == 0 {
+		return r
+	}
+	logf("⚖️  Classified licenses of %d required modules", len(r.Modules)-r.Missing)
+	if r.Missing > 0 {
+		logf(" (%d not in vendor/ or %s)", r.Missing, modCache)
+	}
+	logf("\n")
+	return r
+}
+
+// checkVulnerabilities matches the go.mod requirements of p against the
+// OSV snapshot named by the config, resolved against the analysis root. It
+// returns nil when no snapshot is configured.
+func checkVulnerabilities(p *project) (*osv.Report, error) {
+	path := p.Cfg.OSVDatabase
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.Root, path)
+	}
+	logf("🛡️  Checking dependencies against %s...\n", path)
+	db, err := osv.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("load OSV database: %w", err)
+	}
+	r := db.Check(p.Scan.Modules, p.Scan.WorkReplaces()...)
+	logf("   %d known vulnerabilities in %d requirements (%d database entries)\n", len(r.Vulnerabilities), r.Checked, r.Entries)
+	return r, nil
+}
+
+// diffProtos compares the .proto files at base with those at head in every
+// repo. An empty head compares against the working tree. Both sides list
+// the files git tracks, so excluded and ignored paths are compared like any
+// other. Repos where a revision does not exist are skipped.
+func diffProtos(root string, repos []string, base, head string) (*protodiff.Diff, error) {
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no git repositories found under %s", root)
+	}
+	headName := head
+	if headName == "" {
+		headName = "working tree"
+	}
+	logf("🧬 Comparing .proto files %s → %s...\n", base, headName)
+
+	oldFiles := make(map[string]*parser.ProtoFile)
+	newFiles := make(map[string]*parser.ProtoFile)
+	compared := 0
+	for _, repo := range repos {
+		old, err := protoFilesAt(root, repo, base)
+		if err != nil {
+			logf("   ⚠️  %v, skipping\n", err)
+			continue
+		}
+		var cur map[string]*parser.ProtoFile
+		if head == "" {
+			cur, err = worktreeProtos(root, repo)
+		} else {
+			cur, err = protoFilesAt(root, repo, head)
+		}
+		if err != nil {
+			logf("   ⚠️  %v, skipping\n", err)
+			continue
+		}
+		for k, v := range old {
+			oldFiles[k] = v
+		}
+		for k, v := range cur {
+			newFiles[k] = v
+		}
+		compared++
+	}
+	if compared == 0 {
+		return nil, fmt.Errorf("revision %s not found in any repository", base)
+	}
+
+	d := &protodiff.Diff{
+		Base:    base,
+		Head:    headName,
+		Files:   len(newFiles),
+		Changes: protodiff.Compare(oldFiles, newFiles),
+	}
+	logf("   %d changes, %d breaking\n", len(d.Changes), d.Breaking())
+	return d, nil
+}
+
+// protoFilesAt parses the .proto files of repo at rev, keyed by path relative to root.
+func protoFilesAt(root, repo, rev string) (map[string]*parser.ProtoFile, error) {
+	srcs, err := gitpkg.FilesAtRevision(repo, rev, ".proto")
+	if err != nil {
+		return nil, err
+	}
+	return parseProtos(root, repo, srcs), nil
+}
+
+// worktreeProtos parses the .proto files in the working tree of repo, keyed
+// by path relative to root.
+func worktreeProtos(root, repo string) (map[string]*parser.ProtoFile, error) {
+	srcs, err := gitpkg.WorktreeFiles(repo, ".proto")
+	if err != nil {
+		return nil, err
+	}
+	return parseProtos(root, repo, srcs), nil
+}
+
+// parseProtos parses .proto sources keyed by repo-relative path.
+func parseProtos(root, repo string, srcs map[string][]byte) map[string]*parser.ProtoFile {
+	files := make(map[string]*parser.ProtoFile, len(srcs))
+	for path, src := range srcs {
+		// Syntax errors are recovered from; keep whatever was parsed.
+		pf, _ := parser.ParseProto(src)
+		files[relPath(root, filepath.Join(repo, path))] = pf
+	}
+	return files
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
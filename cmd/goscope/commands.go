@@ -0,0 +1,698 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/gate"
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/openapi"
+	"github.com/goscope/internal/protodiff"
+	"github.com/goscope/internal/report"
+	"github.com/goscope/internal/scanner"
+)
+
+const defaultReportPath = "goscope-report.html"
+
+func runReport(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("report", "html", &opts)
+	protoBase := fs.String("proto-base", "", "list proto breaking changes since this git revision")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	root, err := rootArg(positional)
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "html", "json"); err != nil {
+		return exitUsage, err
+	}
+	out := opts.out
+	if out == "" && opts.format == "html" {
+		out = defaultReportPath
+	}
+	if opts.format == "json" && out == "" {
+		logOut = os.Stderr
+	}
+
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadProject(ctx, root, cfg)
+	if err != nil {
+		return exitError, err
+	}
+	g := buildGraph(p)
+	pg := buildPackageGraph(p)
+	sg := buildServiceGraph(p, pg)
+	h := collectHistory(ctx, p, opts.since)
+	if err := ctx.Err(); err != nil {
+		return exitError, err
+	}
+	dockerServices, technologies := scanner.ScanDockerCompose(p.Root)
+
+	a := &report.Analysis{
+		Version:        version,
+		ProjectName:    p.Name,
+		Root:           p.Root,
+		Scan:           p.Scan,
+		Files:          p.Files,
+		Technologies:   technologies,
+		DockerServices: dockerServices,
+		Graph:          g,
+		Packages:       pg,
+		Services:       sg,
+		GRPC:           graph.BuildGRPCCoverage(p.Files),
+		Calls:          buildServiceCalls(p),
+		Branch:         h.Branch,
+		AuthorStats:    h.AuthorStats,
+		Churn:          h.Churn,
+		Tags:           h.Tags,
+		Commits:        h.Commits,
+		Branches:       h.Branches,
+		TypeCheck:      p.Cfg.TypeCheck,
+		Cache:          p.Cache,
+		Workers:        p.Cfg.Workers(),
+		HotspotCount:   p.Cfg.HotspotCount,
+		Limits:         p.Cfg.Limits,
+	}
+	a.Licenses = scanLicenses(p)
+	if a.Vulns, err = checkVulnerabilities(p); err != nil {
+		return exitError, err
+	}
+	if *protoBase != "" {
+		if a.ProtoDiff, err = diffProtos(p.Root, p.Scan.GitRepos, *protoBase, ""); err != nil {
+			return exitError, err
+		}
+	}
+
+	if opts.format == "json" {
+		w, closeOut, err := outputWriter(out)
+		if err != nil {
+			return exitError, err
+		}
+		defer closeOutput(closeOut, &code, &err)
+		if err := report.WriteJSON(a, w); err != nil {
+			return exitError, fmt.Errorf("write json: %w", err)
+		}
+		if out != "" {
+			logf("✅ Analysis saved to %s\n", out)
+		}
+		return exitOK, nil
+	}
+
+	logf("📝 Writing report...\n")
+	if err := report.Generate(a, out); err != nil {
+		return exitError, fmt.Errorf("write %s: %w", out, err)
+	}
+	logf("✅ Report saved to %s\n", out)
+
+	if opts.open {
+		if err := openBrowser(out); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not open browser: %v\n", err)
+		}
+	}
+	return exitOK, nil
+}
+
+// scanSummary is the --format json output of `goscope scan`.
+type scanSummary struct {
+	Root            string                   `json:"root"`
+	ServicesRoot    string                   `json:"servicesRoot,omitempty"`
+	GitRepos        []string                 `json:"gitRepos"`
+	Modules         []gomod.Module           `json:"modules"`
+	Workspace       *gomod.Workspace         `json:"workspace,omitempty"`
+	Microservices   []microserviceSummary    `json:"microservices"`
+	ForeignServices []scanner.ForeignService `json:"foreignServices"`
+	IgnoredFiles    int                      `json:"ignoredFiles"` // left out by ignore files and include/exclude globs
+	SkippedFiles    int                      `json:"skippedFiles"` // left out beyond maxFilesAnalyze
+}
+
+type microserviceSummary struct {
+	Name       string `json:"name"`
+	GoFiles    int    `json:"goFiles"`
+	ProtoFiles int    `json:"protoFiles"`
+	Lines      int    `json:"lines"`
+	Decls      int    `json:"declarations"`
+	Generated  int    `json:"generatedFiles"`   // files with a "Code generated" header
+	Shared     bool   `json:"shared,omitempty"` // a shared library from the config's service map
+}
+
+func runScan(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("scan", "text", &opts)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	root, err := rootArg(positional)
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "text", "json"); err != nil {
+		return exitUsage, err
+	}
+	w, closeOut, err := outputWriter(opts.out)
+	if err != nil {
+		return exitError, err
+	}
+	defer closeOutput(closeOut, &code, &err)
+	if opts.format == "json" && opts.out == "" {
+		logOut = os.Stderr
+	}
+
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadProject(ctx, root, cfg)
+	if err != nil {
+		return exitError, err
+	}
+
+	sum := scanSummary{
+		Root:            p.Root,
+		ServicesRoot:    p.Scan.ServicesRoot,
+		GitRepos:        p.Scan.GitRepos,
+		Modules:         p.Scan.Modules,
+		Workspace:       p.Scan.Workspace,
+		ForeignServices: p.Scan.ForeignServices,
+		IgnoredFiles:    p.Scan.Ignored,
+		SkippedFiles:    p.Scan.Skipped,
+	}
+	byMS := make(map[string]*microserviceSummary)
+	for _, f := range p.Files {
+		ms := byMS[f.MicroserviceName]
+		if ms == nil {
+			ms = &microserviceSummary{Name: f.MicroserviceName, Shared: p.Scan.Shared[f.MicroserviceName]}
+			byMS[f.MicroserviceName] = ms
+		}
+		if f.FileType == "proto" {
+			ms.ProtoFiles++
+		} else {
+			ms.GoFiles++
+		}
+		ms.Lines += f.LineCount
+		ms.Decls += len(f.Declarations)
+		if f.Generated {
+			ms.Generated++
+		}
+	}
+	for _, ms := range byMS {
+		sum.Microservices = append(sum.Microservices, *ms)
+	}
+	sort.Slice(sum.Microservices, func(i, j int) bool {
+		return sum.Microservices[i].Lines > sum.Microservices[j].Lines
+	})
+
+	if opts.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sum); err != nil {
+			return exitError, err
+		}
+		return exitOK, nil
+	}
+
+	fmt.Fprintf(w, "\n%-32s %8s %8s %10s %8s\n", "MICROSERVICE", "GO", "PROTO", "LINES", "DECLS")
+	for _, ms := range sum.Microservices {
+		name := ms.Name
+		if ms.Shared {
+			name += " (library)"
+		}
+		fmt.Fprintf(w, "%-32s %8d %8d %10d %8d\n", name, ms.GoFiles, ms.ProtoFiles, ms.Lines, ms.Decls)
+	}
+	for _, fs := range sum.ForeignServices {
+		fmt.Fprintf(w, "%-32s %8s %8s %10d %8s  (%s)\n", fs.Name, "-", "-", fs.LineCount, "-", fs.Language)
+	}
+	if len(sum.Modules) > 1 {
+		fmt.Fprintf(w, "\n%-40s %6s %8s %8s  %s\n", "MODULE", "GO", "FILES", "REQUIRES", "DIR")
+		for _, m := range sum.Modules {
+			dir, err := filepath.Rel(p.Root, m.Dir)
+			if err != nil {
+				dir = m.Dir
+			}
+			if m.Workspace {
+				dir += " (go.work)"
+			}
+			fmt.Fprintf(w, "%-40s %6s %8d %8d  %s\n", m.Path, m.GoVersion, len(p.Scan.ModuleFiles[m.Path]), len(m.Requires), dir)
+		}
+	}
+	return exitOK, nil
+}
+
+// servicesSummary is the --format json output of `goscope services`.
+type servicesSummary struct {
+	Root      string           `json:"root"`
+	Services  []serviceMapping `json:"services"`
+	Unmatched []string         `json:"unmatched"` // config entries that cover no file
+}
+
+type serviceMapping struct {
+	Name   string   `json:"name"`
+	Kind   string   `json:"kind"`   // graph.NodeService or graph.NodeLibrary
+	Source string   `json:"source"` // "config" or "detected"
+	Dirs   []string `json:"dirs"`   // outermost directories holding the files, relative to the root
+	Files  []string `json:"files"`  // relative to the root
+}
+
+func runServices(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("services", "text", &opts)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	root, err := rootArg(positional)
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "text", "json"); err != nil {
+		return exitUsage, err
+	}
+	w, closeOut, err := outputWriter(opts.out)
+	if err != nil {
+		return exitError, err
+	}
+	defer closeOutput(closeOut, &code, &err)
+
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return exitError, err
+	}
+	res, err := scanner.Scan(ctx, abs, cfg)
+	if err != nil {
+		return exitError, fmt.Errorf("scan %s: %w", abs, err)
+	}
+	if res == nil {
+		return exitError, fmt.Errorf("%s is not a directory", abs)
+	}
+
+	configured := make(map[string]bool)
+	for _, svc := range cfg.Services {
+		configured[svc.Name] = true
+	}
+	sum := servicesSummary{Root: abs, Services: []serviceMapping{}, Unmatched: []string{}}
+	for name, paths := range res.Microservices {
+		m := serviceMapping{Name: name, Kind: graph.NodeService, Source: "detected"}
+		if res.Shared[name] {
+			m.Kind = graph.NodeLibrary
+		}
+		if configured[name] {
+			m.Source = "config"
+		}
+		dirs := make(map[string]bool)
+		for _, p := range paths {
+			m.Files = append(m.Files, relPath(abs, p))
+			dirs[relPath(abs, filepath.Dir(p))] = true
+		}
+		sort.Strings(m.Files)
+		m.Dirs = outermostDirs(dirs)
+		sum.Services = append(sum.Services, m)
+	}
+	sort.Slice(sum.Services, func(i, j int) bool { return sum.Services[i].Name < sum.Services[j].Name })
+	for _, svc := range cfg.Services {
+		if _, ok := res.Microservices[svc.Name]; !ok {
+			sum.Unmatched = append(sum.Unmatched, svc.Name)
+		}
+	}
+
+	if opts.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sum); err != nil {
+			return exitError, err
+		}
+		return exitOK, nil
+	}
+	fmt.Fprintf(w, "%-32s %-8s %-9s %6s  %s\n", "SERVICE", "KIND", "SOURCE", "FILES", "DIRS")
+	for _, m := range sum.Services {
+		fmt.Fprintf(w, "%-32s %-8s %-9s %6d  %s\n", m.Name, m.Kind, m.Source, len(m.Files), strings.Join(m.Dirs, ", "))
+	}
+	for _, name := range sum.Unmatched {
+		fmt.Fprintf(w, "⚠️  services entry %q covers no files\n", name)
+	}
+	return exitOK, nil
+}
+
+// outermostDirs returns the sorted directories of dirs that are not below
+// another one of them.
+func outermostDirs(dirs map[string]bool) []string {
+	var out []string
+	for d := range dirs {
+		covered := false
+		for parent := path.Dir(d); parent != "." && parent != "/"; parent = path.Dir(parent) {
+			if dirs[parent] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, d)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func runCheck(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("check", "text", &opts)
+	useBaseline := fs.Bool("baseline", false, "only report findings missing from "+report.DefaultBaselinePath)
+	writeBaseline := fs.Bool("write-baseline", false, "record all current findings in "+report.DefaultBaselinePath)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	root, err := rootArg(positional)
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "text", "json", "sarif"); err != nil {
+		return exitUsage, err
+	}
+	w, closeOut, err := outputWriter(opts.out)
+	if err != nil {
+		return exitError, err
+	}
+	defer closeOutput(closeOut, &code, &err)
+	if opts.format != "text" && opts.out == "" {
+		logOut = os.Stderr
+	}
+
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadProject(ctx, root, cfg)
+	if err != nil {
+		return exitError, err
+	}
+	checkOpts := report.CheckOptions{
+		GitRepos:  p.Scan.GitRepos,
+		Resolver:  p.Resolver,
+		TypeCheck: p.Cfg.TypeCheck,
+		Root:      p.Root,
+		Cache:     p.Cache,
+		Workers:   p.Cfg.Workers(),
+		Context:   ctx,
+
+		MaxViolations: p.Cfg.Limits.FindingsPerCheck,
+		// A baseline must hold every finding, or the ones cut off would
+		// show up as new on the next run.
+		All: *useBaseline || *writeBaseline,
+	}
+	baselinePath := filepath.Join(p.Root, report.DefaultBaselinePath)
+	if *useBaseline && !*writeBaseline {
+		b, err := report.LoadBaseline(baselinePath)
+		if err != nil {
+			return exitError, err
+		}
+		checkOpts.Baseline = b
+	}
+	logf("⚠️  Running anti-pattern checks...\n")
+	bar := newProgress("packages")
+	checkOpts.Progress = bar.update
+	findings := report.Findings(p.Files, checkOpts)
+	bar.finish()
+	if err := ctx.Err(); err != nil {
+		return exitError, err
+	}
+	gates := p.Cfg.Gates
+	in := gate.Input{Findings: findings, Files: p.Files}
+	if gates.NoNewCycles || *writeBaseline {
+		in.Cycles = gate.CycleMembers(buildPackageGraph(p).Cycles(), p.Root)
+	}
+	if *writeBaseline {
+		b := report.NewBaseline(findings, p.Root)
+		b.Cycles = in.Cycles
+		if err := b.Write(baselinePath); err != nil {
+			return exitError, err
+		}
+		logf("✅ Recorded %d findings and %d package cycles in %s\n", len(findings), len(b.Cycles), baselinePath)
+		return exitOK, nil
+	}
+	if checkOpts.Baseline != nil {
+		in.KnownCycles = checkOpts.Baseline.Cycles
+	}
+	if len(gates.DeniedLicenses) > 0 {
+		in.Licenses = scanLicenses(p)
+	}
+	if gates.MinConventionalCommits > 0 {
+		in.Commits = gitpkg.GetCommitMessageStats(ctx, p.Scan.GitRepos, p.Cfg.Workers(), p.Cfg.GitCommitLimit, opts.since)
+		if err := ctx.Err(); err != nil {
+			return exitError, err
+		}
+	}
+	results := gate.Evaluate(gates, in)
+	vulns, err := checkVulnerabilities(p)
+	if err != nil {
+		return exitError, err
+	}
+
+	high := 0
+	for _, f := range findings {
+		if f.Priority == report.PriorityHigh {
+			high++
+		}
+	}
+
+	switch opts.format {
+	case "json":
+		if err := report.WriteCheckJSON(w, findings, vulns); err != nil {
+			return exitError, err
+		}
+	case "sarif":
+		if err := report.WriteSARIF(w, findings, vulns, p.Root, version); err != nil {
+			return exitError, err
+		}
+	default:
+		for _, f := range findings {
+			fmt.Fprintf(w, "%-6s %s:%d  %s\n       %s\n", f.Priority, f.File, f.Line, f.Check, f.Snippet)
+		}
+		if vulns != nil {
+			for _, v := range vulns.Vulnerabilities {
+				fixed := "no fix"
+				if v.Fixed != "" {
+					fixed = "fixed in " + v.Fixed
+				}
+				fmt.Fprintf(w, "%-8s %s  %s@%s (%s)  %s\n", v.Severity, v.ID, v.Package, v.Version, fixed, v.Summary)
+				for _, req := range v.RequiredBy {
+					fmt.Fprintf(w, "         %s:%d\n", relPath(p.Root, req.GoMod), req.Line)
+				}
+			}
+		}
+		fmt.Fprintf(w, "\n%d findings, %d HIGH", len(findings), high)
+		if checkOpts.Baseline != nil {
+			fmt.Fprintf(w, " (new since %s)", report.DefaultBaselinePath)
+		}
+		if vulns != nil {
+			fmt.Fprintf(w, "; %d vulnerable dependencies", len(vulns.Vulnerabilities))
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Machine-readable output only holds findings and vulnerabilities; the
+	// gate summary goes to the log there.
+	gw := io.Writer(w)
+	if opts.format != "text" {
+		gw = logOut
+	}
+	printGates(gw, results)
+	if gate.Failed(results) > 0 {
+		return exitFindings, nil
+	}
+	return exitOK, nil
+}
+
+// printGates writes one line per quality gate and a verdict.
+func printGates(w io.Writer, results []gate.Result) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nQuality gates:")
+	for _, r := range results {
+		mark := "✅"
+		if !r.Passed {
+			mark = "❌"
+		}
+		fmt.Fprintf(w, "  %s %-25s %s\n", mark, r.Gate, r.Detail)
+	}
+	if n := gate.Failed(results); n > 0 {
+		fmt.Fprintf(w, "%d of %d gates failed\n", n, len(results))
+	} else {
+		fmt.Fprintf(w, "all %d gates passed\n", len(results))
+	}
+}
+
+func runProtoDiff(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("proto-diff", "text", &opts)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	if len(positional) < 2 {
+		return exitUsage, fmt.Errorf("%w: usage: goscope proto-diff <rev-a> <rev-b> [path]", errUsage)
+	}
+	base, head := positional[0], positional[1]
+	root, err := rootArg(positional[2:])
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "text", "json"); err != nil {
+		return exitUsage, err
+	}
+	w, closeOut, err := outputWriter(opts.out)
+	if err != nil {
+		return exitError, err
+	}
+	defer closeOutput(closeOut, &code, &err)
+	if opts.format == "json" && opts.out == "" {
+		logOut = os.Stderr
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return exitError, err
+	}
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	res, err := scanner.Scan(ctx, abs, cfg)
+	if err != nil {
+		return exitError, fmt.Errorf("scan %s: %w", abs, err)
+	}
+	if res == nil {
+		return exitError, fmt.Errorf("%s is not a directory", abs)
+	}
+	d, err := diffProtos(abs, res.GitRepos, base, head)
+	if err != nil {
+		return exitError, err
+	}
+
+	if opts.format == "json" {
+		if d.Changes == nil {
+			d.Changes = []protodiff.Change{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			return exitError, err
+		}
+	} else {
+		for _, c := range d.Changes {
+			fmt.Fprintf(w, "%-8s %s:%d  %s  %s\n         %s\n", c.Severity, c.File, c.Line, c.Kind, c.Element, c.Message)
+		}
+		fmt.Fprintf(w, "\n%d changes, %d BREAKING (%s → %s, %d proto files)\n", len(d.Changes), d.Breaking(), d.Base, d.Head, d.Files)
+	}
+
+	if d.Breaking() > 0 {
+		return exitFindings, nil
+	}
+	return exitOK, nil
+}
+
+func runOpenAPI(ctx context.Context, args []string) (code int, err error) {
+	var opts options
+	fs := newFlagSet("openapi", "json", &opts)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	if len(positional) < 1 {
+		return exitUsage, fmt.Errorf("%w: usage: goscope openapi <service> [path]", errUsage)
+	}
+	service := positional[0]
+	root, err := rootArg(positional[1:])
+	if err != nil {
+		return exitUsage, err
+	}
+	if err := checkFormat(opts.format, "json"); err != nil {
+		return exitUsage, err
+	}
+	if opts.out == "" {
+		logOut = os.Stderr
+	}
+
+	cfg, err := loadConfig(root, opts)
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadProject(ctx, root, cfg)
+	if err != nil {
+		return exitError, err
+	}
+	doc := openapi.Generate(service, p.Files, p.Root)
+	if doc == nil {
+		var withRoutes []string
+		seen := make(map[string]bool)
+		for _, f := range p.Files {
+			if len(f.Routes) > 0 && !seen[f.MicroserviceName] {
+				seen[f.MicroserviceName] = true
+				withRoutes = append(withRoutes, f.MicroserviceName)
+			}
+		}
+		sort.Strings(withRoutes)
+		if _, ok := p.Scan.Microservices[service]; !ok {
+			return exitUsage, fmt.Errorf("%w: unknown service %q (services with HTTP routes: %s)", errUsage, service, strings.Join(withRoutes, ", "))
+		}
+		return exitError, fmt.Errorf("service %q registers no HTTP routes (services with routes: %s)", service, strings.Join(withRoutes, ", "))
+	}
+	paths, ops := len(doc.Paths), 0
+	for _, item := range doc.Paths {
+		ops += len(item)
+	}
+	logf("📘 OpenAPI skeleton for %s: %d paths, %d operations\n", service, paths, ops)
+
+	w, closeOut, err := outputWriter(opts.out)
+	if err != nil {
+		return exitError, err
+	}
+	defer closeOutput(closeOut, &code, &err)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return exitError, err
+	}
+	return exitOK, nil
+}
+
+// outputWriter returns a writer for path, or stdout when path is empty,
+// and the function closing it.
+func outputWriter(path string) (io.Writer, func() error, error) {
+	if path == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// closeOutput closes the output of a command, deferred with the command's
+// named results: a failed close, which can lose buffered output, turns a
+// successful exit into exitError.
+func closeOutput(closeOut func() error, code *int, err *error) {
+	if cerr := closeOut(); cerr != nil && *err == nil {
+		*code, *err = exitError, fmt.Errorf("close output: %w", cerr)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/goscope/internal/config"
+)
+
+// version is overridden at build time: go build -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// Exit codes shared by all subcommands so scripts can tell failures apart.
+const (
+	exitOK       = 0 // success
+	exitFindings = 1 // `check` found violations, `proto-diff` found breaking changes
+	exitUsage    = 2 // bad flags or arguments
+	exitError    = 3 // analysis or I/O failure
+)
+
+// errUsage marks errors caused by invalid command-line input.
+var errUsage = errors.New("usage error")
+
+// errInterrupted is reported when the user stops a run with Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) (int, error)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"scan", "Scan and parse a codebase, print a summary", runScan},
+		{"services", "Print the files each microservice resolves to", runServices},
+		{"report", "Generate the HTML report (default command)", runReport},
+		{"check", "Run anti-pattern checks, exit 1 on HIGH findings", runCheck},
+		{"proto-diff", "Report breaking .proto changes between two git revisions", runProtoDiff},
+		{"openapi", "Print an OpenAPI 3.1 skeleton of a service's HTTP routes", runOpenAPI},
+		{"init", "Create a default .goscope.json", runInit},
+		{"version", "Print the goscope version", runVersion},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "--help", "help":
+		printUsage()
+		return exitOK
+	case "-v", "--version":
+		args = []string{"version"}
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		// `goscope ~/backend --open` is shorthand for `goscope report ~/backend --open`.
+		cmd = findCommand("report")
+	} else {
+		args = args[1:]
+	}
+
+	// The first Ctrl-C cancels ctx so running work stops and temporary
+	// files are cleaned up; a second one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	code, err := cmd.run(ctx, args)
+	if err != nil && ctx.Err() != nil {
+		code, err = exitError, errInterrupted
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "❌ %s: %v\n", cmd.name, err)
+		if errors.Is(err, errUsage) {
+			return exitUsage
+		}
+		if code == exitOK {
+			code = exitError
+		}
+	}
+	return code
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `goscope %s — Go backend codebase intelligence
+
+Usage:
+  goscope <command> [path] [flags]
+  goscope <path> [flags]            (same as: goscope report <path>)
+
+Commands:
+`, version)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, `
+Common flags:
+  --config <file>   config file (default: <path>/%s, then ./%s)
+  --out <file>      output file (default depends on command)
+  --open            open the generated report in a browser
+  --format <fmt>    output format (command specific)
+  --since <date>    only analyze git history after this date, e.g. 2024-01-01
+  --no-cache        neither read nor write the analysis cache
+  --workers <n>     files, packages and repos processed at once (default: config)
+
+Exit codes: 0 ok · 1 check/proto-diff found violations · 2 usage error · 3 analysis error
+`, config.DefaultConfigPath, config.DefaultConfigPath)
+}
+
+// options holds the flags shared by all subcommands.
+type options struct {
+	configPath string
+	out        string
+	open       bool
+	format     string
+	since      string
+	noCache    bool
+	workers    int
+}
+
+// newFlagSet creates a FlagSet with the common goscope flags registered.
+func newFlagSet(name, defaultFormat string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.configPath, "config", "", "config file path")
+	fs.StringVar(&opts.out, "out", "", "output file path")
+	fs.BoolVar(&opts.open, "open", false, "open the generated report in a browser")
+	fs.StringVar(&opts.format, "format", defaultFormat, "output format")
+	fs.StringVar(&opts.since, "since", "", "only analyze git history after this date")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "neither read nor write the analysis cache")
+	fs.IntVar(&opts.workers, "workers", 0, "files, packages and repos processed at once")
+	return fs
+}
+
+// parseArgs parses flags that may appear before or after positional arguments
+// and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// rootArg returns the analysis root from positional args, defaulting to ".".
+func rootArg(positional []string) (string, error) {
+	switch len(positional) {
+	case 0:
+		return ".", nil
+	case 1:
+		return positional[0], nil
+	default:
+		return "", fmt.Errorf("%w: expected one path, got %d", errUsage, len(positional))
+	}
+}
+
+// loadConfig loads --config if given, otherwise <root>/.goscope.json, otherwise
+// ./.goscope.json, applies flags that override config settings and
+// validates the result.
+func loadConfig(root string, opts options) (config.Config, error) {
+	path := config.DefaultConfigPath
+	rootCfg := filepath.Join(root, config.DefaultConfigPath)
+	if opts.configPath != "" {
+		path = opts.configPath
+	} else if _, err := os.Stat(rootCfg); err == nil {
+		path = rootCfg
+	}
+	cfg := config.Load(path)
+	if opts.noCache {
+		cfg.EnableCache = false
+	}
+	if opts.workers > 0 {
+		cfg.EnableParallel = true
+		cfg.Concurrency = opts.workers
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%w: invalid config %s:\n%v", errUsage, path, err)
+	}
+	return cfg, nil
+}
+
+func checkFormat(format string, allowed ...string) error {
+	for _, a := range allowed {
+		if format == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unsupported --format %q (want %s)", errUsage, format, strings.Join(allowed, ", "))
+}
+
+func openBrowser(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", abs)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", abs)
+	default:
+		cmd = exec.Command("xdg-open", abs)
+	}
+	return cmd.Start()
+}
+
+func runInit(ctx context.Context, args []string) (int, error) {
+	var opts options
+	fs := newFlagSet("init", "", &opts)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage, err
+	}
+	if len(positional) > 0 {
+		return exitUsage, fmt.Errorf("%w: init takes no arguments, use --out", errUsage)
+	}
+	path := opts.out
+	if path == "" {
+		path = opts.configPath
+	}
+	if path == "" {
+		path = config.DefaultConfigPath
+	}
+	if _, err := os.Stat(path); err == nil {
+		return exitError, fmt.Errorf("%s already exists", path)
+	}
+	if err := config.CreateDefault(path); err != nil {
+		return exitError, err
+	}
+	return exitOK, nil
+}
+
+func runVersion(ctx context.Context, args []string) (int, error) {
+	fmt.Printf("goscope %s (%s, %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return exitOK, nil
+}
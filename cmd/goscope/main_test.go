@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goscope/internal/config"
+	"github.com/goscope/internal/report"
+)
+
+// TestMain points the user cache directory at a temporary one, so commands
+// run with the default enableCache never write into the developer's cache.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "goscope-test-cache")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("XDG_CACHE_HOME", dir)
+	os.Setenv("HOME", dir)
+	os.Setenv("LocalAppData", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestParseArgs_Interspersed(t *testing.T) {
+	var opts options
+	fs := newFlagSet("report", "html", &opts)
+	pos, err := parseArgs(fs, []string{"--since", "2024-01-01", "~/backend", "--open", "--out", "r.html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pos) != 1 || pos[0] != "~/backend" {
+		t.Errorf("positional = %v, want [~/backend]", pos)
+	}
+	if !opts.open || opts.out != "r.html" || opts.since != "2024-01-01" || opts.format != "html" {
+		t.Errorf("opts = %+v", opts)
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	if code := run(nil); code != exitUsage {
+		t.Errorf("run() = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"report", "--format", "pdf"}); code != exitUsage {
+		t.Errorf("run(report --format pdf) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"scan", "a", "b"}); code != exitUsage {
+		t.Errorf("run(scan a b) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"proto-diff", "main"}); code != exitUsage {
+		t.Errorf("run(proto-diff main) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"openapi"}); code != exitUsage {
+		t.Errorf("run(openapi) = %d, want %d", code, exitUsage)
+	}
+	if code := run([]string{"version"}); code != exitOK {
+		t.Errorf("run(version) = %d, want %d", code, exitOK)
+	}
+}
+
+func TestCloseOutput(t *testing.T) {
+	failing := func() error { return os.ErrClosed }
+	code, err := exitFindings, error(nil)
+	closeOutput(failing, &code, &err)
+	if code != exitError || err == nil {
+		t.Errorf("failed close: code %d, err %v; want %d and an error", code, err, exitError)
+	}
+	first := fmt.Errorf("scan failed")
+	code, err = exitError, first
+	closeOutput(failing, &code, &err)
+	if err != first {
+		t.Errorf("failed close after an error: err = %v, want the first error", err)
+	}
+	code, err = exitOK, nil
+	closeOutput(func() error { return nil }, &code, &err)
+	if code != exitOK || err != nil {
+		t.Errorf("clean close: code %d, err %v", code, err)
+	}
+}
+
+func TestRunInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.json")
+	if code := run([]string{"init", "--out", path}); code != exitOK {
+		t.Fatalf("run(init) = %d, want %d", code, exitOK)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("config not created: %v", err)
+	}
+	// A second init must not overwrite the existing file.
+	if code := run([]string{"init", "--out", path}); code != exitError {
+		t.Errorf("run(init) on existing file = %d, want %d", code, exitError)
+	}
+}
+
+func TestRunServices(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"cmd/api/main.go":      "package main",
+		"internal/orders/o.go": "package orders",
+		"pkg/log/log.go":       "package log",
+		".goscope.json": `{"services": [
+			{"name": "api", "paths": ["cmd/api", "internal/"]},
+			{"name": "platform", "paths": ["pkg/"], "shared": true},
+			{"name": "ghost", "paths": ["nothing/"]}
+		]}`,
+	} {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	out := filepath.Join(t.TempDir(), "services.json")
+	if code := run([]string{"services", root, "--format", "json", "--out", out}); code != exitOK {
+		t.Fatalf("run(services) = %d, want %d", code, exitOK)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum servicesSummary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, m := range sum.Services {
+		got[m.Name] = fmt.Sprintf("%s %s %s", m.Kind, m.Source, strings.Join(m.Dirs, ","))
+	}
+	want := map[string]string{
+		"api":      "service config cmd/api,internal/orders",
+		"platform": "library config pkg/log",
+	}
+	if len(got) != len(want) {
+		t.Errorf("services = %v, want %v", got, want)
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %q, want %q", name, got[name], w)
+		}
+	}
+	if len(sum.Unmatched) != 1 || sum.Unmatched[0] != "ghost" {
+		t.Errorf("Unmatched = %v, want [ghost]", sum.Unmatched)
+	}
+}
+
+func TestReportProtoBase_ExcludedPaths(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Dev", "-c", "user.email=dev@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	write := func(path, content string) {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	write("go.mod", "module example.com/users\n\ngo 1.22\n")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("api/users.proto", "syntax = \"proto3\";\npackage users;\nmessage User { string id = 1; }\n")
+	write("api/old.proto", "syntax = \"proto3\";\npackage old;\nmessage Old { string id = 1; }\n")
+	write("vendor/example.com/common/common.proto", "syntax = \"proto3\";\npackage common;\nmessage Money { int64 units = 1; }\n")
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+	os.Remove(filepath.Join(root, "api", "old.proto"))
+
+	out := filepath.Join(t.TempDir(), "report.json")
+	if code := run([]string{"report", root, "--proto-base", "HEAD", "--format", "json", "--out", out}); code != exitOK {
+		t.Fatalf("run(report) = %d, want %d", code, exitOK)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		ProtoDiff struct {
+			Files   int
+			Changes []struct{ File, Element string }
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// The vendored proto is excluded from the analysis but unchanged, so
+	// only the deleted file is reported.
+	if doc.ProtoDiff.Files != 2 {
+		t.Errorf("Files = %d, want 2", doc.ProtoDiff.Files)
+	}
+	for _, c := range doc.ProtoDiff.Changes {
+		if c.File != "api/old.proto" {
+			t.Errorf("unexpected change in %s: %s", c.File, c.Element)
+		}
+	}
+	if len(doc.ProtoDiff.Changes) == 0 {
+		t.Error("removal of api/old.proto not reported")
+	}
+}
+
+func TestCheckBaseline_ManyViolationsInOneFile(t *testing.T) {
+	root := t.TempDir()
+	var src strings.Builder
+	src.WriteString("package store\n")
+	for i := 0; i < 14; i++ {
+		fmt.Fprintf(&src, "\nfunc Load%d() { panic(\"load %d\") }\n", i, i)
+	}
+	path := filepath.Join(root, "store", "store.go")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/store\n\ngo 1.22\n"), 0644)
+	os.WriteFile(path, []byte(src.String()), 0644)
+
+	if code := run([]string{"check", root, "--write-baseline", "--no-cache"}); code != exitOK {
+		t.Fatalf("run(check --write-baseline) = %d, want %d", code, exitOK)
+	}
+	data, err := os.ReadFile(filepath.Join(root, report.DefaultBaselinePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b report.Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+	panics := 0
+	for _, e := range b.Findings {
+		if e.Check == "panic" {
+			panics++
+		}
+	}
+	if panics != 14 {
+		t.Errorf("baseline has %d panic findings, want 14", panics)
+	}
+
+	// Fixing one violation must not surface the others as new.
+	os.WriteFile(path, []byte(strings.Replace(src.String(), `panic("load 0")`, "", 1)), 0644)
+	if code := run([]string{"check", root, "--baseline", "--no-cache"}); code != exitOK {
+		t.Errorf("run(check --baseline) = %d, want %d", code, exitOK)
+	}
+}
+
+// syntheticTree creates repos git repositories under a temporary root, each
+// a Go module with files source files committed over commits commits.
+func syntheticTree(b *testing.B, repos, files, commits int) string {
+	b.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git not installed")
+	}
+	root := b.TempDir()
+	for r := 0; r < repos; r++ {
+		dir := filepath.Join(root, fmt.Sprintf("svc%d", r))
+		git := func(args ...string) {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=Dev", "-c", "user.email=dev@example.com"}, args...)...)
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				b.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+			}
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "internal", "store"), 0755); err != nil {
+			b.Fatal(err)
+		}
+		git("init", "-q")
+		os.WriteFile(filepath.Join(dir, "go.mod"), []byte(fmt.Sprintf("module example.com/svc%d\n\ngo 1.22\n", r)), 0644)
+		for c := 0; c < commits; c++ {
+			for f := c % 3; f < files; f += 3 {
+				src := fmt.Sprintf(`package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Get%[1]d loads record %[1]d (revision %[2]d).
+func Get%[1]d(db *sql.DB) error {
+	rows, err := db.Query("SELECT id FROM t%[1]d")
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		defer fmt.Println("row")
+	}
+	return nil // TODO: revision %[2]d
+}
+`, f, c)
+				os.WriteFile(filepath.Join(dir, "internal", "store", fmt.Sprintf("f%d.go", f)), []byte(src), 0644)
+			}
+			git("add", "-A")
+			git("commit", "-q", "-m", fmt.Sprintf("feat: revision %d", c))
+		}
+	}
+	return root
+}
+
+// BenchmarkPipeline runs scan, parse, git history and anti-pattern checks on
+// a synthetic multi-repo tree with one worker and with several. Much of the
+// work is waiting on git, so extra workers help even on few CPUs.
+func BenchmarkPipeline(b *testing.B) {
+	root := syntheticTree(b, 8, 60, 15)
+	logOut = io.Discard
+	defer func() { logOut = os.Stdout }()
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := config.DefaultConfig()
+			cfg.EnableCache = false
+			cfg.Concurrency = workers
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				p, err := loadProject(ctx, root, cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				collectHistory(ctx, p, "")
+				findings := report.Findings(p.Files, report.CheckOptions{
+					GitRepos: p.Scan.GitRepos,
+					Resolver: p.Resolver,
+					Root:     p.Root,
+					Workers:  cfg.Workers(),
+					Context:  ctx,
+				})
+				if len(findings) == 0 {
+					b.Fatal("no findings")
+				}
+			}
+		})
+	}
+}
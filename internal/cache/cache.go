@@ -0,0 +1,139 @@
+// Package cache stores analysis results on disk between runs. Entries are
+// addressed by keys derived from file contents (and, for git data, the HEAD
+// commit), so a changed input simply misses and stale entries are pruned by
+// age.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultDir returns the cache directory: goscope under the user's cache
+// directory (see os.UserCacheDir), so analyzing a checkout never writes
+// into it. Keys include file paths, so several roots can share it.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goscope"), nil
+}
+
+// formatVersion is mixed into every key. Bump it when a cached type changes
+// shape or a producer changes its output for the same input.
+const formatVersion = "9"
+
+// Cache is an on-disk key/value store of JSON values. A nil *Cache is a
+// disabled cache: Get always misses and Put does nothing.
+type Cache struct {
+	dir  string
+	salt string
+}
+
+// Open returns a cache in dir, creating it if needed. Entries written by a
+// different goscope version are not visible.
+func Open(dir, version string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, salt: formatVersion + "\x00" + version}, nil
+}
+
+// Key hashes parts into a cache key. Parts are length-delimited, so
+// ("ab", "c") and ("a", "bc") differ.
+func Key(parts ...string) string {
+	h := sha256.New()
+	var n [8]byte
+	for _, p := range parts {
+		binary.LittleEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Hash returns the content hash used in keys.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	name := Key(c.salt, key)
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+// Get decodes the entry for key into v and reports whether it was found.
+// Unreadable entries count as misses.
+func (c *Cache) Get(key string, v any) bool {
+	if c == nil {
+		return false
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false
+	}
+	// Mark the entry as used so Prune keeps it.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return true
+}
+
+// Put stores v under key. Failures are ignored: the cache is an
+// optimization and the next run recomputes the entry.
+func (c *Cache) Put(key string, v any) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// Write and rename so concurrent runs never read a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Prune removes entries not used for longer than maxAge and returns how
+// many were removed.
+func (c *Cache) Prune(maxAge time.Duration) int {
+	if c == nil {
+		return 0
+	}
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed
+}
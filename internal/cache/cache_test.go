@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c, err := Open(dir, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type entry struct {
+		Name  string
+		Lines map[int]string
+	}
+	key := Key("blame", "a.go", Hash([]byte("package a")))
+	var got entry
+	if c.Get(key, &got) {
+		t.Fatal("hit on empty cache")
+	}
+	c.Put(key, entry{Name: "a", Lines: map[int]string{3: "ann"}})
+	if !c.Get(key, &got) || got.Name != "a" || got.Lines[3] != "ann" {
+		t.Fatalf("Get = %+v", got)
+	}
+
+	other, err := Open(dir, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Get(key, &got) {
+		t.Error("entry visible to another version")
+	}
+
+	var disabled *Cache
+	disabled.Put(key, got)
+	if disabled.Get(key, &got) || disabled.Prune(0) != 0 {
+		t.Error("nil cache should be a no-op")
+	}
+
+	if Key("ab", "c") == Key("a", "bc") {
+		t.Error("Key is ambiguous")
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(c.path(key), old, old); err != nil {
+		t.Fatal(err)
+	}
+	c.Put(Key("fresh"), 1)
+	if n := c.Prune(24 * time.Hour); n != 1 {
+		t.Errorf("Prune removed %d entries, want 1", n)
+	}
+	if c.Get(key, &got) {
+		t.Error("pruned entry still present")
+	}
+	var n int
+	if !c.Get(Key("fresh"), &n) || n != 1 {
+		t.Error("fresh entry was pruned")
+	}
+}
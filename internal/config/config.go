@@ -2,23 +2,81 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"runtime"
+	"strings"
 )
 
 type Config struct {
-	ProjectName     string   `json:"project_name"`
-	ExcludePaths    []string `json:"excludePaths"`
-	MaxFilesAnalyze int      `json:"maxFilesAnalyze"`
-	GitCommitLimit  int      `json:"gitCommitLimit"`
-	EnableCache     bool     `json:"enableCache"`
-	EnableParallel  bool     `json:"enableParallel"`
-	HotspotCount    int      `json:"hotspotCount"`
-	FileExtensions  []string `json:"fileExtensions"`
+	ProjectName      string    `json:"project_name"`
+	ExcludePaths     []string  `json:"excludePaths"`
+	Include          []string  `json:"include"`          // gitignore-style globs; when set, only matching Go/proto files are analyzed
+	Exclude          []string  `json:"exclude"`          // gitignore-style globs of paths to skip
+	RespectGitignore bool      `json:"respectGitignore"` // also skip paths ignored by .gitignore files
+	ExcludeGenerated bool      `json:"excludeGenerated"` // drop files with a "Code generated ... DO NOT EDIT." header
+	MaxFilesAnalyze  int       `json:"maxFilesAnalyze"`
+	GitCommitLimit   int       `json:"gitCommitLimit"`
+	EnableCache      bool      `json:"enableCache"`
+	EnableParallel   bool      `json:"enableParallel"`
+	Concurrency      int       `json:"concurrency"`  // parallel workers; 0 means one per CPU
+	HotspotCount     int       `json:"hotspotCount"` // rows in the report's hot zones table
+	FileExtensions   []string  `json:"fileExtensions"`
+	TypeCheck        bool      `json:"typeCheck"`   // type-check packages for anti-pattern checks (needs the Go toolchain)
+	Services         []Service `json:"services"`    // explicit service map; overrides microservice detection
+	OSVDatabase      string    `json:"osvDatabase"` // local OSV snapshot (zip, directory or JSON file) to check go.mod requirements against
+	Limits           Limits    `json:"limits"`
+	Gates            Gates     `json:"gates"`
+}
+
+// Service assigns the Go/proto files under Paths to a named microservice
+// instead of the one detection would infer. Entries are tried in order and
+// the first whose paths cover a file wins; files no entry covers are still
+// detected. A Shared entry is a library used by several services: it is
+// analyzed like a service but not counted as one.
+type Service struct {
+	Name   string   `json:"name"`
+	Paths  []string `json:"paths"` // gitignore-style globs; a directory pattern covers everything below it
+	Shared bool     `json:"shared"`
+}
+
+// Limits bound the size of report sections and of the more expensive
+// analysis steps.
+type Limits struct {
+	TeamRows         int `json:"teamRows"`         // authors in the team table
+	LongestFunctions int `json:"longestFunctions"` // rows in the longest functions table
+	FindingsPerCheck int `json:"findingsPerCheck"` // violations listed per anti-pattern check
+	TypeRefDecls     int `json:"typeRefDecls"`     // declarations searched for when linking files by type references
+}
+
+// Gates are the quality thresholds enforced by `goscope check`. A zero
+// value disables a gate.
+type Gates struct {
+	NoHighFindings         bool     `json:"noHighFindings"`         // fail on any HIGH anti-pattern finding
+	NoNewCycles            bool     `json:"noNewCycles"`            // fail on package cycles not in the baseline
+	MaxFunctionLines       int      `json:"maxFunctionLines"`       // longest allowed function
+	MaxTodosPerService     int      `json:"maxTodosPerService"`     // TODO+FIXME comments per microservice must stay below this
+	MinConventionalCommits float64  `json:"minConventionalCommits"` // required share of conventional commits, 0–1
+	DeniedLicenses         []string `json:"deniedLicenses"`         // SPDX IDs or families ("GPL") no required module may use
 }
 
 const DefaultConfigPath = ".goscope.json"
 
+// Workers returns how many parallel workers to use: one when parallelism
+// is disabled, otherwise Concurrency or, when unset, one per CPU.
+func (c Config) Workers() int {
+	switch {
+	case !c.EnableParallel:
+		return 1
+	case c.Concurrency > 0:
+		return c.Concurrency
+	default:
+		return runtime.GOMAXPROCS(0)
+	}
+}
+
 func DefaultConfig() Config {
 	return Config{
 		ProjectName: "",
@@ -27,13 +85,82 @@ func DefaultConfig() Config {
 			"build", ".idea", ".vscode", "__pycache__", ".cache",
 			"DerivedData", "Pods", "target",
 		},
-		MaxFilesAnalyze: 50000,
-		GitCommitLimit:  1000,
-		EnableCache:     false,
-		EnableParallel:  true,
-		HotspotCount:    15,
-		FileExtensions:  []string{"go", "proto"},
+		RespectGitignore: true,
+		MaxFilesAnalyze:  50000,
+		GitCommitLimit:   1000,
+		EnableCache:      true,
+		EnableParallel:   true,
+		Concurrency:      0,
+		HotspotCount:     15,
+		FileExtensions:   []string{"go", "proto"},
+		TypeCheck:        false,
+		Limits: Limits{
+			TeamRows:         30,
+			LongestFunctions: 20,
+			FindingsPerCheck: 10,
+			TypeRefDecls:     500,
+		},
+		Gates: Gates{NoHighFindings: true},
+	}
+}
+
+// Validate reports every setting that cannot be honored, such as a zero
+// file limit or a commit ratio above one.
+func (c Config) Validate() error {
+	var errs []error
+	atLeastOne := func(name string, v int) {
+		if v < 1 {
+			errs = append(errs, fmt.Errorf("%s must be at least 1, got %d", name, v))
+		}
+	}
+	notNegative := func(name string, v int) {
+		if v < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", name, v))
+		}
+	}
+	atLeastOne("maxFilesAnalyze", c.MaxFilesAnalyze)
+	atLeastOne("gitCommitLimit", c.GitCommitLimit)
+	atLeastOne("hotspotCount", c.HotspotCount)
+	notNegative("concurrency", c.Concurrency)
+	if len(c.FileExtensions) == 0 {
+		errs = append(errs, errors.New("fileExtensions must not be empty"))
+	}
+	checkPatterns := func(name string, patterns []string) {
+		for _, p := range patterns {
+			for _, seg := range strings.Split(strings.TrimPrefix(p, "!"), "/") {
+				if _, err := path.Match(seg, ""); err != nil {
+					errs = append(errs, fmt.Errorf("%s pattern %q is malformed", name, p))
+					break
+				}
+			}
+		}
+	}
+	checkPatterns("include", c.Include)
+	checkPatterns("exclude", c.Exclude)
+	seen := make(map[string]bool)
+	for i, svc := range c.Services {
+		switch {
+		case svc.Name == "":
+			errs = append(errs, fmt.Errorf("services[%d] has no name", i))
+		case seen[svc.Name]:
+			errs = append(errs, fmt.Errorf("services[%d]: service %q is listed twice", i, svc.Name))
+		}
+		seen[svc.Name] = true
+		if len(svc.Paths) == 0 {
+			errs = append(errs, fmt.Errorf("services[%d] (%s) has no paths", i, svc.Name))
+		}
+		checkPatterns(fmt.Sprintf("services[%d] (%s)", i, svc.Name), svc.Paths)
+	}
+	atLeastOne("limits.teamRows", c.Limits.TeamRows)
+	atLeastOne("limits.longestFunctions", c.Limits.LongestFunctions)
+	atLeastOne("limits.findingsPerCheck", c.Limits.FindingsPerCheck)
+	atLeastOne("limits.typeRefDecls", c.Limits.TypeRefDecls)
+	notNegative("gates.maxFunctionLines", c.Gates.MaxFunctionLines)
+	notNegative("gates.maxTodosPerService", c.Gates.MaxTodosPerService)
+	if r := c.Gates.MinConventionalCommits; r < 0 || r > 1 {
+		errs = append(errs, fmt.Errorf("gates.minConventionalCommits must be between 0 and 1, got %g", r))
 	}
+	return errors.Join(errs...)
 }
 
 func Load(path string) Config {
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxFilesAnalyze = 0
+	cfg.Concurrency = -2
+	cfg.FileExtensions = nil
+	cfg.Limits.FindingsPerCheck = 0
+	cfg.Gates.MinConventionalCommits = 60
+	cfg.Services = []Service{
+		{Name: "orders", Paths: []string{"services/orders"}},
+		{Name: "orders", Paths: []string{"pkg/[orders"}},
+		{Paths: []string{"pkg"}, Shared: true},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, want := range []string{
+		"maxFilesAnalyze must be at least 1, got 0",
+		"concurrency must not be negative, got -2",
+		"fileExtensions must not be empty",
+		"limits.findingsPerCheck must be at least 1, got 0",
+		"gates.minConventionalCommits must be between 0 and 1, got 60",
+		`services[1]: service "orders" is listed twice`,
+		`services[1] (orders) pattern "pkg/[orders" is malformed`,
+		"services[2] has no name",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package gate evaluates the quality gates configured in .goscope.json, so
+// `goscope check` can fail a CI job on more than anti-pattern findings.
+package gate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/config"
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/report"
+)
+
+// maxExamples is how many offenders a failed gate lists.
+const maxExamples = 3
+
+// Input is what the gates are evaluated on. Fields a disabled gate does not
+// need may be left empty.
+type Input struct {
+	Findings    []report.Finding
+	Cycles      [][]string // members of each package cycle, see CycleMembers
+	KnownCycles [][]string // accepted cycles from the baseline
+	Files       []*parser.ParsedFile
+	Commits     gitpkg.CommitStats
+	Licenses    *license.Report // scanned with the gate's deny-list
+}
+
+// Result is the outcome of one gate.
+type Result struct {
+	Gate   string // stable identifier, e.g. "max-function-lines"
+	Passed bool
+	Detail string // one-line summary with the worst offenders
+}
+
+// Evaluate runs every enabled gate in a fixed order.
+func Evaluate(g config.Gates, in Input) []Result {
+	var out []Result
+	if g.NoHighFindings {
+		out = append(out, noHighFindings(in.Findings))
+	}
+	if g.NoNewCycles {
+		out = append(out, noNewCycles(in.Cycles, in.KnownCycles))
+	}
+	if g.MaxFunctionLines > 0 {
+		out = append(out, maxFunctionLines(in.Files, g.MaxFunctionLines))
+	}
+	if g.MaxTodosPerService > 0 {
+		out = append(out, maxTodosPerService(in.Files, g.MaxTodosPerService))
+	}
+	if g.MinConventionalCommits > 0 {
+		out = append(out, minConventionalCommits(in.Commits, g.MinConventionalCommits))
+	}
+	if len(g.DeniedLicenses) > 0 {
+		out = append(out, deniedLicenses(in.Licenses))
+	}
+	return out
+}
+
+// Failed returns how many results did not pass.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// CycleMembers returns the members of each cycle relative to root, so
+// cycles compare equal across checkouts.
+func CycleMembers(cycles []graph.Cycle, root string) [][]string {
+	out := make([][]string, 0, len(cycles))
+	for _, c := range cycles {
+		members := make([]string, len(c.Members))
+		for i, m := range c.Members {
+			if rel, err := filepath.Rel(root, m); err == nil && !strings.HasPrefix(rel, "..") {
+				m = rel
+			}
+			members[i] = filepath.ToSlash(m)
+		}
+		sort.Strings(members)
+		out = append(out, members)
+	}
+	return out
+}
+
+func noHighFindings(findings []report.Finding) Result {
+	var high []string
+	for _, f := range findings {
+		if f.Priority == report.PriorityHigh {
+			high = append(high, fmt.Sprintf("%s:%d %s", f.File, f.Line, f.ID))
+		}
+	}
+	r := Result{Gate: "no-high-findings", Passed: len(high) == 0, Detail: "no HIGH findings"}
+	if !r.Passed {
+		r.Detail = fmt.Sprintf("%d HIGH findings: %s", len(high), examples(high))
+	}
+	return r
+}
+
+// noNewCycles fails on cycles that are not contained in a known one. A
+// known cycle that shrank or stayed the same is not new; one that pulled in
+// another package is.
+func noNewCycles(cycles, known [][]string) Result {
+	var fresh []string
+	for _, c := range cycles {
+		if !containedIn(c, known) {
+			fresh = append(fresh, strings.Join(c, " ↔ "))
+		}
+	}
+	r := Result{Gate: "no-new-cycles", Passed: len(fresh) == 0}
+	switch {
+	case !r.Passed:
+		r.Detail = fmt.Sprintf("%d new package cycles: %s", len(fresh), examples(fresh))
+	case len(cycles) > 0:
+		r.Detail = fmt.Sprintf("%d package cycles, all in the baseline", len(cycles))
+	default:
+		r.Detail = "no package cycles"
+	}
+	return r
+}
+
+func containedIn(members []string, known [][]string) bool {
+	for _, k := range known {
+		set := make(map[string]bool, len(k))
+		for _, m := range k {
+			set[m] = true
+		}
+		all := true
+		for _, m := range members {
+			all = all && set[m]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// maxFunctionLines checks every function declaration, measured from its
+// func keyword to its closing brace like the report's function lengths.
+// Files the parser could only read line by line have no declaration ends;
+// their functions are measured like the report does, from the longest
+// function and those of at least 25 lines.
+func maxFunctionLines(files []*parser.ParsedFile, limit int) Result {
+	type fn struct {
+		name  string
+		lines int
+	}
+	var long []fn
+	for _, f := range files {
+		if !declarationEnds(f) {
+			seen := make(map[string]bool)
+			funcs := f.BigFunctions
+			if f.LongestFunction != nil {
+				funcs = append(funcs[:len(funcs):len(funcs)], *f.LongestFunction)
+			}
+			for _, fi := range funcs {
+				if fi.LineCount > limit && !seen[fi.Name] {
+					seen[fi.Name] = true
+					long = append(long, fn{fmt.Sprintf("%s %s", shortPath(f.FilePath), fi.Name), fi.LineCount})
+				}
+			}
+			continue
+		}
+		for _, d := range f.Declarations {
+			if d.Kind != parser.DeclFunc {
+				continue
+			}
+			lines := d.EndLine - d.Line + 1
+			if lines <= limit {
+				continue
+			}
+			name := d.Name
+			if d.Receiver != "" {
+				name = strings.TrimPrefix(d.Receiver, "*") + "." + name
+			}
+			long = append(long, fn{fmt.Sprintf("%s %s", shortPath(f.FilePath), name), lines})
+		}
+	}
+	sort.Slice(long, func(i, j int) bool {
+		if long[i].lines != long[j].lines {
+			return long[i].lines > long[j].lines
+		}
+		return long[i].name < long[j].name
+	})
+	r := Result{Gate: "max-function-lines", Passed: len(long) == 0,
+		Detail: fmt.Sprintf("no function over %d lines", limit)}
+	if !r.Passed {
+		names := make([]string, len(long))
+		for i, l := range long {
+			names[i] = fmt.Sprintf("%s (%d)", l.name, l.lines)
+		}
+		r.Detail = fmt.Sprintf("%d functions over %d lines: %s", len(long), limit, examples(names))
+	}
+	return r
+}
+
+// declarationEnds reports whether every function of f has a known end line.
+func declarationEnds(f *parser.ParsedFile) bool {
+	for _, d := range f.Declarations {
+		if d.Kind == parser.DeclFunc && d.EndLine == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// maxTodosPerService fails services with limit or more TODO and FIXME
+// comments: the count has to stay below the limit.
+func maxTodosPerService(files []*parser.ParsedFile, limit int) Result {
+	counts := make(map[string]int)
+	for _, f := range files {
+		ms := f.MicroserviceName
+		if ms == "" {
+			ms = "root"
+		}
+		counts[ms] += f.TodoCount + f.FixmeCount
+	}
+	var over []string
+	for ms, n := range counts {
+		if n >= limit {
+			over = append(over, ms)
+		}
+	}
+	sort.Slice(over, func(i, j int) bool {
+		if counts[over[i]] != counts[over[j]] {
+			return counts[over[i]] > counts[over[j]]
+		}
+		return over[i] < over[j]
+	})
+	r := Result{Gate: "max-todos-per-service", Passed: len(over) == 0,
+		Detail: fmt.Sprintf("every service has fewer than %d TODO/FIXME", limit)}
+	if !r.Passed {
+		for i, ms := range over {
+			over[i] = fmt.Sprintf("%s (%d)", ms, counts[ms])
+		}
+		r.Detail = fmt.Sprintf("%d services with %d or more TODO/FIXME: %s", len(over), limit, examples(over))
+	}
+	return r
+}
+
+// minConventionalCommits uses the same notion of a conventional commit as
+// the report: a conventional-commit prefix or a ticket reference.
+func minConventionalCommits(cs gitpkg.CommitStats, want float64) Result {
+	r := Result{Gate: "min-conventional-commits", Passed: true}
+	if cs.Total == 0 {
+		r.Detail = "no commits to check"
+		return r
+	}
+	ratio := float64(cs.Typed) / float64(cs.Total)
+	r.Passed = ratio >= want
+	r.Detail = fmt.Sprintf("%.0f%% of %d commits are conventional (minimum %.0f%%)", ratio*100, cs.Total, want*100)
+	return r
+}
+
+func deniedLicenses(r *license.Report) Result {
+	res := Result{Gate: "denied-licenses", Passed: true, Detail: "no licenses to check"}
+	if r == nil {
+		return res
+	}
+	var denied []string
+	for _, m := range r.DeniedModules() {
+		denied = append(denied, fmt.Sprintf("%s@%s (%s)", m.Path, m.Version, strings.Join(m.Licenses, ", ")))
+	}
+	res.Passed = len(denied) == 0
+	res.Detail = fmt.Sprintf("no denied licenses in %d required modules", len(r.Modules))
+	if r.Missing > 0 {
+		res.Detail += fmt.Sprintf(" (%d not in the module cache)", r.Missing)
+	}
+	if !res.Passed {
+		res.Detail = fmt.Sprintf("%d modules under denied licenses: %s", len(denied), examples(denied))
+	}
+	return res
+}
+
+func examples(items []string) string {
+	if len(items) > maxExamples {
+		return strings.Join(items[:maxExamples], ", ") + ", …"
+	}
+	return strings.Join(items, ", ")
+}
+
+// shortPath keeps the last three path elements, like the findings list.
+func shortPath(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if len(parts) > 3 {
+		parts = parts[len(parts)-3:]
+	}
+	return strings.Join(parts, "/")
+}
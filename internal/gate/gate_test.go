@@ -0,0 +1,127 @@
+package gate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goscope/internal/config"
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/report"
+)
+
+func TestEvaluate(t *testing.T) {
+	files := []*parser.ParsedFile{
+		{
+			FilePath:         "/code/orders/api/handler.go",
+			MicroserviceName: "orders",
+			TodoCount:        3,
+			FixmeCount:       1,
+			Declarations: []parser.Declaration{
+				{Name: "Handler", Kind: parser.DeclType, Line: 3, EndLine: 200},
+				{Name: "Handle", Kind: parser.DeclFunc, Receiver: "*Handler", Line: 10, EndLine: 129},
+				{Name: "validate", Kind: parser.DeclFunc, Line: 140, EndLine: 179},
+			},
+		},
+		{
+			FilePath:  "/code/users/store.go",
+			TodoCount: 1,
+			Declarations: []parser.Declaration{
+				{Name: "Get", Kind: parser.DeclFunc, Line: 5, EndLine: 24},
+				{Name: "Put", Kind: parser.DeclFunc, Line: 30, EndLine: 41},
+			},
+		},
+	}
+	in := Input{
+		Findings: []report.Finding{
+			{ID: "panic", Priority: report.PriorityHigh, File: "orders/api/handler.go", Line: 7},
+			{ID: "naked-return", Priority: report.PriorityLow, File: "users/store.go", Line: 3},
+		},
+		Cycles:      [][]string{{"orders/a", "orders/b"}, {"users/x", "users/y", "users/z"}},
+		KnownCycles: [][]string{{"orders/a", "orders/b", "orders/c"}, {"users/x", "users/y"}},
+		Files:       files,
+		Commits:     gitpkg.CommitStats{Total: 10, Typed: 7},
+		Licenses: &license.Report{Missing: 1, Modules: []license.Module{
+			{Path: "github.com/acme/gpl", Version: "v1.0.0", Licenses: []string{"GPL-3.0"}, Denied: true},
+			{Path: "golang.org/x/sys", Version: "v0.20.0", Licenses: []string{"BSD-3-Clause"}},
+			{Path: "github.com/acme/gone", Version: "v2.0.0"},
+		}},
+	}
+	gates := config.Gates{
+		NoHighFindings:         true,
+		NoNewCycles:            true,
+		MaxFunctionLines:       50,
+		MaxTodosPerService:     4,
+		MinConventionalCommits: 0.7,
+		DeniedLicenses:         []string{"GPL"},
+	}
+
+	results := Evaluate(gates, in)
+	want := []struct {
+		gate   string
+		passed bool
+		detail string
+	}{
+		{"no-high-findings", false, "1 HIGH findings: orders/api/handler.go:7 panic"},
+		{"no-new-cycles", false, "1 new package cycles: users/x ↔ users/y ↔ users/z"},
+		{"max-function-lines", false, "1 functions over 50 lines: orders/api/handler.go Handler.Handle (120)"},
+		{"max-todos-per-service", false, "1 services with 4 or more TODO/FIXME: orders (4)"},
+		{"min-conventional-commits", true, "70% of 10 commits are conventional (minimum 70%)"},
+		{"denied-licenses", false, "1 modules under denied licenses: github.com/acme/gpl@v1.0.0 (GPL-3.0)"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Gate != w.gate || r.Passed != w.passed || r.Detail != w.detail {
+			t.Errorf("result %d = %+v, want %s passed=%v %q", i, r, w.gate, w.passed, w.detail)
+		}
+	}
+	if n := Failed(results); n != 5 {
+		t.Errorf("Failed = %d, want 5", n)
+	}
+
+	// Functions under the parser's big-function threshold are measured too,
+	// not only the longest one of each file.
+	r := Evaluate(config.Gates{MaxFunctionLines: 10}, in)
+	if len(r) != 1 || !strings.Contains(r[0].Detail, "4 functions over 10 lines") || !strings.Contains(r[0].Detail, "users/store.go Get (20)") {
+		t.Errorf("short limit: %+v", r)
+	}
+	// Files read by the regex fallback have no end lines; their measured
+	// functions are used instead.
+	fallback := &parser.ParsedFile{
+		FilePath:        "/code/users/broken.go",
+		Declarations:    []parser.Declaration{{Name: "Parse", Kind: parser.DeclFunc, Line: 3}},
+		LongestFunction: &parser.FunctionInfo{Name: "Parse", LineCount: 90},
+		BigFunctions:    []parser.FunctionInfo{{Name: "Parse", LineCount: 90}},
+	}
+	r = Evaluate(config.Gates{MaxFunctionLines: 50}, Input{Files: append(files, fallback)})
+	if len(r) != 1 || r[0].Detail != "2 functions over 50 lines: orders/api/handler.go Handler.Handle (120), code/users/broken.go Parse (90)" {
+		t.Errorf("fallback file: %+v", r)
+	}
+	if r := Evaluate(config.Gates{MaxTodosPerService: 5}, in); !r[0].Passed || r[0].Detail != "every service has fewer than 5 TODO/FIXME" {
+		t.Errorf("todos below the limit: %+v", r[0])
+	}
+
+	if r := Evaluate(config.Gates{}, in); len(r) != 0 {
+		t.Errorf("disabled gates produced %+v", r)
+	}
+	if r := Evaluate(config.Gates{MinConventionalCommits: 0.9}, Input{}); !r[0].Passed {
+		t.Errorf("no commits should pass: %+v", r[0])
+	}
+	in.Licenses.Modules = in.Licenses.Modules[1:]
+	r = Evaluate(config.Gates{DeniedLicenses: []string{"GPL"}}, in)
+	if len(r) != 1 || !r[0].Passed || r[0].Detail != "no denied licenses in 2 required modules (1 not in the module cache)" {
+		t.Errorf("clean licenses: %+v", r)
+	}
+}
+
+func TestCycleMembers(t *testing.T) {
+	got := CycleMembers([]graph.Cycle{{Members: []string{"/code/svc/b", "/code/svc/a", "github.com/x/y"}}}, "/code")
+	if len(got) != 1 || strings.Join(got[0], ",") != "github.com/x/y,svc/a,svc/b" {
+		t.Errorf("CycleMembers = %v", got)
+	}
+}
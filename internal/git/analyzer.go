@@ -2,6 +2,7 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -12,60 +13,61 @@ import (
 	"time"
 
 	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/pool"
 )
 
 // AuthorStats holds repo-wide author statistics.
 type AuthorStats struct {
-	FilesModified      int
-	TotalCommits       int
-	FirstCommit        float64
-	LastCommit         float64
-	MicroserviceCounts map[string]int
-	TotalLOCAdded      int
+	FilesModified      int            `json:"filesModified"`
+	TotalCommits       int            `json:"totalCommits"`
+	FirstCommit        float64        `json:"firstCommit"` // unix timestamp
+	LastCommit         float64        `json:"lastCommit"`  // unix timestamp
+	MicroserviceCounts map[string]int `json:"microserviceCounts"`
+	TotalLOCAdded      int            `json:"totalLocAdded"`
 }
 
 // FileChurnStat holds churn data for a single file.
 type FileChurnStat struct {
-	RelPath     string
-	ChangeCount int
-	TopAuthors  []string
+	RelPath     string   `json:"relPath"`
+	ChangeCount int      `json:"changeCount"`
+	TopAuthors  []string `json:"topAuthors"`
 }
 
 // TagStats holds semver tag analysis.
 type TagStats struct {
-	TotalTags    int
-	SemverTags   int
-	LatestSemver string
-	SemverList   []string
+	TotalTags    int      `json:"totalTags"`
+	SemverTags   int      `json:"semverTags"`
+	LatestSemver string   `json:"latestSemver"`
+	SemverList   []string `json:"semverList"`
 }
 
 // CommitStats holds conventional-commit analysis.
 type CommitStats struct {
-	Total      int
-	Typed      int
-	TypeCounts map[string]int
-	Samples    []string // sample non-conventional messages
+	Total      int            `json:"total"`
+	Typed      int            `json:"typed"`
+	TypeCounts map[string]int `json:"typeCounts"`
+	Samples    []string       `json:"samples"` // sample non-conventional messages
 }
 
 // BranchInfo holds data for a single local branch.
 type BranchInfo struct {
-	Name         string
-	LastActivity float64 // unix timestamp
-	DaysInactive int
+	Name         string  `json:"name"`
+	LastActivity float64 `json:"lastActivity"` // unix timestamp
+	DaysInactive int     `json:"daysInactive"`
 }
 
 // BranchStats holds branch management metrics.
 type BranchStats struct {
-	TotalBranches      int
-	StaleBranches      []BranchInfo
-	StaleThresholdDays int
-	AvgLifetimeDays    float64 // avg time from first to last commit in merged branches
-	AvgTTMDays         float64 // avg time from first branch commit to merge
-	AvgIntegDelayHours float64 // avg time from last branch commit to merge (review delay)
-	MaxDepth           int     // max nesting depth inferred from branch names
-	RollbackCount      int
-	TotalMainCommits   int
-	PeakCommitDay      string // day of week with the most commits
+	TotalBranches      int          `json:"totalBranches"`
+	StaleBranches      []BranchInfo `json:"staleBranches"`
+	StaleThresholdDays int          `json:"staleThresholdDays"`
+	AvgLifetimeDays    float64      `json:"avgLifetimeDays"`    // avg time from first to last commit in merged branches
+	AvgTTMDays         float64      `json:"avgTtmDays"`         // avg time from first branch commit to merge
+	AvgIntegDelayHours float64      `json:"avgIntegDelayHours"` // avg time from last branch commit to merge (review delay)
+	MaxDepth           int          `json:"maxDepth"`           // max nesting depth inferred from branch names
+	RollbackCount      int          `json:"rollbackCount"`
+	TotalMainCommits   int          `json:"totalMainCommits"`
+	PeakCommitDay      string       `json:"peakCommitDay"` // day of week with the most commits
 }
 
 // GitSummary bundles all git data produced for a report.
@@ -81,23 +83,59 @@ type GitSummary struct {
 type Analyzer struct {
 	RepoPath    string
 	CommitLimit int
+	Since       string // optional git date expression, e.g. "2024-01-01" or "6 months ago"
+
+	ctx context.Context // kills running git processes when done; nil means never
 }
 
 func NewAnalyzer(repoPath string, commitLimit int) *Analyzer {
 	return &Analyzer{RepoPath: repoPath, CommitLimit: commitLimit}
 }
 
+func (a *Analyzer) context() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+// eachRepo calls fn with an Analyzer for every repository, up to workers
+// (at least one) at a time, and returns the results in repository order so
+// merging them is deterministic. Repositories not reached before ctx is done
+// yield zero values; callers report ctx.Err() themselves.
+func eachRepo[T any](ctx context.Context, gitRepos []string, workers, commitLimit int, since string, fn func(a *Analyzer) T) []T {
+	out, _ := pool.Map(ctx, max(workers, 1), len(gitRepos), func(i int) T {
+		return fn(&Analyzer{RepoPath: gitRepos[i], CommitLimit: commitLimit, Since: since, ctx: ctx})
+	})
+	return out
+}
+
+// logOutputs runs `git log <history window> <args>` in every repository.
+func logOutputs(ctx context.Context, gitRepos []string, workers, commitLimit int, since string, args ...string) []string {
+	return eachRepo(ctx, gitRepos, workers, commitLimit, since, func(a *Analyzer) string {
+		logArgs := append([]string{"log"}, historyWindow(commitLimit, since)...)
+		return a.git(a.RepoPath, append(logArgs, args...)...)
+	})
+}
+
+// historyWindow returns the git log arguments limiting how much history is read.
+func historyWindow(commitLimit int, since string) []string {
+	args := []string{fmt.Sprintf("-%d", commitLimit)}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	return args
+}
+
 func (a *Analyzer) CurrentBranch() string {
 	out := a.git(a.RepoPath, "rev-parse", "--abbrev-ref", "HEAD")
 	return strings.TrimSpace(out)
 }
 
 // GetAuthorStatsMultiRepo collects author stats from multiple git repos.
-func GetAuthorStatsMultiRepo(gitRepos []string, commitLimit int) map[string]*AuthorStats {
+func GetAuthorStatsMultiRepo(ctx context.Context, gitRepos []string, workers, commitLimit int, since string) map[string]*AuthorStats {
 	stats := make(map[string]*AuthorStats)
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: commitLimit}
-		out := a.git(repo, "log", fmt.Sprintf("-%d", commitLimit), "--pretty=format:%an\t%at")
+	for _, out := range logOutputs(ctx, gitRepos, workers, commitLimit, since, "--pretty=format:%an\t%at") {
 		if out == "" {
 			continue
 		}
@@ -136,15 +174,15 @@ type fileStats struct {
 	messages        []string
 }
 
-// EnrichFilesMultiRepo enriches files using git logs from multiple repos.
-func EnrichFilesMultiRepo(gitRepos []string, commitLimit int, files []*parser.ParsedFile, authorStats map[string]*AuthorStats) {
+// EnrichFilesMultiRepo enriches files using git logs from multiple repos and
+// returns the number of file entries found in the logs.
+func EnrichFilesMultiRepo(ctx context.Context, gitRepos []string, workers, commitLimit int, since string, files []*parser.ParsedFile, authorStats map[string]*AuthorStats) int {
 	// Build a merged batch from all repos
 	allBatch := make(map[string]*fileStats)
 
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: commitLimit}
-		batch := a.batchCollectFileStats()
-		for relPath, fs := range batch {
+	batches := eachRepo(ctx, gitRepos, workers, commitLimit, since, (*Analyzer).batchCollectFileStats)
+	for i, repo := range gitRepos {
+		for relPath, fs := range batches[i] {
 			// Convert relative path to absolute for matching
 			absPath := filepath.Join(repo, relPath)
 			allBatch[absPath] = fs
@@ -153,8 +191,6 @@ func EnrichFilesMultiRepo(gitRepos []string, commitLimit int, files []*parser.Pa
 		}
 	}
 
-	fmt.Printf("   Batch git log parsed (%d file entries from %d repos)\n", len(allBatch), len(gitRepos))
-
 	for _, file := range files {
 		var fs *fileStats
 		// Try absolute path first
@@ -199,14 +235,15 @@ func EnrichFilesMultiRepo(gitRepos []string, commitLimit int, files []*parser.Pa
 			}
 		}
 	}
+	return len(allBatch)
 }
 
 func (a *Analyzer) batchCollectFileStats() map[string]*fileStats {
-	cmd := exec.Command("git", "log",
-		fmt.Sprintf("-%d", a.CommitLimit),
+	args := append([]string{"log"}, historyWindow(a.CommitLimit, a.Since)...)
+	cmd := exec.CommandContext(a.context(), "git", append(args,
 		"--pretty=format:__COMMIT__%n%an%n%at%n%s",
 		"--name-only",
-	)
+	)...)
 	cmd.Dir = a.RepoPath
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -256,7 +293,7 @@ func (a *Analyzer) batchCollectFileStats() map[string]*fileStats {
 }
 
 func (a *Analyzer) git(dir string, args ...string) string {
-	cmd := exec.Command("git", args...)
+	cmd := exec.CommandContext(a.context(), "git", args...)
 	cmd.Dir = dir
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -268,14 +305,8 @@ func (a *Analyzer) git(dir string, args ...string) string {
 }
 
 // EnrichAuthorLOC populates TotalLOCAdded in existing AuthorStats entries via --numstat.
-func EnrichAuthorLOC(gitRepos []string, commitLimit int, authorStats map[string]*AuthorStats) {
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: commitLimit}
-		out := a.git(repo, "log",
-			fmt.Sprintf("-%d", commitLimit),
-			"--pretty=format:__AUTHOR__%n%an",
-			"--numstat",
-		)
+func EnrichAuthorLOC(ctx context.Context, gitRepos []string, workers, commitLimit int, since string, authorStats map[string]*AuthorStats) {
+	for _, out := range logOutputs(ctx, gitRepos, workers, commitLimit, since, "--pretty=format:__AUTHOR__%n%an", "--numstat") {
 		if out == "" {
 			continue
 		}
@@ -305,30 +336,20 @@ func EnrichAuthorLOC(gitRepos []string, commitLimit int, authorStats map[string]
 }
 
 // GetChurnStats returns the top N most-changed files across all repos.
-func GetChurnStats(gitRepos []string, commitLimit, topN int) []FileChurnStat {
+func GetChurnStats(ctx context.Context, gitRepos []string, workers, commitLimit int, since string, topN int) []FileChurnStat {
 	type entry struct {
 		changeCount  int
 		authorCounts map[string]int
 	}
 	all := make(map[string]*entry)
 
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: commitLimit}
-		cmd := exec.Command("git", "log",
-			fmt.Sprintf("-%d", commitLimit),
-			"--pretty=format:__COMMIT__%n%an",
-			"--name-only",
-		)
-		cmd.Dir = repo
-		var buf bytes.Buffer
-		cmd.Stdout = &buf
-		cmd.Stderr = &bytes.Buffer{}
-		if err := cmd.Run(); err != nil {
+	outs := logOutputs(ctx, gitRepos, workers, commitLimit, since, "--pretty=format:__COMMIT__%n%an", "--name-only")
+	for i, repo := range gitRepos {
+		if outs[i] == "" {
 			continue
 		}
-		_ = a
 		currentAuthor := ""
-		for _, line := range strings.Split(buf.String(), "\n") {
+		for _, line := range strings.Split(outs[i], "\n") {
 			if line == "__COMMIT__" {
 				currentAuthor = ""
 				continue
@@ -398,13 +419,12 @@ func GetChurnStats(gitRepos []string, commitLimit, topN int) []FileChurnStat {
 var semverRe = regexp.MustCompile(`^v?\d+\.\d+\.\d+`)
 
 // GetTagStats analyzes git tags for semver compliance across all repos.
-func GetTagStats(gitRepos []string) TagStats {
+func GetTagStats(ctx context.Context, gitRepos []string, workers int) TagStats {
 	seen := make(map[string]bool)
 	var ts TagStats
 
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: 0}
-		out := a.git(repo, "tag")
+	outs := eachRepo(ctx, gitRepos, workers, 0, "", func(a *Analyzer) string { return a.git(a.RepoPath, "tag") })
+	for _, out := range outs {
 		if out == "" {
 			continue
 		}
@@ -463,17 +483,12 @@ var conventionalRe = regexp.MustCompile(`^(feat|fix|chore|refactor|docs|style|te
 var ticketRe = regexp.MustCompile(`(#\d+|[A-Z]+-\d+|GH-\d+)`)
 
 // GetCommitMessageStats analyzes commit messages for conventional commit compliance.
-func GetCommitMessageStats(gitRepos []string, commitLimit int) CommitStats {
+func GetCommitMessageStats(ctx context.Context, gitRepos []string, workers, commitLimit int, since string) CommitStats {
 	var cs CommitStats
 	cs.TypeCounts = make(map[string]int)
 	seen := make(map[string]bool)
 
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo, CommitLimit: commitLimit}
-		out := a.git(repo, "log",
-			fmt.Sprintf("-%d", commitLimit),
-			"--pretty=format:%H\t%s",
-		)
+	for _, out := range logOutputs(ctx, gitRepos, workers, commitLimit, since, "--pretty=format:%H\t%s") {
 		if out == "" {
 			continue
 		}
@@ -506,12 +521,12 @@ func GetCommitMessageStats(gitRepos []string, commitLimit int) CommitStats {
 }
 
 // BlameAuthors returns a map of line number (1-based) → author name for a file.
-func BlameAuthors(gitRepos []string, absFilePath string) map[int]string {
+func BlameAuthors(ctx context.Context, gitRepos []string, absFilePath string) map[int]string {
 	for _, repo := range gitRepos {
 		if !strings.HasPrefix(absFilePath, repo) {
 			continue
 		}
-		cmd := exec.Command("git", "blame", "-p", absFilePath)
+		cmd := exec.CommandContext(ctx, "git", "blame", "-p", absFilePath)
 		cmd.Dir = repo
 		var buf bytes.Buffer
 		cmd.Stdout = &buf
@@ -587,8 +602,18 @@ func (a *Analyzer) defaultMainBranch() string {
 	return "HEAD"
 }
 
+// repoBranchData is what GetBranchStats collects from one repository before
+// the results of all repositories are merged.
+type repoBranchData struct {
+	branches                    [][2]string // name, committer date (unix)
+	lifetimes, ttms, integDelay []float64
+	rollbacks                   int
+	dayCounts                   map[time.Weekday]int
+	mainCommits                 int
+}
+
 // GetBranchStats collects branch management metrics across all repos.
-func GetBranchStats(gitRepos []string, staleDays int) BranchStats {
+func GetBranchStats(ctx context.Context, gitRepos []string, workers, staleDays int) BranchStats {
 	var bs BranchStats
 	bs.StaleThresholdDays = staleDays
 	if bs.StaleThresholdDays <= 0 {
@@ -603,21 +628,11 @@ func GetBranchStats(gitRepos []string, staleDays int) BranchStats {
 	var lifetimes, ttms, integDelays []float64
 	dayCounts := make(map[time.Weekday]int)
 
-	for _, repo := range gitRepos {
-		a := &Analyzer{RepoPath: repo}
-		mainBranch := a.defaultMainBranch()
-
-		// 1. Branch inventory: stale detection + depth from naming
-		branchOut := a.git(repo, "for-each-ref",
-			"--format=%(refname:short)\t%(committerdate:unix)",
-			"refs/heads/")
-		for _, line := range strings.Split(strings.TrimSpace(branchOut), "\n") {
-			parts := strings.SplitN(line, "\t", 2)
-			if len(parts) < 2 {
-				continue
-			}
-			name := strings.TrimSpace(parts[0])
-			ts, _ := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	for _, rd := range eachRepo(ctx, gitRepos, workers, 0, "", (*Analyzer).branchData) {
+		// Branch inventory: stale detection + depth from naming
+		for _, br := range rd.branches {
+			name := br[0]
+			ts, _ := strconv.ParseFloat(br[1], 64)
 			if name == "" || seenBranch[name] {
 				continue
 			}
@@ -637,86 +652,14 @@ func GetBranchStats(gitRepos []string, staleDays int) BranchStats {
 				})
 			}
 		}
-
-		// 2. Merge analysis: TTM, Lifetime, Integration Delay
-		// Format: "<merge-ts> <first-parent> <second-parent>"
-		mergeOut := a.git(repo, "log", mainBranch, "--merges",
-			"-50", "--pretty=format:%at %P")
-		for _, line := range strings.Split(strings.TrimSpace(mergeOut), "\n") {
-			fields := strings.Fields(line)
-			if len(fields) < 3 {
-				continue
-			}
-			mergeTs, err := strconv.ParseFloat(fields[0], 64)
-			if err != nil || mergeTs <= 0 {
-				continue
-			}
-			mainParent := fields[1]
-			featureTip := fields[2]
-
-			// Timestamps of all commits on the feature branch not reachable from main
-			featureLog := a.git(repo, "log",
-				fmt.Sprintf("%s..%s", mainParent, featureTip),
-				"--pretty=format:%at")
-			if featureLog == "" {
-				continue
-			}
-			var timestamps []float64
-			for _, tsStr := range strings.Split(strings.TrimSpace(featureLog), "\n") {
-				ts, err := strconv.ParseFloat(strings.TrimSpace(tsStr), 64)
-				if err == nil && ts > 0 {
-					timestamps = append(timestamps, ts)
-				}
-			}
-			if len(timestamps) == 0 {
-				continue
-			}
-
-			minTs, maxTs := timestamps[0], timestamps[0]
-			for _, ts := range timestamps[1:] {
-				if ts < minTs {
-					minTs = ts
-				}
-				if ts > maxTs {
-					maxTs = ts
-				}
-			}
-
-			if d := (maxTs - minTs) / 86400; d >= 0 && d < 365 {
-				lifetimes = append(lifetimes, d)
-			}
-			if d := (mergeTs - minTs) / 86400; d >= 0 && d < 730 {
-				ttms = append(ttms, d)
-			}
-			if h := (mergeTs - maxTs) / 3600; h >= 0 && h < 8760 {
-				integDelays = append(integDelays, h)
-			}
-		}
-
-		// 3. Rollback rate on main
-		rollbackOut := a.git(repo, "log", mainBranch,
-			"--pretty=format:%H", "--grep=revert", "--grep=rollback", "-i")
-		for _, h := range strings.Split(strings.TrimSpace(rollbackOut), "\n") {
-			if strings.TrimSpace(h) != "" {
-				bs.RollbackCount++
-			}
-		}
-
-		// 4. Peak commit day: tally all commit timestamps across all branches
-		dayOut := a.git(repo, "log", "--all",
-			fmt.Sprintf("-%d", 2000), "--pretty=format:%at")
-		for _, line := range strings.Split(strings.TrimSpace(dayOut), "\n") {
-			ts, err := strconv.ParseFloat(strings.TrimSpace(line), 64)
-			if err != nil || ts <= 0 {
-				continue
-			}
-			weekday := time.Unix(int64(ts), 0).UTC().Weekday()
-			dayCounts[weekday]++
+		lifetimes = append(lifetimes, rd.lifetimes...)
+		ttms = append(ttms, rd.ttms...)
+		integDelays = append(integDelays, rd.integDelay...)
+		bs.RollbackCount += rd.rollbacks
+		for day, n := range rd.dayCounts {
+			dayCounts[day] += n
 		}
-
-		countOut := a.git(repo, "rev-list", "--count", mainBranch)
-		n, _ := strconv.Atoi(strings.TrimSpace(countOut))
-		bs.TotalMainCommits += n
+		bs.TotalMainCommits += rd.mainCommits
 	}
 
 	avg := func(vals []float64) float64 {
@@ -752,3 +695,101 @@ func GetBranchStats(gitRepos []string, staleDays int) BranchStats {
 	}
 	return bs
 }
+
+// branchData runs the per-repository git queries of GetBranchStats.
+func (a *Analyzer) branchData() repoBranchData {
+	rd := repoBranchData{dayCounts: make(map[time.Weekday]int)}
+	mainBranch := a.defaultMainBranch()
+
+	// 1. Branch inventory
+	branchOut := a.git(a.RepoPath, "for-each-ref",
+		"--format=%(refname:short)\t%(committerdate:unix)",
+		"refs/heads/")
+	for _, line := range strings.Split(strings.TrimSpace(branchOut), "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		rd.branches = append(rd.branches, [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+	}
+
+	// 2. Merge analysis: TTM, Lifetime, Integration Delay
+	// Format: "<merge-ts> <first-parent> <second-parent>"
+	mergeOut := a.git(a.RepoPath, "log", mainBranch, "--merges",
+		"-50", "--pretty=format:%at %P")
+	for _, line := range strings.Split(strings.TrimSpace(mergeOut), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		mergeTs, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || mergeTs <= 0 {
+			continue
+		}
+		mainParent := fields[1]
+		featureTip := fields[2]
+
+		// Timestamps of all commits on the feature branch not reachable from main
+		featureLog := a.git(a.RepoPath, "log",
+			fmt.Sprintf("%s..%s", mainParent, featureTip),
+			"--pretty=format:%at")
+		if featureLog == "" {
+			continue
+		}
+		var timestamps []float64
+		for _, tsStr := range strings.Split(strings.TrimSpace(featureLog), "\n") {
+			ts, err := strconv.ParseFloat(strings.TrimSpace(tsStr), 64)
+			if err == nil && ts > 0 {
+				timestamps = append(timestamps, ts)
+			}
+		}
+		if len(timestamps) == 0 {
+			continue
+		}
+
+		minTs, maxTs := timestamps[0], timestamps[0]
+		for _, ts := range timestamps[1:] {
+			if ts < minTs {
+				minTs = ts
+			}
+			if ts > maxTs {
+				maxTs = ts
+			}
+		}
+
+		if d := (maxTs - minTs) / 86400; d >= 0 && d < 365 {
+			rd.lifetimes = append(rd.lifetimes, d)
+		}
+		if d := (mergeTs - minTs) / 86400; d >= 0 && d < 730 {
+			rd.ttms = append(rd.ttms, d)
+		}
+		if h := (mergeTs - maxTs) / 3600; h >= 0 && h < 8760 {
+			rd.integDelay = append(rd.integDelay, h)
+		}
+	}
+
+	// 3. Rollback rate on main
+	rollbackOut := a.git(a.RepoPath, "log", mainBranch,
+		"--pretty=format:%H", "--grep=revert", "--grep=rollback", "-i")
+	for _, h := range strings.Split(strings.TrimSpace(rollbackOut), "\n") {
+		if strings.TrimSpace(h) != "" {
+			rd.rollbacks++
+		}
+	}
+
+	// 4. Peak commit day: tally all commit timestamps across all branches
+	dayOut := a.git(a.RepoPath, "log", "--all",
+		fmt.Sprintf("-%d", 2000), "--pretty=format:%at")
+	for _, line := range strings.Split(strings.TrimSpace(dayOut), "\n") {
+		ts, err := strconv.ParseFloat(strings.TrimSpace(line), 64)
+		if err != nil || ts <= 0 {
+			continue
+		}
+		weekday := time.Unix(int64(ts), 0).UTC().Weekday()
+		rd.dayCounts[weekday]++
+	}
+
+	countOut := a.git(a.RepoPath, "rev-list", "--count", mainBranch)
+	rd.mainCommits, _ = strconv.Atoi(strings.TrimSpace(countOut))
+	return rd
+}
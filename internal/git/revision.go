@@ -0,0 +1,89 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// run executes git in dir and returns stdout, or an error carrying stderr.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return out.Bytes(), nil
+}
+
+// ResolveRevision returns the commit hash rev points to in repo.
+func ResolveRevision(repo, rev string) (string, error) {
+	out, err := run(repo, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q in %s", rev, repo)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// FilesAtRevision returns the contents of every file in repo at rev whose
+// path ends with ext, keyed by repo-relative path.
+func FilesAtRevision(repo, rev, ext string) (map[string][]byte, error) {
+	commit, err := ResolveRevision(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	list, err := run(repo, "ls-tree", "-r", "-z", "--name-only", commit)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, path := range strings.Split(string(list), "\x00") {
+		if path == "" || !strings.HasSuffix(path, ext) {
+			continue
+		}
+		src, err := run(repo, "show", commit+":"+path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = src
+	}
+	return files, nil
+}
+
+// WorktreeFiles returns the contents of every file in the working tree of
+// repo whose path ends with ext, keyed by repo-relative path: the tracked
+// files, as listed for a revision by FilesAtRevision, minus the deleted
+// ones, plus the untracked files git does not ignore.
+func WorktreeFiles(repo, ext string) (map[string][]byte, error) {
+	list, err := run(repo, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, path := range strings.Split(string(list), "\x00") {
+		if path == "" || !strings.HasSuffix(path, ext) {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(repo, filepath.FromSlash(path)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[path] = src
+	}
+	return files, nil
+}
@@ -0,0 +1,365 @@
+// Package gomod reads go.mod and go.work files and resolves import paths to
+// package directories inside the scanned tree.
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Module is a Go module found in the scanned tree.
+type Module struct {
+	Path      string    `json:"path"`                // module path from the module directive
+	Dir       string    `json:"dir"`                 // absolute directory containing go.mod
+	GoVersion string    `json:"goVersion"`           // go directive, e.g. "1.22"
+	Toolchain string    `json:"toolchain,omitempty"` // toolchain directive, e.g. "go1.22.3"
+	Requires  []Require `json:"requires,omitempty"`  // require directives
+	Replaces  []Replace `json:"replaces,omitempty"`  // replace directives
+	Workspace bool      `json:"workspace,omitempty"` // listed in a use directive of the root go.work
+}
+
+// Require is a require directive.
+type Require struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Indirect bool   `json:"indirect,omitempty"` // marked "// indirect"
+	Line     int    `json:"-"`                  // line in go.mod
+}
+
+// Replace is a replace directive of a go.mod or go.work file.
+type Replace struct {
+	Old        string `json:"old"`
+	OldVersion string `json:"oldVersion,omitempty"` // "" replaces every version
+	New        string `json:"new"`                  // module path or, for local replacements, a file path
+	NewVersion string `json:"newVersion,omitempty"`
+	Dir        string `json:"dir,omitempty"` // absolute directory of a local replacement
+}
+
+// Workspace is a go.work file.
+type Workspace struct {
+	GoVersion string    `json:"goVersion"`
+	Use       []string  `json:"use"` // absolute module directories
+	Replaces  []Replace `json:"replaces,omitempty"`
+}
+
+// ParseModFile reads the module, go, toolchain, require and replace
+// directives of a go.mod file.
+func ParseModFile(path string) (*Module, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	mod := &Module{Dir: dir}
+	for _, d := range directives(data) {
+		line := d.fields
+		switch line[0] {
+		case "module":
+			if len(line) > 1 {
+				mod.Path = line[1]
+			}
+		case "go":
+			if len(line) > 1 {
+				mod.GoVersion = line[1]
+			}
+		case "toolchain":
+			if len(line) > 1 {
+				mod.Toolchain = line[1]
+			}
+		case "require":
+			if len(line) > 2 {
+				indirect := d.comment == "indirect" || strings.HasPrefix(d.comment, "indirect;")
+				mod.Requires = append(mod.Requires, Require{Path: line[1], Version: line[2], Indirect: indirect, Line: d.line})
+			}
+		case "replace":
+			if r, ok := parseReplace(line[1:], dir); ok {
+				mod.Replaces = append(mod.Replaces, r)
+			}
+		}
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("%s: missing module directive", path)
+	}
+	return mod, nil
+}
+
+// ParseWorkFile reads the go, use and replace directives of a go.work file.
+func ParseWorkFile(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	ws := &Workspace{}
+	for _, d := range directives(data) {
+		line := d.fields
+		switch line[0] {
+		case "go":
+			if len(line) > 1 {
+				ws.GoVersion = line[1]
+			}
+		case "use":
+			if len(line) > 1 {
+				ws.Use = append(ws.Use, absDir(base, line[1]))
+			}
+		case "replace":
+			if r, ok := parseReplace(line[1:], base); ok {
+				ws.Replaces = append(ws.Replaces, r)
+			}
+		}
+	}
+	return ws, nil
+}
+
+// parseReplace parses the arguments of a replace directive,
+// `old [version] => new [version]`. Local replacements are resolved against
+// base.
+func parseReplace(args []string, base string) (Replace, bool) {
+	var r Replace
+	arrow := -1
+	for i, a := range args {
+		if a == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || len(args)-arrow < 2 || len(args)-arrow > 3 {
+		return r, false
+	}
+	r.Old = args[0]
+	if arrow == 2 {
+		r.OldVersion = args[1]
+	}
+	r.New = args[arrow+1]
+	if len(args)-arrow == 3 {
+		r.NewVersion = args[arrow+2]
+	} else if isLocalPath(r.New) {
+		r.Dir = absDir(base, r.New)
+	}
+	return r, true
+}
+
+// isLocalPath reports whether the target of a replace directive is a file
+// path rather than a module path, following the go command's rule.
+func isLocalPath(p string) bool {
+	return filepath.IsAbs(p) || p == "." || p == ".." ||
+		strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") ||
+		strings.HasPrefix(p, `.\`) || strings.HasPrefix(p, `..\`)
+}
+
+// absDir resolves dir against base.
+func absDir(base, dir string) string {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// directive is one directive line of a go.mod/go.work file.
+type directive struct {
+	fields  []string // quotes stripped; lines in a block start with the block keyword
+	comment string   // trailing comment without "//", e.g. "indirect"
+	line    int      // 1-based line number
+}
+
+// directives splits a go.mod/go.work file into directive lines with comments
+// removed and quotes stripped. Lines inside a block such as `use ( ... )`
+// are returned with the block keyword prepended.
+func directives(data []byte) []directive {
+	var out []directive
+	block := ""
+	n := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		comment := ""
+		if i := strings.Index(line, "//"); i >= 0 {
+			line, comment = line[:i], strings.TrimSpace(line[i+2:])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, f := range fields {
+			if s, err := strconv.Unquote(f); err == nil {
+				fields[i] = s
+			}
+		}
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			out = append(out, directive{append([]string{block}, fields...), comment, n})
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			out = append(out, directive{fields, comment, n})
+		}
+	}
+	return out
+}
+
+// IsStdlib reports whether importPath belongs to the standard library,
+// i.e. its first element has no dot.
+func IsStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Resolver maps import paths to directories of local modules. A nil
+// Resolver resolves nothing.
+type Resolver struct {
+	byPath []Module  // sorted by module path length, longest first
+	byDir  []Module  // sorted by directory length, longest first
+	work   []Replace // replace directives of the go.work file
+}
+
+// NewResolver creates a Resolver for mods. When two modules declare the same
+// path, the first one wins. workReplaces are the replace directives of the
+// go.work file, which ResolveFrom applies before those of each module.
+func NewResolver(mods []Module, workReplaces ...Replace) *Resolver {
+	seen := make(map[string]bool)
+	r := &Resolver{work: workReplaces}
+	for _, m := range mods {
+		if seen[m.Path] {
+			continue
+		}
+		seen[m.Path] = true
+		r.byPath = append(r.byPath, m)
+	}
+	r.byDir = append([]Module(nil), r.byPath...)
+	sort.SliceStable(r.byPath, func(i, j int) bool { return len(r.byPath[i].Path) > len(r.byPath[j].Path) })
+	sort.SliceStable(r.byDir, func(i, j int) bool { return len(r.byDir[i].Dir) > len(r.byDir[j].Dir) })
+	return r
+}
+
+// Len returns the number of known modules.
+func (r *Resolver) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.byPath)
+}
+
+// Resolve returns the directory of importPath and the module it belongs to,
+// or ("", nil) when it is not part of a local module.
+func (r *Resolver) Resolve(importPath string) (string, *Module) {
+	if r == nil {
+		return "", nil
+	}
+	for i := range r.byPath {
+		m := &r.byPath[i]
+		if importPath == m.Path {
+			return m.Dir, m
+		}
+		if strings.HasPrefix(importPath, m.Path+"/") {
+			return filepath.Join(m.Dir, filepath.FromSlash(importPath[len(m.Path)+1:])), m
+		}
+	}
+	return "", nil
+}
+
+// ResolveFrom is like Resolve for an import in the package in fromDir, but
+// honors replace directives the way the go command does: those of the
+// go.work file come first, then workspace modules, then the replaces of the
+// importing module. An import replaced by a remote module version is not
+// local even when a module with its path is in the tree.
+func (r *Resolver) ResolveFrom(fromDir, importPath string) (string, *Module) {
+	if r == nil {
+		return "", nil
+	}
+	if dir, m, ok := r.replaced(r.work, importPath); ok {
+		return dir, m
+	}
+	from := r.ModuleForDir(fromDir)
+	if from == nil {
+		return r.Resolve(importPath)
+	}
+	if from.Workspace {
+		if dir, m := r.Resolve(importPath); m != nil && m.Workspace {
+			return dir, m
+		}
+	}
+	if dir, m, ok := r.replaced(from.Replaces, importPath); ok {
+		return dir, m
+	}
+	return r.Resolve(importPath)
+}
+
+// replaced applies the most specific directive in reps matching importPath.
+// ok is false when none matches.
+func (r *Resolver) replaced(reps []Replace, importPath string) (dir string, mod *Module, ok bool) {
+	best := -1
+	for i, rep := range reps {
+		if importPath != rep.Old && !strings.HasPrefix(importPath, rep.Old+"/") {
+			continue
+		}
+		if best < 0 || len(rep.Old) > len(reps[best].Old) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", nil, false
+	}
+	rep := reps[best]
+	rest := strings.TrimPrefix(importPath[len(rep.Old):], "/")
+	if rep.Dir == "" {
+		if rep.New == rep.Old {
+			return "", nil, true
+		}
+		dir, mod = r.Resolve(path.Join(rep.New, rest))
+		return dir, mod, true
+	}
+	dir = filepath.Join(rep.Dir, filepath.FromSlash(rest))
+	for i := range r.byDir {
+		if r.byDir[i].Dir == rep.Dir {
+			return dir, &r.byDir[i], true
+		}
+	}
+	// The replacement is outside the scanned modules.
+	return dir, &Module{Path: rep.Old, Dir: rep.Dir}, true
+}
+
+// ModuleForDir returns the innermost module containing dir, or nil.
+func (r *Resolver) ModuleForDir(dir string) *Module {
+	if r == nil {
+		return nil
+	}
+	for i := range r.byDir {
+		m := &r.byDir[i]
+		if dir == m.Dir || strings.HasPrefix(dir, m.Dir+string(filepath.Separator)) {
+			return m
+		}
+	}
+	return nil
+}
+
+// ImportPath returns the import path of the package in dir, or "" when dir
+// is outside every known module.
+func (r *Resolver) ImportPath(dir string) string {
+	m := r.ModuleForDir(dir)
+	if m == nil {
+		return ""
+	}
+	rel, err := filepath.Rel(m.Dir, dir)
+	if err != nil || rel == "." {
+		return m.Path
+	}
+	return m.Path + "/" + filepath.ToSlash(rel)
+}
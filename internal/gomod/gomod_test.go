@@ -0,0 +1,323 @@
+package gomod
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseModFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go.mod")
+	writeFile(t, path, `// Payments service
+module "github.com/acme/payments" // quoted
+
+go 1.22
+
+toolchain go1.22.3
+
+require (
+	github.com/google/uuid v1.6.0
+	golang.org/x/sys v0.20.0 // indirect
+)
+require github.com/acme/shared v0.0.0
+
+replace github.com/acme/shared => ../shared
+replace (
+	golang.org/x/sys v0.20.0 => golang.org/x/sys v0.21.0
+	github.com/broken =>
+)
+`)
+	mod, err := ParseModFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Path != "github.com/acme/payments" || mod.GoVersion != "1.22" || mod.Toolchain != "go1.22.3" || mod.Dir != dir {
+		t.Errorf("ParseModFile = %+v", mod)
+	}
+	wantReq := []Require{
+		{Path: "github.com/google/uuid", Version: "v1.6.0", Line: 9},
+		{Path: "golang.org/x/sys", Version: "v0.20.0", Indirect: true, Line: 10},
+		{Path: "github.com/acme/shared", Version: "v0.0.0", Line: 12},
+	}
+	if len(mod.Requires) != len(wantReq) {
+		t.Fatalf("Requires = %+v, want %+v", mod.Requires, wantReq)
+	}
+	for i, w := range wantReq {
+		if mod.Requires[i] != w {
+			t.Errorf("Requires[%d] = %+v, want %+v", i, mod.Requires[i], w)
+		}
+	}
+	wantRep := []Replace{
+		{Old: "github.com/acme/shared", New: "../shared", Dir: filepath.Join(filepath.Dir(dir), "shared")},
+		{Old: "golang.org/x/sys", OldVersion: "v0.20.0", New: "golang.org/x/sys", NewVersion: "v0.21.0"},
+	}
+	if len(mod.Replaces) != len(wantRep) {
+		t.Fatalf("Replaces = %+v, want %+v", mod.Replaces, wantRep)
+	}
+	for i, w := range wantRep {
+		if mod.Replaces[i] != w {
+			t.Errorf("Replaces[%d] = %+v, want %+v", i, mod.Replaces[i], w)
+		}
+	}
+
+	writeFile(t, path, "go 1.22\n")
+	if _, err := ParseModFile(path); err == nil {
+		t.Error("ParseModFile without module directive: want error")
+	}
+}
+
+func TestParseWorkFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go.work")
+	writeFile(t, path, `go 1.22
+
+use ./gateway
+use (
+	./services/users // users service
+	"../shared"
+)
+replace github.com/acme/auth v1.2.0 => ./forks/auth
+`)
+	ws, err := ParseWorkFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.GoVersion != "1.22" {
+		t.Errorf("GoVersion = %q", ws.GoVersion)
+	}
+	wantRep := Replace{Old: "github.com/acme/auth", OldVersion: "v1.2.0", New: "./forks/auth", Dir: filepath.Join(dir, "forks", "auth")}
+	if len(ws.Replaces) != 1 || ws.Replaces[0] != wantRep {
+		t.Errorf("Replaces = %+v, want %+v", ws.Replaces, wantRep)
+	}
+	dirs := ws.Use
+	want := []string{
+		filepath.Join(dir, "gateway"),
+		filepath.Join(dir, "services", "users"),
+		filepath.Join(filepath.Dir(dir), "shared"),
+	}
+	if len(dirs) != len(want) {
+		t.Fatalf("ParseWorkFile = %v, want %v", dirs, want)
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Errorf("dirs[%d] = %s, want %s", i, dirs[i], want[i])
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	r := NewResolver([]Module{
+		{Path: "github.com/acme/api", Dir: "/code/api"},
+		{Path: "github.com/acme/api/sdk", Dir: "/code/api/sdk"},
+		{Path: "github.com/acme/users", Dir: "/code/users"},
+	})
+
+	tests := []struct {
+		imp, dir, mod string
+	}{
+		{"github.com/acme/api", "/code/api", "github.com/acme/api"},
+		{"github.com/acme/api/internal/config", "/code/api/internal/config", "github.com/acme/api"},
+		{"github.com/acme/api/sdk/client", "/code/api/sdk/client", "github.com/acme/api/sdk"},
+		{"github.com/acme/apiserver", "", ""},
+		{"github.com/other/config", "", ""},
+	}
+	for _, tt := range tests {
+		dir, mod := r.Resolve(tt.imp)
+		modPath := ""
+		if mod != nil {
+			modPath = mod.Path
+		}
+		if filepath.ToSlash(dir) != tt.dir || modPath != tt.mod {
+			t.Errorf("Resolve(%q) = %q, %q; want %q, %q", tt.imp, dir, modPath, tt.dir, tt.mod)
+		}
+	}
+
+	if got := r.ImportPath(filepath.FromSlash("/code/api/sdk/client")); got != "github.com/acme/api/sdk/client" {
+		t.Errorf("ImportPath = %q", got)
+	}
+	if got := r.ImportPath(filepath.FromSlash("/code/users")); got != "github.com/acme/users" {
+		t.Errorf("ImportPath(module root) = %q", got)
+	}
+	if got := r.ImportPath("/elsewhere"); got != "" {
+		t.Errorf("ImportPath(outside) = %q, want empty", got)
+	}
+
+	var nilResolver *Resolver
+	if dir, mod := nilResolver.Resolve("github.com/acme/api"); dir != "" || mod != nil {
+		t.Error("nil Resolver should resolve nothing")
+	}
+}
+
+func TestResolveFrom(t *testing.T) {
+	shared := Replace{Old: "github.com/acme/shared", New: "../libs/shared", Dir: filepath.FromSlash("/code/libs/shared")}
+	r := NewResolver([]Module{
+		{Path: "github.com/acme/orders", Dir: filepath.FromSlash("/code/orders"), Replaces: []Replace{
+			shared,
+			{Old: "github.com/acme/users", New: "github.com/acme/users", NewVersion: "v1.4.0"},
+		}},
+		{Path: "github.com/acme/users", Dir: filepath.FromSlash("/code/users"), Workspace: true},
+		{Path: "github.com/acme/billing", Dir: filepath.FromSlash("/code/billing"), Workspace: true, Replaces: []Replace{
+			{Old: "github.com/acme/users", New: "github.com/acme/users", NewVersion: "v1.4.0"},
+		}},
+		// A fork checked out under a different module path.
+		{Path: "github.com/forks/shared", Dir: filepath.FromSlash("/code/libs/shared")},
+	}, Replace{Old: "github.com/acme/auth", New: "./auth", Dir: filepath.FromSlash("/code/auth")})
+
+	tests := []struct {
+		from, imp, dir, mod string
+	}{
+		// The module's replace points at the fork's directory.
+		{"/code/orders/api", "github.com/acme/shared/log", "/code/libs/shared/log", "github.com/forks/shared"},
+		// Pinned to a published version: not the local copy.
+		{"/code/orders", "github.com/acme/users/client", "", ""},
+		// Other modules are unaffected by orders' replaces.
+		{"/code/users", "github.com/acme/shared/log", "", ""},
+		// Workspace modules win over a module's own replaces.
+		{"/code/billing", "github.com/acme/users/client", "/code/users/client", "github.com/acme/users"},
+		// go.work replaces apply everywhere, even to unscanned directories.
+		{"/code/orders", "github.com/acme/auth/jwt", "/code/auth/jwt", "github.com/acme/auth"},
+		{"/elsewhere", "github.com/acme/users", "/code/users", "github.com/acme/users"},
+	}
+	for _, tt := range tests {
+		dir, mod := r.ResolveFrom(filepath.FromSlash(tt.from), tt.imp)
+		modPath := ""
+		if mod != nil {
+			modPath = mod.Path
+		}
+		if filepath.ToSlash(dir) != tt.dir || modPath != tt.mod {
+			t.Errorf("ResolveFrom(%s, %q) = %q, %q; want %q, %q", tt.from, tt.imp, dir, modPath, tt.dir, tt.mod)
+		}
+	}
+}
+
+func TestIsStdlib(t *testing.T) {
+	for imp, want := range map[string]bool{
+		"fmt":                        true,
+		"net/http":                   true,
+		"github.com/acme/api":        false,
+		"golang.org/x/sync/errgroup": false,
+	} {
+		if got := IsStdlib(imp); got != want {
+			t.Errorf("IsStdlib(%q) = %v, want %v", imp, got, want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	ordered := []string{
+		"not-a-version",
+		"v0.0.0-20240101120000-abcdef123456",
+		"v1.56.0",
+		"v1.64.0-rc.1",
+		"v1.64.0-rc.2",
+		"v1.64.0",
+		"v1.64.1+incompatible",
+		"v2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := CompareVersions(ordered[i], ordered[j]); got != want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+	if CompareVersions("1.21.3", "1.22") >= 0 || CompareVersions("go1.22rc1", "1.22.0") >= 0 {
+		t.Error("Go versions compare wrong")
+	}
+}
+
+func TestVersionDrift(t *testing.T) {
+	mods := []Module{
+		{Path: "github.com/acme/orders", GoVersion: "1.22", Requires: []Require{
+			{Path: "google.golang.org/grpc", Version: "v1.64.0"},
+			{Path: "github.com/acme/users", Version: "v0.0.0"}, // local module
+			{Path: "github.com/google/uuid", Version: "v1.6.0"},
+		}},
+		{Path: "github.com/acme/users", GoVersion: "1.21", Requires: []Require{
+			{Path: "google.golang.org/grpc", Version: "v1.56.3"},
+			{Path: "github.com/google/uuid", Version: "v1.3.0"},
+			{Path: "go.uber.org/zap", Version: "v1.27.0"},
+		}, Replaces: []Replace{
+			{Old: "github.com/google/uuid", New: "../uuid", Dir: "/code/uuid"},
+			{Old: "go.uber.org/zap", New: "github.com/acme/zap", NewVersion: "v1.27.1"},
+		}},
+		{Path: "github.com/acme/billing", GoVersion: "1.22", Requires: []Require{
+			{Path: "google.golang.org/grpc", Version: "v1.64.0", Indirect: true},
+			{Path: "go.uber.org/zap", Version: "v1.27.0"},
+		}},
+	}
+	drift := VersionDrift(mods)
+	if len(drift) != 2 {
+		t.Fatalf("VersionDrift = %+v, want grpc and zap", drift)
+	}
+	grpc := drift[1]
+	if grpc.Path != "google.golang.org/grpc" || len(grpc.Versions) != 2 ||
+		grpc.Versions[0].Version != "v1.64.0" || strings.Join(grpc.Versions[0].Modules, ",") != "github.com/acme/billing,github.com/acme/orders" ||
+		grpc.Versions[1].Version != "v1.56.3" {
+		t.Errorf("grpc drift = %+v", grpc)
+	}
+	zap := drift[0]
+	if zap.Path != "go.uber.org/zap" || zap.Versions[1].Version != "github.com/acme/zap@v1.27.1" {
+		t.Errorf("zap drift = %+v, want the fork listed", zap)
+	}
+
+	gv := GoVersions(mods)
+	if len(gv) != 2 || gv[0].Version != "1.22" || len(gv[0].Modules) != 2 || gv[1].Version != "1.21" {
+		t.Errorf("GoVersions = %+v", gv)
+	}
+}
+
+func TestEffective(t *testing.T) {
+	req := Require{Path: "go.uber.org/zap", Version: "v1.27.0"}
+	m := Module{Path: "github.com/acme/orders", Replaces: []Replace{
+		{Old: "go.uber.org/zap", New: "github.com/acme/zap", NewVersion: "v1.27.1"},
+		{Old: "go.uber.org/zap", OldVersion: "v1.27.0", New: "github.com/acme/zap", NewVersion: "v1.27.0-fix"},
+	}}
+	work := []Replace{{Old: "go.uber.org/zap", New: "github.com/work/zap", NewVersion: "v1.28.0"}}
+
+	tests := []struct {
+		name        string
+		m           Module
+		req         Require
+		path, vers  string
+		ok          bool
+		workReplace bool
+	}{
+		// A versioned replace wins over an unversioned one listed first.
+		{"versioned first", m, req, "github.com/acme/zap", "v1.27.0-fix", true, false},
+		{"other version", m, Require{Path: req.Path, Version: "v1.26.0"}, "github.com/acme/zap", "v1.27.1", true, false},
+		{"unreplaced", m, Require{Path: "github.com/google/uuid", Version: "v1.6.0"}, "github.com/google/uuid", "v1.6.0", true, false},
+		// go.work replaces only apply to workspace modules, before their own.
+		{"not in workspace", m, req, "github.com/acme/zap", "v1.27.0-fix", true, true},
+		{"workspace", Module{Path: m.Path, Replaces: m.Replaces, Workspace: true}, req, "github.com/work/zap", "v1.28.0", true, true},
+		{"local", Module{Replaces: []Replace{{Old: req.Path, New: "../zap", Dir: "/code/zap"}}}, req, "", "", false, false},
+	}
+	for _, tt := range tests {
+		var w []Replace
+		if tt.workReplace {
+			w = work
+		}
+		path, vers, ok := tt.m.Effective(tt.req, w...)
+		if path != tt.path || vers != tt.vers || ok != tt.ok {
+			t.Errorf("%s: Effective = %q, %q, %v; want %q, %q, %v", tt.name, path, vers, ok, tt.path, tt.vers, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package gomod
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VersionUse is one version of a dependency or of Go and the modules that
+// use it.
+type VersionUse struct {
+	Version string   `json:"version"`
+	Modules []string `json:"modules"` // module paths, sorted
+}
+
+// Drift is a dependency that modules require at different versions.
+type Drift struct {
+	Path     string       `json:"path"`
+	Versions []VersionUse `json:"versions"` // newest first
+}
+
+// VersionDrift returns the dependencies mods require at more than one
+// version, sorted by path. Each requirement's version is taken after the
+// requiring module's replace directives, so a module pinned to a fork shows
+// up as "fork@version". Requirements replaced by a local directory and
+// requirements of modules in mods are skipped: they are built from source.
+// work are the replace directives of the go.work file; see Module.Effective.
+func VersionDrift(mods []Module, work ...Replace) []Drift {
+	local := make(map[string]bool)
+	for _, m := range mods {
+		local[m.Path] = true
+	}
+	users := make(map[string]map[string][]string) // path -> version -> modules
+	for _, m := range mods {
+		for _, req := range m.Requires {
+			if local[req.Path] {
+				continue
+			}
+			path, v, ok := m.Effective(req, work...)
+			if !ok {
+				continue
+			}
+			if path != req.Path {
+				v = path + "@" + v
+			}
+			if users[req.Path] == nil {
+				users[req.Path] = make(map[string][]string)
+			}
+			users[req.Path][v] = appendUnique(users[req.Path][v], m.Path)
+		}
+	}
+	var out []Drift
+	for path, byVersion := range users {
+		if len(byVersion) > 1 {
+			out = append(out, Drift{Path: path, Versions: versionUses(byVersion)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// GoVersions groups mods by their go directive, newest first. Modules
+// without one are left out.
+func GoVersions(mods []Module) []VersionUse {
+	byVersion := make(map[string][]string)
+	for _, m := range mods {
+		if m.GoVersion != "" {
+			byVersion[m.GoVersion] = appendUnique(byVersion[m.GoVersion], m.Path)
+		}
+	}
+	return versionUses(byVersion)
+}
+
+// Effective returns the module path and version that m builds req with
+// after its replace directives. For a workspace module the replace
+// directives of the go.work file, work, are applied first. Within each set
+// a directive for req's exact version wins over one for every version, as
+// in the go command. ok is false when a replace directive points req at a
+// local directory.
+func (m Module) Effective(req Require, work ...Replace) (path, version string, ok bool) {
+	r, found := Replace{}, false
+	if m.Workspace {
+		r, found = matchReplace(work, req)
+	}
+	if !found {
+		r, found = matchReplace(m.Replaces, req)
+	}
+	switch {
+	case !found:
+		return req.Path, req.Version, true
+	case r.Dir != "":
+		return "", "", false
+	}
+	return r.New, r.NewVersion, true
+}
+
+// matchReplace returns the directive of reps that applies to req,
+// preferring one for req's version over one for every version.
+func matchReplace(reps []Replace, req Require) (Replace, bool) {
+	var all *Replace
+	for i, r := range reps {
+		if r.Old != req.Path {
+			continue
+		}
+		if r.OldVersion == req.Version {
+			return r, true
+		}
+		if r.OldVersion == "" && all == nil {
+			all = &reps[i]
+		}
+	}
+	if all == nil {
+		return Replace{}, false
+	}
+	return *all, true
+}
+
+func versionUses(byVersion map[string][]string) []VersionUse {
+	out := make([]VersionUse, 0, len(byVersion))
+	for v, mods := range byVersion {
+		sort.Strings(mods)
+		out = append(out, VersionUse{Version: v, Modules: mods})
+	}
+	sort.Slice(out, func(i, j int) bool { return CompareVersions(out[i].Version, out[j].Version) > 0 })
+	return out
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// CompareVersions compares two module versions ("v1.64.0", "v0.0.0-2024…")
+// or Go versions ("1.22", "1.21.3", "go1.22rc1") by semantic version
+// precedence and returns -1, 0 or +1. Strings that are not versions sort
+// before those that are and among themselves lexically.
+func CompareVersions(a, b string) int {
+	va, oka := parseVersion(a)
+	vb, okb := parseVersion(b)
+	switch {
+	case !oka && !okb:
+		return strings.Compare(a, b)
+	case !oka:
+		return -1
+	case !okb:
+		return 1
+	}
+	for i := range va.nums {
+		if va.nums[i] != vb.nums[i] {
+			if va.nums[i] < vb.nums[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	// A pre-release precedes the release.
+	switch {
+	case va.pre == vb.pre:
+		return 0
+	case va.pre == "":
+		return 1
+	case vb.pre == "":
+		return -1
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+type version struct {
+	nums [3]int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "go"), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	core := s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		core, v.pre = s[:i], s[i+1:]
+	} else if i := strings.IndexAny(s, "abcdefghijklmnopqrstuvwxyz"); i > 0 {
+		// Go toolchain pre-releases: "1.22rc1", "1.21beta2".
+		core, v.pre = s[:i], s[i:]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.nums[i] = n
+	}
+	return v, true
+}
+
+// comparePrerelease compares dot-separated pre-release identifiers:
+// numeric ones numerically and below alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		na, errA := strconv.Atoi(as[i])
+		nb, errB := strconv.Atoi(bs[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
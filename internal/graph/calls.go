@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/scanner"
+)
+
+// Protocols of service calls. Addresses with another scheme, such as
+// "ws" or "amqp", are labelled with the scheme.
+const (
+	CallGRPC = "grpc"
+	CallHTTP = "http"
+	CallTCP  = "tcp" // host:port without a scheme or a hint of the protocol
+)
+
+// Where a service call was found.
+const (
+	SourceClient = "client" // New<Service>Client in Go code
+	SourceCode   = "code"   // address literal in Go code
+	SourceConfig = "config" // docker-compose environment or config file
+)
+
+// ServiceCall is a directed runtime call from one microservice to another.
+type ServiceCall struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Protocols []string   `json:"protocols"` // sorted, e.g. ["grpc", "http"]
+	Sites     []CallSite `json:"sites"`
+}
+
+// CallSite is one place showing that a service calls another.
+type CallSite struct {
+	Protocol string `json:"protocol"`
+	Source   string `json:"source"` // SourceClient, SourceCode or SourceConfig
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Detail   string `json:"detail"` // the client constructor, the address or KEY=value
+}
+
+// BuildServiceCalls infers the calls between microservices from gRPC client
+// constructors, resolved like the gRPC edges of the service graph, and
+// from service addresses: URLs, host:port pairs and Kubernetes service DNS
+// names in Go string literals and in the configuration endpoints found by
+// the scanner. An address host names a microservice, a docker-compose
+// service built from one (aliases), or a microservice whose name matches
+// once case, separators and a "service", "svc", "server" or "api" suffix
+// are ignored. Calls are sorted by caller and callee.
+func BuildServiceCalls(files []*parser.ParsedFile, endpoints []scanner.Endpoint, aliases map[string]string) []ServiceCall {
+	resolve := newHostResolver(files, aliases)
+	calls := make(map[[2]string]*ServiceCall)
+	add := func(from, to string, site CallSite) {
+		if from == "" || to == "" || from == to {
+			return
+		}
+		key := [2]string{from, to}
+		c := calls[key]
+		if c == nil {
+			c = &ServiceCall{From: from, To: to}
+			calls[key] = c
+		}
+		c.Protocols = appendUnique(c.Protocols, site.Protocol)
+		c.Sites = append(c.Sites, site)
+	}
+
+	servers, owners := grpcIndex(files)
+	for _, f := range files {
+		for _, r := range f.GRPCClients {
+			for _, to := range grpcTargets(r, servers, owners) {
+				add(f.MicroserviceName, to, CallSite{
+					Protocol: CallGRPC, Source: SourceClient, File: f.FilePath, Line: r.Line,
+					Detail: "New" + r.Service + "Client",
+				})
+			}
+		}
+		for _, a := range f.Addresses {
+			add(f.MicroserviceName, resolve(a.Host), CallSite{
+				Protocol: callProtocol(a.Scheme, ""), Source: SourceCode, File: f.FilePath, Line: a.Line, Detail: a.Value,
+			})
+		}
+	}
+	for _, e := range endpoints {
+		add(resolve(e.Service), resolve(e.Host), CallSite{
+			Protocol: callProtocol(e.Scheme, e.Key), Source: SourceConfig, File: e.File, Line: e.Line,
+			Detail: fmt.Sprintf("%s=%s", e.Key, e.Value),
+		})
+	}
+
+	out := make([]ServiceCall, 0, len(calls))
+	for _, c := range calls {
+		sort.Strings(c.Protocols)
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From < out[j].From
+		}
+		return out[i].To < out[j].To
+	})
+	return out
+}
+
+// callProtocol names the protocol of an address with the given scheme,
+// held in a config key when key is set.
+func callProtocol(scheme, key string) string {
+	switch scheme {
+	case "http", "https":
+		return CallHTTP
+	case "grpc", "grpcs", "dns", "xds":
+		return CallGRPC
+	case "":
+		if strings.Contains(strings.ToUpper(key), "GRPC") {
+			return CallGRPC
+		}
+		return CallTCP
+	}
+	return scheme
+}
+
+// newHostResolver returns a function mapping a host or service name to a
+// microservice, or "" when it names none or several.
+func newHostResolver(files []*parser.ParsedFile, aliases map[string]string) func(string) string {
+	names := make(map[string]bool)
+	byKey := make(map[string][]string)
+	for _, f := range files {
+		if ms := f.MicroserviceName; ms != "" && !names[ms] {
+			names[ms] = true
+			k := serviceKey(ms)
+			byKey[k] = append(byKey[k], ms)
+		}
+	}
+	return func(host string) string {
+		if names[host] {
+			return host
+		}
+		if ms, ok := aliases[host]; ok && names[ms] {
+			return ms
+		}
+		if ms := byKey[serviceKey(host)]; len(ms) == 1 {
+			return ms[0]
+		}
+		return ""
+	}
+}
+
+// serviceKey normalizes a service name for matching: "User-Service",
+// "user_svc" and "users" all become "user".
+func serviceKey(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	key := sb.String()
+	for _, suffix := range []string{"service", "svc", "server", "api"} {
+		if trimmed := strings.TrimSuffix(key, suffix); trimmed != key && trimmed != "" {
+			key = trimmed
+			break
+		}
+	}
+	if len(key) > 3 {
+		key = strings.TrimSuffix(key, "s")
+	}
+	return key
+}
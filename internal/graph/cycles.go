@@ -0,0 +1,264 @@
+package graph
+
+import "sort"
+
+// Cycle is a strongly connected component with more than one vertex.
+type Cycle struct {
+	Members []string    `json:"members"` // vertices of the component, sorted
+	Edges   [][2]string `json:"edges"`   // edges between members
+	Path    []string    `json:"path"`    // one concrete cycle, first vertex repeated at the end
+	Break   [][2]string `json:"break"`   // suggested edges whose removal makes the component acyclic
+}
+
+// StronglyConnectedComponents returns the SCCs of the graph using Tarjan's
+// algorithm. Vertices and neighbors are visited in sorted order so the
+// result is deterministic. Singleton components are included.
+func (g *DependencyGraph) StronglyConnectedComponents() [][]string {
+	vertices := g.sortedVertices()
+	index := make(map[string]int, len(vertices))
+	low := make(map[string]int, len(vertices))
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	next := 0
+
+	// Iterative DFS: recursion depth would follow the longest import chain.
+	type frame struct {
+		v         string
+		neighbors []string
+		i         int
+	}
+	for _, root := range vertices {
+		if _, seen := index[root]; seen {
+			continue
+		}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		work := []*frame{{v: root, neighbors: g.sortedNeighbors(root)}}
+
+		for len(work) > 0 {
+			f := work[len(work)-1]
+			if f.i < len(f.neighbors) {
+				w := f.neighbors[f.i]
+				f.i++
+				if _, seen := index[w]; !seen {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					work = append(work, &frame{v: w, neighbors: g.sortedNeighbors(w)})
+				} else if onStack[w] && index[w] < low[f.v] {
+					low[f.v] = index[w]
+				}
+				continue
+			}
+
+			work = work[:len(work)-1]
+			if len(work) > 0 {
+				if parent := work[len(work)-1].v; low[f.v] < low[parent] {
+					low[parent] = low[f.v]
+				}
+			}
+			if low[f.v] != index[f.v] {
+				continue
+			}
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == f.v {
+					break
+				}
+			}
+			sort.Strings(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+	return sccs
+}
+
+// Cycles returns every SCC with more than one vertex, largest first, with a
+// sample cycle and a suggested set of edges to break it.
+func (g *DependencyGraph) Cycles() []Cycle {
+	var cycles []Cycle
+	for _, scc := range g.StronglyConnectedComponents() {
+		if len(scc) < 2 {
+			continue
+		}
+		members := make(map[string]bool, len(scc))
+		for _, v := range scc {
+			members[v] = true
+		}
+		c := Cycle{Members: scc}
+		for _, v := range scc {
+			for _, w := range g.sortedNeighbors(v) {
+				if members[w] {
+					c.Edges = append(c.Edges, [2]string{v, w})
+				}
+			}
+		}
+		c.Break = feedbackEdges(scc, c.Edges)
+		if len(c.Break) > 0 {
+			b := c.Break[0]
+			if p := shortestPath(b[1], b[0], c.Edges); p != nil {
+				c.Path = append(p, b[1])
+			}
+		}
+		cycles = append(cycles, c)
+	}
+	sort.SliceStable(cycles, func(i, j int) bool {
+		return len(cycles[i].Members) > len(cycles[j].Members)
+	})
+	return cycles
+}
+
+// feedbackEdges returns a small set of edges whose removal leaves the
+// component acyclic. Finding the minimum set is NP-hard, so it orders the
+// vertices with the Eades–Lin–Smyth heuristic, takes the edges pointing
+// backwards in that order, then drops any edge that can be restored
+// without recreating a cycle.
+func feedbackEdges(vertices []string, edges [][2]string) [][2]string {
+	out := make(map[string]map[string]bool)
+	in := make(map[string]map[string]bool)
+	for _, v := range vertices {
+		out[v] = make(map[string]bool)
+		in[v] = make(map[string]bool)
+	}
+	for _, e := range edges {
+		out[e[0]][e[1]] = true
+		in[e[1]][e[0]] = true
+	}
+
+	remaining := make(map[string]bool, len(vertices))
+	for _, v := range vertices {
+		remaining[v] = true
+	}
+	remove := func(v string) {
+		delete(remaining, v)
+		for w := range out[v] {
+			delete(in[w], v)
+		}
+		for w := range in[v] {
+			delete(out[w], v)
+		}
+	}
+
+	var head, tail []string
+	for len(remaining) > 0 {
+		changed := true
+		for changed {
+			changed = false
+			for _, v := range vertices {
+				if !remaining[v] {
+					continue
+				}
+				switch {
+				case len(out[v]) == 0:
+					tail = append(tail, v)
+					remove(v)
+					changed = true
+				case len(in[v]) == 0:
+					head = append(head, v)
+					remove(v)
+					changed = true
+				}
+			}
+		}
+		if len(remaining) == 0 {
+			break
+		}
+		best, bestDelta := "", 0
+		for _, v := range vertices {
+			if !remaining[v] {
+				continue
+			}
+			if d := len(out[v]) - len(in[v]); best == "" || d > bestDelta {
+				best, bestDelta = v, d
+			}
+		}
+		head = append(head, best)
+		remove(best)
+	}
+
+	order := make(map[string]int, len(vertices))
+	for i, v := range head {
+		order[v] = i
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		order[tail[i]] = len(order)
+	}
+
+	var kept, back [][2]string
+	for _, e := range edges {
+		if order[e[0]] > order[e[1]] {
+			back = append(back, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	// Restore back edges that no longer close a cycle.
+	var result [][2]string
+	for _, e := range back {
+		if shortestPath(e[1], e[0], kept) == nil {
+			kept = append(kept, e)
+		} else {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// shortestPath returns the vertices of a shortest path from src to dst over
+// edges (inclusive of both ends), or nil if dst is unreachable.
+func shortestPath(src, dst string, edges [][2]string) []string {
+	adj := make(map[string][]string)
+	for _, e := range edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+	}
+	prev := map[string]string{src: ""}
+	queue := []string{src}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if v == dst {
+			var path []string
+			for ; v != ""; v = prev[v] {
+				path = append(path, v)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, w := range adj[v] {
+			if _, seen := prev[w]; !seen {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *DependencyGraph) sortedVertices() []string {
+	vs := make([]string, 0, len(g.Vertices))
+	for v := range g.Vertices {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func (g *DependencyGraph) sortedNeighbors(v string) []string {
+	ns := make([]string, 0, len(g.adjacency[v]))
+	for w := range g.adjacency[v] {
+		ns = append(ns, w)
+	}
+	sort.Strings(ns)
+	return ns
+}
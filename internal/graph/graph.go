@@ -1,9 +1,13 @@
 package graph
 
 import (
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/goscope/internal/cache"
+	"github.com/goscope/internal/gomod"
 	"github.com/goscope/internal/parser"
 )
 
@@ -14,8 +18,17 @@ type DependencyGraph struct {
 	adjacency     map[string]map[string]bool
 	reverseAdj    map[string]map[string]bool
 	PageRankScores map[string]float64
+
+	// Cache, when set, lets Build reuse the type-reference edges of
+	// microservices whose files are unchanged since an earlier run.
+	Cache *cache.Cache
+	// MaxTypeRefDecls caps how many declarations of a microservice are
+	// searched for when linking files by type references; 0 means 500.
+	MaxTypeRefDecls int
 }
 
+const defaultMaxTypeRefDecls = 500
+
 func New() *DependencyGraph {
 	return &DependencyGraph{
 		Vertices:       make(map[string]bool),
@@ -56,27 +69,18 @@ func (g *DependencyGraph) InDegree(v string) int {
 }
 
 // Build builds the graph from parsed files using import-based edges.
-func (g *DependencyGraph) Build(files []*parser.ParsedFile) {
-	nameToPath := make(map[string]string)
-
+// Imports are resolved to package directories through resolver; when no
+// modules are known it falls back to matching the last import path segment.
+func (g *DependencyGraph) Build(files []*parser.ParsedFile, resolver *gomod.Resolver) {
 	for _, f := range files {
 		g.AddVertex(f.FilePath)
-		nameToPath[f.FileNameWithoutExt()] = f.FilePath
-		if f.ModuleName != "" {
-			nameToPath[f.ModuleName] = f.FilePath
-		}
 	}
 
 	// Import-based edges
-	for _, src := range files {
-		for _, imp := range src.Imports {
-			// Try matching on last path segment
-			parts := strings.Split(imp, "/")
-			baseName := parts[len(parts)-1]
-			if targetPath, ok := nameToPath[baseName]; ok {
-				g.AddEdge(src.FilePath, targetPath)
-			}
-		}
+	if resolver.Len() > 0 {
+		g.buildResolvedImportEdges(files, resolver)
+	} else {
+		g.buildNameMatchEdges(files)
 	}
 
 	// Type-reference edges within same microservice
@@ -90,11 +94,82 @@ func (g *DependencyGraph) Build(files []*parser.ParsedFile) {
 	}
 
 	for _, msFiles := range byMS {
-		g.buildTypeRefEdges(msFiles)
+		for _, e := range g.cachedTypeRefEdges(msFiles) {
+			g.AddEdge(e[0], e[1])
+		}
+	}
+}
+
+// cachedTypeRefEdges is typeRefEdges with the result cached under the
+// paths and contents of files.
+func (g *DependencyGraph) cachedTypeRefEdges(files []*parser.ParsedFile) [][2]string {
+	maxDecls := g.MaxTypeRefDecls
+	if maxDecls <= 0 {
+		maxDecls = defaultMaxTypeRefDecls
+	}
+	parts := []string{"typerefs", strconv.Itoa(maxDecls)}
+	for _, f := range files {
+		if f.ContentHash == "" {
+			return typeRefEdges(files, maxDecls)
+		}
+		parts = append(parts, f.FilePath, f.ContentHash)
+	}
+	key := cache.Key(parts...)
+	var edges [][2]string
+	if g.Cache.Get(key, &edges) {
+		return edges
+	}
+	edges = typeRefEdges(files, maxDecls)
+	g.Cache.Put(key, edges)
+	return edges
+}
+
+// buildResolvedImportEdges links each file to the files of every local
+// package it imports.
+func (g *DependencyGraph) buildResolvedImportEdges(files []*parser.ParsedFile, resolver *gomod.Resolver) {
+	byDir := make(map[string][]string)
+	for _, f := range files {
+		if f.FileType == "go" && !strings.HasSuffix(f.FilePath, "_test.go") {
+			dir := filepath.Dir(f.FilePath)
+			byDir[dir] = append(byDir[dir], f.FilePath)
+		}
+	}
+	for _, src := range files {
+		for _, imp := range src.Imports {
+			dir, mod := resolver.ResolveFrom(filepath.Dir(src.FilePath), imp)
+			if mod == nil {
+				continue
+			}
+			for _, target := range byDir[dir] {
+				g.AddEdge(src.FilePath, target)
+			}
+		}
+	}
+}
+
+// buildNameMatchEdges links imports to files by last path segment. Used
+// when the tree has no go.mod files to resolve against.
+func (g *DependencyGraph) buildNameMatchEdges(files []*parser.ParsedFile) {
+	nameToPath := make(map[string]string)
+	for _, f := range files {
+		nameToPath[f.FileNameWithoutExt()] = f.FilePath
+		if f.ModuleName != "" {
+			nameToPath[f.ModuleName] = f.FilePath
+		}
+	}
+	for _, src := range files {
+		for _, imp := range src.Imports {
+			// Try matching on last path segment
+			parts := strings.Split(imp, "/")
+			baseName := parts[len(parts)-1]
+			if targetPath, ok := nameToPath[baseName]; ok {
+				g.AddEdge(src.FilePath, targetPath)
+			}
+		}
 	}
 }
 
-func (g *DependencyGraph) buildTypeRefEdges(files []*parser.ParsedFile) {
+func typeRefEdges(files []*parser.ParsedFile, maxDecls int) [][2]string {
 	type declInfo struct {
 		name string
 		path string
@@ -112,11 +187,12 @@ func (g *DependencyGraph) buildTypeRefEdges(files []*parser.ParsedFile) {
 		}
 	}
 
-	if len(decls) > 500 {
-		decls = decls[:500]
+	if len(decls) > maxDecls {
+		decls = decls[:maxDecls]
 	}
 
 	// Read file contents and check for type references
+	var edges [][2]string
 	for _, f := range files {
 		content, err := readFileContent(f.FilePath)
 		if err != nil {
@@ -127,10 +203,11 @@ func (g *DependencyGraph) buildTypeRefEdges(files []*parser.ParsedFile) {
 				continue
 			}
 			if containsTypeName(content, d.name) {
-				g.AddEdge(f.FilePath, d.path)
+				edges = append(edges, [2]string{f.FilePath, d.path})
 			}
 		}
 	}
+	return edges
 }
 
 func containsTypeName(content, typeName string) bool {
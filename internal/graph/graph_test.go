@@ -1,9 +1,15 @@
 package graph
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/goscope/internal/cache"
+	"github.com/goscope/internal/gomod"
 	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/scanner"
 )
 
 func TestGraphBasic(t *testing.T) {
@@ -109,9 +115,452 @@ func TestBuildFromFiles(t *testing.T) {
 	}
 
 	g := New()
-	g.Build(files)
+	g.Build(files, nil)
 
 	if len(g.Vertices) < 2 {
 		t.Errorf("Vertices = %d, want >= 2", len(g.Vertices))
 	}
 }
+
+func moduleFixture() ([]*parser.ParsedFile, *gomod.Resolver) {
+	resolver := gomod.NewResolver([]gomod.Module{
+		{Path: "github.com/acme/users", Dir: "/code/users"},
+		{Path: "github.com/acme/orders", Dir: "/code/orders"},
+	})
+	files := []*parser.ParsedFile{
+		{
+			FilePath: "/code/users/main.go", PackageName: "main", MicroserviceName: "users", FileType: "go",
+			Imports: []string{"fmt", "github.com/acme/users/config", "github.com/acme/orders/client", "github.com/spf13/viper"},
+		},
+		{
+			FilePath: "/code/users/config/config.go", PackageName: "config", MicroserviceName: "users", FileType: "go",
+			Imports: []string{"github.com/spf13/viper"},
+		},
+		{
+			FilePath: "/code/orders/client/client.go", PackageName: "client", MicroserviceName: "orders", FileType: "go",
+		},
+		{
+			FilePath: "/code/orders/config/config.go", PackageName: "config", MicroserviceName: "orders", FileType: "go",
+		},
+	}
+	return files, resolver
+}
+
+func TestBuildResolvedImports(t *testing.T) {
+	files, resolver := moduleFixture()
+	g := New()
+	g.Build(files, resolver)
+
+	if !g.adjacency["/code/users/main.go"]["/code/users/config/config.go"] {
+		t.Error("missing edge main.go → users/config")
+	}
+	if !g.adjacency["/code/users/main.go"]["/code/orders/client/client.go"] {
+		t.Error("missing edge main.go → orders/client")
+	}
+	// Name matching would have linked users/config to orders/config too.
+	if g.adjacency["/code/users/main.go"]["/code/orders/config/config.go"] {
+		t.Error("bogus edge main.go → orders/config")
+	}
+}
+
+func TestBuildPackageGraph(t *testing.T) {
+	files, resolver := moduleFixture()
+	pg := BuildPackageGraph(files, resolver)
+
+	want := map[[2]string]EdgeKind{
+		{"/code/users", "/code/users/config"}:            EdgeIntraService,
+		{"/code/users", "/code/orders/client"}:           EdgeCrossService,
+		{"/code/users", "github.com/spf13/viper"}:        EdgeExternal,
+		{"/code/users/config", "github.com/spf13/viper"}: EdgeExternal,
+	}
+	if len(pg.EdgeKinds) != len(want) {
+		t.Errorf("EdgeKinds = %v, want %v", pg.EdgeKinds, want)
+	}
+	for e, kind := range want {
+		if pg.EdgeKinds[e] != kind {
+			t.Errorf("edge %v kind = %q, want %q", e, pg.EdgeKinds[e], kind)
+		}
+	}
+	if _, ok := pg.Packages["fmt"]; ok {
+		t.Error("stdlib package should not be a vertex")
+	}
+	if p := pg.Packages["/code/users/config"]; p.ImportPath != "github.com/acme/users/config" || p.Module != "github.com/acme/users" {
+		t.Errorf("users/config = %+v", p)
+	}
+
+	top := pg.MostImported(1, true)
+	if len(top) != 1 || top[0].Package.ID != "github.com/spf13/viper" || top[0].Total != 2 {
+		t.Errorf("MostImported(external) = %+v", top)
+	}
+}
+
+func newGraph(edges ...[2]string) *DependencyGraph {
+	g := New()
+	for _, e := range edges {
+		g.AddVertex(e[0])
+		g.AddVertex(e[1])
+	}
+	for _, e := range edges {
+		g.AddEdge(e[0], e[1])
+	}
+	return g
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	// a ⇄ b → c → d → c, e → a
+	g := newGraph([2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"b", "c"},
+		[2]string{"c", "d"}, [2]string{"d", "c"}, [2]string{"e", "a"})
+
+	sccs := g.StronglyConnectedComponents()
+	got := make(map[string]int)
+	for i, scc := range sccs {
+		for _, v := range scc {
+			got[v] = i
+		}
+	}
+	if len(sccs) != 3 {
+		t.Fatalf("SCCs = %v, want 3 components", sccs)
+	}
+	if got["a"] != got["b"] || got["c"] != got["d"] || got["a"] == got["c"] || got["e"] == got["a"] {
+		t.Errorf("SCCs = %v", sccs)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	// Two 3-cycles sharing the edge c → a: removing it breaks both.
+	g := newGraph([2]string{"a", "b"}, [2]string{"b", "c"}, [2]string{"c", "a"},
+		[2]string{"a", "d"}, [2]string{"d", "c"}, [2]string{"x", "a"})
+
+	cycles := g.Cycles()
+	if len(cycles) != 1 {
+		t.Fatalf("Cycles = %+v, want 1", cycles)
+	}
+	c := cycles[0]
+	if len(c.Members) != 4 || len(c.Edges) != 5 {
+		t.Errorf("members = %v, edges = %v", c.Members, c.Edges)
+	}
+	if len(c.Break) != 1 || c.Break[0] != [2]string{"c", "a"} {
+		t.Errorf("Break = %v, want [[c a]]", c.Break)
+	}
+	if len(c.Path) < 3 || c.Path[0] != c.Path[len(c.Path)-1] {
+		t.Errorf("Path = %v, want a closed cycle", c.Path)
+	}
+
+	// Removing the suggested edges must leave no cycles.
+	h := New()
+	for v := range g.Vertices {
+		h.AddVertex(v)
+	}
+	for _, e := range g.Edges {
+		if e != c.Break[0] {
+			h.AddEdge(e[0], e[1])
+		}
+	}
+	if left := h.Cycles(); len(left) != 0 {
+		t.Errorf("cycles left after breaking: %+v", left)
+	}
+}
+
+func TestPackageGraphCycles(t *testing.T) {
+	resolver := gomod.NewResolver([]gomod.Module{{Path: "example.com/m", Dir: "/m"}})
+	files := []*parser.ParsedFile{
+		{FilePath: "/m/a/a.go", FileType: "go", Imports: []string{"example.com/m/b"}},
+		{FilePath: "/m/b/b.go", FileType: "go", Imports: []string{"example.com/m/a"}},
+	}
+	cycles := BuildPackageGraph(files, resolver).Cycles()
+	if len(cycles) != 1 || len(cycles[0].Members) != 2 || len(cycles[0].Break) != 1 {
+		t.Errorf("package cycles = %+v", cycles)
+	}
+
+	// An external test package may import a package that imports the
+	// package under test; go build accepts it, so it is no cycle.
+	files = []*parser.ParsedFile{
+		{FilePath: "/m/a/a.go", FileType: "go", PackageName: "a"},
+		{FilePath: "/m/a/a_test.go", FileType: "go", PackageName: "a_test", Imports: []string{"example.com/m/a", "example.com/m/b"}},
+		{FilePath: "/m/b/b.go", FileType: "go", PackageName: "b", Imports: []string{"example.com/m/a"}},
+	}
+	if cycles := BuildPackageGraph(files, resolver).Cycles(); len(cycles) != 0 {
+		t.Errorf("external test package cycles = %+v, want none", cycles)
+	}
+}
+
+func TestPackageGraphSkipsTestImports(t *testing.T) {
+	resolver := gomod.NewResolver([]gomod.Module{{Path: "example.com/m", Dir: "/m"}})
+	files := []*parser.ParsedFile{
+		{FilePath: "/m/a/a.go", FileType: "go", PackageName: "a", Imports: []string{"example.com/m/b"}},
+		{FilePath: "/m/a/a_test.go", FileType: "go", PackageName: "a",
+			Imports: []string{"example.com/m/c", "github.com/stretchr/testify/assert"}},
+		{FilePath: "/m/b/b.go", FileType: "go", PackageName: "b"},
+		{FilePath: "/m/c/c.go", FileType: "go", PackageName: "c"},
+	}
+	pg := BuildPackageGraph(files, resolver)
+	if len(pg.EdgeKinds) != 1 || pg.EdgeKinds[[2]string{"/m/a", "/m/b"}] != EdgeIntraService {
+		t.Errorf("EdgeKinds = %v, want only /m/a -> /m/b", pg.EdgeKinds)
+	}
+	if _, ok := pg.Packages["github.com/stretchr/testify/assert"]; ok {
+		t.Error("test-only import recorded as a package")
+	}
+	if p := pg.Packages["/m/a"]; len(p.Files) != 2 {
+		t.Errorf("/m/a files = %v, want the test file too", p.Files)
+	}
+}
+
+func TestPackageGraphReplace(t *testing.T) {
+	resolver := gomod.NewResolver([]gomod.Module{
+		{Path: "github.com/acme/orders", Dir: "/code/orders", Replaces: []gomod.Replace{
+			{Old: "github.com/acme/shared", New: "../libs/shared", Dir: "/code/libs/shared"},
+		}},
+		{Path: "github.com/forks/shared", Dir: "/code/libs/shared"},
+	})
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/orders/main.go", FileType: "go", Imports: []string{"github.com/acme/shared/log"}},
+		{FilePath: "/code/libs/shared/log/log.go", FileType: "go"},
+	}
+	pg := BuildPackageGraph(files, resolver)
+	if pg.OutDegree("/code/orders") != 1 || pg.InDegree("/code/libs/shared/log") != 1 {
+		t.Errorf("edges = %v, want orders -> the replacement directory", pg.Edges)
+	}
+	if _, ok := pg.Packages["github.com/acme/shared/log"]; ok {
+		t.Error("replaced import recorded as external")
+	}
+}
+
+func TestBuildServiceGraph(t *testing.T) {
+	resolver := gomod.NewResolver([]gomod.Module{
+		{Path: "github.com/acme/users", Dir: "/code/users"},
+		{Path: "github.com/acme/orders", Dir: "/code/orders"},
+		{Path: "github.com/acme/userspace", Dir: "/code/userspace"},
+	})
+	usersProto, _ := parser.ParseProto([]byte(`syntax = "proto3";
+package users.v1;
+option go_package = "github.com/acme/gen/users/v1;usersv1";
+service UserService { rpc Get(Req) returns (Req); }
+message Req {}`))
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/proto/users/v1/users.proto", MicroserviceName: "proto", FileType: "proto", Proto: usersProto,
+			Declarations: []parser.Declaration{{Name: "UserService", Kind: parser.DeclService}, {Name: "Req", Kind: parser.DeclMessage}}},
+		{FilePath: "/code/users/server.go", MicroserviceName: "users", FileType: "go",
+			Imports:      []string{"github.com/acme/gen/users/v1"},
+			GRPCServers:  []parser.GRPCRef{{Service: "UserService", ImportPath: "github.com/acme/gen/users/v1"}},
+			Declarations: []parser.Declaration{{Name: "Store", Kind: parser.DeclInterface}, {Name: "server", Kind: parser.DeclStruct}}},
+		{FilePath: "/code/orders/main.go", MicroserviceName: "orders", FileType: "go",
+			Imports:     []string{"github.com/acme/gen/users/v1", "github.com/acme/userspace/util"},
+			GRPCClients: []parser.GRPCRef{{Service: "UserService", ImportPath: "github.com/acme/gen/users/v1"}, {Service: "Cluster", ImportPath: "github.com/redis/go-redis/v9"}}},
+		{FilePath: "/code/userspace/util/util.go", MicroserviceName: "userspace", FileType: "go"},
+	}
+	sg := BuildServiceGraph(files, BuildPackageGraph(files, resolver))
+
+	want := map[[2]string][]string{
+		{"orders", "users"}:     {DepGRPC},
+		{"orders", "proto"}:     {DepProto},
+		{"orders", "userspace"}: {DepImport},
+		{"users", "proto"}:      {DepProto},
+	}
+	if len(sg.Deps) != len(want) {
+		for k, d := range sg.Deps {
+			t.Logf("%v %v", k, d.Kinds)
+		}
+		t.Fatalf("got %d deps, want %d", len(sg.Deps), len(want))
+	}
+	for key, kinds := range want {
+		d := sg.Deps[key]
+		if d == nil || strings.Join(d.Kinds, ",") != strings.Join(kinds, ",") {
+			t.Errorf("dep %v = %+v, want kinds %v", key, d, kinds)
+		}
+	}
+
+	users := sg.Metrics["users"]
+	if users.Ca != 1 || users.Ce != 1 || users.Instability != 0.5 || users.Abstractness != 0.5 || users.Distance != 0 {
+		t.Errorf("users metrics = %+v", users)
+	}
+	orders := sg.Metrics["orders"]
+	if orders.Ca != 0 || orders.Ce != 3 || orders.Instability != 1 {
+		t.Errorf("orders metrics = %+v", orders)
+	}
+	if top := sg.SortedMetrics()[0]; top.Name != "proto" {
+		t.Errorf("most depended-upon service = %s, want proto", top.Name)
+	}
+
+	sg.MarkLibraries(map[string]bool{"proto": true})
+	if sg.Metrics["proto"].Kind != NodeLibrary || sg.Metrics["users"].Kind != NodeService || sg.Libraries() != 1 {
+		t.Errorf("after MarkLibraries: proto %s, users %s, %d libraries", sg.Metrics["proto"].Kind, sg.Metrics["users"].Kind, sg.Libraries())
+	}
+}
+
+func TestBuildGRPCCoverage(t *testing.T) {
+	ordersProto, _ := parser.ParseProto([]byte(`syntax = "proto3";
+package orders.v1;
+option go_package = "github.com/acme/gen/orders/v1;ordersv1";
+service OrderService {
+  rpc Get(Req) returns (Req);
+  rpc List(Req) returns (Req);
+  rpc Cancel(Req) returns (Req);
+}
+service AdminService { rpc Purge(Req) returns (Req); }
+message Req {}`))
+	legacyProto, _ := parser.ParseProto([]byte(`syntax = "proto3";
+package legacy;
+option go_package = "github.com/acme/gen/legacy";
+service OrderService { rpc Get(Req) returns (Req); }
+message Req {}`))
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/proto/orders/v1/orders.proto", MicroserviceName: "proto", FileType: "proto", Proto: ordersProto},
+		{FilePath: "/code/proto/legacy/orders.proto", MicroserviceName: "proto", FileType: "proto", Proto: legacyProto},
+		{FilePath: "/code/orders/grpcapi/server.go", MicroserviceName: "orders", FileType: "go", PackageName: "grpcapi",
+			Imports: []string{"github.com/acme/gen/orders/v1"},
+			Declarations: []parser.Declaration{
+				{Name: "Server", Kind: parser.DeclStruct, Line: 10, Fields: []parser.StructField{
+					{Name: "UnimplementedOrderServiceServer", Type: "ordersv1.UnimplementedOrderServiceServer", Embedded: true},
+					{Name: "db", Type: "*sql.DB"},
+				}},
+				{Name: "Get", Kind: parser.DeclFunc, Receiver: "*Server"},
+			}},
+		{FilePath: "/code/orders/grpcapi/list.go", MicroserviceName: "orders", FileType: "go", PackageName: "grpcapi",
+			Declarations: []parser.Declaration{{Name: "List", Kind: parser.DeclFunc, Receiver: "*Server"}}},
+		{FilePath: "/code/orders/main.go", MicroserviceName: "orders", FileType: "go", PackageName: "main",
+			GRPCServers: []parser.GRPCRef{{Service: "OrderService", ImportPath: "github.com/acme/gen/orders/v1", Impl: "grpcapi.Server"}}},
+		{FilePath: "/code/gateway/main.go", MicroserviceName: "gateway", FileType: "go",
+			GRPCClients: []parser.GRPCRef{{Service: "OrderService", ImportPath: "github.com/acme/gen/orders/v1"}}},
+		{FilePath: "/code/billing/client.go", MicroserviceName: "billing", FileType: "go",
+			GRPCClients: []parser.GRPCRef{{Service: "OrderService", ImportPath: "github.com/acme/gen/legacy"}}},
+	}
+	cov := BuildGRPCCoverage(files)
+	if len(cov.Services) != 3 {
+		t.Fatalf("got %d services, want 3", len(cov.Services))
+	}
+	byKey := make(map[string]*GRPCService)
+	for _, s := range cov.Services {
+		byKey[s.Package+"."+s.Name] = s
+	}
+
+	orders := byKey["orders.v1.OrderService"]
+	if len(orders.Implementations) != 1 {
+		t.Fatalf("implementations = %+v", orders.Implementations)
+	}
+	impl := orders.Implementations[0]
+	if impl.Type != "grpcapi.Server" || !impl.Registered || impl.Embeds != "ordersv1.UnimplementedOrderServiceServer" ||
+		impl.Line != 10 || strings.Join(impl.Missing, ",") != "Cancel" {
+		t.Errorf("impl = %+v", impl)
+	}
+	for _, r := range orders.RPCs {
+		served := strings.Join(r.ServedBy, ",")
+		if (r.Name == "Cancel") != r.Unimplemented || (r.Name != "Cancel" && served != "orders") {
+			t.Errorf("rpc %+v", r)
+		}
+	}
+	if strings.Join(orders.Clients, ",") != "gateway" || strings.Join(orders.Registered, ",") != "orders" {
+		t.Errorf("clients %v, registered %v", orders.Clients, orders.Registered)
+	}
+
+	legacy := byKey["legacy.OrderService"]
+	if len(legacy.Implementations) != 0 || legacy.RPCs[0].Unimplemented || strings.Join(legacy.Clients, ",") != "billing" {
+		t.Errorf("legacy = %+v", legacy)
+	}
+	if admin := byKey["orders.v1.AdminService"]; admin.RPCs[0].Unimplemented {
+		t.Errorf("AdminService without implementations reported unimplemented")
+	}
+	if n := cov.Unimplemented(); n != 1 {
+		t.Errorf("Unimplemented() = %d, want 1", n)
+	}
+}
+
+func TestBuildTypeRefEdgesCached(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(filepath.Join(dir, "cache"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := map[string]string{
+		"a.go": "package svc\n\nfunc use(o *Order) {}\n",
+		"b.go": "package svc\n\ntype Order struct{}\n",
+	}
+	var files []*parser.ParsedFile
+	for _, name := range []string{"a.go", "b.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(srcs[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &parser.ParsedFile{
+			FilePath:         path,
+			MicroserviceName: "svc",
+			ContentHash:      cache.Hash([]byte(srcs[name])),
+		})
+	}
+	files[1].Declarations = []parser.Declaration{{Name: "Order", Kind: parser.DeclStruct}}
+	want := [2]string{files[0].FilePath, files[1].FilePath}
+
+	for run := 1; run <= 2; run++ {
+		g := New()
+		g.Cache = c
+		g.Build(files, nil)
+		if len(g.Edges) != 1 || g.Edges[0] != want {
+			t.Fatalf("run %d: edges %v, want [%v]", run, g.Edges, want)
+		}
+		// The second run must not need the files.
+		for _, f := range files {
+			os.Remove(f.FilePath)
+		}
+	}
+}
+
+func TestBuildServiceCalls(t *testing.T) {
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/orders/proto/orders.proto", MicroserviceName: "orders", FileType: "proto",
+			Proto: &parser.ProtoFile{Services: []parser.ProtoService{{Name: "OrderService"}}}},
+		{FilePath: "/code/orders/main.go", MicroserviceName: "orders", FileType: "go",
+			Addresses: []parser.Address{{Value: "orders:9090", Host: "orders", Line: 4}}}, // its own address
+		{FilePath: "/code/gateway/main.go", MicroserviceName: "gateway", FileType: "go",
+			GRPCClients: []parser.GRPCRef{{Service: "OrderService", Line: 10}},
+			Addresses: []parser.Address{
+				{Value: "http://user-service:8080", Scheme: "http", Host: "user-service", Line: 12},
+				{Value: "orders:9090", Scheme: "grpc", Host: "orders", Line: 11},
+				{Value: "http://github:80", Scheme: "http", Host: "github", Line: 13},
+			}},
+		{FilePath: "/code/users/main.go", MicroserviceName: "users", FileType: "go"},
+		{FilePath: "/code/users-admin/main.go", MicroserviceName: "users-admin", FileType: "go"},
+	}
+	endpoints := []scanner.Endpoint{
+		{Service: "users-api", Key: "ORDERS_ADDR", Value: "orders:9090", Host: "orders", File: "/code/docker-compose.yml", Line: 7},
+		{Service: "users", Key: "ORDERS_GRPC", Value: "orders.prod.svc:9090", Host: "orders", File: "/code/users/.env", Line: 1},
+		{Service: "users-admin", Key: "BACKEND", Value: "ws://users-api", Scheme: "ws", Host: "users-api", File: "/code/users-admin/app.yaml", Line: 2},
+	}
+	calls := BuildServiceCalls(files, endpoints, map[string]string{"users-api": "users"})
+
+	type call struct{ from, to, protocols string }
+	want := []call{
+		{"gateway", "orders", "grpc"},
+		{"gateway", "users", "http"},
+		{"users", "orders", "grpc,tcp"},
+		{"users-admin", "users", "ws"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v", calls)
+	}
+	for i, w := range want {
+		c := calls[i]
+		if got := (call{c.From, c.To, strings.Join(c.Protocols, ",")}); got != w {
+			t.Errorf("call %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if s := calls[0].Sites; len(s) != 2 || s[0].Source != SourceClient || s[0].Detail != "NewOrderServiceClient" || s[1].Source != SourceCode {
+		t.Errorf("gateway → orders sites = %+v", s)
+	}
+	if s := calls[2].Sites[0]; s.Source != SourceConfig || s.Detail != "ORDERS_ADDR=orders:9090" || s.Line != 7 {
+		t.Errorf("users → orders site = %+v", s)
+	}
+}
+
+func TestServiceKey(t *testing.T) {
+	for name, want := range map[string]string{
+		"user-service": "user",
+		"Users":        "user",
+		"user_svc":     "user",
+		"orders-api":   "order",
+		"api":          "api",
+		"bus":          "bus",
+	} {
+		if got := serviceKey(name); got != want {
+			t.Errorf("serviceKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
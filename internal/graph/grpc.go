@@ -0,0 +1,278 @@
+package graph
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// GRPCCoverage maps every proto service to the Go types that implement it
+// and the microservices that call it.
+type GRPCCoverage struct {
+	Services []*GRPCService `json:"services"` // sorted by microservice and name
+}
+
+// GRPCService is a proto service and its Go servers and clients.
+type GRPCService struct {
+	Name            string       `json:"name"`    // proto service name, e.g. "OrderService"
+	Package         string       `json:"package"` // proto package
+	Proto           string       `json:"proto"`   // path of the .proto file
+	Microservice    string       `json:"microservice"`
+	Implementations []GRPCImpl   `json:"implementations"`
+	Registered      []string     `json:"registered"` // microservices calling Register<Service>Server
+	Clients         []string     `json:"clients"`    // microservices calling New<Service>Client
+	RPCs            []GRPCMethod `json:"rpcs"`
+}
+
+// GRPCImpl is a Go type implementing a proto service: it embeds
+// Unimplemented<Service>Server (or Unsafe<Service>Server) or is passed to
+// Register<Service>Server.
+type GRPCImpl struct {
+	Type         string   `json:"type"` // package-qualified, e.g. "grpcapi.orderServer"
+	File         string   `json:"file"`
+	Line         int      `json:"line"`
+	Microservice string   `json:"microservice"`
+	Embeds       string   `json:"embeds,omitempty"` // the embedded Unimplemented or Unsafe stub
+	Registered   bool     `json:"registered"`
+	Missing      []string `json:"missing"` // RPCs the type does not define, answered by the stub if any
+}
+
+// GRPCMethod is one RPC and the microservices whose implementations define
+// it.
+type GRPCMethod struct {
+	Name          string   `json:"name"`
+	ServedBy      []string `json:"servedBy"`
+	Unimplemented bool     `json:"unimplemented"` // implementations exist but none defines the RPC
+}
+
+// Unimplemented returns the number of RPCs that have implementations of
+// their service but are not defined by any of them.
+func (c *GRPCCoverage) Unimplemented() int {
+	n := 0
+	for _, s := range c.Services {
+		for _, r := range s.RPCs {
+			if r.Unimplemented {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// goType is a Go type declaration and its methods.
+type goType struct {
+	file    *parser.ParsedFile
+	decl    parser.Declaration
+	methods map[string]bool
+}
+
+// BuildGRPCCoverage matches the services of the .proto files to the Go
+// types implementing them. A type implements a service when it embeds the
+// generated Unimplemented<Service>Server or Unsafe<Service>Server, or when
+// it is passed to Register<Service>Server; its RPCs are its own methods,
+// so an RPC left to the embedded stub is reported missing. Services with
+// the same name in several proto packages are told apart by the import
+// path of the generated package where the Go code shows it.
+func BuildGRPCCoverage(files []*parser.ParsedFile) *GRPCCoverage {
+	// Go types by package directory and name, with their methods.
+	types := make(map[[2]string]*goType)
+	for _, f := range files {
+		if f.FileType != "go" {
+			continue
+		}
+		dir := filepath.Dir(f.FilePath)
+		for _, d := range f.Declarations {
+			switch {
+			case d.Kind == parser.DeclStruct || d.Kind == parser.DeclType:
+				t := typeEntry(types, dir, d.Name)
+				t.file, t.decl = f, d
+			case d.Kind == parser.DeclFunc && d.Receiver != "":
+				typeEntry(types, dir, receiverName(d.Receiver)).methods[d.Name] = true
+			}
+		}
+	}
+
+	cov := &GRPCCoverage{Services: []*GRPCService{}}
+	byName := make(map[string][]*GRPCService)
+	goPackages := make(map[*GRPCService]string)
+	for _, f := range files {
+		if f.Proto == nil {
+			continue
+		}
+		for _, ps := range f.Proto.Services {
+			s := &GRPCService{
+				Name: ps.Name, Package: f.Proto.Package, Proto: f.FilePath, Microservice: f.MicroserviceName,
+				Implementations: []GRPCImpl{}, Registered: []string{}, Clients: []string{}, RPCs: []GRPCMethod{},
+			}
+			for _, r := range ps.RPCs {
+				s.RPCs = append(s.RPCs, GRPCMethod{Name: r.Name, ServedBy: []string{}})
+			}
+			cov.Services = append(cov.Services, s)
+			byName[ps.Name] = append(byName[ps.Name], s)
+			goPackages[s] = goPackagePath(f.Proto)
+		}
+	}
+	if len(cov.Services) == 0 {
+		return cov
+	}
+	// pick returns the services named name, narrowed to the one generated
+	// into importPath when that tells them apart.
+	pick := func(name, importPath string) []*GRPCService {
+		all := byName[name]
+		if len(all) < 2 || importPath == "" {
+			return all
+		}
+		for _, s := range all {
+			if goPackages[s] == importPath {
+				return []*GRPCService{s}
+			}
+		}
+		return all
+	}
+
+	// Implementations: embedded stubs first, then registrations.
+	impls := make(map[*GRPCService]map[*goType]*GRPCImpl)
+	addImpl := func(s *GRPCService, t *goType) *GRPCImpl {
+		if impls[s] == nil {
+			impls[s] = make(map[*goType]*GRPCImpl)
+		}
+		if impl := impls[s][t]; impl != nil {
+			return impl
+		}
+		impl := &GRPCImpl{
+			Type:         t.file.PackageName + "." + t.decl.Name,
+			File:         t.file.FilePath,
+			Line:         t.decl.Line,
+			Microservice: t.file.MicroserviceName,
+		}
+		impls[s][t] = impl
+		return impl
+	}
+	for _, t := range types {
+		if t.file == nil {
+			continue
+		}
+		for _, field := range t.decl.Fields {
+			if !field.Embedded {
+				continue
+			}
+			var svc string
+			for _, prefix := range []string{"Unimplemented", "Unsafe"} {
+				if strings.HasPrefix(field.Name, prefix) && strings.HasSuffix(field.Name, "Server") {
+					svc = strings.TrimSuffix(strings.TrimPrefix(field.Name, prefix), "Server")
+				}
+			}
+			if svc == "" {
+				continue
+			}
+			importPath := ""
+			if qual, _, ok := strings.Cut(strings.TrimPrefix(field.Type, "*"), "."); ok {
+				importPath = t.file.ImportPath(qual)
+			}
+			for _, s := range pick(svc, importPath) {
+				addImpl(s, t).Embeds = strings.TrimPrefix(field.Type, "*")
+			}
+		}
+	}
+	for _, f := range files {
+		for _, r := range f.GRPCServers {
+			services := pick(r.Service, r.ImportPath)
+			for _, s := range services {
+				if f.MicroserviceName != "" {
+					s.Registered = appendUnique(s.Registered, f.MicroserviceName)
+				}
+				if t := findType(types, files, f, r.Impl); t != nil {
+					addImpl(s, t).Registered = true
+				}
+			}
+		}
+		for _, r := range f.GRPCClients {
+			for _, s := range pick(r.Service, r.ImportPath) {
+				if f.MicroserviceName != "" {
+					s.Clients = appendUnique(s.Clients, f.MicroserviceName)
+				}
+			}
+		}
+	}
+
+	for _, s := range cov.Services {
+		for t, impl := range impls[s] {
+			impl.Missing = []string{}
+			for i, r := range s.RPCs {
+				if t.methods[r.Name] {
+					s.RPCs[i].ServedBy = appendUnique(s.RPCs[i].ServedBy, impl.Microservice)
+				} else {
+					impl.Missing = append(impl.Missing, r.Name)
+				}
+			}
+			s.Implementations = append(s.Implementations, *impl)
+		}
+		sort.Slice(s.Implementations, func(i, j int) bool {
+			a, b := s.Implementations[i], s.Implementations[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.Line < b.Line
+		})
+		for i := range s.RPCs {
+			sort.Strings(s.RPCs[i].ServedBy)
+			s.RPCs[i].Unimplemented = len(s.Implementations) > 0 && len(s.RPCs[i].ServedBy) == 0
+		}
+		sort.Strings(s.Registered)
+		sort.Strings(s.Clients)
+	}
+	sort.SliceStable(cov.Services, func(i, j int) bool {
+		a, b := cov.Services[i], cov.Services[j]
+		if a.Microservice != b.Microservice {
+			return a.Microservice < b.Microservice
+		}
+		return a.Name < b.Name
+	})
+	return cov
+}
+
+func typeEntry(types map[[2]string]*goType, dir, name string) *goType {
+	key := [2]string{dir, name}
+	t := types[key]
+	if t == nil {
+		t = &goType{methods: make(map[string]bool)}
+		types[key] = t
+	}
+	return t
+}
+
+// receiverName returns the type name of a method receiver: "*Set[T]" -> "Set".
+func receiverName(recv string) string {
+	recv = strings.TrimPrefix(recv, "*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return recv
+}
+
+// findType resolves the implementation type named in a registration in f:
+// an unqualified name is declared in f's package; a qualified one in a
+// package of that name in f's microservice.
+func findType(types map[[2]string]*goType, files []*parser.ParsedFile, f *parser.ParsedFile, name string) *goType {
+	if name == "" {
+		return nil
+	}
+	qual, typ, ok := strings.Cut(name, ".")
+	if !ok {
+		if t := types[[2]string{filepath.Dir(f.FilePath), name}]; t != nil && t.file != nil {
+			return t
+		}
+		return nil
+	}
+	for _, other := range files {
+		if other.MicroserviceName != f.MicroserviceName || other.PackageName != qual {
+			continue
+		}
+		if t := types[[2]string{filepath.Dir(other.FilePath), typ}]; t != nil && t.file != nil {
+			return t
+		}
+	}
+	return nil
+}
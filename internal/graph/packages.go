@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/parser"
+)
+
+// EdgeKind classifies a package import edge.
+type EdgeKind string
+
+const (
+	EdgeIntraService EdgeKind = "intra-service" // both packages belong to the same microservice
+	EdgeCrossService EdgeKind = "cross-service" // local package of another microservice
+	EdgeExternal     EdgeKind = "external"      // package outside every local module
+)
+
+// Package is a vertex of the package graph.
+type Package struct {
+	ID           string   `json:"id"`                     // directory for local packages, import path for external ones
+	ImportPath   string   `json:"importPath,omitempty"`   // "" for local packages outside any module
+	Name         string   `json:"name,omitempty"`         // Go package name (local packages only)
+	Module       string   `json:"module,omitempty"`       // module path ("" for external packages)
+	Microservice string   `json:"microservice,omitempty"` // owning microservice (local packages only)
+	External     bool     `json:"external"`               // not part of a local module
+	Files        []string `json:"files,omitempty"`        // parsed Go files (local packages only)
+}
+
+// PackageGraph is a package-level import graph built from resolved imports.
+// Standard library imports are not included.
+type PackageGraph struct {
+	*DependencyGraph
+	Packages  map[string]*Package
+	EdgeKinds map[[2]string]EdgeKind
+}
+
+// BuildPackageGraph groups Go files by directory and links packages through
+// their imports, resolved against the local modules known to resolver.
+// Test files belong to their directory's package but their imports are
+// left out: an external test package may import packages that import the
+// package under test, and test-only dependencies are not dependencies of
+// the package.
+func BuildPackageGraph(files []*parser.ParsedFile, resolver *gomod.Resolver) *PackageGraph {
+	pg := &PackageGraph{
+		DependencyGraph: New(),
+		Packages:        make(map[string]*Package),
+		EdgeKinds:       make(map[[2]string]EdgeKind),
+	}
+
+	var goFiles []*parser.ParsedFile
+	for _, f := range files {
+		if f.FileType != "go" {
+			continue
+		}
+		goFiles = append(goFiles, f)
+		dir := filepath.Dir(f.FilePath)
+		pkg := pg.Packages[dir]
+		if pkg == nil {
+			pkg = &Package{
+				ID:           dir,
+				ImportPath:   resolver.ImportPath(dir),
+				Microservice: f.MicroserviceName,
+			}
+			if m := resolver.ModuleForDir(dir); m != nil {
+				pkg.Module = m.Path
+			}
+			pg.Packages[dir] = pkg
+			pg.AddVertex(dir)
+		}
+		// Prefer the non-test package name for packages with external tests.
+		if pkg.Name == "" || strings.HasSuffix(pkg.Name, "_test") {
+			pkg.Name = f.PackageName
+		}
+		pkg.Files = append(pkg.Files, f.FilePath)
+	}
+
+	for _, f := range goFiles {
+		if strings.HasSuffix(f.FilePath, "_test.go") {
+			continue
+		}
+		src := filepath.Dir(f.FilePath)
+		for _, imp := range f.Imports {
+			// Resolve first: dotless module paths look like stdlib imports.
+			dir, mod := resolver.ResolveFrom(src, imp)
+			if mod != nil {
+				// Imports of unscanned (excluded) local packages are dropped.
+				if _, ok := pg.Packages[dir]; ok {
+					pg.addEdge(src, dir)
+				}
+				continue
+			}
+			if gomod.IsStdlib(imp) {
+				continue
+			}
+			if _, ok := pg.Packages[imp]; !ok {
+				pg.Packages[imp] = &Package{ID: imp, ImportPath: imp, External: true}
+				pg.AddVertex(imp)
+			}
+			pg.addEdge(src, imp)
+		}
+	}
+	return pg
+}
+
+func (pg *PackageGraph) addEdge(src, dst string) {
+	if src == dst {
+		return
+	}
+	from, to := pg.Packages[src], pg.Packages[dst]
+	kind := EdgeIntraService
+	switch {
+	case to.External:
+		kind = EdgeExternal
+	case from.Microservice != to.Microservice:
+		kind = EdgeCrossService
+	}
+	pg.AddEdge(src, dst)
+	pg.EdgeKinds[[2]string{src, dst}] = kind
+}
+
+// Label returns a short display name for a package: its import path, or the
+// directory when it is outside every module.
+func (p *Package) Label() string {
+	if p.ImportPath != "" {
+		return p.ImportPath
+	}
+	return p.ID
+}
+
+// EdgeCounts returns the number of edges of each kind.
+func (pg *PackageGraph) EdgeCounts() map[EdgeKind]int {
+	counts := make(map[EdgeKind]int)
+	for _, k := range pg.EdgeKinds {
+		counts[k]++
+	}
+	return counts
+}
+
+// PackageStat is a package with its importers grouped by edge kind.
+type PackageStat struct {
+	Package *Package
+	ByKind  map[EdgeKind]int
+	Total   int
+}
+
+// MostImported returns up to limit packages ordered by number of importing
+// packages. External packages are included only when external is true.
+func (pg *PackageGraph) MostImported(limit int, external bool) []PackageStat {
+	stats := make(map[string]*PackageStat)
+	for e, kind := range pg.EdgeKinds {
+		pkg := pg.Packages[e[1]]
+		if pkg.External != external {
+			continue
+		}
+		st := stats[e[1]]
+		if st == nil {
+			st = &PackageStat{Package: pkg, ByKind: make(map[EdgeKind]int)}
+			stats[e[1]] = st
+		}
+		st.ByKind[kind]++
+		st.Total++
+	}
+	out := make([]PackageStat, 0, len(stats))
+	for _, st := range stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Package.Label() < out[j].Package.Label()
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
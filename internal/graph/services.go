@@ -0,0 +1,291 @@
+package graph
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// Reasons one microservice depends on another.
+const (
+	DepImport = "import" // Go import of a package owned by the other service
+	DepProto  = "proto"  // use of the other service's proto definitions or generated code
+	DepGRPC   = "grpc"   // gRPC client for a service the other one serves or defines
+)
+
+// Kinds of service graph nodes.
+const (
+	NodeService = "service"
+	NodeLibrary = "library" // shared library from the config's service map
+)
+
+// ServiceDep is a directed dependency between two microservices.
+type ServiceDep struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Kinds []string `json:"kinds"` // sorted, e.g. ["grpc", "import"]
+	Count int      `json:"count"` // number of import sites and client constructions
+}
+
+// ServiceMetrics holds Robert C. Martin's package coupling metrics applied
+// to a microservice.
+type ServiceMetrics struct {
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind"`          // NodeService or NodeLibrary
+	Ca            int      `json:"ca"`            // afferent coupling: services depending on this one
+	Ce            int      `json:"ce"`            // efferent coupling: services this one depends on
+	Instability   float64  `json:"instability"`   // Ce / (Ca + Ce), 0 when isolated
+	Abstractness  float64  `json:"abstractness"`  // abstract types / all types
+	Distance      float64  `json:"distance"`      // |A + I - 1|, distance from the main sequence
+	AbstractTypes int      `json:"abstractTypes"` // Go interfaces and proto services
+	ConcreteTypes int      `json:"concreteTypes"` // Go structs and named types, proto messages and enums
+	Dependents    []string `json:"dependents"`
+	Dependencies  []string `json:"dependencies"`
+}
+
+// ServiceGraph is the microservice-level dependency graph.
+type ServiceGraph struct {
+	*DependencyGraph
+	Deps    map[[2]string]*ServiceDep
+	Metrics map[string]*ServiceMetrics
+}
+
+// BuildServiceGraph derives service dependencies from cross-service package
+// imports in pg, imports of Go packages generated from another service's
+// .proto files (matched by go_package), proto imports across services, and
+// gRPC clients constructed for services another microservice registers or
+// defines. Files without a microservice are ignored.
+func BuildServiceGraph(files []*parser.ParsedFile, pg *PackageGraph) *ServiceGraph {
+	sg := &ServiceGraph{
+		DependencyGraph: New(),
+		Deps:            make(map[[2]string]*ServiceDep),
+		Metrics:         make(map[string]*ServiceMetrics),
+	}
+	for _, f := range files {
+		if f.MicroserviceName != "" && !sg.Vertices[f.MicroserviceName] {
+			sg.AddVertex(f.MicroserviceName)
+			sg.Metrics[f.MicroserviceName] = &ServiceMetrics{Name: f.MicroserviceName, Kind: NodeService}
+		}
+	}
+
+	// 1. Resolved cross-service imports.
+	if pg != nil {
+		for e, kind := range pg.EdgeKinds {
+			if kind == EdgeCrossService {
+				sg.addDep(pg.Packages[e[0]].Microservice, pg.Packages[e[1]].Microservice, DepImport)
+			}
+		}
+	}
+
+	// 2. Proto ownership: go_package import paths, proto file paths and
+	// service definitions.
+	goPkgOwner := make(map[string]string)
+	var protoFiles []*parser.ParsedFile
+	for _, f := range files {
+		if f.Proto == nil || f.MicroserviceName == "" {
+			continue
+		}
+		protoFiles = append(protoFiles, f)
+		if goPkg := goPackagePath(f.Proto); goPkg != "" {
+			goPkgOwner[goPkg] = f.MicroserviceName
+		}
+	}
+	for _, f := range files {
+		if f.MicroserviceName == "" {
+			continue
+		}
+		if f.Proto != nil {
+			for _, imp := range f.Proto.Imports {
+				for _, pf := range protoFiles {
+					if strings.HasSuffix(filepath.ToSlash(pf.FilePath), "/"+imp) {
+						sg.addDep(f.MicroserviceName, pf.MicroserviceName, DepProto)
+						break
+					}
+				}
+			}
+			continue
+		}
+		for _, imp := range f.Imports {
+			if owner, ok := goPkgOwner[imp]; ok {
+				sg.addDep(f.MicroserviceName, owner, DepProto)
+			}
+		}
+	}
+
+	// 3. gRPC clients → the services that register a server for them, or
+	// failing that, the services whose .proto defines them.
+	servers, protoServiceOwners := grpcIndex(files)
+	for _, f := range files {
+		for _, r := range f.GRPCClients {
+			for _, target := range grpcTargets(r, servers, protoServiceOwners) {
+				sg.addDep(f.MicroserviceName, target, DepGRPC)
+			}
+		}
+	}
+
+	sg.computeMetrics(files)
+	return sg
+}
+
+// MarkLibraries sets the kind of the named nodes to NodeLibrary.
+func (sg *ServiceGraph) MarkLibraries(names map[string]bool) {
+	for name, m := range sg.Metrics {
+		if names[name] {
+			m.Kind = NodeLibrary
+		}
+	}
+}
+
+// Libraries returns the number of NodeLibrary nodes.
+func (sg *ServiceGraph) Libraries() int {
+	n := 0
+	for _, m := range sg.Metrics {
+		if m.Kind == NodeLibrary {
+			n++
+		}
+	}
+	return n
+}
+
+type protoOwner struct {
+	microservice string
+	goPackage    string
+}
+
+// grpcIndex returns, by proto service name, the microservices that call
+// Register<Service>Server and those whose .proto files define the service.
+func grpcIndex(files []*parser.ParsedFile) (servers map[string][]string, owners map[string][]protoOwner) {
+	servers = make(map[string][]string)
+	owners = make(map[string][]protoOwner)
+	for _, f := range files {
+		if f.MicroserviceName == "" {
+			continue
+		}
+		for _, r := range f.GRPCServers {
+			servers[r.Service] = appendUnique(servers[r.Service], f.MicroserviceName)
+		}
+		if f.Proto != nil {
+			goPkg := goPackagePath(f.Proto)
+			for _, s := range f.Proto.Services {
+				owners[s.Name] = append(owners[s.Name], protoOwner{f.MicroserviceName, goPkg})
+			}
+		}
+	}
+	return servers, owners
+}
+
+// grpcTargets returns the microservices a client of r talks to. Calls that
+// match neither a registered server nor a proto service (e.g.
+// redis.NewClusterClient) yield nothing.
+func grpcTargets(r parser.GRPCRef, servers map[string][]string, owners map[string][]protoOwner) []string {
+	if s := servers[r.Service]; len(s) > 0 {
+		return s
+	}
+	var all, exact []string
+	for _, o := range owners[r.Service] {
+		all = appendUnique(all, o.microservice)
+		if r.ImportPath != "" && o.goPackage == r.ImportPath {
+			exact = appendUnique(exact, o.microservice)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return all
+}
+
+func (sg *ServiceGraph) addDep(from, to, kind string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	key := [2]string{from, to}
+	d := sg.Deps[key]
+	if d == nil {
+		d = &ServiceDep{From: from, To: to}
+		sg.Deps[key] = d
+		sg.AddEdge(from, to)
+	}
+	d.Count++
+	for _, k := range d.Kinds {
+		if k == kind {
+			return
+		}
+	}
+	d.Kinds = append(d.Kinds, kind)
+	sort.Strings(d.Kinds)
+}
+
+func (sg *ServiceGraph) computeMetrics(files []*parser.ParsedFile) {
+	for _, f := range files {
+		m := sg.Metrics[f.MicroserviceName]
+		if m == nil {
+			continue
+		}
+		for _, d := range f.Declarations {
+			switch d.Kind {
+			case parser.DeclInterface, parser.DeclService:
+				m.AbstractTypes++
+			case parser.DeclStruct, parser.DeclType, parser.DeclMessage, parser.DeclEnum:
+				m.ConcreteTypes++
+			}
+		}
+	}
+	for name, m := range sg.Metrics {
+		m.Dependents = sortedSet(sg.reverseAdj[name])
+		m.Dependencies = sortedSet(sg.adjacency[name])
+		m.Ca, m.Ce = len(m.Dependents), len(m.Dependencies)
+		if m.Ca+m.Ce > 0 {
+			m.Instability = float64(m.Ce) / float64(m.Ca+m.Ce)
+		}
+		if total := m.AbstractTypes + m.ConcreteTypes; total > 0 {
+			m.Abstractness = float64(m.AbstractTypes) / float64(total)
+		}
+		m.Distance = math.Abs(m.Abstractness + m.Instability - 1)
+	}
+}
+
+// SortedMetrics returns the metrics of every service, most depended-upon first.
+func (sg *ServiceGraph) SortedMetrics() []*ServiceMetrics {
+	out := make([]*ServiceMetrics, 0, len(sg.Metrics))
+	for _, m := range sg.Metrics {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Ca != out[j].Ca {
+			return out[i].Ca > out[j].Ca
+		}
+		if out[i].Ce != out[j].Ce {
+			return out[i].Ce > out[j].Ce
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// goPackagePath returns the import path of a proto file's go_package option,
+// without the optional ";name" suffix.
+func goPackagePath(pf *parser.ProtoFile) string {
+	path, _, _ := strings.Cut(pf.Options["go_package"], ";")
+	return path
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
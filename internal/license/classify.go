@@ -0,0 +1,106 @@
+package license
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Identifiers Classify returns besides SPDX license IDs.
+const (
+	Unknown = "unknown" // a license file no rule recognizes
+	None    = "none"    // the module has no license file
+)
+
+// copyleft are the headers of the licenses that name others in their text:
+// GPL-3.0 mentions the AGPL and MPL-2.0 names the GPL family as secondary
+// licenses. The header nearest to the start of the file wins.
+var copyleft = []struct {
+	header   string
+	versions []struct{ marker, id string }
+}{
+	{"mozilla public license", []struct{ marker, id string }{{"2 0", "MPL-2.0"}, {"1 1", "MPL-1.1"}}},
+	{"gnu affero general public license", []struct{ marker, id string }{{"version 3", "AGPL-3.0"}}},
+	{"gnu lesser general public license", []struct{ marker, id string }{{"version 3", "LGPL-3.0"}, {"version 2 1", "LGPL-2.1"}}},
+	{"gnu library general public license", []struct{ marker, id string }{{"version 2", "LGPL-2.0"}}},
+	{"gnu general public license", []struct{ marker, id string }{{"version 3", "GPL-3.0"}, {"version 2", "GPL-2.0"}}},
+}
+
+// permissive are phrases from the body of permissive licenses, tried in
+// order.
+var permissive = []struct{ id, phrase string }{
+	{"Apache-2.0", "apache license version 2 0"},
+	{"MIT", "permission is hereby granted free of charge to any person obtaining a copy"},
+	{"ISC", "permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted"},
+	{"BSD-3-Clause", "redistribution and use in source and binary forms"},
+	{"Unlicense", "this is free and unencumbered software released into the public domain"},
+	{"CC0-1.0", "cc0 1 0 universal"},
+}
+
+// Classify returns the SPDX identifier of the license text, or Unknown.
+func Classify(text string) string {
+	t := normalize(text)
+	best, bestAt := "", -1
+	for _, c := range copyleft {
+		at := strings.Index(t, c.header)
+		if at < 0 || (bestAt >= 0 && at >= bestAt) {
+			continue
+		}
+		// The version follows the header within a sentence or two.
+		tail := t[at+len(c.header):]
+		if len(tail) > 160 {
+			tail = tail[:160]
+		}
+		for _, v := range c.versions {
+			if strings.Contains(tail, v.marker) {
+				best, bestAt = v.id, at
+				break
+			}
+		}
+	}
+	if best != "" {
+		return best
+	}
+	for _, p := range permissive {
+		if !strings.Contains(t, p.phrase) {
+			continue
+		}
+		if p.id == "BSD-3-Clause" {
+			// BSD-3 adds the non-endorsement clause to BSD-2.
+			if strings.Contains(t, "may be used to endorse or promote products") ||
+				strings.Contains(t, "may not be used to endorse or promote products") {
+				return "BSD-3-Clause"
+			}
+			return "BSD-2-Clause"
+		}
+		return p.id
+	}
+	return Unknown
+}
+
+// normalize lowercases text and reduces every run of non-alphanumeric
+// characters to one space, so line breaks and punctuation do not matter.
+func normalize(text string) string {
+	var sb strings.Builder
+	space := true
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			space = false
+		} else if !space {
+			sb.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Family groups an identifier for display: "GPL" for GPL-2.0 and GPL-3.0,
+// "BSD" for the BSD variants. Other identifiers are their own family.
+func Family(id string) string {
+	for _, f := range []string{"AGPL", "LGPL", "GPL", "MPL", "BSD"} {
+		if strings.HasPrefix(id, f+"-") {
+			return f
+		}
+	}
+	return id
+}
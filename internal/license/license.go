@@ -0,0 +1,223 @@
+// Package license finds the license of every module the scanned go.mod
+// files require, reading the module sources from a vendor directory or the
+// local module cache. Nothing is downloaded.
+package license
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/goscope/internal/gomod"
+)
+
+// Module is a required module and the licenses found in its root.
+type Module struct {
+	Path       string   `json:"path"`
+	Version    string   `json:"version"`
+	Dir        string   `json:"dir,omitempty"` // where the sources were found; "" when they were not
+	Licenses   []string `json:"licenses"`      // SPDX identifiers, Unknown or None; empty when the sources were not found
+	Denied     bool     `json:"denied,omitempty"`
+	RequiredBy []string `json:"requiredBy"` // paths of the requiring modules, sorted
+}
+
+// Report is the license of every required module.
+type Report struct {
+	ModuleCache string   `json:"moduleCache"`
+	Deny        []string `json:"deny,omitempty"`
+	Modules     []Module `json:"modules"` // sorted by path and version
+	Missing     int      `json:"missing"` // modules found neither in vendor/ nor in the module cache
+}
+
+// ModuleCache returns the module cache directory the go command uses:
+// $GOMODCACHE, else $GOPATH/pkg/mod with the first GOPATH entry, else
+// ~/go/pkg/mod.
+func ModuleCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "go", "pkg", "mod")
+}
+
+// Scan looks up the requirements of mods, after their replace directives,
+// in each module's vendor directory and then in cache, and classifies their
+// license files. Requirements on modules in mods and those replaced by
+// local directories are part of the tree and skipped. Modules whose
+// licenses match an entry of deny are marked Denied. work are the replace
+// directives of the go.work file, applied first for workspace modules.
+func Scan(mods []gomod.Module, cache string, deny []string, work ...gomod.Replace) *Report {
+	local := make(map[string]bool)
+	for _, m := range mods {
+		local[m.Path] = true
+	}
+	type key struct{ path, version string }
+	byKey := make(map[key]*Module)
+	var order []key
+	for _, m := range mods {
+		vendor := ""
+		if _, err := os.Stat(filepath.Join(m.Dir, "vendor", "modules.txt")); err == nil {
+			vendor = filepath.Join(m.Dir, "vendor")
+		}
+		for _, req := range m.Requires {
+			if local[req.Path] {
+				continue
+			}
+			path, version, ok := m.Effective(req, work...)
+			if !ok {
+				continue
+			}
+			k := key{path, version}
+			lm := byKey[k]
+			if lm == nil {
+				lm = &Module{Path: path, Version: version}
+				byKey[k] = lm
+				order = append(order, k)
+			}
+			if lm.Dir == "" && vendor != "" {
+				// Vendored sources are stored under the required path.
+				if dir := filepath.Join(vendor, filepath.FromSlash(req.Path)); isDir(dir) {
+					lm.Dir = dir
+				}
+			}
+			lm.RequiredBy = appendUnique(lm.RequiredBy, m.Path)
+		}
+	}
+
+	r := &Report{ModuleCache: cache, Deny: deny, Modules: make([]Module, 0, len(order))}
+	for _, k := range order {
+		lm := byKey[k]
+		if lm.Dir == "" && cache != "" {
+			if dir := filepath.Join(cache, escape(lm.Path)+"@"+escape(lm.Version)); isDir(dir) {
+				lm.Dir = dir
+			}
+		}
+		if lm.Dir == "" {
+			r.Missing++
+		} else {
+			lm.Licenses = licensesIn(lm.Dir)
+			lm.Denied = Denied(lm.Licenses, deny)
+		}
+		if lm.Licenses == nil {
+			lm.Licenses = []string{}
+		}
+		sort.Strings(lm.RequiredBy)
+		r.Modules = append(r.Modules, *lm)
+	}
+	sort.Slice(r.Modules, func(i, j int) bool {
+		a, b := r.Modules[i], r.Modules[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return gomod.CompareVersions(a.Version, b.Version) < 0
+	})
+	return r
+}
+
+// DeniedModules returns the modules marked Denied.
+func (r *Report) DeniedModules() []Module {
+	var out []Module
+	for _, m := range r.Modules {
+		if m.Denied {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Denied reports whether any of licenses matches an entry of deny. Entries
+// are SPDX identifiers ("GPL-3.0"), families ("GPL" covers GPL-2.0 and
+// GPL-3.0 but not LGPL) or Unknown and None, compared case-insensitively.
+func Denied(licenses, deny []string) bool {
+	for _, l := range licenses {
+		for _, d := range deny {
+			if strings.EqualFold(d, l) || strings.EqualFold(d, Family(l)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// licensesIn classifies the license files in the root of dir: LICENSE,
+// LICENCE, COPYING and UNLICENSE with any extension or suffix. It returns
+// None when there are none.
+func licensesIn(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{None}
+	}
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() || !isLicenseFile(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		out = appendUnique(out, Classify(string(data)))
+	}
+	if len(out) == 0 {
+		return []string{None}
+	}
+	// A recognized license makes an unrecognized extra file (a NOTICE-like
+	// LICENSE.third-party, say) uninteresting.
+	if len(out) > 1 {
+		known := out[:0]
+		for _, l := range out {
+			if l != Unknown {
+				known = append(known, l)
+			}
+		}
+		out = known
+	}
+	sort.Strings(out)
+	return out
+}
+
+func isLicenseFile(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if upper == prefix || strings.HasPrefix(upper, prefix+".") || strings.HasPrefix(upper, prefix+"-") || strings.HasPrefix(upper, prefix+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// escape applies the module cache's case encoding: every upper-case letter
+// becomes "!" followed by its lower-case form.
+func escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
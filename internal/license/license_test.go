@@ -0,0 +1,125 @@
+package license
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goscope/internal/gomod"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct{ text, want string }{
+		{"MIT License\n\nCopyright (c) 2020 Ann\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\nof this software...", "MIT"},
+		{"                                 Apache License\n                           Version 2.0, January 2004\n", "Apache-2.0"},
+		{"Copyright 2009 The Go Authors.\n\nRedistribution and use in source and binary forms, with or without\nmodification, are permitted...\n   * Neither the name of Google LLC nor the names of its\ncontributors may be used to endorse or promote products derived", "BSD-3-Clause"},
+		{"Redistribution and use in source and binary forms, with or without modification, are permitted provided that the following conditions are met:", "BSD-2-Clause"},
+		{"ISC License\n\nPermission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted", "ISC"},
+		{"Mozilla Public License Version 2.0\n...\n\"Secondary License\" means either the GNU General Public License, Version 2.0, the GNU Lesser General Public License, Version 2.1, the GNU Affero General Public License, Version 3.0", "MPL-2.0"},
+		{"GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n...13. Use with the GNU Affero General Public License.", "GPL-3.0"},
+		{"GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991", "GPL-2.0"},
+		{"GNU AFFERO GENERAL PUBLIC LICENSE\nVersion 3, 19 November 2007", "AGPL-3.0"},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n\nThis version of the GNU Lesser General Public License incorporates\nthe terms and conditions of version 3 of the GNU General Public License", "LGPL-3.0"},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", "LGPL-2.1"},
+		{"This program is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License", "GPL-3.0"},
+		{"This is free and unencumbered software released into the public domain.", "Unlicense"},
+		{"All rights reserved. Do not copy.", Unknown},
+	} {
+		if got := Classify(tt.text); got != tt.want {
+			t.Errorf("Classify(%.40q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDenied(t *testing.T) {
+	for _, tt := range []struct {
+		licenses, deny []string
+		want           bool
+	}{
+		{[]string{"GPL-3.0"}, []string{"GPL"}, true},
+		{[]string{"LGPL-2.1"}, []string{"GPL"}, false},
+		{[]string{"AGPL-3.0"}, []string{"agpl-3.0"}, true},
+		{[]string{"MIT", "Unknown"}, []string{"unknown"}, true},
+		{[]string{"BSD-3-Clause"}, []string{"GPL", "AGPL"}, false},
+		{[]string{}, []string{"none"}, false},
+	} {
+		if got := Denied(tt.licenses, tt.deny); got != tt.want {
+			t.Errorf("Denied(%v, %v) = %v, want %v", tt.licenses, tt.deny, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	cache := filepath.Join(root, "modcache")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const mit = "Permission is hereby granted, free of charge, to any person obtaining a copy"
+	write(filepath.Join(cache, "github.com", "!burnt!sushi", "toml@v1.3.2", "COPYING"), mit)
+	write(filepath.Join(cache, "github.com", "acme", "gpl@v1.0.0", "LICENSE.md"), "GNU GENERAL PUBLIC LICENSE Version 3")
+	write(filepath.Join(cache, "github.com", "acme", "bare@v0.1.0", "go.mod"), "module github.com/acme/bare")
+	write(filepath.Join(cache, "github.com", "acme", "fork@v1.1.0", "LICENSE"), "Apache License Version 2.0")
+	write(filepath.Join(root, "orders", "vendor", "modules.txt"), "# golang.org/x/sys v0.20.0\n")
+	write(filepath.Join(root, "orders", "vendor", "golang.org", "x", "sys", "LICENSE"),
+		"Redistribution and use in source and binary forms ... may be used to endorse or promote products")
+
+	mods := []gomod.Module{
+		{Path: "github.com/acme/orders", Dir: filepath.Join(root, "orders"), Requires: []gomod.Require{
+			{Path: "github.com/BurntSushi/toml", Version: "v1.3.2"},
+			{Path: "golang.org/x/sys", Version: "v0.20.0"},
+			{Path: "github.com/acme/gpl", Version: "v1.0.0"},
+			{Path: "github.com/acme/users", Version: "v0.0.0"},
+			{Path: "github.com/acme/upstream", Version: "v1.0.0"},
+		}, Replaces: []gomod.Replace{{Old: "github.com/acme/upstream", New: "github.com/acme/fork", NewVersion: "v1.1.0"}}},
+		{Path: "github.com/acme/users", Dir: filepath.Join(root, "users"), Requires: []gomod.Require{
+			{Path: "github.com/BurntSushi/toml", Version: "v1.3.2"},
+			{Path: "github.com/acme/bare", Version: "v0.1.0"},
+			{Path: "github.com/acme/gone", Version: "v2.0.0"},
+		}},
+	}
+	r := Scan(mods, cache, []string{"GPL", "none"})
+	got := make(map[string]Module)
+	for _, m := range r.Modules {
+		got[m.Path+"@"+m.Version] = m
+	}
+	for key, want := range map[string]struct {
+		license string
+		denied  bool
+	}{
+		"github.com/BurntSushi/toml@v1.3.2": {"MIT", false},
+		"golang.org/x/sys@v0.20.0":          {"BSD-3-Clause", false},
+		"github.com/acme/gpl@v1.0.0":        {"GPL-3.0", true},
+		"github.com/acme/fork@v1.1.0":       {"Apache-2.0", false},
+		"github.com/acme/bare@v0.1.0":       {None, true},
+		"github.com/acme/gone@v2.0.0":       {"", false},
+	} {
+		m, ok := got[key]
+		if !ok {
+			t.Errorf("%s missing from %+v", key, r.Modules)
+			continue
+		}
+		license := ""
+		if len(m.Licenses) > 0 {
+			license = m.Licenses[0]
+		}
+		if license != want.license || m.Denied != want.denied {
+			t.Errorf("%s = %v denied %v, want %s denied %v", key, m.Licenses, m.Denied, want.license, want.denied)
+		}
+	}
+	if len(r.Modules) != 6 || r.Missing != 1 {
+		t.Errorf("%d modules, %d missing, want 6 and 1", len(r.Modules), r.Missing)
+	}
+	if toml := got["github.com/BurntSushi/toml@v1.3.2"]; len(toml.RequiredBy) != 2 {
+		t.Errorf("toml RequiredBy = %v, want both modules", toml.RequiredBy)
+	}
+	if n := len(r.DeniedModules()); n != 2 {
+		t.Errorf("DeniedModules = %d, want 2", n)
+	}
+}
@@ -0,0 +1,480 @@
+// Package openapi bootstraps an OpenAPI 3.1 document for a microservice
+// from the HTTP routes the parser extracted and the Go types their handlers
+// bind and write. The result is a skeleton to review, not a specification:
+// anything the source does not show without type checking is left out.
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// Info is the document's metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to the operations of a path.
+type PathItem map[string]*Operation
+
+// Operation is one method of a path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Handler     string              `json:"x-handler"`              // file:line of the handler, relative to the root
+	Middleware  []string            `json:"x-middleware,omitempty"` // outermost first
+}
+
+// Parameter is a path parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the JSON body an operation binds.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation, keyed by status code or
+// "default".
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas of the Go types the operations reference.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is the subset of JSON Schema the generator emits.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Description          string             `json:"description,omitempty"`
+}
+
+// anyMethods are the operations emitted for routes that match every method.
+var anyMethods = []string{"get", "post", "put", "patch", "delete"}
+
+// operationMethods are the methods a path item can hold operations for.
+// OpenAPI has no CONNECT operation.
+var operationMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Generate returns the document of the routes registered in the files of
+// service. Types are looked up in the service's files first, then in all
+// of files, so models in shared libraries resolve. Handler locations are
+// relative to root. Routes whose method OpenAPI has no operation for, such
+// as CONNECT, are skipped. Handlers that cannot be resolved, such as a
+// method name declared on several types, point at the registration and get
+// a default response. It returns nil when the service registers no other
+// routes.
+func Generate(service string, files []*parser.ParsedFile, root string) *Document {
+	g := &generator{types: newTypeIndex(files, service), schemas: make(map[string]*Schema)}
+	index := parser.NewFuncIndex(files)
+	doc := &Document{OpenAPI: Version, Paths: make(map[string]PathItem)}
+	ids := make(map[string]int)
+	routes := 0
+	for _, f := range files {
+		if f.MicroserviceName != service {
+			continue
+		}
+		for _, r := range f.Routes {
+			methods := []string{strings.ToLower(r.Method)}
+			if r.Method == parser.MethodAny {
+				methods = anyMethods
+			} else if !operationMethods[methods[0]] {
+				continue
+			}
+			routes++
+			path, params := Path(r.Path)
+			var io *parser.HandlerIO
+			handlerFile, line := f, r.Line
+			if r.Handler == parser.InlineHandler {
+				io = r.IO
+			} else if hf, d := index.Lookup(f, r.Handler); d != nil {
+				io, handlerFile, line = d.IO, hf, d.Line
+			}
+			item := doc.Paths[path]
+			if item == nil {
+				item = make(PathItem)
+				doc.Paths[path] = item
+			}
+			for _, m := range methods {
+				if item[m] != nil {
+					continue // registered twice; the first registration wins
+				}
+				op := &Operation{
+					OperationID: operationID(r, m, path, ids),
+					Summary:     r.Handler,
+					Tags:        []string{service},
+					Handler:     fmt.Sprintf("%s:%d", relPath(root, handlerFile.FilePath), line),
+					Middleware:  r.Middleware,
+					Responses:   make(map[string]Response),
+				}
+				for _, p := range params {
+					op.Parameters = append(op.Parameters, Parameter{Name: p, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+				}
+				g.fill(op, io)
+				item[m] = op
+			}
+		}
+	}
+	if routes == 0 {
+		return nil
+	}
+	doc.Info = Info{
+		Title:   service,
+		Version: "0.0.0",
+		Description: fmt.Sprintf("Skeleton generated by goscope from %d route registrations. "+
+			"Request and response schemas are inferred from handler code; review before publishing.", routes),
+	}
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// fill adds the request body and responses of a handler to op.
+func (g *generator) fill(op *Operation, io *parser.HandlerIO) {
+	if io == nil || len(io.Responses) == 0 {
+		op.Responses["default"] = Response{Description: "Not inferred from the handler"}
+	}
+	if io == nil {
+		return
+	}
+	if io.Request != "" {
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schema(io.Request))}
+	}
+	for _, r := range io.Responses {
+		key, desc := "default", "Response"
+		if r.Status != 0 {
+			key, desc = strconv.Itoa(r.Status), http.StatusText(r.Status)
+		}
+		resp, ok := op.Responses[key]
+		if !ok {
+			resp = Response{Description: desc}
+		}
+		if r.Type != "" && resp.Content == nil {
+			resp.Content = jsonContent(g.schema(r.Type))
+		}
+		op.Responses[key] = resp
+	}
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+var (
+	colonParam = regexp.MustCompile(`^[:*]([A-Za-z0-9_]*)\??$`)
+	braceParam = regexp.MustCompile(`\{([A-Za-z0-9_]+)(?:\.\.\.)?(?::[^}]*)?\}`)
+)
+
+// Path converts a route pattern to an OpenAPI path template and returns
+// its parameters in order: gin, echo and fiber ":id" and "*path" segments
+// and chi, gorilla/mux and net/http "{id}", "{id:[0-9]+}" and "{path...}"
+// all become "{id}" or "{path}". An anonymous wildcard is named "path";
+// Go 1.22's "{$}" end anchor is dropped.
+func Path(pattern string) (string, []string) {
+	var params []string
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		if m := colonParam.FindStringSubmatch(seg); m != nil {
+			name := m[1]
+			if name == "" {
+				name = "path"
+			}
+			segs[i] = "{" + name + "}"
+			params = append(params, name)
+			continue
+		}
+		seg = strings.ReplaceAll(seg, "{$}", "")
+		segs[i] = braceParam.ReplaceAllStringFunc(seg, func(p string) string {
+			name := braceParam.FindStringSubmatch(p)[1]
+			params = append(params, name)
+			return "{" + name + "}"
+		})
+	}
+	path := strings.Join(segs, "/")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path, params
+}
+
+// operationID names an operation after its handler, or after the method
+// and path for inline handlers, with a numeric suffix when taken.
+func operationID(r parser.HTTPRoute, method, path string, ids map[string]int) string {
+	id := strings.TrimSuffix(r.Handler, "()")
+	id = id[strings.LastIndex(id, ".")+1:]
+	if r.Handler == parser.InlineHandler {
+		var sb strings.Builder
+		sb.WriteString(method)
+		for _, w := range strings.FieldsFunc(path, func(c rune) bool { return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') }) {
+			sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+		id = sb.String()
+	} else if r.Method == parser.MethodAny {
+		id += strings.ToUpper(method[:1]) + method[1:]
+	}
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		id += strconv.Itoa(n)
+	}
+	return id
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// typeDecl is a Go type declaration and the package it is declared in.
+type typeDecl struct {
+	pkg  string
+	decl *parser.Declaration
+}
+
+// typeIndex finds Go type declarations by name, preferring those of one
+// service.
+type typeIndex struct {
+	service map[string][]typeDecl
+	all     map[string][]typeDecl
+}
+
+func newTypeIndex(files []*parser.ParsedFile, service string) *typeIndex {
+	x := &typeIndex{service: make(map[string][]typeDecl), all: make(map[string][]typeDecl)}
+	for _, f := range files {
+		if f.FileType != "go" {
+			continue
+		}
+		for i := range f.Declarations {
+			d := &f.Declarations[i]
+			switch d.Kind {
+			case parser.DeclStruct, parser.DeclType, parser.DeclInterface:
+			default:
+				continue
+			}
+			td := typeDecl{pkg: f.PackageName, decl: d}
+			x.all[d.Name] = append(x.all[d.Name], td)
+			if f.MicroserviceName == service {
+				x.service[d.Name] = append(x.service[d.Name], td)
+			}
+		}
+	}
+	return x
+}
+
+// lookup returns the declaration of name, qualified by pkg when pkg is
+// not "": one in the service's files of package pkg, then any in the
+// service, then the same in all files.
+func (x *typeIndex) lookup(pkg, name string) *parser.Declaration {
+	for _, candidates := range [][]typeDecl{x.service[name], x.all[name]} {
+		for _, td := range candidates {
+			if pkg == "" || td.pkg == pkg {
+				return td.decl
+			}
+		}
+		if len(candidates) > 0 && pkg != "" {
+			return candidates[0].decl
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	types   *typeIndex
+	schemas map[string]*Schema // components, by Go type name
+}
+
+// schema returns the schema of a Go type written as in the source, e.g.
+// "[]*models.Order".
+func (g *generator) schema(typ string) *Schema {
+	expr, err := goparser.ParseExpr(typ)
+	if err != nil {
+		return &Schema{Description: "Go type " + typ}
+	}
+	return g.exprSchema(expr, "")
+}
+
+// wellKnown are the schemas of library types that marshal to scalars or
+// free-form objects.
+var wellKnown = map[string]Schema{
+	"time.Time":       {Type: "string", Format: "date-time"},
+	"time.Duration":   {Type: "integer", Format: "int64"},
+	"uuid.UUID":       {Type: "string", Format: "uuid"},
+	"json.RawMessage": {},
+	"json.Number":     {Type: "number"},
+	"gin.H":           {Type: "object"},
+	"echo.Map":        {Type: "object"},
+	"fiber.Map":       {Type: "object"},
+	"decimal.Decimal": {Type: "string"},
+	"sql.NullString":  {Type: "string"},
+	"sql.NullInt64":   {Type: "integer", Format: "int64"},
+	"sql.NullBool":    {Type: "boolean"},
+	"sql.NullTime":    {Type: "string", Format: "date-time"},
+}
+
+// basic are the schemas of Go's predeclared types.
+var basic = map[string]Schema{
+	"string": {Type: "string"}, "bool": {Type: "boolean"},
+	"int": {Type: "integer"}, "int8": {Type: "integer"}, "int16": {Type: "integer"},
+	"int32": {Type: "integer", Format: "int32"}, "int64": {Type: "integer", Format: "int64"},
+	"uint": {Type: "integer"}, "uint8": {Type: "integer"}, "uint16": {Type: "integer"},
+	"uint32": {Type: "integer", Format: "int32"}, "uint64": {Type: "integer", Format: "int64"},
+	"byte": {Type: "integer"}, "rune": {Type: "integer", Format: "int32"},
+	"float32": {Type: "number", Format: "float"}, "float64": {Type: "number", Format: "double"},
+	"any": {}, "error": {Type: "string"},
+}
+
+// exprSchema converts a type expression. pkg qualifies unqualified names
+// met inside the declaration of a type from another package.
+func (g *generator) exprSchema(e ast.Expr, pkg string) *Schema {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return g.exprSchema(e.X, pkg)
+	case *ast.ParenExpr:
+		return g.exprSchema(e.X, pkg)
+	case *ast.ArrayType:
+		if id, ok := e.Elt.(*ast.Ident); ok && id.Name == "byte" && e.Len == nil {
+			return &Schema{Type: "string", Format: "byte"} // base64, like encoding/json
+		}
+		return &Schema{Type: "array", Items: g.exprSchema(e.Elt, pkg)}
+	case *ast.MapType:
+		return &Schema{Type: "object", AdditionalProperties: g.exprSchema(e.Value, pkg)}
+	case *ast.InterfaceType:
+		return &Schema{}
+	case *ast.StructType:
+		return &Schema{Type: "object"}
+	case *ast.IndexExpr:
+		return g.exprSchema(e.X, pkg) // generic instantiation: the generic type
+	case *ast.IndexListExpr:
+		return g.exprSchema(e.X, pkg)
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			if s, ok := wellKnown[id.Name+"."+e.Sel.Name]; ok {
+				return &s
+			}
+			return g.named(id.Name, e.Sel.Name)
+		}
+	case *ast.Ident:
+		if s, ok := basic[e.Name]; ok {
+			return &s
+		}
+		return g.named(pkg, e.Name)
+	}
+	return &Schema{}
+}
+
+// named returns a reference to the component of a declared type, adding
+// the component on first use. Non-struct types are inlined.
+func (g *generator) named(pkg, name string) *Schema {
+	d := g.types.lookup(pkg, name)
+	if d == nil {
+		return &Schema{Description: "Go type " + strings.TrimPrefix(pkg+"."+name, ".")}
+	}
+	switch d.Kind {
+	case parser.DeclInterface:
+		return &Schema{}
+	case parser.DeclType:
+		if expr, err := goparser.ParseExpr(d.Underlying); err == nil && d.Underlying != name {
+			return g.exprSchema(expr, pkg)
+		}
+		return &Schema{}
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.schemas[name]; ok {
+		return ref
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.schemas[name] = s // before the fields, for recursive types
+	g.addFields(s, d, pkg)
+	sort.Strings(s.Required)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return ref
+}
+
+// addFields adds the JSON properties of a struct declaration to s, with
+// the fields of embedded structs promoted as encoding/json does. Fields
+// without omitempty are listed as required.
+func (g *generator) addFields(s *Schema, d *parser.Declaration, pkg string) {
+	for _, f := range d.Fields {
+		if f.JSONName == "-" {
+			continue
+		}
+		if f.Embedded && f.JSONName == "" {
+			ref := strings.TrimPrefix(f.Type, "*")
+			epkg, ename := pkg, ref
+			if i := strings.LastIndex(ref, "."); i >= 0 {
+				epkg, ename = ref[:i], ref[i+1:]
+			}
+			if ed := g.types.lookup(epkg, ename); ed != nil && ed.Kind == parser.DeclStruct {
+				g.addFields(s, ed, epkg)
+				continue
+			}
+		}
+		if !f.Embedded && !ast.IsExported(f.Name) {
+			continue
+		}
+		name := f.JSONName
+		if name == "" {
+			name = f.Name
+		}
+		expr, err := goparser.ParseExpr(f.Type)
+		if err != nil {
+			s.Properties[name] = &Schema{Description: "Go type " + f.Type}
+		} else {
+			s.Properties[name] = g.exprSchema(expr, pkg)
+		}
+		if !f.OmitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
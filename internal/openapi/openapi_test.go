@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goscope/internal/parser"
+)
+
+func TestPath(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, want string
+		params        []string
+	}{
+		{"/users/:id", "/users/{id}", []string{"id"}},
+		{"/static/*filepath", "/static/{filepath}", []string{"filepath"}},
+		{"/debug/*", "/debug/{path}", []string{"path"}},
+		{"/items/:id?", "/items/{id}", []string{"id"}},
+		{"/orgs/{org}/repos/{id:[0-9]+}", "/orgs/{org}/repos/{id}", []string{"org", "id"}},
+		{"/files/{name...}", "/files/{name}", []string{"name"}},
+		{"/{$}", "/", nil},
+		{"", "/", nil},
+	} {
+		got, params := Path(tt.pattern)
+		if got != tt.want || strings.Join(params, ",") != strings.Join(tt.params, ",") {
+			t.Errorf("Path(%q) = %q %v, want %q %v", tt.pattern, got, params, tt.want, tt.params)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	models := &parser.ParsedFile{FilePath: "/src/shared/models/order.go", MicroserviceName: "shared", PackageName: "models", FileType: "go",
+		Declarations: []parser.Declaration{
+			{Name: "Base", Kind: parser.DeclStruct, Fields: []parser.StructField{
+				{Name: "ID", Type: "string", JSONName: "id"},
+				{Name: "CreatedAt", Type: "time.Time", JSONName: "createdAt"},
+			}},
+			{Name: "Order", Kind: parser.DeclStruct, Fields: []parser.StructField{
+				{Name: "Base", Type: "Base", Embedded: true},
+				{Name: "Status", Type: "Status", JSONName: "status"},
+				{Name: "Items", Type: "[]*Item", JSONName: "items", OmitEmpty: true},
+				{Name: "Parent", Type: "*Order", JSONName: "parent", OmitEmpty: true},
+				{Name: "Secret", Type: "string", JSONName: "-"},
+				{Name: "internal", Type: "int"},
+			}},
+			{Name: "Item", Kind: parser.DeclStruct, Fields: []parser.StructField{{Name: "SKU", Type: "string"}}},
+			{Name: "Status", Kind: parser.DeclType, Underlying: "string"},
+		}}
+	router := &parser.ParsedFile{FilePath: "/src/orders/api/router.go", MicroserviceName: "orders", PackageName: "api", FileType: "go",
+		Declarations: []parser.Declaration{
+			{Name: "Create", Kind: parser.DeclFunc, Line: 30, IO: &parser.HandlerIO{
+				Request: "CreateRequest",
+				Responses: []parser.HandlerResponse{
+					{Status: 400, Type: "gin.H"},
+					{Status: 201, Type: "models.Order"},
+				},
+			}},
+			{Name: "CreateRequest", Kind: parser.DeclStruct, Fields: []parser.StructField{
+				{Name: "Items", Type: "map[string]int", JSONName: "items"},
+			}},
+		},
+		Routes: []parser.HTTPRoute{
+			{Framework: parser.FrameworkGin, Method: "POST", Path: "/orders", Handler: "h.Create", Middleware: []string{"auth"}, Line: 12},
+			{Framework: parser.FrameworkGin, Method: "GET", Path: "/orders/:id", Handler: parser.InlineHandler, Line: 13,
+				IO: &parser.HandlerIO{Responses: []parser.HandlerResponse{{Status: 200, Type: "[]models.Order"}}}},
+			{Framework: parser.FrameworkGin, Method: parser.MethodAny, Path: "/legacy", Handler: "legacy", Line: 14},
+			{Framework: parser.FrameworkGin, Method: "CONNECT", Path: "/tunnel", Handler: "tunnel", Line: 15},
+			{Framework: parser.FrameworkGin, Method: "HTTP.METHODGET", Path: "/legacy", Handler: "legacy", Line: 16},
+		}}
+	files := []*parser.ParsedFile{models, router}
+
+	if doc := Generate("shared", files, "/src"); doc != nil {
+		t.Errorf("Generate(shared) = %+v, want nil", doc)
+	}
+	doc := Generate("orders", files, "/src")
+	if doc == nil {
+		t.Fatal("Generate(orders) = nil")
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "orders" {
+		t.Errorf("header = %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if _, ok := doc.Paths["/tunnel"]; ok || !strings.Contains(doc.Info.Description, "from 3 route registrations") {
+		t.Errorf("routes without an OpenAPI operation were kept: %v, %q", doc.Paths, doc.Info.Description)
+	}
+
+	create := doc.Paths["/orders"]["post"]
+	if create == nil {
+		t.Fatalf("no POST /orders in %v", doc.Paths)
+	}
+	if create.OperationID != "Create" || create.Handler != "orders/api/router.go:30" || create.Middleware[0] != "auth" {
+		t.Errorf("create = %+v", create)
+	}
+	if ref := create.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/CreateRequest" {
+		t.Errorf("request schema = %q", ref)
+	}
+	if ref := create.Responses["201"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/Order" {
+		t.Errorf("201 schema = %q", ref)
+	}
+	if r := create.Responses["400"]; r.Description != "Bad Request" || r.Content["application/json"].Schema.Type != "object" {
+		t.Errorf("400 = %+v", r)
+	}
+
+	get := doc.Paths["/orders/{id}"]["get"]
+	if get == nil || len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || !get.Parameters[0].Required {
+		t.Fatalf("get = %+v", get)
+	}
+	if get.OperationID != "getOrdersId" || get.Handler != "orders/api/router.go:13" {
+		t.Errorf("get = %+v", get)
+	}
+	if s := get.Responses["200"].Content["application/json"].Schema; s.Type != "array" || s.Items.Ref != "#/components/schemas/Order" {
+		t.Errorf("get 200 schema = %+v", s)
+	}
+
+	legacy := doc.Paths["/legacy"]
+	if len(legacy) != len(anyMethods) || legacy["patch"].OperationID != "legacyPatch" {
+		t.Errorf("ANY route = %+v", legacy)
+	}
+	if _, ok := legacy["get"].Responses["default"]; !ok {
+		t.Errorf("legacy responses = %+v, want default", legacy["get"].Responses)
+	}
+
+	order := doc.Components.Schemas["Order"]
+	data, _ := json.Marshal(order)
+	for _, want := range []string{
+		`"id":{"type":"string"}`,                             // promoted from Base
+		`"createdAt":{"type":"string","format":"date-time"}`, // well-known type
+		`"status":{"type":"string"}`,                         // named non-struct type, inlined
+		`"items":{"type":"array","items":{"$ref":"#/components/schemas/Item"}}`,
+		`"parent":{"$ref":"#/components/schemas/Order"}`, // recursive
+		`"required":["createdAt","id","status"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Order schema %s missing %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "Secret") || strings.Contains(string(data), "internal") {
+		t.Errorf("Order schema %s has skipped fields", data)
+	}
+	if s := doc.Components.Schemas["Item"]; s.Properties["SKU"] == nil {
+		t.Errorf("Item schema = %+v, want the Go field name without a tag", s)
+	}
+	if s := doc.Components.Schemas["CreateRequest"].Properties["items"]; s.Type != "object" || s.AdditionalProperties.Type != "integer" {
+		t.Errorf("map schema = %+v", s)
+	}
+}
+
+func TestGenerate_AmbiguousHandler(t *testing.T) {
+	io := func(typ string) *parser.HandlerIO {
+		return &parser.HandlerIO{Responses: []parser.HandlerResponse{{Status: 201, Type: typ}}}
+	}
+	handlers := &parser.ParsedFile{FilePath: "/src/api/handlers/handlers.go", MicroserviceName: "api", PackageName: "handlers", FileType: "go",
+		Declarations: []parser.Declaration{
+			{Name: "Create", Kind: parser.DeclFunc, Receiver: "*Orders", Line: 10, IO: io("Order")},
+			{Name: "Create", Kind: parser.DeclFunc, Receiver: "*Users", Line: 20, IO: io("User")},
+		}}
+	router := &parser.ParsedFile{FilePath: "/src/api/router.go", MicroserviceName: "api", PackageName: "main", FileType: "go",
+		Routes: []parser.HTTPRoute{
+			{Framework: parser.FrameworkGin, Method: "POST", Path: "/orders", Handler: "orders.Create", Line: 7},
+		}}
+	doc := Generate("api", []*parser.ParsedFile{handlers, router}, "/src")
+	op := doc.Paths["/orders"]["post"]
+	if op == nil {
+		t.Fatalf("no POST /orders in %v", doc.Paths)
+	}
+	if op.Handler != "api/router.go:7" {
+		t.Errorf("handler = %q, want the registration", op.Handler)
+	}
+	if _, ok := op.Responses["default"]; !ok || len(op.Responses) != 1 {
+		t.Errorf("responses = %+v, want only default", op.Responses)
+	}
+}
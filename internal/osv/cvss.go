@@ -0,0 +1,86 @@
+package osv
+
+import (
+	"math"
+	"strings"
+)
+
+// cvss3Score computes the base score of a CVSS v3.0/v3.1 vector such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H".
+// Spec: https://www.first.org/cvss/v3.1/specification-document#7-1-Base-Metrics-Equations
+func cvss3Score(vector string) (float64, bool) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3.") {
+		return 0, false
+	}
+	m := make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, ":"); ok {
+			m[k] = v
+		}
+	}
+	changed := m["S"] == "C"
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	if changed {
+		weights["PR"]["L"], weights["PR"]["H"] = 0.68, 0.5
+	}
+	w := make(map[string]float64)
+	for k, vals := range weights {
+		v, ok := vals[m[k]]
+		if !ok {
+			return 0, false
+		}
+		w[k] = v
+	}
+	if s := m["S"]; s != "U" && s != "C" {
+		return 0, false
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp returns the smallest number with one decimal place that is at
+// least x, avoiding floating-point artifacts as the specification
+// requires.
+func roundUp(x float64) float64 {
+	n := int(math.Round(x * 100000))
+	if n%10000 == 0 {
+		return float64(n) / 100000
+	}
+	return (math.Floor(float64(n)/10000) + 1) / 10
+}
+
+// cvss3Rating maps a base score to its qualitative rating.
+func cvss3Rating(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
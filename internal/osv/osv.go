@@ -0,0 +1,445 @@
+// Package osv matches go.mod requirements against a local snapshot of an
+// OSV vulnerability database (https://ossf.github.io/osv-schema/), such as a
+// mirror of the Go vulnerability database or the Go/all.zip export of
+// osv.dev.
+package osv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/gomod"
+)
+
+// Severities, from most to least severe.
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN" // the entry carries no usable rating
+)
+
+// Entry is an OSV record, reduced to the fields goscope uses.
+type Entry struct {
+	ID        string     `json:"id"`
+	Aliases   []string   `json:"aliases"`
+	Summary   string     `json:"summary"`
+	Details   string     `json:"details"`
+	Withdrawn string     `json:"withdrawn"`
+	Affected  []Affected `json:"affected"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	References []struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"references"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"` // GitHub advisories: LOW, MODERATE, HIGH, CRITICAL
+		URL      string `json:"url"`      // Go vulnerability database
+	} `json:"database_specific"`
+}
+
+// Affected lists the affected versions of one package.
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string  `json:"type"`
+		Events []Event `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+// Event is an entry of an affected range. Exactly one field is set.
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// Database is a loaded snapshot, indexed by Go module path.
+type Database struct {
+	Path    string
+	Entries int // entries with at least one affected Go module
+	byPath  map[string][]*Entry
+}
+
+// Load reads a snapshot: a zip archive or a directory of OSV JSON files, or
+// a single JSON file holding one entry or an array of entries. Files that
+// are not OSV entries, such as the index files of the Go vulnerability
+// database, and withdrawn entries are skipped.
+func Load(path string) (*Database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	db := &Database{Path: path, byPath: make(map[string][]*Entry)}
+	switch {
+	case info.IsDir():
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(p, ".json") {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return db.add(p, data)
+		})
+	case strings.HasSuffix(path, ".zip"):
+		err = db.loadZip(path)
+	default:
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			err = db.add(path, data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *Database) loadZip(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", path, f.Name, err)
+		}
+		if err := db.add(path+":"+f.Name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add indexes the entries in data, one object or an array of them.
+func (db *Database) add(name string, data []byte) error {
+	var entries []*Entry
+	data = bytes.TrimSpace(data)
+	var err error
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		var e Entry
+		err = json.Unmarshal(data, &e)
+		entries = append(entries, &e)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for _, e := range entries {
+		if e == nil || e.ID == "" || e.Withdrawn != "" {
+			continue
+		}
+		indexed := false
+		for _, a := range e.Affected {
+			if a.Package.Ecosystem != "Go" || a.Package.Name == "" {
+				continue
+			}
+			list := db.byPath[a.Package.Name]
+			if len(list) == 0 || list[len(list)-1] != e {
+				db.byPath[a.Package.Name] = append(list, e)
+			}
+			indexed = true
+		}
+		if indexed {
+			db.Entries++
+		}
+	}
+	return nil
+}
+
+// Vulnerability is an entry that affects a dependency at the version the
+// scanned modules build with.
+type Vulnerability struct {
+	ID         string     `json:"id"`
+	Aliases    []string   `json:"aliases,omitempty"` // CVE and GHSA identifiers
+	Summary    string     `json:"summary"`
+	Severity   string     `json:"severity"`        // CRITICAL, HIGH, MEDIUM, LOW or UNKNOWN
+	Score      float64    `json:"score,omitempty"` // CVSS v3 base score when the entry has a vector
+	URL        string     `json:"url,omitempty"`
+	Package    string     `json:"package"`         // module path of the dependency
+	Version    string     `json:"version"`         // version in use
+	Fixed      string     `json:"fixed,omitempty"` // first fixed version after Version, "" if none is known
+	RequiredBy []Requirer `json:"requiredBy"`
+}
+
+// Requirer is a require directive that pulls in a vulnerable version.
+type Requirer struct {
+	Module string `json:"module"` // path of the requiring module
+	GoMod  string `json:"goMod"`  // absolute path of its go.mod
+	Line   int    `json:"line"`
+}
+
+// Report is the result of checking the scanned modules against a database.
+type Report struct {
+	Database        string          `json:"database"`
+	Entries         int             `json:"entries"` // entries with affected Go modules in the database
+	Checked         int             `json:"checked"` // distinct module@version requirements checked
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Check matches the requirements of mods, after their replace directives,
+// against db. Requirements on modules in mods and those replaced by local
+// directories are built from source and skipped. work are the replace
+// directives of the go.work file, applied first for workspace modules.
+// Vulnerabilities are sorted by severity, then package and ID.
+func (db *Database) Check(mods []gomod.Module, work ...gomod.Replace) *Report {
+	local := make(map[string]bool)
+	for _, m := range mods {
+		local[m.Path] = true
+	}
+	type use struct{ path, version string }
+	requirers := make(map[use][]Requirer)
+	var uses []use
+	for _, m := range mods {
+		for _, req := range m.Requires {
+			if local[req.Path] {
+				continue
+			}
+			path, version, ok := m.Effective(req, work...)
+			if !ok {
+				continue
+			}
+			u := use{path, version}
+			if _, seen := requirers[u]; !seen {
+				uses = append(uses, u)
+			}
+			requirers[u] = append(requirers[u], Requirer{Module: m.Path, GoMod: filepath.Join(m.Dir, "go.mod"), Line: req.Line})
+		}
+	}
+
+	r := &Report{Database: db.Path, Entries: db.Entries, Checked: len(uses), Vulnerabilities: []Vulnerability{}}
+	for _, u := range uses {
+		for _, e := range db.byPath[u.path] {
+			affected, fixed := e.affects(u.path, u.version)
+			if !affected {
+				continue
+			}
+			severity, score := e.severity()
+			r.Vulnerabilities = append(r.Vulnerabilities, Vulnerability{
+				ID:         e.ID,
+				Aliases:    e.Aliases,
+				Summary:    e.summary(),
+				Severity:   severity,
+				Score:      score,
+				URL:        e.url(),
+				Package:    u.path,
+				Version:    u.version,
+				Fixed:      fixed,
+				RequiredBy: requirers[u],
+			})
+		}
+	}
+	sort.Slice(r.Vulnerabilities, func(i, j int) bool {
+		a, b := r.Vulnerabilities[i], r.Vulnerabilities[j]
+		if ra, rb := SeverityRank(a.Severity), SeverityRank(b.Severity); ra != rb {
+			return ra < rb
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return gomod.CompareVersions(a.Version, b.Version) < 0
+	})
+	return r
+}
+
+// Count returns the number of vulnerabilities of each severity.
+func (r *Report) Count() map[string]int {
+	n := make(map[string]int)
+	for _, v := range r.Vulnerabilities {
+		n[v.Severity]++
+	}
+	return n
+}
+
+// SeverityRank orders severities from CRITICAL (0) to UNKNOWN (4).
+func SeverityRank(s string) int {
+	switch s {
+	case SeverityCritical:
+		return 0
+	case SeverityHigh:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 3
+	}
+	return 4
+}
+
+// affects reports whether version of the module path is affected by e and
+// returns the first fixed version above it.
+func (e *Entry) affects(path, version string) (bool, string) {
+	for _, a := range e.Affected {
+		if a.Package.Ecosystem != "Go" || a.Package.Name != path {
+			continue
+		}
+		for _, v := range a.Versions {
+			if gomod.CompareVersions(v, version) == 0 {
+				return true, fixedAfter(a, version)
+			}
+		}
+		for _, rng := range a.Ranges {
+			if (rng.Type == "SEMVER" || rng.Type == "ECOSYSTEM") && inRange(rng.Events, version) {
+				return true, fixedAfter(a, version)
+			}
+		}
+	}
+	return false, ""
+}
+
+// inRange evaluates the events of a range for version: introduced starts an
+// affected interval, fixed ends it before and last_affected after the
+// event's version.
+func inRange(events []Event, version string) bool {
+	type point struct {
+		v    string
+		kind byte // 'i', 'f' or 'l'
+	}
+	var pts []point
+	for _, ev := range events {
+		switch {
+		case ev.Introduced != "":
+			pts = append(pts, point{ev.Introduced, 'i'})
+		case ev.Fixed != "":
+			pts = append(pts, point{ev.Fixed, 'f'})
+		case ev.LastAffected != "":
+			pts = append(pts, point{ev.LastAffected, 'l'})
+		}
+	}
+	sort.SliceStable(pts, func(i, j int) bool { return eventCompare(pts[i].v, pts[j].v) < 0 })
+	affected := false
+	for _, p := range pts {
+		c := eventCompare(p.v, version)
+		if c > 0 {
+			break
+		}
+		switch p.kind {
+		case 'i':
+			affected = true
+		case 'f':
+			affected = false
+		case 'l':
+			if c < 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// eventCompare compares versions of range events, where "0" precedes every
+// version.
+func eventCompare(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "0":
+		return -1
+	case b == "0":
+		return 1
+	}
+	return gomod.CompareVersions(a, b)
+}
+
+// fixedAfter returns the lowest fixed version of a above version, with the
+// "v" prefix Go module versions carry.
+func fixedAfter(a Affected, version string) string {
+	best := ""
+	for _, rng := range a.Ranges {
+		for _, ev := range rng.Events {
+			if ev.Fixed == "" || eventCompare(ev.Fixed, version) <= 0 {
+				continue
+			}
+			if best == "" || eventCompare(ev.Fixed, best) < 0 {
+				best = ev.Fixed
+			}
+		}
+	}
+	if best != "" && !strings.HasPrefix(best, "v") {
+		best = "v" + best
+	}
+	return best
+}
+
+// severity rates e from its database-specific rating or its CVSS v3 vector.
+func (e *Entry) severity() (string, float64) {
+	score, scored := 0.0, false
+	for _, s := range e.Severity {
+		if s.Type == "CVSS_V3" {
+			if v, ok := cvss3Score(s.Score); ok {
+				score, scored = v, true
+				break
+			}
+		}
+	}
+	switch strings.ToUpper(e.DatabaseSpecific.Severity) {
+	case "CRITICAL":
+		return SeverityCritical, score
+	case "HIGH":
+		return SeverityHigh, score
+	case "MODERATE", "MEDIUM":
+		return SeverityMedium, score
+	case "LOW":
+		return SeverityLow, score
+	}
+	if !scored {
+		return SeverityUnknown, 0
+	}
+	return cvss3Rating(score), score
+}
+
+func (e *Entry) summary() string {
+	if e.Summary != "" {
+		return e.Summary
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(e.Details), "\n")
+	return first
+}
+
+// url returns the advisory page of e: the database's own link, else the
+// first ADVISORY or WEB reference.
+func (e *Entry) url() string {
+	if e.DatabaseSpecific.URL != "" {
+		return e.DatabaseSpecific.URL
+	}
+	for _, kind := range []string{"ADVISORY", "WEB"} {
+		for _, r := range e.References {
+			if r.Type == kind {
+				return r.URL
+			}
+		}
+	}
+	return ""
+}
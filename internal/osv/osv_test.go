@@ -0,0 +1,204 @@
+package osv
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goscope/internal/gomod"
+)
+
+const grpcEntry = `{
+  "id": "GO-2023-2153",
+  "aliases": ["CVE-2023-44487", "GHSA-qppj-fm5r-hxr3"],
+  "summary": "Denial of service from HTTP/2 Rapid Reset in google.golang.org/grpc",
+  "affected": [{
+    "package": {"ecosystem": "Go", "name": "google.golang.org/grpc"},
+    "ranges": [{"type": "SEMVER", "events": [
+      {"introduced": "0"}, {"fixed": "1.56.3"},
+      {"introduced": "1.57.0"}, {"fixed": "1.57.1"}
+    ]}]
+  }],
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H"}],
+  "database_specific": {"url": "https://pkg.go.dev/vuln/GO-2023-2153"}
+}`
+
+const netEntries = `[{
+  "id": "GHSA-4374-p667-p6c8",
+  "details": "HTTP/2 stream resets can exhaust memory.\nMore text.",
+  "affected": [{
+    "package": {"ecosystem": "Go", "name": "golang.org/x/net"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0.1.0"}, {"last_affected": "0.16.0"}]}]
+  }],
+  "references": [{"type": "WEB", "url": "https://example.com/web"}, {"type": "ADVISORY", "url": "https://example.com/advisory"}],
+  "database_specific": {"severity": "MODERATE"}
+}, {
+  "id": "GO-2020-0001",
+  "withdrawn": "2021-01-01T00:00:00Z",
+  "affected": [{"package": {"ecosystem": "Go", "name": "golang.org/x/net"}, "versions": ["0.10.0"]}]
+}, {
+  "id": "PYSEC-2021-1",
+  "affected": [{"package": {"ecosystem": "PyPI", "name": "golang.org/x/net"}, "versions": ["0.10.0"]}]
+}]`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	// A Go vulnerability database mirror: entries plus index files.
+	mirror := filepath.Join(dir, "vulndb")
+	writeFile(t, filepath.Join(mirror, "ID", "GO-2023-2153.json"), grpcEntry)
+	writeFile(t, filepath.Join(mirror, "index", "db.json"), `{"modified": "2024-01-01T00:00:00Z"}`)
+	writeFile(t, filepath.Join(mirror, "index", "modules.json"), `[{"path": "google.golang.org/grpc", "vulns": [{"id": "GO-2023-2153"}]}]`)
+	writeFile(t, filepath.Join(mirror, "index", "vulns.json"), `[{"id": "GO-2023-2153", "modified": "2024-01-01T00:00:00Z"}]`)
+
+	// An osv.dev export.
+	archive := filepath.Join(dir, "all.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{"GO-2023-2153.json": grpcEntry, "net.json": netEntries} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	single := filepath.Join(dir, "net.json")
+	writeFile(t, single, netEntries)
+
+	for _, tt := range []struct {
+		path    string
+		entries int
+	}{
+		{mirror, 1},
+		{archive, 2},
+		{single, 1}, // withdrawn and non-Go entries are skipped
+	} {
+		db, err := Load(tt.path)
+		if err != nil {
+			t.Errorf("Load(%s): %v", tt.path, err)
+			continue
+		}
+		if db.Entries != tt.entries {
+			t.Errorf("Load(%s): %d entries, want %d", tt.path, db.Entries, tt.entries)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "bad.json"), `{"id": `)
+	if _, err := Load(filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("Load of malformed JSON: want error")
+	}
+	if _, err := Load(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Load of missing path: want error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	db := &Database{Path: "db", byPath: make(map[string][]*Entry)}
+	if err := db.add("grpc.json", []byte(grpcEntry)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.add("net.json", []byte(netEntries)); err != nil {
+		t.Fatal(err)
+	}
+	mods := []gomod.Module{
+		{Path: "github.com/acme/orders", Dir: "/code/orders", Requires: []gomod.Require{
+			{Path: "google.golang.org/grpc", Version: "v1.56.0", Line: 5},
+			{Path: "golang.org/x/net", Version: "v0.16.0", Line: 6},
+			{Path: "github.com/acme/users", Version: "v0.0.0", Line: 7},
+		}},
+		{Path: "github.com/acme/users", Dir: "/code/users", Requires: []gomod.Require{
+			{Path: "google.golang.org/grpc", Version: "v1.56.0", Line: 3},
+			{Path: "golang.org/x/net", Version: "v0.17.0", Line: 4}, // past last_affected
+		}},
+		{Path: "github.com/acme/billing", Dir: "/code/billing", Requires: []gomod.Require{
+			{Path: "google.golang.org/grpc", Version: "v1.57.0", Line: 3},
+		}, Replaces: []gomod.Replace{
+			{Old: "google.golang.org/grpc", New: "google.golang.org/grpc", NewVersion: "v1.57.1"},
+		}},
+	}
+	r := db.Check(mods)
+	if r.Checked != 4 || r.Entries != 2 {
+		t.Errorf("Checked = %d, Entries = %d, want 4 and 2", r.Checked, r.Entries)
+	}
+	if len(r.Vulnerabilities) != 2 {
+		t.Fatalf("Vulnerabilities = %+v, want 2", r.Vulnerabilities)
+	}
+
+	grpc := r.Vulnerabilities[0]
+	if grpc.ID != "GO-2023-2153" || grpc.Severity != SeverityHigh || grpc.Score != 7.5 ||
+		grpc.Fixed != "v1.56.3" || grpc.URL != "https://pkg.go.dev/vuln/GO-2023-2153" {
+		t.Errorf("grpc vulnerability = %+v", grpc)
+	}
+	want := []Requirer{
+		{Module: "github.com/acme/orders", GoMod: filepath.Join("/code/orders", "go.mod"), Line: 5},
+		{Module: "github.com/acme/users", GoMod: filepath.Join("/code/users", "go.mod"), Line: 3},
+	}
+	if len(grpc.RequiredBy) != len(want) || grpc.RequiredBy[0] != want[0] || grpc.RequiredBy[1] != want[1] {
+		t.Errorf("RequiredBy = %+v, want %+v", grpc.RequiredBy, want)
+	}
+
+	net := r.Vulnerabilities[1]
+	if net.ID != "GHSA-4374-p667-p6c8" || net.Severity != SeverityMedium || net.Fixed != "" ||
+		net.Summary != "HTTP/2 stream resets can exhaust memory." || net.URL != "https://example.com/advisory" {
+		t.Errorf("net vulnerability = %+v", net)
+	}
+	if c := r.Count(); c[SeverityHigh] != 1 || c[SeverityMedium] != 1 {
+		t.Errorf("Count = %v", c)
+	}
+}
+
+func TestInRange(t *testing.T) {
+	events := []Event{{Introduced: "0"}, {Fixed: "1.2.0"}, {Introduced: "1.3.0"}, {LastAffected: "1.4.1"}}
+	for v, want := range map[string]bool{
+		"v0.0.1":                             true,
+		"v1.1.9":                             true,
+		"v1.2.0":                             false,
+		"v1.2.5":                             false,
+		"v1.3.0":                             true,
+		"v1.4.1":                             true,
+		"v1.4.2":                             false,
+		"v1.2.0-rc.1":                        true,
+		"v0.0.0-20230101000000-abcdefabcdef": true,
+	} {
+		if got := inRange(events, v); got != want {
+			t.Errorf("inRange(%s) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestCVSS3Score(t *testing.T) {
+	for vector, want := range map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H": 9.9,
+		"CVSS:3.0/AV:N/AC:H/PR:N/UI:R/S:U/C:L/I:N/A:N": 3.1,
+		"CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:N/I:N/A:N": 0,
+	} {
+		if got, ok := cvss3Score(vector); !ok || got != want {
+			t.Errorf("cvss3Score(%s) = %v, %v, want %v", vector, got, ok, want)
+		}
+	}
+	for _, vector := range []string{"", "CVSS:2.0/AV:N", "CVSS:3.1/AV:N/AC:L"} {
+		if _, ok := cvss3Score(vector); ok {
+			t.Errorf("cvss3Score(%q) ok, want invalid", vector)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// grpcDialers are the grpc-go functions taking a target address, with the
+// index of that argument.
+var grpcDialers = map[string]int{"Dial": 0, "DialContext": 1, "NewClient": 0}
+
+// ParseAddress splits a service address as written in code or
+// configuration: a URL such as "http://user-service:8080/v1", a gRPC target
+// such as "dns:///user-service:9090", a host:port pair such as
+// "user-service:9090" or a Kubernetes service DNS name such as
+// "users.prod.svc.cluster.local". It returns the scheme, "" when there is
+// none, and the service name: the host, or the first label of a Kubernetes
+// name. ok is false for anything else and for hosts that cannot name a
+// service of the codebase: localhost, IP addresses and other domain names.
+func ParseAddress(s string) (scheme, host string, ok bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > 512 || strings.ContainsAny(s, " \t\n\"'%{}$<>") {
+		return "", "", false
+	}
+	if rest, found := strings.CutPrefix(s, "dns:///"); found {
+		scheme, s = "dns", rest
+	}
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" {
+			return "", "", false
+		}
+		scheme, s = strings.ToLower(u.Scheme), u.Hostname()
+	} else if h, port, err := net.SplitHostPort(s); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", "", false
+		}
+		s = h
+	} else if !strings.Contains(s, ".svc") {
+		return "", "", false
+	}
+	if host = serviceHost(s); host == "" {
+		return "", "", false
+	}
+	return scheme, host, true
+}
+
+// serviceHost returns the service named by a host: the host itself when it
+// is a single DNS label, the first label of a Kubernetes service name
+// (<service>.<namespace>.svc[.cluster.local]), and "" otherwise.
+func serviceHost(h string) string {
+	labels := strings.Split(h, ".")
+	if len(labels) > 1 {
+		if len(labels) < 3 || labels[2] != "svc" || (len(labels) > 3 && strings.Join(labels[3:], ".") != "cluster.local") {
+			return ""
+		}
+	}
+	name := labels[0]
+	if name == "" || name == "localhost" || name[0] < 'a' || name[0] > 'z' {
+		return ""
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return ""
+		}
+	}
+	return name
+}
+
+// addresses returns the string literals of file that name another
+// service. Literals passed to grpc.Dial, grpc.DialContext or
+// grpc.NewClient, directly or through a constant or variable, get the
+// scheme "grpc" when they have none.
+func addresses(fset *token.FileSet, file *ast.File) []Address {
+	var out []Address
+	index := make(map[*ast.BasicLit]int) // literal -> position in out
+	named := make(map[string]*ast.BasicLit)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportSpec, *ast.Field:
+			return false
+		case *ast.BasicLit:
+			if n.Kind != token.STRING {
+				break
+			}
+			v, err := strconv.Unquote(n.Value)
+			if err != nil {
+				break
+			}
+			if scheme, host, ok := ParseAddress(v); ok {
+				index[n] = len(out)
+				out = append(out, Address{Value: v, Scheme: scheme, Host: host, Line: fset.Position(n.Pos()).Line})
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					if lit, ok := n.Values[i].(*ast.BasicLit); ok {
+						named[name.Name] = lit
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					id, ok := lhs.(*ast.Ident)
+					lit, isLit := n.Rhs[i].(*ast.BasicLit)
+					if ok && isLit {
+						named[id.Name] = lit
+					}
+				}
+			}
+		}
+		return true
+	})
+	if len(out) == 0 {
+		return nil
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		arg, ok := grpcDialers[sel.Sel.Name]
+		if !ok || arg >= len(call.Args) {
+			return true
+		}
+		var lit *ast.BasicLit
+		switch a := call.Args[arg].(type) {
+		case *ast.BasicLit:
+			lit = a
+		case *ast.Ident:
+			lit = named[a.Name]
+		}
+		if i, ok := index[lit]; ok && out[i].Scheme == "" {
+			out[i].Scheme = "grpc"
+		}
+		return true
+	})
+	return out
+}
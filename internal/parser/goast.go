@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"bytes"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// bigFunctionLines is the minimum length for a function to be listed in BigFunctions.
+const bigFunctionLines = 25
+
+// parseGoAST parses Go source with go/parser and fills a ParsedFile.
+// It returns an error if the source has syntax errors so the caller can
+// fall back to the regex parser.
+func parseGoAST(filePath, microservice string, src []byte) (*ParsedFile, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, filePath, src, goparser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	pf := &ParsedFile{
+		FilePath:         filePath,
+		ModuleName:       file.Name.Name,
+		PackageName:      file.Name.Name,
+		MicroserviceName: microservice,
+		LineCount:        countLines(src),
+		FileType:         "go",
+	}
+
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		pf.Imports = append(pf.Imports, path)
+		// Blank and dot imports bind no name; keyed by name they would
+		// overwrite one another.
+		if imp.Name != nil && imp.Name.Name != "_" && imp.Name.Name != "." {
+			if pf.ImportAliases == nil {
+				pf.ImportAliases = make(map[string]string)
+			}
+			pf.ImportAliases[imp.Name.Name] = path
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				kind := DeclType
+				switch ts.Type.(type) {
+				case *ast.StructType:
+					kind = DeclStruct
+				case *ast.InterfaceType:
+					kind = DeclInterface
+				}
+				decl := Declaration{
+					Name:     ts.Name.Name,
+					Kind:     kind,
+					Exported: ts.Name.IsExported(),
+					Line:     fset.Position(ts.Pos()).Line,
+					EndLine:  fset.Position(ts.End()).Line,
+				}
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					decl.Fields = structFields(t)
+				case *ast.InterfaceType:
+				default:
+					decl.Underlying = types.ExprString(t)
+				}
+				pf.Declarations = append(pf.Declarations, decl)
+
+				// The first documented struct/interface describes the file.
+				if pf.Description == "" && kind != DeclType {
+					doc := ts.Doc
+					if doc == nil && !d.Lparen.IsValid() {
+						doc = d.Doc
+					}
+					if doc != nil {
+						pf.Description = strings.Join(strings.Fields(doc.Text()), " ")
+					}
+				}
+			}
+
+		case *ast.FuncDecl:
+			start := fset.Position(d.Pos()).Line
+			end := fset.Position(d.End()).Line
+			decl := Declaration{
+				Name:     d.Name.Name,
+				Kind:     DeclFunc,
+				Exported: d.Name.IsExported(),
+				Line:     start,
+				EndLine:  end,
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				decl.Receiver = types.ExprString(d.Recv.List[0].Type)
+			}
+			if d.Body != nil {
+				decl.IO = handlerIO(d.Type, d.Body)
+			}
+			pf.Declarations = append(pf.Declarations, decl)
+
+			if d.Body == nil {
+				continue
+			}
+			length := end - start + 1
+			fi := FunctionInfo{Name: d.Name.Name, LineCount: length, FilePath: filePath}
+			if pf.LongestFunction == nil || length > pf.LongestFunction.LineCount {
+				best := fi
+				pf.LongestFunction = &best
+			}
+			if length >= bigFunctionLines {
+				pf.BigFunctions = append(pf.BigFunctions, fi)
+			}
+		}
+	}
+
+	pf.GRPCClients, pf.GRPCServers = grpcRefs(fset, file, pf.Imports, pf.ImportAliases)
+	pf.Routes = httpRoutes(fset, file, pf.Imports, pf.ImportAliases)
+	pf.Addresses = addresses(fset, file)
+
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			todo, fixme := countMarkers(c.Text)
+			pf.TodoCount += todo
+			pf.FixmeCount += fixme
+		}
+	}
+
+	return pf, nil
+}
+
+// grpcRefs finds calls to generated gRPC constructors and registrations:
+// New<Service>Client(cc) and Register<Service>Server(s, impl).
+func grpcRefs(fset *token.FileSet, file *ast.File, imports []string, aliases map[string]string) (clients, servers []GRPCRef) {
+	vars := make(map[string]string) // local variable types, for the implementation passed to Register
+	ast.Inspect(file, func(n ast.Node) bool {
+		if as, ok := n.(*ast.AssignStmt); ok && len(as.Lhs) == len(as.Rhs) {
+			for i, lhs := range as.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok {
+					if t := exprType(as.Rhs[i], vars); t != "" {
+						vars[id.Name] = t
+					}
+				}
+			}
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var name, pkg string
+		switch fn := call.Fun.(type) {
+		case *ast.Ident:
+			name = fn.Name
+		case *ast.SelectorExpr:
+			id, ok := fn.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			name, pkg = fn.Sel.Name, id.Name
+		default:
+			return true
+		}
+		ref := GRPCRef{Line: fset.Position(call.Pos()).Line}
+		if pkg != "" {
+			ref.ImportPath = importForName(pkg, imports, aliases)
+		}
+		switch {
+		case len(call.Args) == 1 && strings.HasPrefix(name, "New") && strings.HasSuffix(name, "Client") && len(name) > len("NewClient"):
+			ref.Service = name[len("New") : len(name)-len("Client")]
+			clients = append(clients, ref)
+		case len(call.Args) == 2 && strings.HasPrefix(name, "Register") && strings.HasSuffix(name, "Server") && len(name) > len("RegisterServer"):
+			ref.Service = name[len("Register") : len(name)-len("Server")]
+			ref.Impl = implType(call.Args[1], vars)
+			servers = append(servers, ref)
+		}
+		return true
+	})
+	return clients, servers
+}
+
+// implType returns the type of the implementation passed to
+// Register<Service>Server: &server{}, new(server), a variable holding one,
+// or, for constructor calls such as grpcapi.NewOrderServer(db), the
+// constructed type by the New<Type> naming convention. It returns "" when
+// none of these apply.
+func implType(e ast.Expr, vars map[string]string) string {
+	if t := strings.TrimPrefix(exprType(e, vars), "*"); t != "" {
+		return t
+	}
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		if strings.HasPrefix(fn.Name, "New") && len(fn.Name) > len("New") {
+			return fn.Name[len("New"):]
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := fn.X.(*ast.Ident); ok && strings.HasPrefix(fn.Sel.Name, "New") && len(fn.Sel.Name) > len("New") {
+			return pkg.Name + "." + fn.Sel.Name[len("New"):]
+		}
+	}
+	return ""
+}
+
+// importForName returns the import path a package identifier most likely
+// refers to: an explicit alias, an import whose last element matches, or a
+// versioned import such as ".../users/v1" used as users or usersv1.
+func importForName(name string, imports []string, aliases map[string]string) string {
+	if path, ok := aliases[name]; ok {
+		return path
+	}
+	for _, imp := range imports {
+		elems := strings.Split(imp, "/")
+		last := elems[len(elems)-1]
+		if last == name || strings.ReplaceAll(last, "-", "_") == name {
+			return imp
+		}
+		if len(elems) > 1 && isMajorVersion(last) {
+			prev := elems[len(elems)-2]
+			if prev == name || prev+last == name {
+				return imp
+			}
+		}
+	}
+	return ""
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// countMarkers counts TODO/FIXME markers at the start of comment lines.
+func countMarkers(comment string) (todo, fixme int) {
+	var lines []string
+	if strings.HasPrefix(comment, "//") {
+		lines = []string{strings.TrimPrefix(comment, "//")}
+	} else {
+		body := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+		lines = strings.Split(body, "\n")
+	}
+	for _, l := range lines {
+		l = strings.TrimLeft(strings.TrimSpace(l), "* ")
+		switch {
+		case strings.HasPrefix(l, "TODO"):
+			todo++
+		case strings.HasPrefix(l, "FIXME"):
+			fixme++
+		}
+	}
+	return todo, fixme
+}
+
+// countLines counts lines the same way bufio.Scanner does: a trailing
+// newline does not start an extra line.
+func countLines(src []byte) int {
+	if len(src) == 0 {
+		return 0
+	}
+	n := bytes.Count(src, []byte{'\n'})
+	if src[len(src)-1] != '\n' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// statusCodes maps the names of the net/http status constants, which
+// fiber and echo mirror, to their codes: "StatusNotFound" -> 404.
+var statusCodes = func() map[string]int {
+	m := map[string]int{"StatusTeapot": http.StatusTeapot}
+	for code := 100; code < 600; code++ {
+		text := http.StatusText(code)
+		if text == "" {
+			continue
+		}
+		var name strings.Builder
+		for _, w := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '-' }) {
+			name.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+		m["Status"+name.String()] = code
+	}
+	return m
+}()
+
+// bindMethods are the methods that decode the request body into their
+// argument: gin's ShouldBindJSON(&req), echo's Bind(&req), fiber's
+// BodyParser(&req) and encoding/json's Decoder.Decode(&req).
+var bindMethods = map[string]bool{
+	"ShouldBindJSON": true, "BindJSON": true, "ShouldBind": true, "Bind": true,
+	"BodyParser": true, "Decode": true,
+}
+
+// jsonMethods are the methods that write their last argument as a JSON
+// response, with the status as first argument when there are two: gin's
+// c.JSON(200, v), echo's c.JSONPretty(200, v, "  "), fiber's c.JSON(v).
+var jsonMethods = map[string]bool{
+	"JSON": true, "IndentedJSON": true, "PureJSON": true, "SecureJSON": true,
+	"AbortWithStatusJSON": true, "JSONPretty": true,
+}
+
+// statusMethods write a response without a body: echo's NoContent(204),
+// fiber's SendStatus(204), gin's AbortWithStatus(401).
+var statusMethods = map[string]bool{
+	"NoContent": true, "SendStatus": true, "AbortWithStatus": true,
+}
+
+// handlerIO finds the JSON request and response bodies of a handler:
+// what the body binds or decodes and what it writes, with the Go types of
+// local variables and parameters tracked by name. Closures returned by the
+// function are included, so handler factories such as
+// func (h *H) Create() http.HandlerFunc are covered. It returns nil when
+// the function does neither.
+func handlerIO(ft *ast.FuncType, body *ast.BlockStmt) *HandlerIO {
+	io := &HandlerIO{}
+	vars := make(map[string]string)
+	bindFields(vars, ft)
+	pending := 0 // status set by w.WriteHeader or render.Status for the next body
+	chained := make(map[*ast.CallExpr]bool)
+
+	var walk func(n ast.Node) bool
+	walk = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				if lit, ok := r.(*ast.FuncLit); ok {
+					bindFields(vars, lit.Type)
+					ast.Inspect(lit.Body, walk)
+				}
+			}
+		case *ast.DeclStmt:
+			gd, ok := n.Decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				break
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					switch {
+					case vs.Type != nil:
+						vars[name.Name] = types.ExprString(vs.Type)
+					case i < len(vs.Values):
+						vars[name.Name] = exprType(vs.Values[i], vars)
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE && len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						vars[id.Name] = exprType(n.Rhs[i], vars)
+					}
+				}
+			}
+		case *ast.CallExpr:
+			handlerCall(n, io, vars, &pending, chained)
+		}
+		return true
+	}
+	ast.Inspect(body, walk)
+	if pending != 0 {
+		io.Responses = append(io.Responses, HandlerResponse{Status: pending})
+	}
+	if io.Request == "" && len(io.Responses) == 0 {
+		return nil
+	}
+	return io
+}
+
+// handlerCall records the request or response body a call reads or writes.
+func handlerCall(call *ast.CallExpr, io *HandlerIO, vars map[string]string, pending *int, chained map[*ast.CallExpr]bool) {
+	var name, pkg string
+	var recv ast.Expr
+	switch fn := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		name, recv = fn.Sel.Name, fn.X
+		if id, ok := fn.X.(*ast.Ident); ok {
+			pkg = id.Name
+		}
+	default:
+		return
+	}
+	args := call.Args
+	switch {
+	case bindMethods[name] && len(args) == 1:
+		if name == "Decode" && !isCallTo(recv, "NewDecoder") && exprType(recv, vars) != "*json.Decoder" {
+			return
+		}
+		if io.Request == "" {
+			io.Request = strings.TrimPrefix(exprType(args[0], vars), "*")
+		}
+	case name == "Unmarshal" && pkg == "json" && len(args) == 2:
+		if io.Request == "" {
+			io.Request = strings.TrimPrefix(exprType(args[1], vars), "*")
+		}
+	case name == "Encode" && len(args) == 1 && (isCallTo(recv, "NewEncoder") || exprType(recv, vars) == "*json.Encoder"),
+		name == "JSON" && pkg == "render" && len(args) == 3:
+		// json.NewEncoder(w).Encode(v) and chi's render.JSON(w, r, v).
+		status := *pending
+		if status == 0 {
+			status = http.StatusOK
+		}
+		*pending = 0
+		io.Responses = append(io.Responses, HandlerResponse{Status: status, Type: bodyType(args[len(args)-1], vars)})
+	case name == "WriteHeader" && len(args) == 1,
+		name == "Status" && pkg == "render" && len(args) == 2:
+		if *pending != 0 {
+			io.Responses = append(io.Responses, HandlerResponse{Status: *pending})
+		}
+		*pending = statusCode(args[len(args)-1])
+	case name == "Error" && pkg == "http" && len(args) == 3:
+		io.Responses = append(io.Responses, HandlerResponse{Status: statusCode(args[2])})
+	case jsonMethods[name] && len(args) >= 1:
+		status := http.StatusOK
+		body := args[len(args)-1]
+		switch {
+		case name == "JSONPretty" && len(args) == 3:
+			status, body = statusCode(args[0]), args[1]
+		case len(args) >= 2:
+			status = statusCode(args[0])
+		default:
+			// fiber: c.Status(201).JSON(v)
+			if inner, ok := recv.(*ast.CallExpr); ok && isCallTo(inner, "Status") && len(inner.Args) == 1 {
+				status = statusCode(inner.Args[0])
+				chained[inner] = true
+			}
+		}
+		io.Responses = append(io.Responses, HandlerResponse{Status: status, Type: bodyType(body, vars)})
+	case statusMethods[name] && len(args) == 1:
+		io.Responses = append(io.Responses, HandlerResponse{Status: statusCode(args[0])})
+	case name == "Status" && len(args) == 1 && pkg != "render" && !chained[call]:
+		// gin's c.Status(204); fiber's c.Status(201).JSON(v) is handled
+		// at the outer call, which the walk visits first.
+		io.Responses = append(io.Responses, HandlerResponse{Status: statusCode(args[0])})
+	}
+}
+
+// bindFields records the types of a function's parameters.
+func bindFields(vars map[string]string, ft *ast.FuncType) {
+	if ft == nil || ft.Params == nil {
+		return
+	}
+	for _, f := range ft.Params.List {
+		for _, name := range f.Names {
+			vars[name.Name] = types.ExprString(f.Type)
+		}
+	}
+}
+
+// isCallTo reports whether e is a call of a function or method named name,
+// e.g. json.NewDecoder(r.Body).
+func isCallTo(e ast.Expr, name string) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name == name
+	case *ast.SelectorExpr:
+		return fn.Sel.Name == name
+	}
+	return false
+}
+
+// exprType returns the Go type of a value as written in the source, or ""
+// when it cannot be told without type checking.
+func exprType(e ast.Expr, vars map[string]string) string {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return exprType(e.X, vars)
+	case *ast.Ident:
+		return vars[e.Name]
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return types.ExprString(e.Type)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			if t := exprType(e.X, vars); t != "" {
+				return "*" + t
+			}
+		}
+	case *ast.StarExpr:
+		return strings.TrimPrefix(exprType(e.X, vars), "*")
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			return "string"
+		case token.INT:
+			return "int"
+		case token.FLOAT:
+			return "float64"
+		}
+	case *ast.CallExpr:
+		switch {
+		case isCallTo(e, "NewDecoder"):
+			return "*json.Decoder"
+		case isCallTo(e, "NewEncoder"):
+			return "*json.Encoder"
+		}
+		if id, ok := e.Fun.(*ast.Ident); ok && len(e.Args) > 0 {
+			switch id.Name {
+			case "new":
+				return "*" + types.ExprString(e.Args[0])
+			case "make":
+				return types.ExprString(e.Args[0])
+			}
+		}
+	}
+	return ""
+}
+
+// bodyType is the type of a response body without pointer indirection.
+func bodyType(e ast.Expr, vars map[string]string) string {
+	return strings.TrimPrefix(exprType(e, vars), "*")
+}
+
+// statusCode returns the value of an integer literal or a Status*
+// constant such as http.StatusCreated, or 0.
+func statusCode(e ast.Expr) int {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if n, err := strconv.Atoi(e.Value); err == nil {
+			return n
+		}
+	case *ast.SelectorExpr:
+		return statusCodes[e.Sel.Name]
+	case *ast.Ident:
+		return statusCodes[e.Name]
+	}
+	return 0
+}
+
+// structFields returns the fields of a struct type with their json tags.
+func structFields(st *ast.StructType) []StructField {
+	var out []StructField
+	for _, f := range st.Fields.List {
+		typ := types.ExprString(f.Type)
+		jsonName, omitEmpty := "", false
+		if f.Tag != nil {
+			if tag, err := strconv.Unquote(f.Tag.Value); err == nil {
+				opts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+				jsonName = opts[0]
+				for _, o := range opts[1:] {
+					omitEmpty = omitEmpty || o == "omitempty" || o == "omitzero"
+				}
+			}
+		}
+		if len(f.Names) == 0 {
+			name := strings.TrimPrefix(typ, "*")
+			name = name[strings.LastIndex(name, ".")+1:]
+			out = append(out, StructField{Name: name, Type: typ, Embedded: true, JSONName: jsonName, OmitEmpty: omitEmpty})
+			continue
+		}
+		for _, n := range f.Names {
+			out = append(out, StructField{Name: n.Name, Type: typ, JSONName: jsonName, OmitEmpty: omitEmpty})
+		}
+	}
+	return out
+}
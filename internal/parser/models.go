@@ -28,8 +28,38 @@ const (
 
 // Declaration represents a named declaration in source code.
 type Declaration struct {
-	Name string   `json:"name"`
-	Kind DeclKind `json:"kind"`
+	Name       string        `json:"name"`
+	Kind       DeclKind      `json:"kind"`
+	Receiver   string        `json:"receiver,omitempty"` // method receiver type, e.g. "*Set[T]"
+	Exported   bool          `json:"exported"`
+	Line       int           `json:"line,omitempty"`       // 1-based start line
+	EndLine    int           `json:"endLine,omitempty"`    // 1-based end line (0 if unknown)
+	Fields     []StructField `json:"fields,omitempty"`     // Go structs
+	Underlying string        `json:"underlying,omitempty"` // Go named non-struct types, e.g. "string"
+	IO         *HandlerIO    `json:"io,omitempty"`         // Go funcs that decode or encode JSON bodies
+}
+
+// StructField is a field of a Go struct.
+type StructField struct {
+	Name      string `json:"name"` // type name for embedded fields
+	Type      string `json:"type"` // as written, e.g. "[]*Item"
+	Embedded  bool   `json:"embedded,omitempty"`
+	JSONName  string `json:"jsonName,omitempty"` // name from the json tag; "-" when the field is skipped
+	OmitEmpty bool   `json:"omitEmpty,omitempty"`
+}
+
+// HandlerIO is what an HTTP handler decodes from the request body and
+// writes in its responses, as far as the source shows it.
+type HandlerIO struct {
+	Request   string            `json:"request,omitempty"` // Go type bound or decoded from the body
+	Responses []HandlerResponse `json:"responses,omitempty"`
+}
+
+// HandlerResponse is a response written by a handler, e.g.
+// c.JSON(http.StatusCreated, order).
+type HandlerResponse struct {
+	Status int    `json:"status,omitempty"` // 0 when not a constant
+	Type   string `json:"type,omitempty"`   // Go type of the JSON body; "" for responses without one or of unknown type
 }
 
 // FunctionInfo holds info about a function's size.
@@ -44,6 +74,7 @@ type ParsedFile struct {
 	FilePath        string       `json:"filePath"`
 	ModuleName      string       `json:"moduleName"` // microservice name
 	Imports         []string     `json:"imports"`
+	ImportAliases   map[string]string `json:"importAliases,omitempty"` // explicit import name -> path, except _ and .
 	GitMeta         GitMetadata  `json:"gitMetadata"`
 	Description     string       `json:"description"`
 	LineCount       int          `json:"lineCount"`
@@ -55,6 +86,48 @@ type ParsedFile struct {
 	LongestFunction *FunctionInfo `json:"longestFunction,omitempty"`
 	BigFunctions    []FunctionInfo `json:"bigFunctions,omitempty"` // functions >= 25 lines
 	FileType        string       `json:"fileType"` // "go" or "proto"
+	Proto           *ProtoFile   `json:"proto,omitempty"` // structured model for .proto files
+	GRPCClients     []GRPCRef    `json:"grpcClients,omitempty"` // New<Service>Client calls
+	GRPCServers     []GRPCRef    `json:"grpcServers,omitempty"` // Register<Service>Server calls
+	Routes          []HTTPRoute  `json:"routes,omitempty"` // HTTP route registrations
+	Addresses       []Address    `json:"addresses,omitempty"` // string literals naming other services
+	ContentHash     string       `json:"contentHash,omitempty"` // sha256 of the contents, set by the caller when caching
+	Generated       bool         `json:"generated,omitempty"` // has a "// Code generated ... DO NOT EDIT." header
+}
+
+// GRPCRef is a call into generated gRPC code, e.g. userspb.NewUserServiceClient(conn).
+type GRPCRef struct {
+	Service    string `json:"service"`              // proto service name, e.g. "UserService"
+	ImportPath string `json:"importPath,omitempty"` // generated package, "" when called unqualified
+	Impl       string `json:"impl,omitempty"`       // servers: Go type of the implementation, e.g. "server" or "grpcapi.Server"
+	Line       int    `json:"line"`
+}
+
+// Address is a string literal naming another service, e.g.
+// "http://user-service:8080" or "orders.prod.svc.cluster.local:9090".
+type Address struct {
+	Value  string `json:"value"`
+	Scheme string `json:"scheme,omitempty"` // "grpc" for targets of grpc.Dial and grpc.NewClient without one
+	Host   string `json:"host"`             // service name, see ParseAddress
+	Line   int    `json:"line"`
+}
+
+// HTTPRoute is an HTTP endpoint registered on a router, e.g.
+// r.GET("/users/:id", auth, h.GetUser).
+type HTTPRoute struct {
+	Framework  string     `json:"framework"`
+	Method     string     `json:"method"`               // upper case, or ANY
+	Path       string     `json:"path"`                 // pattern including the prefixes of enclosing groups
+	Handler    string     `json:"handler"`              // handler expression, e.g. "h.GetUser"; InlineHandler for function literals
+	Middleware []string   `json:"middleware,omitempty"` // outermost first: router, group, then route middleware
+	Line       int        `json:"line"`                 // line of the registration
+	IO         *HandlerIO `json:"io,omitempty"`         // inline handlers only; see FuncIndex for named ones
+}
+
+// ImportPath returns the import path a package identifier used in the file
+// most likely refers to, or "".
+func (p *ParsedFile) ImportPath(name string) string {
+	return importForName(name, p.Imports, p.ImportAliases)
 }
 
 // FileName returns just the file name from the path.
@@ -77,3 +150,115 @@ func (p *ParsedFile) FileNameWithoutExt() string {
 	}
 	return name
 }
+
+// ProtoFile is the structured model of a parsed .proto file.
+type ProtoFile struct {
+	Syntax   string            `json:"syntax"` // "proto2", "proto3" or an edition
+	Package  string            `json:"package"`
+	Imports  []string          `json:"imports"`
+	Options  map[string]string `json:"options,omitempty"` // file options, e.g. go_package
+	Services []ProtoService    `json:"services,omitempty"`
+	Messages []ProtoMessage    `json:"messages,omitempty"`
+	Enums    []ProtoEnum       `json:"enums,omitempty"`
+}
+
+// ProtoService is a gRPC service definition.
+type ProtoService struct {
+	Name       string            `json:"name"`
+	RPCs       []ProtoRPC        `json:"rpcs"`
+	Options    map[string]string `json:"options,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Line       int               `json:"line"`
+}
+
+// ProtoRPC is a single rpc inside a service.
+type ProtoRPC struct {
+	Name            string            `json:"name"`
+	InputType       string            `json:"inputType"`
+	OutputType      string            `json:"outputType"`
+	ClientStreaming bool              `json:"clientStreaming,omitempty"`
+	ServerStreaming bool              `json:"serverStreaming,omitempty"`
+	Options         map[string]string `json:"options,omitempty"`
+	Deprecated      bool              `json:"deprecated,omitempty"`
+	Line            int               `json:"line"`
+}
+
+// StreamingMode returns "unary", "client-stream", "server-stream" or "bidi".
+func (r ProtoRPC) StreamingMode() string {
+	switch {
+	case r.ClientStreaming && r.ServerStreaming:
+		return "bidi"
+	case r.ClientStreaming:
+		return "client-stream"
+	case r.ServerStreaming:
+		return "server-stream"
+	default:
+		return "unary"
+	}
+}
+
+// ProtoMessage is a message definition, possibly with nested types.
+type ProtoMessage struct {
+	Name       string            `json:"name"`
+	FullName   string            `json:"fullName"` // dotted name relative to the package, e.g. "Outer.Inner"
+	Fields     []ProtoField      `json:"fields,omitempty"`
+	Messages   []ProtoMessage    `json:"messages,omitempty"`
+	Enums      []ProtoEnum       `json:"enums,omitempty"`
+	Reserved   ProtoReserved     `json:"reserved"`
+	Options    map[string]string `json:"options,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Line       int               `json:"line"`
+}
+
+// ProtoField is a message field. Map fields have Type "map<K, V>".
+type ProtoField struct {
+	Name       string            `json:"name"`
+	Number     int               `json:"number"`
+	Type       string            `json:"type"`
+	Label      string            `json:"label,omitempty"` // "repeated", "optional", "required" or ""
+	Oneof      string            `json:"oneof,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Line       int               `json:"line"`
+}
+
+// ProtoEnum is an enum definition.
+type ProtoEnum struct {
+	Name       string            `json:"name"`
+	FullName   string            `json:"fullName"`
+	Values     []ProtoEnumValue  `json:"values"`
+	Reserved   ProtoReserved     `json:"reserved"`
+	Options    map[string]string `json:"options,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Line       int               `json:"line"`
+}
+
+// ProtoEnumValue is a single enum constant.
+type ProtoEnumValue struct {
+	Name       string `json:"name"`
+	Number     int    `json:"number"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Line       int    `json:"line"`
+}
+
+// ProtoReserved lists reserved field numbers and names.
+type ProtoReserved struct {
+	Ranges []ProtoRange `json:"ranges,omitempty"`
+	Names  []string     `json:"names,omitempty"`
+}
+
+// ProtoRange is an inclusive number range; End == Start for a single number.
+type ProtoRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Contains reports whether n is inside any reserved range.
+func (r ProtoReserved) Contains(n int) bool {
+	for _, rg := range r.Ranges {
+		if n >= rg.Start && n <= rg.End {
+			return true
+		}
+	}
+	return false
+}
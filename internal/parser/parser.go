@@ -2,6 +2,9 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
+	"go/token"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -15,16 +18,50 @@ var (
 	goTypeDecl     = regexp.MustCompile(`^type\s+(\w+)\s+(struct|interface)\b`)
 	goFuncDecl     = regexp.MustCompile(`^func\s+(?:\(\s*\w+\s+\*?\w+\s*\)\s+)?(\w+)\s*\(`)
 	goDocComment   = regexp.MustCompile(`^//\s?(.*)`)
+	goGenerated    = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
 )
 
 // ParseGoFile parses a .go file and extracts imports, declarations, etc.
+// It uses the go/ast parser and falls back to the line-based regex parser
+// for files that do not parse (e.g. templates or work-in-progress code).
 func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
-	f, err := os.Open(filePath)
+	src, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return ParseGoSource(filePath, microservice, src)
+}
+
+// ParseGoSource parses in-memory Go source read from filePath, like
+// ParseGoFile.
+func ParseGoSource(filePath, microservice string, src []byte) (*ParsedFile, error) {
+	pf, err := parseGoAST(filePath, microservice, src)
+	if err != nil {
+		pf, err = parseGoRegex(filePath, microservice, bytes.NewReader(src))
+	}
+	if pf != nil {
+		pf.Generated = IsGenerated(src)
+	}
+	return pf, err
+}
+
+// IsGenerated reports whether Go source carries the standard
+// "// Code generated ... DO NOT EDIT." comment before its package clause.
+func IsGenerated(src []byte) bool {
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if goGenerated.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
 
+// parseGoRegex is the line-based fallback parser used when go/parser fails.
+func parseGoRegex(filePath, microservice string, r io.Reader) (*ParsedFile, error) {
 	var (
 		imports      []string
 		declarations []Declaration
@@ -47,7 +84,7 @@ func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
 		inFunc       bool
 	)
 
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 
@@ -88,7 +125,7 @@ func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
 			if m[2] == "interface" {
 				kind = DeclInterface
 			}
-			declarations = append(declarations, Declaration{Name: m[1], Kind: kind})
+			declarations = append(declarations, Declaration{Name: m[1], Kind: kind, Exported: token.IsExported(m[1]), Line: lineCount})
 			if description == "" && len(docLines) > 0 {
 				description = strings.Join(docLines, " ")
 			}
@@ -97,7 +134,7 @@ func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
 
 		// Function declarations
 		if m := goFuncDecl.FindStringSubmatch(trimmed); len(m) > 1 {
-			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclFunc})
+			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclFunc, Exported: token.IsExported(m[1]), Line: lineCount})
 
 			// Start tracking longest function
 			if !inFunc {
@@ -120,7 +157,7 @@ func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
 						FilePath:  filePath,
 					}
 				}
-				if length >= 25 {
+				if length >= bigFunctionLines {
 					bigFuncs = append(bigFuncs, FunctionInfo{
 						Name:      curFuncName,
 						LineCount: length,
@@ -167,75 +204,38 @@ func ParseGoFile(filePath, microservice string) (*ParsedFile, error) {
 
 // ParseProtoFile parses a .proto file.
 func ParseProtoFile(filePath, microservice string) (*ParsedFile, error) {
-	f, err := os.Open(filePath)
+	src, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	var (
-		imports      []string
-		declarations []Declaration
-		lineCount    int
-		todoCount    int
-		fixmeCount   int
-		pkgName      string
-		description  string
-	)
-
-	reImport := regexp.MustCompile(`^\s*import\s+"([^"]+)"`)
-	rePackage := regexp.MustCompile(`^\s*package\s+(\S+)\s*;`)
-	reMessage := regexp.MustCompile(`^\s*message\s+(\w+)`)
-	reService := regexp.MustCompile(`^\s*service\s+(\w+)`)
-	reRPC := regexp.MustCompile(`^\s*rpc\s+(\w+)`)
-	reEnum := regexp.MustCompile(`^\s*enum\s+(\w+)`)
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineCount++
-		trimmed := strings.TrimSpace(line)
+	return ParseProtoSource(filePath, microservice, src), nil
+}
 
-		if m := rePackage.FindStringSubmatch(trimmed); len(m) > 1 {
-			pkgName = m[1]
-		}
-		if m := reImport.FindStringSubmatch(trimmed); len(m) > 1 {
-			imports = append(imports, m[1])
-		}
-		if m := reMessage.FindStringSubmatch(trimmed); len(m) > 1 {
-			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclMessage})
-		}
-		if m := reService.FindStringSubmatch(trimmed); len(m) > 1 {
-			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclService})
-		}
-		if m := reRPC.FindStringSubmatch(trimmed); len(m) > 1 {
-			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclRPC})
-		}
-		if m := reEnum.FindStringSubmatch(trimmed); len(m) > 1 {
-			declarations = append(declarations, Declaration{Name: m[1], Kind: DeclEnum})
-		}
+// ParseProtoSource parses in-memory .proto source, e.g. a file read from a
+// git revision. Syntax errors are tolerated: whatever could be parsed is kept.
+func ParseProtoSource(filePath, microservice string, src []byte) *ParsedFile {
+	proto, comments, _ := parseProto(src)
 
-		if strings.Contains(trimmed, "// TODO") {
-			todoCount++
-		}
-		if strings.Contains(trimmed, "// FIXME") {
-			fixmeCount++
-		}
+	var todoCount, fixmeCount int
+	for _, c := range comments {
+		todo, fixme := countMarkers(c.text)
+		todoCount += todo
+		fixmeCount += fixme
 	}
 
 	return &ParsedFile{
 		FilePath:         filePath,
-		ModuleName:       pkgName,
-		Imports:          imports,
-		Description:      description,
-		LineCount:        lineCount,
-		Declarations:     declarations,
-		PackageName:      pkgName,
+		ModuleName:       proto.Package,
+		Imports:          proto.Imports,
+		LineCount:        countLines(src),
+		Declarations:     protoDeclarations(proto),
+		PackageName:      proto.Package,
 		MicroserviceName: microservice,
 		TodoCount:        todoCount,
 		FixmeCount:       fixmeCount,
 		FileType:         "proto",
-	}, nil
+		Proto:            proto,
+	}
 }
 
 // ParseFile dispatches to the right parser based on extension.
@@ -250,3 +250,15 @@ func ParseFile(filePath, microservice string) (*ParsedFile, error) {
 		return nil, nil
 	}
 }
+
+// ParseSource is ParseFile for source that was already read from filePath.
+func ParseSource(filePath, microservice string, src []byte) (*ParsedFile, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".go":
+		return ParseGoSource(filePath, microservice, src)
+	case ".proto":
+		return ParseProtoSource(filePath, microservice, src), nil
+	default:
+		return nil, nil
+	}
+}
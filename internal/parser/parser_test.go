@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -198,4 +200,636 @@ func TestParseFile_DispatchesByExtension(t *testing.T) {
 	if pf.FileType != "proto" {
 		t.Errorf("ParseFile(.proto) FileType = %q, want proto", pf.FileType)
 	}
+
+	// ParseSource does not read the file again.
+	missing := filepath.Join(t.TempDir(), "gone.go")
+	pf, err = ParseSource(missing, "ms", []byte("package x\ntype Foo struct{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.FileType != "go" || len(pf.Declarations) != 1 || pf.FilePath != missing {
+		t.Errorf("ParseSource(.go) = %+v", pf)
+	}
+	if pf, _ := ParseSource("x.txt", "ms", []byte("text")); pf != nil {
+		t.Errorf("ParseSource(.txt) = %+v, want nil", pf)
+	}
+}
+
+func TestParseGoFile_GenericsAndGroupedTypes(t *testing.T) {
+	src := `package set
+
+import (
+	"fmt"
+	str "strings"
+	_ "embed"
+)
+
+type (
+	// Set is a generic set.
+	Set[T comparable] struct {
+		m map[T]struct{}
+	}
+	Lister interface {
+		List() []string
+	}
+	ID string
+)
+
+func (s *Set[T]) Add(v T) {
+	s.m[v] = struct{}{}
+}
+
+func helper() string {
+	return fmt.Sprint("}}}", str.ToUpper("{"))
+}
+`
+	path := tmpFile(t, "set.go", src)
+	pf, err := ParseGoFile(path, "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pf.Imports) != 3 {
+		t.Errorf("Imports = %v, want 3 entries", pf.Imports)
+	}
+	if pf.ImportAliases["str"] != "strings" {
+		t.Errorf("ImportAliases[str] = %q, want strings", pf.ImportAliases["str"])
+	}
+
+	byName := map[string]Declaration{}
+	for _, d := range pf.Declarations {
+		byName[d.Name] = d
+	}
+	if d := byName["Set"]; d.Kind != DeclStruct || !d.Exported || d.Line != 11 {
+		t.Errorf("Set = %+v, want exported struct at line 11", d)
+	}
+	if d := byName["Lister"]; d.Kind != DeclInterface {
+		t.Errorf("Lister kind = %q, want interface", d.Kind)
+	}
+	if d := byName["ID"]; d.Kind != DeclType {
+		t.Errorf("ID kind = %q, want type", d.Kind)
+	}
+	if d := byName["Add"]; d.Receiver != "*Set[T]" || d.Line != 20 || d.EndLine != 22 {
+		t.Errorf("Add = %+v, want receiver *Set[T] at lines 20-22", d)
+	}
+	if d := byName["helper"]; d.Exported {
+		t.Error("helper should not be exported")
+	}
+	if pf.Description != "Set is a generic set." {
+		t.Errorf("Description = %q", pf.Description)
+	}
+	// Braces inside string literals must not break function length.
+	if pf.LongestFunction == nil || pf.LongestFunction.LineCount != 3 {
+		t.Errorf("LongestFunction = %+v, want 3 lines", pf.LongestFunction)
+	}
+}
+
+func TestParseGoFile_BlankAndDotImports(t *testing.T) {
+	src := `package main
+
+import (
+	_ "embed"
+	_ "net/http/pprof"
+	. "math"
+	str "strings"
+)
+`
+	pf, err := ParseGoFile(tmpFile(t, "main.go", src), "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pf.Imports) != 4 {
+		t.Errorf("Imports = %v, want 4 entries", pf.Imports)
+	}
+	if len(pf.ImportAliases) != 1 || pf.ImportAliases["str"] != "strings" {
+		t.Errorf("ImportAliases = %v, want only str -> strings", pf.ImportAliases)
+	}
+}
+
+func TestParseGoFile_FallbackOnSyntaxError(t *testing.T) {
+	src := "package broken\n\ntype Foo struct {\n\tA int\n}\n\nfunc Bar() {\n\tif {\n}\n"
+	path := tmpFile(t, "broken.go", src)
+	pf, err := ParseGoFile(path, "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.PackageName != "broken" {
+		t.Errorf("PackageName = %q, want broken", pf.PackageName)
+	}
+	found := false
+	for _, d := range pf.Declarations {
+		if d.Name == "Foo" && d.Kind == DeclStruct {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("regex fallback missed struct Foo: %+v", pf.Declarations)
+	}
+}
+
+func TestParseGoFile_GRPCRefs(t *testing.T) {
+	src := `package main
+
+import (
+	"google.golang.org/grpc"
+	ordersv1 "github.com/acme/gen/orders/v1"
+	"github.com/acme/gen/users/v1"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	conn, _ := grpc.Dial("users:50051")
+	users := usersv1.NewUserServiceClient(conn)
+	orders := ordersv1.NewOrderServiceClient(conn)
+	rdb := redis.NewClient(&redis.Options{})
+	s := grpc.NewServer()
+	ordersv1.RegisterOrderServiceServer(s, &server{})
+	_, _, _ = users, orders, rdb
+}
+`
+	path := tmpFile(t, "main.go", src)
+	pf, err := ParseGoFile(path, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GRPCRef{
+		{Service: "UserService", ImportPath: "github.com/acme/gen/users/v1", Line: 12},
+		{Service: "OrderService", ImportPath: "github.com/acme/gen/orders/v1", Line: 13},
+	}
+	if len(pf.GRPCClients) != len(want) {
+		t.Fatalf("GRPCClients = %+v, want %+v", pf.GRPCClients, want)
+	}
+	for i := range want {
+		if pf.GRPCClients[i] != want[i] {
+			t.Errorf("GRPCClients[%d] = %+v, want %+v", i, pf.GRPCClients[i], want[i])
+		}
+	}
+	if len(pf.GRPCServers) != 1 || pf.GRPCServers[0].Service != "OrderService" || pf.GRPCServers[0].Impl != "server" {
+		t.Errorf("GRPCServers = %+v", pf.GRPCServers)
+	}
+}
+
+func TestParseGoFile_Generated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n// source: user.proto\n\npackage userpb\n", true},
+		{"// Copyright 2024 Acme\n\n// Code generated by mockgen. DO NOT EDIT.\r\npackage mocks\n", true},
+		{"package x\n\n// Code generated by hand. DO NOT EDIT.\n", false},
+		{"// Code generated by a tool; edit freely.\npackage x\n", false},
+		{"// Code generated by a tool. DO NOT EDIT.\npackage x\nfunc (\n", true}, // regex fallback
+	}
+	for _, tt := range tests {
+		pf, err := ParseGoFile(tmpFile(t, "gen.go", tt.src), "svc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pf.Generated != tt.want {
+			t.Errorf("Generated = %v, want %v for %q", pf.Generated, tt.want, tt.src)
+		}
+	}
+}
+
+func TestParseGoFile_Routes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // "METHOD path handler [middleware...]"
+	}{
+		{"gin", `package api
+
+import "github.com/gin-gonic/gin"
+
+func Routes(h *Handler) *gin.Engine {
+	r := gin.Default()
+	r.Use(gin.Recovery())
+	r.GET("/health", func(c *gin.Context) {})
+	v1 := r.Group("/api/v1", auth)
+	{
+		users := v1.Group("/users")
+		users.GET("/:id", h.GetUser)
+		users.POST("", rateLimit(10), h.CreateUser)
+		users.Match([]string{"PUT", "PATCH"}, "/:id", h.UpdateUser)
+	}
+	return r
+}
+`, []string{
+			"GET /health func literal [gin.Recovery()]",
+			"GET /api/v1/users/:id h.GetUser [gin.Recovery() auth]",
+			"POST /api/v1/users h.CreateUser [gin.Recovery() auth rateLimit()]",
+			"PUT /api/v1/users/:id h.UpdateUser [gin.Recovery() auth]",
+			"PATCH /api/v1/users/:id h.UpdateUser [gin.Recovery() auth]",
+		}},
+		{"echo", `package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func register(e *echo.Echo, h *Handler) {
+	e.Use(middleware.Logger())
+	g := e.Group("/orders", jwt)
+	g.GET("/:id", h.Get, audit)
+	g.Add("DELETE", "/:id", h.Delete)
+}
+`, []string{
+			"GET /orders/:id h.Get [middleware.Logger() jwt audit]",
+			"DELETE /orders/:id h.Delete [middleware.Logger() jwt]",
+		}},
+		{"fiber", `package api
+
+import "github.com/gofiber/fiber/v2"
+
+const prefix = "/api"
+
+func main() {
+	app := fiber.New()
+	api := app.Group(prefix, logger)
+	api.Get("/items", listItems)
+	api.Route("/admin", func(r fiber.Router) {
+		r.Delete("/items/:id", deleteItem)
+	})
+	app.Listen(":3000")
+}
+`, []string{
+			"GET /api/items listItems [logger]",
+			"DELETE /api/admin/items/:id deleteItem [logger]",
+		}},
+		{"chi", `package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func (s *Server) routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Route("/accounts", func(r chi.Router) {
+		r.With(paginate).Get("/", s.listAccounts)
+		r.Group(func(r chi.Router) {
+			r.Use(s.auth)
+			r.Post("/{id}/close", s.closeAccount)
+		})
+	})
+	admin := chi.NewRouter()
+	admin.Get("/stats", s.stats)
+	r.Mount("/admin", admin)
+	r.Mount("/debug", middleware.Profiler())
+	r.Method("PUT", "/config", http.HandlerFunc(s.putConfig))
+	return r
+}
+`, []string{
+			"GET /accounts/ s.listAccounts [middleware.RequestID paginate]",
+			"POST /accounts/{id}/close s.closeAccount [middleware.RequestID s.auth]",
+			"GET /admin/stats s.stats [middleware.RequestID]",
+			"ANY /debug/* middleware.Profiler() [middleware.RequestID]",
+			"PUT /config s.putConfig [middleware.RequestID]",
+		}},
+		{"gorilla", `package api
+
+import "github.com/gorilla/mux"
+
+func NewRouter(h *Handler) *mux.Router {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(cors)
+	api.HandleFunc("/products/{id}", h.Product).Methods("GET", "HEAD")
+	api.Handle("/upload", h.Upload())
+	return r
+}
+`, []string{
+			"GET /api/products/{id} h.Product [cors]",
+			"HEAD /api/products/{id} h.Product [cors]",
+			"ANY /api/upload h.Upload() [cors]",
+		}},
+		{"net/http", `package main
+
+import (
+	"net/http"
+	"time"
+)
+
+type server struct{ mux *http.ServeMux }
+
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", getItem)
+	mux.Handle("POST /items", logging(auth(http.HandlerFunc(createItem))))
+	http.HandleFunc("/healthz", healthz)
+	resp, _ := http.Get("http://example.com/")
+	_ = time.Now()
+	_ = resp
+}
+
+func (s *server) routes() {
+	s.mux.HandleFunc("/metrics", s.metrics)
+}
+`, []string{
+			"GET /items/{id} getItem []",
+			"POST /items createItem [logging auth]",
+			"ANY /healthz healthz []",
+			"ANY /metrics s.metrics []",
+		}},
+		{"method constants", `package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func routes(r chi.Router, method string) {
+	r.Method(http.MethodGet, "/users/{id}", getUser)
+	r.Method(method, "/dynamic", dynamic)
+	r.Method(http.StatusText(200), "/status", status)
+}
+`, []string{
+			"GET /users/{id} getUser []",
+		}},
+		{"gorilla method constants", `package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func routes(m *mux.Router) {
+	m.HandleFunc("/orders", createOrder).Methods(http.MethodPost, http.MethodPut)
+}
+`, []string{
+			"POST /orders createOrder []",
+			"PUT /orders createOrder []",
+		}},
+		{"not routers", `package api
+
+import "github.com/gofiber/fiber/v2"
+
+type server struct{ sessions *Store }
+
+func auth(c *fiber.Ctx) error {
+	if c.Get("Authorization", "none") == "none" {
+		return fiber.ErrUnauthorized
+	}
+	return c.Next()
+}
+
+func (s *server) logout(client *Client) {
+	s.sessions.Delete("session", 1)
+	var m *Store
+	m.Delete("/session", 1)
+	client.Get("/upstream", nil)
+}
+
+func (s *server) routes() {
+	s.app.Get("/ping", ping)
+}
+`, []string{
+			"GET /ping ping []",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := ParseGoFile(tmpFile(t, "routes.go", tt.src), "svc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range pf.Routes {
+				if r.Framework != pf.Routes[0].Framework || r.Line == 0 {
+					t.Errorf("route %+v: mixed framework or no line", r)
+				}
+				got = append(got, fmt.Sprintf("%s %s %s %v", r.Method, r.Path, r.Handler, r.Middleware))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("routes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseGoFile_HandlerIO(t *testing.T) {
+	src := `package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Order struct {
+	Base
+	ID    string   ` + "`json:\"id\"`" + `
+	Tags  []string ` + "`json:\"tags,omitempty\"`" + `
+	Debug bool     ` + "`json:\"-\"`" + `
+	A, B  int
+}
+
+type Status string
+
+func (h *H) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(201, &Order{})
+}
+
+func (h *H) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var f Filter
+		_ = json.NewDecoder(r.Body).Decode(&f)
+		orders := make([]Order, 0)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(orders)
+	}
+}
+
+func get(c *fiber.Ctx) error {
+	if c.Params("id") == "" {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.Status(fiber.StatusOK).JSON(Order{})
+}
+
+func helper() int { return 1 }
+`
+	pf, err := ParseGoFile(tmpFile(t, "h.go", src), "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := make(map[string]Declaration)
+	for _, d := range pf.Declarations {
+		decls[d.Name] = d
+	}
+	for name, want := range map[string]string{
+		"Create": "CreateRequest -> 400:gin.H 201:Order",
+		"List":   "Filter -> 202:[]Order",
+		"get":    " -> 404: 200:Order",
+	} {
+		io := decls[name].IO
+		if io == nil {
+			t.Errorf("%s: no IO", name)
+			continue
+		}
+		got := io.Request + " ->"
+		for _, r := range io.Responses {
+			got += fmt.Sprintf(" %d:%s", r.Status, r.Type)
+		}
+		if got != want {
+			t.Errorf("%s IO = %q, want %q", name, got, want)
+		}
+	}
+	if decls["helper"].IO != nil {
+		t.Errorf("helper IO = %+v, want nil", decls["helper"].IO)
+	}
+
+	wantFields := []StructField{
+		{Name: "Base", Type: "Base", Embedded: true},
+		{Name: "ID", Type: "string", JSONName: "id"},
+		{Name: "Tags", Type: "[]string", JSONName: "tags", OmitEmpty: true},
+		{Name: "Debug", Type: "bool", JSONName: "-"},
+		{Name: "A", Type: "int"},
+		{Name: "B", Type: "int"},
+	}
+	fields := decls["Order"].Fields
+	if len(fields) != len(wantFields) {
+		t.Fatalf("Order fields = %+v", fields)
+	}
+	for i := range wantFields {
+		if fields[i] != wantFields[i] {
+			t.Errorf("field %d = %+v, want %+v", i, fields[i], wantFields[i])
+		}
+	}
+	if u := decls["Status"].Underlying; u != "string" {
+		t.Errorf("Status underlying = %q, want string", u)
+	}
+}
+
+func TestFuncIndex(t *testing.T) {
+	file := func(path, pkg string, decls ...Declaration) *ParsedFile {
+		return &ParsedFile{FilePath: path, PackageName: pkg, MicroserviceName: "api", Declarations: decls}
+	}
+	router := file("/api/router.go", "main", Declaration{Name: "health", Kind: DeclFunc, Line: 30})
+	files := []*ParsedFile{
+		router,
+		file("/api/orders/orders.go", "orders", Declaration{Name: "Create", Kind: DeclFunc, Line: 10}),
+		file("/api/users/users.go", "users", Declaration{Name: "Create", Kind: DeclFunc, Line: 20},
+			Declaration{Name: "List", Kind: DeclFunc, Line: 40}),
+		file("/api/handlers/h.go", "handlers",
+			Declaration{Name: "Get", Kind: DeclFunc, Receiver: "*Orders", Line: 5},
+			Declaration{Name: "Get", Kind: DeclFunc, Receiver: "*Users", Line: 15},
+			Declaration{Name: "Delete", Kind: DeclFunc, Receiver: "*Users", Line: 25}),
+		file("/api/main.go", "main", Declaration{Name: "ready", Kind: DeclFunc, Line: 3}),
+		{FilePath: "/billing/h.go", PackageName: "billing", MicroserviceName: "billing",
+			Declarations: []Declaration{{Name: "Delete", Kind: DeclFunc, Line: 9}}},
+	}
+	x := NewFuncIndex(files)
+	tests := []struct {
+		handler string
+		want    string // "file:line", "" when not resolved
+	}{
+		{"health", "/api/router.go:30"},
+		{"ready", "/api/main.go:3"}, // same package, other file
+		{"orders.Create", "/api/orders/orders.go:10"},
+		{"users.Create", "/api/users/users.go:20"},
+		{"users.List()", "/api/users/users.go:40"},
+		{"h.Delete", "/api/handlers/h.go:25"},
+		{"h.Create", ""}, // declared in orders and users
+		{"h.Get", ""},    // methods of two receivers
+		{"Create", ""},   // not in router's package
+		{"h.Missing", ""},
+		{InlineHandler, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if hf, d := x.Lookup(router, tt.handler); d != nil {
+			got = fmt.Sprintf("%s:%d", hf.FilePath, d.Line)
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.handler, got, tt.want)
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, tt := range []struct {
+		in, scheme, host string
+		ok               bool
+	}{
+		{"http://user-service:8080/v1", "http", "user-service", true},
+		{"HTTPS://billing", "https", "billing", true},
+		{"dns:///orders:9090", "dns", "orders", true},
+		{"user-service:9090", "", "user-service", true},
+		{"users.prod.svc.cluster.local:50051", "", "users", true},
+		{"grpc://users.prod.svc", "grpc", "users", true},
+		{"payments.default.svc.cluster.local", "", "payments", true},
+		{"http://localhost:8080", "", "", false},
+		{"127.0.0.1:5432", "", "", false},
+		{"https://api.github.com/repos", "", "", false},
+		{"users.example.com:443", "", "", false},
+		{":8080", "", "", false},
+		{"12:30", "", "", false},
+		{"user-service:latest", "", "", false},
+		{"http://%s:8080", "", "", false},
+		{"hello world", "", "", false},
+	} {
+		scheme, host, ok := ParseAddress(tt.in)
+		if scheme != tt.scheme || host != tt.host || ok != tt.ok {
+			t.Errorf("ParseAddress(%q) = %q, %q, %v; want %q, %q, %v", tt.in, scheme, host, ok, tt.scheme, tt.host, tt.ok)
+		}
+	}
+}
+
+func TestParseGoFile_Addresses(t *testing.T) {
+	src := `package main
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+)
+
+const usersAddr = "users:50051"
+
+type Config struct {
+	URL string ` + "`json:\"url\" default:\"http://ignored:80\"`" + `
+}
+
+func main() {
+	conn, _ := grpc.NewClient(usersAddr)
+	_, _ = grpc.DialContext(ctx, "dns:///orders:9090")
+	http.Get("http://billing-service:8080/invoices")
+	target := "payments.prod.svc.cluster.local:443"
+	_, _ = conn, target
+	_ = "http://localhost:8080"
+}
+`
+	path := tmpFile(t, "main.go", src)
+	pf, err := ParseGoFile(path, "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Address{
+		{Value: "users:50051", Scheme: "grpc", Host: "users", Line: 9},
+		{Value: "dns:///orders:9090", Scheme: "dns", Host: "orders", Line: 17},
+		{Value: "http://billing-service:8080/invoices", Scheme: "http", Host: "billing-service", Line: 18},
+		{Value: "payments.prod.svc.cluster.local:443", Host: "payments", Line: 19},
+	}
+	if len(pf.Addresses) != len(want) {
+		t.Fatalf("Addresses = %+v, want %+v", pf.Addresses, want)
+	}
+	for i := range want {
+		if pf.Addresses[i] != want[i] {
+			t.Errorf("Addresses[%d] = %+v, want %+v", i, pf.Addresses[i], want[i])
+		}
+	}
 }
@@ -0,0 +1,650 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ── tokenizer ─────────────────────────────────────────────────────────────────
+
+type protoTokKind int
+
+const (
+	ptEOF protoTokKind = iota
+	ptIdent
+	ptNumber
+	ptString
+	ptSymbol
+)
+
+type protoToken struct {
+	kind protoTokKind
+	text string // identifiers, numbers and symbols as written; strings unquoted
+	line int
+}
+
+// protoComment is a comment found while tokenizing, kept for TODO/FIXME counting.
+type protoComment struct {
+	text string
+	line int
+}
+
+// tokenizeProto splits .proto source into tokens and comments.
+func tokenizeProto(src []byte) ([]protoToken, []protoComment, error) {
+	var (
+		toks     []protoToken
+		comments []protoComment
+		line     = 1
+		i        = 0
+		n        = len(src)
+	)
+	for i < n {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '/' && i+1 < n && src[i+1] == '/':
+			start := i
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			comments = append(comments, protoComment{text: string(src[start:i]), line: line})
+		case c == '/' && i+1 < n && src[i+1] == '*':
+			start, startLine := i, line
+			i += 2
+			for i < n && !(src[i] == '*' && i+1 < n && src[i+1] == '/') {
+				if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= n {
+				return toks, comments, fmt.Errorf("line %d: unterminated block comment", startLine)
+			}
+			i += 2
+			comments = append(comments, protoComment{text: string(src[start:i]), line: startLine})
+		case c == '"' || c == '\'':
+			quote, startLine := c, line
+			var sb strings.Builder
+			i++
+			for i < n && src[i] != quote {
+				if src[i] == '\n' {
+					return toks, comments, fmt.Errorf("line %d: newline in string literal", startLine)
+				}
+				if src[i] == '\\' && i+1 < n {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if i >= n {
+				return toks, comments, fmt.Errorf("line %d: unterminated string literal", startLine)
+			}
+			i++
+			toks = append(toks, protoToken{kind: ptString, text: sb.String(), line: startLine})
+		case isProtoIdentStart(c):
+			start := i
+			for i < n && (isProtoIdentStart(src[i]) || isProtoDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, protoToken{kind: ptIdent, text: string(src[start:i]), line: line})
+		case c == '.' && i+1 < n && isProtoIdentStart(src[i+1]):
+			// Fully-qualified type reference: .pkg.Type
+			start := i
+			i++
+			for i < n && (isProtoIdentStart(src[i]) || isProtoDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, protoToken{kind: ptIdent, text: string(src[start:i]), line: line})
+		case isProtoDigit(c):
+			start := i
+			for i < n && (isProtoIdentStart(src[i]) || isProtoDigit(src[i]) || src[i] == '.' ||
+				((src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			toks = append(toks, protoToken{kind: ptNumber, text: string(src[start:i]), line: line})
+		default:
+			toks = append(toks, protoToken{kind: ptSymbol, text: string(c), line: line})
+			i++
+		}
+	}
+	toks = append(toks, protoToken{kind: ptEOF, line: line})
+	return toks, comments, nil
+}
+
+func isProtoIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isProtoDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ── parser ────────────────────────────────────────────────────────────────────
+
+type protoParser struct {
+	toks []protoToken
+	pos  int
+	err  error // first syntax error; parsing continues after recovery
+}
+
+// ParseProto parses .proto source into a ProtoFile. The parser recovers from
+// unexpected statements, so a partial model is returned together with the
+// first syntax error encountered.
+func ParseProto(src []byte) (*ProtoFile, error) {
+	pf, _, err := parseProto(src)
+	return pf, err
+}
+
+func parseProto(src []byte) (*ProtoFile, []protoComment, error) {
+	toks, comments, err := tokenizeProto(src)
+	p := &protoParser{toks: toks, err: err}
+	pf := &ProtoFile{Syntax: "proto2"}
+	p.parseFile(pf)
+	return pf, comments, p.err
+}
+
+func (p *protoParser) peek() protoToken { return p.toks[p.pos] }
+
+func (p *protoParser) next() protoToken {
+	t := p.toks[p.pos]
+	if t.kind != ptEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *protoParser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == ptSymbol || t.kind == ptIdent) && t.text == text
+}
+
+func (p *protoParser) accept(text string) bool {
+	if p.is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *protoParser) fail(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf("line %d: %s", p.peek().line, fmt.Sprintf(format, args...))
+	}
+}
+
+func (p *protoParser) expect(text string) bool {
+	if p.accept(text) {
+		return true
+	}
+	p.fail("expected %q, found %q", text, p.peek().text)
+	return false
+}
+
+func (p *protoParser) ident() string {
+	t := p.peek()
+	if t.kind != ptIdent {
+		p.fail("expected identifier, found %q", t.text)
+		return ""
+	}
+	p.pos++
+	return t.text
+}
+
+func (p *protoParser) number() int {
+	neg := p.accept("-")
+	t := p.peek()
+	if t.kind == ptIdent && t.text == "max" {
+		p.pos++
+		return 536870911
+	}
+	if t.kind != ptNumber {
+		p.fail("expected number, found %q", t.text)
+		return 0
+	}
+	p.pos++
+	v, err := strconv.ParseInt(t.text, 0, 64)
+	if err != nil {
+		p.fail("invalid number %q", t.text)
+	}
+	if neg {
+		v = -v
+	}
+	return int(v)
+}
+
+// skipStatement skips to the end of the current statement or block.
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for {
+		t := p.next()
+		if t.kind == ptEOF {
+			return
+		}
+		if t.kind != ptSymbol {
+			continue
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// constant reads an option value: identifier, number, string(s) or aggregate.
+func (p *protoParser) constant() string {
+	t := p.peek()
+	switch {
+	case t.kind == ptString:
+		var sb strings.Builder
+		for p.peek().kind == ptString {
+			sb.WriteString(p.next().text)
+		}
+		return sb.String()
+	case t.kind == ptSymbol && (t.text == "-" || t.text == "+"):
+		p.pos++
+		return t.text + p.next().text
+	case t.kind == ptSymbol && t.text == "{":
+		// Aggregate value: keep the raw token text.
+		start := p.pos
+		depth := 0
+		for {
+			tok := p.next()
+			if tok.kind == ptEOF {
+				break
+			}
+			if tok.text == "{" && tok.kind == ptSymbol {
+				depth++
+			}
+			if tok.text == "}" && tok.kind == ptSymbol {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		var parts []string
+		for _, tok := range p.toks[start:p.pos] {
+			parts = append(parts, tok.text)
+		}
+		return strings.Join(parts, " ")
+	default:
+		return p.next().text
+	}
+}
+
+// optionName reads `name`, `(custom.ext)` or `(custom.ext).field`.
+func (p *protoParser) optionName() string {
+	var sb strings.Builder
+	for {
+		if p.accept("(") {
+			sb.WriteString("(" + p.ident() + ")")
+			p.expect(")")
+		} else {
+			sb.WriteString(p.ident())
+		}
+		if p.peek().kind == ptIdent && strings.HasPrefix(p.peek().text, ".") {
+			sb.WriteString(p.next().text)
+		}
+		if !p.accept(".") {
+			return sb.String()
+		}
+		sb.WriteString(".")
+	}
+}
+
+// optionStatement parses `option name = value;` after the `option` keyword.
+func (p *protoParser) optionStatement(opts map[string]string) map[string]string {
+	name := p.optionName()
+	p.expect("=")
+	val := p.constant()
+	p.expect(";")
+	if opts == nil {
+		opts = make(map[string]string)
+	}
+	opts[name] = val
+	return opts
+}
+
+// fieldOptions parses `[a = 1, (b) = "x"]` if present.
+func (p *protoParser) fieldOptions() map[string]string {
+	if !p.accept("[") {
+		return nil
+	}
+	opts := make(map[string]string)
+	for !p.is("]") && p.peek().kind != ptEOF {
+		name := p.optionName()
+		p.expect("=")
+		opts[name] = p.constant()
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect("]")
+	return opts
+}
+
+func (p *protoParser) parseFile(pf *ProtoFile) {
+	for p.peek().kind != ptEOF {
+		switch {
+		case p.accept(";"):
+		case p.accept("syntax"), p.accept("edition"):
+			p.expect("=")
+			pf.Syntax = p.constant()
+			p.expect(";")
+		case p.accept("package"):
+			pf.Package = p.ident()
+			p.expect(";")
+		case p.accept("import"):
+			if !p.accept("public") {
+				p.accept("weak")
+			}
+			if t := p.next(); t.kind == ptString {
+				pf.Imports = append(pf.Imports, t.text)
+			} else {
+				p.fail("expected import path, found %q", t.text)
+			}
+			p.expect(";")
+		case p.accept("option"):
+			pf.Options = p.optionStatement(pf.Options)
+		case p.is("message"):
+			pf.Messages = append(pf.Messages, p.parseMessage(""))
+		case p.is("enum"):
+			pf.Enums = append(pf.Enums, p.parseEnum(""))
+		case p.is("service"):
+			pf.Services = append(pf.Services, p.parseService())
+		default:
+			// extend blocks and unknown statements
+			if !p.is("extend") {
+				p.fail("unexpected %q", p.peek().text)
+			}
+			p.skipStatement()
+		}
+	}
+}
+
+func (p *protoParser) parseMessage(parent string) ProtoMessage {
+	line := p.next().line // "message"
+	m := ProtoMessage{Name: p.ident(), Line: line}
+	m.FullName = joinProtoName(parent, m.Name)
+	if !p.expect("{") {
+		p.skipStatement()
+		return m
+	}
+	p.parseMessageBody(&m, "")
+	m.Deprecated = m.Options["deprecated"] == "true"
+	return m
+}
+
+// parseMessageBody parses fields and nested declarations up to the closing brace.
+// oneof is the enclosing oneof name, if any.
+func (p *protoParser) parseMessageBody(m *ProtoMessage, oneof string) {
+	for !p.accept("}") {
+		if p.peek().kind == ptEOF {
+			p.fail("unexpected end of file in message %s", m.Name)
+			return
+		}
+		switch {
+		case p.accept(";"):
+		case p.accept("option"):
+			m.Options = p.optionStatement(m.Options)
+		case p.is("message"):
+			m.Messages = append(m.Messages, p.parseMessage(m.FullName))
+		case p.is("enum"):
+			m.Enums = append(m.Enums, p.parseEnum(m.FullName))
+		case p.accept("reserved"):
+			p.parseReserved(&m.Reserved)
+		case p.accept("oneof"):
+			name := p.ident()
+			if p.expect("{") {
+				p.parseMessageBody(m, name)
+			}
+		case p.is("extensions"), p.is("extend"):
+			p.skipStatement()
+		default:
+			p.parseField(m, oneof)
+		}
+	}
+}
+
+func (p *protoParser) parseField(m *ProtoMessage, oneof string) {
+	line := p.peek().line
+	f := ProtoField{Oneof: oneof, Line: line}
+	if p.is("repeated") || p.is("optional") || p.is("required") {
+		f.Label = p.next().text
+	}
+	if p.accept("map") {
+		p.expect("<")
+		key := p.ident()
+		p.expect(",")
+		val := p.ident()
+		p.expect(">")
+		f.Type = "map<" + key + ", " + val + ">"
+	} else if p.is("group") {
+		p.skipStatement()
+		return
+	} else {
+		f.Type = p.ident()
+	}
+	f.Name = p.ident()
+	if !p.expect("=") {
+		p.skipStatement()
+		return
+	}
+	f.Number = p.number()
+	f.Options = p.fieldOptions()
+	f.Deprecated = f.Options["deprecated"] == "true"
+	p.expect(";")
+	if f.Name != "" {
+		m.Fields = append(m.Fields, f)
+	}
+}
+
+func (p *protoParser) parseReserved(r *ProtoReserved) {
+	for {
+		if t := p.peek(); t.kind == ptString {
+			r.Names = append(r.Names, p.next().text)
+		} else if t.kind == ptIdent && !strings.Contains(t.text, ".") && t.text != "max" {
+			// editions syntax: reserved foo, bar;
+			r.Names = append(r.Names, p.next().text)
+		} else {
+			start := p.number()
+			end := start
+			if p.accept("to") {
+				end = p.number()
+			}
+			r.Ranges = append(r.Ranges, ProtoRange{Start: start, End: end})
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+}
+
+func (p *protoParser) parseEnum(parent string) ProtoEnum {
+	line := p.next().line // "enum"
+	e := ProtoEnum{Name: p.ident(), Line: line}
+	e.FullName = joinProtoName(parent, e.Name)
+	if !p.expect("{") {
+		p.skipStatement()
+		return e
+	}
+	for !p.accept("}") {
+		if p.peek().kind == ptEOF {
+			p.fail("unexpected end of file in enum %s", e.Name)
+			break
+		}
+		switch {
+		case p.accept(";"):
+		case p.accept("option"):
+			e.Options = p.optionStatement(e.Options)
+		case p.accept("reserved"):
+			p.parseReserved(&e.Reserved)
+		default:
+			v := ProtoEnumValue{Line: p.peek().line, Name: p.ident()}
+			if !p.expect("=") {
+				p.skipStatement()
+				continue
+			}
+			v.Number = p.number()
+			opts := p.fieldOptions()
+			v.Deprecated = opts["deprecated"] == "true"
+			p.expect(";")
+			e.Values = append(e.Values, v)
+		}
+	}
+	e.Deprecated = e.Options["deprecated"] == "true"
+	return e
+}
+
+func (p *protoParser) parseService() ProtoService {
+	line := p.next().line // "service"
+	s := ProtoService{Name: p.ident(), Line: line}
+	if !p.expect("{") {
+		p.skipStatement()
+		return s
+	}
+	for !p.accept("}") {
+		if p.peek().kind == ptEOF {
+			p.fail("unexpected end of file in service %s", s.Name)
+			break
+		}
+		switch {
+		case p.accept(";"):
+		case p.accept("option"):
+			s.Options = p.optionStatement(s.Options)
+		case p.is("rpc"):
+			s.RPCs = append(s.RPCs, p.parseRPC())
+		default:
+			p.fail("unexpected %q in service %s", p.peek().text, s.Name)
+			p.skipStatement()
+		}
+	}
+	s.Deprecated = s.Options["deprecated"] == "true"
+	return s
+}
+
+func (p *protoParser) parseRPC() ProtoRPC {
+	line := p.next().line // "rpc"
+	r := ProtoRPC{Name: p.ident(), Line: line}
+	p.expect("(")
+	// "stream" is only a keyword when followed by a type name.
+	if p.is("stream") && p.toks[p.pos+1].kind == ptIdent {
+		p.pos++
+		r.ClientStreaming = true
+	}
+	r.InputType = p.ident()
+	p.expect(")")
+	p.expect("returns")
+	p.expect("(")
+	if p.is("stream") && p.toks[p.pos+1].kind == ptIdent {
+		p.pos++
+		r.ServerStreaming = true
+	}
+	r.OutputType = p.ident()
+	p.expect(")")
+	if p.accept("{") {
+		for !p.accept("}") {
+			if p.peek().kind == ptEOF {
+				break
+			}
+			if p.accept("option") {
+				r.Options = p.optionStatement(r.Options)
+			} else if !p.accept(";") {
+				p.skipStatement()
+			}
+		}
+	} else {
+		p.expect(";")
+	}
+	r.Deprecated = r.Options["deprecated"] == "true"
+	return r
+}
+
+func joinProtoName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// ── ParsedFile adapter ────────────────────────────────────────────────────────
+
+// protoDeclarations flattens a ProtoFile into declarations. Nested messages
+// and enums use their dotted FullName; RPCs carry their service as Receiver.
+func protoDeclarations(pf *ProtoFile) []Declaration {
+	var decls []Declaration
+	var addMessage func(m ProtoMessage)
+	addEnum := func(e ProtoEnum) {
+		decls = append(decls, Declaration{Name: e.FullName, Kind: DeclEnum, Exported: true, Line: e.Line})
+	}
+	addMessage = func(m ProtoMessage) {
+		decls = append(decls, Declaration{Name: m.FullName, Kind: DeclMessage, Exported: true, Line: m.Line})
+		for _, e := range m.Enums {
+			addEnum(e)
+		}
+		for _, nested := range m.Messages {
+			addMessage(nested)
+		}
+	}
+	for _, m := range pf.Messages {
+		addMessage(m)
+	}
+	for _, e := range pf.Enums {
+		addEnum(e)
+	}
+	for _, s := range pf.Services {
+		decls = append(decls, Declaration{Name: s.Name, Kind: DeclService, Exported: true, Line: s.Line})
+		for _, r := range s.RPCs {
+			decls = append(decls, Declaration{Name: r.Name, Kind: DeclRPC, Receiver: s.Name, Exported: true, Line: r.Line})
+		}
+	}
+	return decls
+}
+
+// AllMessages returns every message in the file including nested ones.
+func (pf *ProtoFile) AllMessages() []ProtoMessage {
+	var out []ProtoMessage
+	var walk func(ms []ProtoMessage)
+	walk = func(ms []ProtoMessage) {
+		for _, m := range ms {
+			out = append(out, m)
+			walk(m.Messages)
+		}
+	}
+	walk(pf.Messages)
+	return out
+}
+
+// AllEnums returns every enum in the file including ones nested in messages.
+func (pf *ProtoFile) AllEnums() []ProtoEnum {
+	out := append([]ProtoEnum(nil), pf.Enums...)
+	for _, m := range pf.AllMessages() {
+		out = append(out, m.Enums...)
+	}
+	return out
+}
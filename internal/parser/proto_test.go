@@ -0,0 +1,139 @@
+package parser
+
+import "testing"
+
+const protoFixture = `syntax = "proto3";
+package billing.v1;
+
+import "google/protobuf/timestamp.proto";
+import public "common/money.proto";
+
+option go_package = "github.com/acme/billing/gen/billingv1;billingv1";
+
+// TODO: split invoice messages
+message Invoice {
+  reserved 4, 8 to 10;
+  reserved "legacy_total";
+
+  string id = 1;
+  repeated LineItem items = 2 [deprecated = true];
+  map<string, string> labels = 3;
+  google.protobuf.Timestamp created_at = 5;
+
+  oneof payer {
+    string user_id = 6;
+    string org_id = 7;
+  }
+
+  message LineItem {
+    string sku = 1;
+    int64 amount = 2;
+  }
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    STATUS_PAID = 1 [deprecated = true];
+  }
+}
+
+service BillingService {
+  option deprecated = true;
+  rpc GetInvoice(GetInvoiceRequest) returns (Invoice);
+  rpc StreamInvoices(ListRequest) returns (stream Invoice) {
+    option (google.api.http) = { get: "/v1/invoices" };
+  }
+  rpc Upload(stream Chunk) returns (UploadReply);
+  rpc Chat(stream Msg) returns (stream Msg);
+}
+`
+
+func TestParseProto_Structure(t *testing.T) {
+	pf, err := ParseProto([]byte(protoFixture))
+	if err != nil {
+		t.Fatalf("ParseProto error: %v", err)
+	}
+	if pf.Syntax != "proto3" || pf.Package != "billing.v1" {
+		t.Errorf("Syntax/Package = %q/%q", pf.Syntax, pf.Package)
+	}
+	if len(pf.Imports) != 2 {
+		t.Errorf("Imports = %v, want 2", pf.Imports)
+	}
+	if got := pf.Options["go_package"]; got != "github.com/acme/billing/gen/billingv1;billingv1" {
+		t.Errorf("go_package = %q", got)
+	}
+
+	if len(pf.Messages) != 1 {
+		t.Fatalf("top-level messages = %d, want 1", len(pf.Messages))
+	}
+	inv := pf.Messages[0]
+	if len(inv.Fields) != 6 {
+		t.Fatalf("Invoice fields = %d, want 6: %+v", len(inv.Fields), inv.Fields)
+	}
+	items := inv.Fields[1]
+	if items.Name != "items" || items.Number != 2 || items.Label != "repeated" || !items.Deprecated {
+		t.Errorf("items field = %+v", items)
+	}
+	if inv.Fields[2].Type != "map<string, string>" {
+		t.Errorf("labels type = %q", inv.Fields[2].Type)
+	}
+	if inv.Fields[4].Oneof != "payer" || inv.Fields[5].Number != 7 {
+		t.Errorf("oneof fields = %+v %+v", inv.Fields[4], inv.Fields[5])
+	}
+	if !inv.Reserved.Contains(9) || inv.Reserved.Contains(5) || len(inv.Reserved.Names) != 1 {
+		t.Errorf("Reserved = %+v", inv.Reserved)
+	}
+	if len(inv.Messages) != 1 || inv.Messages[0].FullName != "Invoice.LineItem" {
+		t.Errorf("nested messages = %+v", inv.Messages)
+	}
+	if len(inv.Enums) != 1 || len(inv.Enums[0].Values) != 2 || !inv.Enums[0].Values[1].Deprecated {
+		t.Errorf("nested enums = %+v", inv.Enums)
+	}
+
+	if len(pf.Services) != 1 {
+		t.Fatalf("services = %d, want 1", len(pf.Services))
+	}
+	svc := pf.Services[0]
+	if !svc.Deprecated || len(svc.RPCs) != 4 {
+		t.Fatalf("service = %+v", svc)
+	}
+	wantModes := []string{"unary", "server-stream", "client-stream", "bidi"}
+	for i, r := range svc.RPCs {
+		if r.StreamingMode() != wantModes[i] {
+			t.Errorf("%s mode = %q, want %q", r.Name, r.StreamingMode(), wantModes[i])
+		}
+	}
+	if svc.RPCs[0].InputType != "GetInvoiceRequest" || svc.RPCs[0].OutputType != "Invoice" {
+		t.Errorf("GetInvoice types = %q -> %q", svc.RPCs[0].InputType, svc.RPCs[0].OutputType)
+	}
+}
+
+func TestParseProtoSource_Declarations(t *testing.T) {
+	pf := ParseProtoSource("billing.proto", "billing", []byte(protoFixture))
+	kinds := map[DeclKind]int{}
+	for _, d := range pf.Declarations {
+		kinds[d.Kind]++
+		if d.Kind == DeclRPC && d.Receiver != "BillingService" {
+			t.Errorf("rpc %s Receiver = %q", d.Name, d.Receiver)
+		}
+	}
+	if kinds[DeclMessage] != 2 || kinds[DeclEnum] != 1 || kinds[DeclService] != 1 || kinds[DeclRPC] != 4 {
+		t.Errorf("declaration kinds = %v", kinds)
+	}
+	if pf.TodoCount != 1 {
+		t.Errorf("TodoCount = %d, want 1", pf.TodoCount)
+	}
+	if pf.Proto == nil {
+		t.Error("Proto model not attached")
+	}
+}
+
+func TestParseProto_RecoversFromErrors(t *testing.T) {
+	src := "syntax = \"proto3\";\nmessage A { string x = ; }\nmessage B { int32 y = 1; }\n"
+	pf, err := ParseProto([]byte(src))
+	if err == nil {
+		t.Error("expected a syntax error")
+	}
+	if len(pf.Messages) != 2 || pf.Messages[1].Name != "B" || len(pf.Messages[1].Fields) != 1 {
+		t.Errorf("messages after recovery = %+v", pf.Messages)
+	}
+}
@@ -0,0 +1,766 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HTTP frameworks whose route registrations are extracted.
+const (
+	FrameworkGin     = "gin"
+	FrameworkEcho    = "echo"
+	FrameworkFiber   = "fiber"
+	FrameworkChi     = "chi"
+	FrameworkGorilla = "gorilla/mux"
+	FrameworkNetHTTP = "net/http"
+)
+
+// MethodAny is the method of a route that matches every HTTP method.
+const MethodAny = "ANY"
+
+// InlineHandler is the handler of routes registered with a function literal.
+const InlineHandler = "func literal"
+
+// frameworkImports maps the import paths of the router packages, without
+// a major version suffix, to frameworks.
+var frameworkImports = []struct{ prefix, framework string }{
+	{"github.com/gin-gonic/gin", FrameworkGin},
+	{"github.com/labstack/echo", FrameworkEcho},
+	{"github.com/gofiber/fiber", FrameworkFiber},
+	{"github.com/go-chi/chi", FrameworkChi},
+	{"github.com/gorilla/mux", FrameworkGorilla},
+	{"net/http", FrameworkNetHTTP},
+}
+
+func frameworkOf(importPath string) string {
+	if i := strings.LastIndex(importPath, "/"); i > 0 && isMajorVersion(importPath[i+1:]) {
+		importPath = importPath[:i]
+	}
+	for _, f := range frameworkImports {
+		if importPath == f.prefix {
+			return f.framework
+		}
+	}
+	return ""
+}
+
+// routerConstructors are the functions that create a root router.
+var routerConstructors = map[string]map[string]bool{
+	FrameworkGin:     {"New": true, "Default": true},
+	FrameworkEcho:    {"New": true},
+	FrameworkFiber:   {"New": true},
+	FrameworkChi:     {"NewRouter": true, "NewMux": true},
+	FrameworkGorilla: {"NewRouter": true},
+	FrameworkNetHTTP: {"NewServeMux": true},
+}
+
+// routerTypes are the types of parameters and fields that hold a router.
+var routerTypes = map[string]map[string]bool{
+	FrameworkGin:     {"Engine": true, "RouterGroup": true, "IRouter": true, "IRoutes": true},
+	FrameworkEcho:    {"Echo": true, "Group": true},
+	FrameworkFiber:   {"App": true, "Router": true, "Group": true},
+	FrameworkChi:     {"Router": true, "Mux": true},
+	FrameworkGorilla: {"Router": true},
+	FrameworkNetHTTP: {"ServeMux": true},
+}
+
+// routeMethods maps the registration methods of each framework to the HTTP
+// method they register; "" means the method is an argument.
+var routeMethods = map[string]map[string]string{
+	FrameworkGin: {"GET": "GET", "POST": "POST", "PUT": "PUT", "DELETE": "DELETE", "PATCH": "PATCH",
+		"HEAD": "HEAD", "OPTIONS": "OPTIONS", "Any": MethodAny, "Handle": "", "Match": ""},
+	FrameworkEcho: {"GET": "GET", "POST": "POST", "PUT": "PUT", "DELETE": "DELETE", "PATCH": "PATCH",
+		"HEAD": "HEAD", "OPTIONS": "OPTIONS", "CONNECT": "CONNECT", "TRACE": "TRACE", "Any": MethodAny, "Add": "", "Match": ""},
+	FrameworkFiber: {"Get": "GET", "Post": "POST", "Put": "PUT", "Delete": "DELETE", "Patch": "PATCH",
+		"Head": "HEAD", "Options": "OPTIONS", "Connect": "CONNECT", "Trace": "TRACE", "All": MethodAny, "Add": ""},
+	FrameworkChi: {"Get": "GET", "Post": "POST", "Put": "PUT", "Delete": "DELETE", "Patch": "PATCH",
+		"Head": "HEAD", "Options": "OPTIONS", "Connect": "CONNECT", "Trace": "TRACE",
+		"Handle": MethodAny, "HandleFunc": MethodAny, "Method": "", "MethodFunc": ""},
+	FrameworkGorilla: {"Handle": MethodAny, "HandleFunc": MethodAny},
+	FrameworkNetHTTP: {"Handle": MethodAny, "HandleFunc": MethodAny},
+}
+
+// routerNode is a router or route group. Prefixes and middleware are
+// resolved when the file has been walked, so a chi router mounted after
+// its routes were registered still gets the mount prefix.
+type routerNode struct {
+	framework  string
+	parent     *routerNode
+	prefix     string
+	middleware []string
+}
+
+func (n *routerNode) path(p string) string {
+	for ; n != nil; n = n.parent {
+		p = joinRoutePath(n.prefix, p)
+	}
+	return p
+}
+
+func (n *routerNode) chain() []string {
+	var nodes []*routerNode
+	for ; n != nil; n = n.parent {
+		nodes = append(nodes, n)
+	}
+	var out []string
+	for i := len(nodes) - 1; i >= 0; i-- {
+		out = append(out, nodes[i].middleware...)
+	}
+	return out
+}
+
+func joinRoutePath(prefix, p string) string {
+	switch {
+	case prefix == "":
+		return p
+	case p == "":
+		return prefix
+	case strings.HasSuffix(prefix, "/") && strings.HasPrefix(p, "/"):
+		return prefix + p[1:]
+	}
+	return prefix + p
+}
+
+// routeRef is a route whose path and middleware depend on its router.
+type routeRef struct {
+	node       *routerNode
+	route      HTTPRoute
+	middleware []string
+}
+
+// routeExtractor walks one file. Variables are tracked by name per
+// function, which is enough for the usual setup code.
+type routeExtractor struct {
+	fset    *token.FileSet
+	pkgs    map[string]string // package identifiers in use -> framework
+	consts  map[string]string // package-level string constants
+	refs    []routeRef
+	methods map[*ast.CallExpr][]string // gorilla .Methods(...) of a registration call
+}
+
+type routeScope map[string]*routerNode
+
+// httpRoutes extracts the route registrations of the HTTP frameworks the
+// file imports, in source order.
+func httpRoutes(fset *token.FileSet, file *ast.File, imports []string, aliases map[string]string) []HTTPRoute {
+	x := &routeExtractor{fset: fset,
+		pkgs: make(map[string]string), consts: make(map[string]string), methods: make(map[*ast.CallExpr][]string)}
+	for _, imp := range imports {
+		if fw := frameworkOf(imp); fw != "" {
+			name := imp[strings.LastIndex(imp, "/")+1:]
+			for alias, path := range aliases {
+				if path == imp {
+					name = alias
+				}
+			}
+			if isMajorVersion(name) {
+				elems := strings.Split(imp, "/")
+				name = elems[len(elems)-2]
+			}
+			x.pkgs[name] = fw
+		}
+	}
+	if len(x.pkgs) == 0 {
+		return nil
+	}
+
+	global := make(routeScope)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || (gd.Tok != token.CONST && gd.Tok != token.VAR) {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					continue
+				}
+				if gd.Tok == token.CONST {
+					if s, ok := stringLit(vs.Values[i]); ok {
+						x.consts[name.Name] = s
+					}
+				} else if n := x.router(vs.Values[i], global, false); n != nil {
+					global[name.Name] = n
+				}
+			}
+		}
+	}
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		scope := x.child(global, fd.Type)
+		if fd.Recv != nil {
+			x.bindParams(scope, fd.Recv)
+		}
+		x.walk(fd.Body, scope)
+	}
+
+	routes := make([]HTTPRoute, 0, len(x.refs))
+	for _, ref := range x.refs {
+		r := ref.route
+		r.Path = ref.node.path(r.Path)
+		r.Middleware = append(ref.node.chain(), ref.middleware...)
+		routes = append(routes, r)
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Line < routes[j].Line })
+	return routes
+}
+
+// child returns a scope for a function body: a copy of outer with the
+// parameters bound, either to a new router when typed as one or to nil.
+func (x *routeExtractor) child(outer routeScope, ft *ast.FuncType) routeScope {
+	scope := make(routeScope, len(outer))
+	for k, v := range outer {
+		scope[k] = v
+	}
+	if ft != nil {
+		x.bindParams(scope, ft.Params)
+	}
+	return scope
+}
+
+// bindParams binds parameters typed as a router to a new router and the
+// others to nil, so a *fiber.Ctx or *http.Request is never guessed to be a
+// router.
+func (x *routeExtractor) bindParams(scope routeScope, fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, f := range fields.List {
+		fw := x.routerType(f.Type)
+		for _, name := range f.Names {
+			if fw != "" {
+				scope[name.Name] = &routerNode{framework: fw}
+			} else {
+				scope[name.Name] = nil
+			}
+		}
+	}
+}
+
+// routerType returns the framework of a router type such as *gin.Engine or
+// chi.Router, or "".
+func (x *routeExtractor) routerType(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	fw := x.pkgs[pkg.Name]
+	if routerTypes[fw][sel.Sel.Name] {
+		return fw
+	}
+	return ""
+}
+
+func (x *routeExtractor) walk(body ast.Node, scope routeScope) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			x.walk(n.Body, x.child(scope, n.Type))
+			return false
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					if node := x.router(n.Rhs[i], scope, false); node != nil {
+						scope[types.ExprString(lhs)] = node
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					if node := x.router(n.Values[i], scope, false); node != nil {
+						scope[name.Name] = node
+					}
+				} else if fw := x.routerType(n.Type); fw != "" {
+					scope[name.Name] = &routerNode{framework: fw}
+				} else if n.Type != nil {
+					scope[name.Name] = nil
+				}
+			}
+		case *ast.CallExpr:
+			return x.call(n, scope)
+		}
+		return true
+	})
+}
+
+// router evaluates expr to the router it denotes: a known variable or
+// field, a constructor call or a group derived from another router. With
+// guess, a variable or field of unknown type becomes a new root router of
+// the file's only framework; one declared with another type does not.
+func (x *routeExtractor) router(expr ast.Expr, scope routeScope, guess bool) *routerNode {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return x.router(e.X, scope, guess)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return x.router(e.X, scope, guess)
+		}
+	case *ast.Ident, *ast.SelectorExpr:
+		key := types.ExprString(e)
+		if n, ok := scope[key]; ok {
+			return n
+		}
+		if id, ok := e.(*ast.Ident); ok && x.pkgs[id.Name] != "" {
+			return nil // a package, e.g. http.HandleFunc
+		}
+		if guess {
+			if fw := x.soleFramework(); fw != "" {
+				n := &routerNode{framework: fw}
+				scope[key] = n
+				return n
+			}
+		}
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && x.pkgs[pkg.Name] != "" && scope[pkg.Name] == nil {
+			fw := x.pkgs[pkg.Name]
+			if routerConstructors[fw][sel.Sel.Name] {
+				return &routerNode{framework: fw}
+			}
+			return nil
+		}
+		switch sel.Sel.Name {
+		case "Group":
+			parent := x.router(sel.X, scope, false)
+			if parent == nil || parent.framework == FrameworkChi || len(e.Args) == 0 {
+				return nil
+			}
+			p, _ := x.pathArg(e.Args[0])
+			n := &routerNode{framework: parent.framework, parent: parent, prefix: p}
+			n.middleware = handlerNames(e.Args[1:])
+			return n
+		case "With":
+			if parent := x.router(sel.X, scope, false); parent != nil && parent.framework == FrameworkChi {
+				return &routerNode{framework: FrameworkChi, parent: parent, middleware: handlerNames(e.Args)}
+			}
+		case "Subrouter":
+			// r.PathPrefix("/api").Subrouter()
+			inner, ok := e.Fun.(*ast.SelectorExpr).X.(*ast.CallExpr)
+			if !ok {
+				return nil
+			}
+			innerSel, ok := inner.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return nil
+			}
+			parent := x.router(innerSel.X, scope, false)
+			if parent == nil || parent.framework != FrameworkGorilla {
+				return nil
+			}
+			n := &routerNode{framework: FrameworkGorilla, parent: parent}
+			if innerSel.Sel.Name == "PathPrefix" && len(inner.Args) == 1 {
+				n.prefix, _ = x.pathArg(inner.Args[0])
+			}
+			return n
+		}
+	}
+	return nil
+}
+
+// soleFramework returns the framework of the file's imports when there is
+// exactly one besides net/http, or net/http alone.
+func (x *routeExtractor) soleFramework() string {
+	fw := ""
+	for _, f := range x.pkgs {
+		switch {
+		case f == FrameworkNetHTTP:
+			if fw == "" {
+				fw = f
+			}
+		case fw == "" || fw == FrameworkNetHTTP:
+			fw = f
+		case fw != f:
+			return ""
+		}
+	}
+	return fw
+}
+
+// call handles a method call on a router: middleware, groups with
+// callbacks, mounts and route registrations. It returns whether to walk the
+// call's children.
+func (x *routeExtractor) call(call *ast.CallExpr, scope routeScope) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return true
+	}
+	name := sel.Sel.Name
+	if name == "Methods" {
+		if inner, ok := sel.X.(*ast.CallExpr); ok {
+			var methods []string
+			for _, a := range call.Args {
+				if m, ok := x.methodArg(a); ok {
+					methods = append(methods, m)
+				}
+			}
+			x.methods[inner] = methods
+		}
+		return true
+	}
+
+	// Package-level net/http registrations use the default ServeMux.
+	if pkg, ok := sel.X.(*ast.Ident); ok && x.pkgs[pkg.Name] == FrameworkNetHTTP && scope[pkg.Name] == nil {
+		if name == "Handle" || name == "HandleFunc" {
+			x.register(call, &routerNode{framework: FrameworkNetHTTP}, MethodAny)
+		}
+		return true
+	}
+
+	switch name {
+	case "Use":
+		if n := x.router(sel.X, scope, false); n != nil {
+			for _, a := range call.Args {
+				if _, isPath := x.pathArg(a); !isPath {
+					n.middleware = append(n.middleware, handlerName(a))
+				}
+			}
+		}
+		return true
+	case "Route", "Group":
+		// chi r.Route("/p", func(r chi.Router) {...}), r.Group(func(r chi.Router) {...})
+		// and fiber app.Route("/p", func(r fiber.Router) {...}).
+		var lit *ast.FuncLit
+		prefix := ""
+		switch {
+		case len(call.Args) >= 2:
+			lit, _ = call.Args[1].(*ast.FuncLit)
+			prefix, _ = x.pathArg(call.Args[0])
+		case len(call.Args) == 1 && name == "Group":
+			lit, _ = call.Args[0].(*ast.FuncLit)
+		}
+		if lit == nil {
+			return true
+		}
+		parent := x.router(sel.X, scope, false)
+		if parent == nil || (parent.framework != FrameworkChi && parent.framework != FrameworkFiber) {
+			return true
+		}
+		child := &routerNode{framework: parent.framework, parent: parent, prefix: prefix}
+		inner := x.child(scope, lit.Type)
+		if params := lit.Type.Params; params != nil && len(params.List) > 0 && len(params.List[0].Names) > 0 {
+			inner[params.List[0].Names[0].Name] = child
+		}
+		x.walk(lit.Body, inner)
+		return false
+	case "Mount":
+		parent := x.router(sel.X, scope, false)
+		if parent == nil || parent.framework != FrameworkChi || len(call.Args) != 2 {
+			return true
+		}
+		prefix, _ := x.pathArg(call.Args[0])
+		if sub := x.router(call.Args[1], scope, false); sub != nil && sub != parent && sub.parent == nil {
+			sub.parent, sub.prefix = parent, prefix
+			return true
+		}
+		x.refs = append(x.refs, routeRef{node: parent, route: HTTPRoute{
+			Framework: FrameworkChi, Method: MethodAny, Path: joinRoutePath(prefix, "/*"),
+			Handler: handlerName(call.Args[1]), Line: x.fset.Position(call.Pos()).Line,
+		}})
+		return true
+	}
+
+	// A registration needs a path argument, so guessing the router of an
+	// unknown receiver does not pick up http.Client.Get(url),
+	// c.Get("Authorization") and the like.
+	if len(call.Args) < 2 {
+		return true
+	}
+	for fw, methods := range routeMethods {
+		method, ok := methods[name]
+		if !ok {
+			continue
+		}
+		node := x.router(sel.X, scope, false)
+		if node == nil {
+			pathIdx := 0
+			if method == "" {
+				pathIdx = 1
+			}
+			if pathIdx >= len(call.Args) || !x.routePath(call.Args[pathIdx]) {
+				continue
+			}
+			if node = x.router(sel.X, scope, true); node == nil {
+				continue
+			}
+		}
+		if node.framework != fw {
+			continue
+		}
+		x.register(call, node, method)
+		return true
+	}
+	return true
+}
+
+// register records a route registration call on node. method is the HTTP
+// method, or "" when the call passes it as its first argument; a call whose
+// method cannot be resolved, such as a variable, is not recorded.
+func (x *routeExtractor) register(call *ast.CallExpr, node *routerNode, method string) {
+	args := call.Args
+	var methods []string
+	if method == "" {
+		if len(args) < 3 {
+			return
+		}
+		if lit, ok := args[0].(*ast.CompositeLit); ok {
+			// gin/echo Match([]string{...}, path, ...)
+			for _, elt := range lit.Elts {
+				if m, ok := x.methodArg(elt); ok {
+					methods = append(methods, m)
+				}
+			}
+		} else if m, ok := x.methodArg(args[0]); ok {
+			methods = []string{m}
+		}
+		if len(methods) == 0 {
+			return
+		}
+		args = args[1:]
+	} else {
+		methods = []string{method}
+	}
+	path, ok := x.pathArg(args[0])
+	if !ok {
+		path = types.ExprString(args[0])
+	}
+	handlers := args[1:]
+	if len(handlers) == 0 {
+		return
+	}
+	if node.framework == FrameworkNetHTTP {
+		// Go 1.22 patterns: "GET /items/{id}".
+		if m, p, ok := strings.Cut(path, " "); ok && methods[0] == MethodAny {
+			methods, path = []string{m}, strings.TrimSpace(p)
+		}
+	}
+	if ms, ok := x.methods[call]; ok && len(ms) > 0 {
+		methods = ms
+	}
+
+	var handler ast.Expr
+	var middleware []string
+	switch node.framework {
+	case FrameworkEcho:
+		// e.GET(path, handler, middleware...)
+		handler, middleware = handlers[0], handlerNames(handlers[1:])
+	case FrameworkNetHTTP, FrameworkGorilla, FrameworkChi:
+		handler, middleware = unwrapHandler(handlers[len(handlers)-1])
+	default:
+		handler, middleware = handlers[len(handlers)-1], handlerNames(handlers[:len(handlers)-1])
+	}
+	line := x.fset.Position(call.Pos()).Line
+	var io *HandlerIO
+	if lit, ok := handler.(*ast.FuncLit); ok {
+		io = handlerIO(lit.Type, lit.Body)
+	}
+	for _, m := range methods {
+		x.refs = append(x.refs, routeRef{node: node, middleware: middleware, route: HTTPRoute{
+			Framework: node.framework,
+			Method:    strings.ToUpper(m),
+			Path:      path,
+			Handler:   handlerName(handler),
+			Line:      line,
+			IO:        io,
+		}})
+	}
+}
+
+// unwrapHandler splits a net/http handler such as
+// logging(auth(http.HandlerFunc(h))) into the handler and the wrapping
+// middleware, outermost first.
+func unwrapHandler(expr ast.Expr) (ast.Expr, []string) {
+	var middleware []string
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return expr, middleware
+		}
+		if name := types.ExprString(call.Fun); name != "http.HandlerFunc" {
+			middleware = append(middleware, name)
+		}
+		expr = call.Args[len(call.Args)-1]
+	}
+}
+
+func handlerNames(exprs []ast.Expr) []string {
+	var out []string
+	for _, e := range exprs {
+		out = append(out, handlerName(e))
+	}
+	return out
+}
+
+// handlerName renders a handler or middleware expression: calls keep only
+// the function, e.g. "middleware.Auth()".
+func handlerName(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.FuncLit:
+		return InlineHandler
+	case *ast.CallExpr:
+		return types.ExprString(e.Fun) + "()"
+	}
+	return types.ExprString(e)
+}
+
+// pathArg returns the value of a string literal or of a string constant
+// declared in the file.
+func (x *routeExtractor) pathArg(e ast.Expr) (string, bool) {
+	if s, ok := stringLit(e); ok {
+		return s, true
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		s, ok := x.consts[id.Name]
+		return s, ok
+	}
+	return "", false
+}
+
+// routePath reports whether e can be the path of a route registered on a
+// router of unknown type: a string constant declared in the file, or a
+// literal that is empty, starts with "/" or is a net/http pattern such as
+// "GET /items".
+func (x *routeExtractor) routePath(e ast.Expr) bool {
+	if id, ok := e.(*ast.Ident); ok {
+		_, ok := x.consts[id.Name]
+		return ok
+	}
+	s, ok := stringLit(e)
+	if !ok {
+		return false
+	}
+	if _, p, found := strings.Cut(s, " "); found {
+		s = strings.TrimSpace(p)
+	}
+	return s == "" || strings.HasPrefix(s, "/")
+}
+
+// methodArg returns the HTTP method an argument names: a string literal or
+// constant, or a method constant of a framework package such as
+// http.MethodGet or fiber.MethodPost.
+func (x *routeExtractor) methodArg(e ast.Expr) (string, bool) {
+	if s, ok := x.pathArg(e); ok {
+		return strings.ToUpper(s), s != ""
+	}
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || x.pkgs[pkg.Name] == "" {
+		return "", false
+	}
+	verb, ok := strings.CutPrefix(sel.Sel.Name, "Method")
+	if !ok {
+		return "", false
+	}
+	switch verb = strings.ToUpper(verb); verb {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE":
+		return verb, true
+	}
+	return "", false
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// FuncIndex finds the declarations of route handlers by name.
+type FuncIndex struct {
+	byService map[string]map[string][]funcRef // microservice -> name -> declarations
+}
+
+type funcRef struct {
+	file *ParsedFile
+	decl *Declaration
+}
+
+// NewFuncIndex indexes the functions and methods declared in files.
+func NewFuncIndex(files []*ParsedFile) *FuncIndex {
+	x := &FuncIndex{byService: make(map[string]map[string][]funcRef)}
+	for _, f := range files {
+		for i := range f.Declarations {
+			d := &f.Declarations[i]
+			if d.Kind != DeclFunc {
+				continue
+			}
+			names := x.byService[f.MicroserviceName]
+			if names == nil {
+				names = make(map[string][]funcRef)
+				x.byService[f.MicroserviceName] = names
+			}
+			names[d.Name] = append(names[d.Name], funcRef{f, d})
+		}
+	}
+	return x
+}
+
+// Lookup returns the declaration a handler expression registered in f
+// refers to, by its last name: "h.Create", "handlers.List()" and "Create"
+// all look for Create, first among the declarations of f, then in f's
+// microservice. A bare name is a function of f's package and a qualifier
+// naming a package, as in "orders.Create", picks that package's function.
+// Receiver types are not resolved, so a name declared more than once where
+// it is looked for is ambiguous. It returns nil for inline handlers,
+// ambiguous names and names not found.
+func (x *FuncIndex) Lookup(f *ParsedFile, handler string) (*ParsedFile, *Declaration) {
+	expr := strings.TrimSuffix(handler, "()")
+	name := expr[strings.LastIndex(expr, ".")+1:]
+	var local []funcRef
+	for i := range f.Declarations {
+		if d := &f.Declarations[i]; d.Kind == DeclFunc && d.Name == name {
+			local = append(local, funcRef{f, d})
+		}
+	}
+	if len(local) > 0 {
+		return only(local)
+	}
+	refs := x.byService[f.MicroserviceName][name]
+	qual, _, qualified := strings.Cut(expr, ".")
+	var inPkg []funcRef
+	for _, ref := range refs {
+		if ref.decl.Receiver != "" {
+			continue
+		}
+		if qualified && ref.file.PackageName == qual && strings.Count(expr, ".") == 1 ||
+			!qualified && filepath.Dir(ref.file.FilePath) == filepath.Dir(f.FilePath) {
+			inPkg = append(inPkg, ref)
+		}
+	}
+	if len(inPkg) > 0 || !qualified {
+		return only(inPkg)
+	}
+	return only(refs)
+}
+
+// only returns the single declaration of refs, or nil when there are none
+// or several.
+func only(refs []funcRef) (*ParsedFile, *Declaration) {
+	if len(refs) != 1 {
+		return nil, nil
+	}
+	return refs[0].file, refs[0].decl
+}
@@ -0,0 +1,55 @@
+// Package pool runs independent jobs on a bounded number of goroutines
+// while keeping results in input order, so parallel runs produce the same
+// output as sequential ones.
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Map calls fn(i) for every i in [0, n) on up to workers goroutines and
+// returns the results indexed like the inputs. Once ctx is done no new call
+// starts; Map waits for running calls and returns ctx.Err(). A workers
+// value below 1 means one.
+func Map[T any](ctx context.Context, workers, n int, fn func(i int) T) ([]T, error) {
+	out := make([]T, n)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return out, err
+			}
+			out[i] = fn(i)
+		}
+		return out, ctx.Err()
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out[i] = fn(i)
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return out, ctx.Err()
+}
@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	for _, workers := range []int{0, 1, 4, 100} {
+		got, err := Map(context.Background(), workers, 50, func(i int) int {
+			time.Sleep(time.Duration(50-i) * 10 * time.Microsecond)
+			return i * i
+		})
+		if err != nil {
+			t.Fatalf("workers=%d: %v", workers, err)
+		}
+		for i, v := range got {
+			if v != i*i {
+				t.Fatalf("workers=%d: out[%d] = %d, want %d", workers, i, v, i*i)
+			}
+		}
+	}
+
+	if got, err := Map(context.Background(), 4, 0, func(int) int { return 1 }); err != nil || len(got) != 0 {
+		t.Errorf("empty input: %v, %v", got, err)
+	}
+}
+
+func TestMapCancel(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls atomic.Int32
+		_, err := Map(ctx, workers, 1000, func(i int) int {
+			if calls.Add(1) == 10 {
+				cancel()
+			}
+			return i
+		})
+		if err != context.Canceled {
+			t.Errorf("workers=%d: err = %v, want context.Canceled", workers, err)
+		}
+		if n := calls.Load(); n >= 1000 {
+			t.Errorf("workers=%d: all %d jobs ran after cancel", workers, n)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package protodiff detects wire- and API-breaking changes between two sets
+// of parsed .proto files.
+package protodiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// Severities.
+const (
+	SeverityBreaking = "BREAKING" // breaks wire compatibility or generated stubs
+	SeverityWarning  = "WARNING"  // wire-safe but breaks JSON, source or intent
+)
+
+// Change kinds.
+const (
+	KindMessageRemoved      = "message-removed"
+	KindEnumRemoved         = "enum-removed"
+	KindServiceRemoved      = "service-removed"
+	KindRPCRemoved          = "rpc-removed"
+	KindRPCTypeChanged      = "rpc-type-changed"
+	KindRPCStreamingChanged = "rpc-streaming-changed"
+	KindFieldRemoved        = "field-removed"
+	KindFieldRenumbered     = "field-renumbered"
+	KindFieldRenamed        = "field-renamed"
+	KindFieldTypeChanged    = "field-type-changed"
+	KindFieldLabelChanged   = "field-label-changed"
+	KindFieldOneofChanged   = "field-oneof-changed"
+	KindEnumValueRemoved    = "enum-value-removed"
+	KindEnumValueRenumbered = "enum-value-renumbered"
+)
+
+// Change is a single incompatible difference between two revisions.
+type Change struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	File     string `json:"file"`    // file in the new revision, or the old one for removals
+	Line     int    `json:"line"`    // line of the element in File
+	Element  string `json:"element"` // fully qualified name, e.g. "users.v1.User.email"
+	Message  string `json:"message"`
+}
+
+// Diff is the result of comparing the proto files of two revisions.
+type Diff struct {
+	Base    string   `json:"base"`
+	Head    string   `json:"head"`
+	Files   int      `json:"files"` // .proto files compared at head
+	Changes []Change `json:"changes"`
+}
+
+// Breaking returns the number of BREAKING changes.
+func (d *Diff) Breaking() int {
+	n := 0
+	for _, c := range d.Changes {
+		if c.Severity == SeverityBreaking {
+			n++
+		}
+	}
+	return n
+}
+
+// Compare reports breaking changes from old to new. Both maps are keyed by
+// file path. Elements are matched by fully qualified name, so moving a
+// message between files of the same package is not a change.
+func Compare(old, new map[string]*parser.ProtoFile) []Change {
+	o, n := index(old), index(new)
+	var changes []Change
+
+	for _, name := range sortedKeys(o.messages) {
+		om := o.messages[name]
+		nm, ok := n.messages[name]
+		if !ok {
+			if !parentRemoved(name, o.messages, n.messages) {
+				changes = append(changes, Change{SeverityBreaking, KindMessageRemoved, om.file, om.msg.Line, name,
+					fmt.Sprintf("message %s was removed", name)})
+			}
+			continue
+		}
+		changes = append(changes, compareFields(name, om, nm, o, n)...)
+	}
+
+	for _, name := range sortedKeys(o.enums) {
+		oe := o.enums[name]
+		ne, ok := n.enums[name]
+		if !ok {
+			if !parentRemoved(name, o.messages, n.messages) {
+				changes = append(changes, Change{SeverityBreaking, KindEnumRemoved, oe.file, oe.enum.Line, name,
+					fmt.Sprintf("enum %s was removed", name)})
+			}
+			continue
+		}
+		changes = append(changes, compareEnumValues(name, oe, ne)...)
+	}
+
+	for _, name := range sortedKeys(o.services) {
+		osvc := o.services[name]
+		nsvc, ok := n.services[name]
+		if !ok {
+			changes = append(changes, Change{SeverityBreaking, KindServiceRemoved, osvc.file, osvc.svc.Line, name,
+				fmt.Sprintf("service %s was removed", name)})
+			continue
+		}
+		changes = append(changes, compareRPCs(name, osvc, nsvc, o, n)...)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Severity != changes[j].Severity {
+			return changes[i].Severity == SeverityBreaking
+		}
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].Line < changes[j].Line
+	})
+	return changes
+}
+
+func compareFields(msgName string, om, nm msgEntry, o, n protoIndex) []Change {
+	var changes []Change
+	newByNum := make(map[int]parser.ProtoField)
+	newByName := make(map[string]parser.ProtoField)
+	for _, f := range nm.msg.Fields {
+		newByNum[f.Number] = f
+		newByName[f.Name] = f
+	}
+
+	for _, of := range om.msg.Fields {
+		elem := msgName + "." + of.Name
+		nf, ok := newByNum[of.Number]
+		if !ok {
+			if moved, ok := newByName[of.Name]; ok {
+				changes = append(changes, Change{SeverityBreaking, KindFieldRenumbered, nm.file, moved.Line, elem,
+					fmt.Sprintf("field %s changed number %d → %d", of.Name, of.Number, moved.Number)})
+				continue
+			}
+			if nm.msg.Reserved.Contains(of.Number) {
+				changes = append(changes, Change{SeverityWarning, KindFieldRemoved, nm.file, nm.msg.Line, elem,
+					fmt.Sprintf("field %s = %d was removed (number reserved)", of.Name, of.Number)})
+			} else {
+				changes = append(changes, Change{SeverityBreaking, KindFieldRemoved, nm.file, nm.msg.Line, elem,
+					fmt.Sprintf("field %s = %d was removed without reserving its number", of.Name, of.Number)})
+			}
+			continue
+		}
+
+		if nf.Name != of.Name {
+			changes = append(changes, Change{SeverityWarning, KindFieldRenamed, nm.file, nf.Line, elem,
+				fmt.Sprintf("field %d renamed %s → %s (breaks JSON and generated code)", of.Number, of.Name, nf.Name)})
+		}
+		ot, nt := o.resolve(of.Type, msgName), n.resolve(nf.Type, msgName)
+		if ot != nt {
+			sev, note := SeverityBreaking, ""
+			if wireCompatible(ot, nt) {
+				sev, note = SeverityWarning, " (wire-compatible)"
+			}
+			changes = append(changes, Change{sev, KindFieldTypeChanged, nm.file, nf.Line, elem,
+				fmt.Sprintf("field %s changed type %s → %s%s", of.Name, of.Type, nf.Type, note)})
+		}
+		if of.Label != nf.Label {
+			sev := SeverityWarning
+			if of.Label == "repeated" || nf.Label == "repeated" {
+				sev = SeverityBreaking
+			}
+			changes = append(changes, Change{sev, KindFieldLabelChanged, nm.file, nf.Line, elem,
+				fmt.Sprintf("field %s changed label %s → %s", of.Name, labelName(of.Label), labelName(nf.Label))})
+		}
+		if of.Oneof != nf.Oneof {
+			changes = append(changes, Change{SeverityWarning, KindFieldOneofChanged, nm.file, nf.Line, elem,
+				fmt.Sprintf("field %s moved from oneof %q to %q", of.Name, of.Oneof, nf.Oneof)})
+		}
+	}
+	return changes
+}
+
+func compareEnumValues(enumName string, oe, ne enumEntry) []Change {
+	var changes []Change
+	newByNum := make(map[int]parser.ProtoEnumValue)
+	newByName := make(map[string]parser.ProtoEnumValue)
+	for _, v := range ne.enum.Values {
+		newByNum[v.Number] = v
+		newByName[v.Name] = v
+	}
+	for _, ov := range oe.enum.Values {
+		elem := enumName + "." + ov.Name
+		if nv, ok := newByName[ov.Name]; ok {
+			if nv.Number != ov.Number {
+				changes = append(changes, Change{SeverityBreaking, KindEnumValueRenumbered, ne.file, nv.Line, elem,
+					fmt.Sprintf("enum value %s changed number %d → %d", ov.Name, ov.Number, nv.Number)})
+			}
+			continue
+		}
+		if _, ok := newByNum[ov.Number]; ok {
+			// Renamed in place: the number is unchanged, so the wire format is too.
+			continue
+		}
+		if ne.enum.Reserved.Contains(ov.Number) {
+			changes = append(changes, Change{SeverityWarning, KindEnumValueRemoved, ne.file, ne.enum.Line, elem,
+				fmt.Sprintf("enum value %s = %d was removed (number reserved)", ov.Name, ov.Number)})
+		} else {
+			changes = append(changes, Change{SeverityBreaking, KindEnumValueRemoved, ne.file, ne.enum.Line, elem,
+				fmt.Sprintf("enum value %s = %d was removed without reserving its number", ov.Name, ov.Number)})
+		}
+	}
+	return changes
+}
+
+func compareRPCs(svcName string, osvc, nsvc svcEntry, o, n protoIndex) []Change {
+	var changes []Change
+	newRPCs := make(map[string]parser.ProtoRPC)
+	for _, r := range nsvc.svc.RPCs {
+		newRPCs[r.Name] = r
+	}
+	for _, or := range osvc.svc.RPCs {
+		elem := svcName + "." + or.Name
+		nr, ok := newRPCs[or.Name]
+		if !ok {
+			changes = append(changes, Change{SeverityBreaking, KindRPCRemoved, nsvc.file, nsvc.svc.Line, elem,
+				fmt.Sprintf("rpc %s was removed", or.Name)})
+			continue
+		}
+		if o.resolve(or.InputType, osvc.pkg) != n.resolve(nr.InputType, nsvc.pkg) {
+			changes = append(changes, Change{SeverityBreaking, KindRPCTypeChanged, nsvc.file, nr.Line, elem,
+				fmt.Sprintf("rpc %s request changed %s → %s", or.Name, or.InputType, nr.InputType)})
+		}
+		if o.resolve(or.OutputType, osvc.pkg) != n.resolve(nr.OutputType, nsvc.pkg) {
+			changes = append(changes, Change{SeverityBreaking, KindRPCTypeChanged, nsvc.file, nr.Line, elem,
+				fmt.Sprintf("rpc %s response changed %s → %s", or.Name, or.OutputType, nr.OutputType)})
+		}
+		if or.StreamingMode() != nr.StreamingMode() {
+			changes = append(changes, Change{SeverityBreaking, KindRPCStreamingChanged, nsvc.file, nr.Line, elem,
+				fmt.Sprintf("rpc %s changed streaming mode %s → %s", or.Name, or.StreamingMode(), nr.StreamingMode())})
+		}
+	}
+	return changes
+}
+
+// ─── Indexing ───
+
+type msgEntry struct {
+	file string
+	msg  parser.ProtoMessage
+}
+
+type enumEntry struct {
+	file string
+	enum parser.ProtoEnum
+}
+
+type svcEntry struct {
+	file string
+	pkg  string
+	svc  parser.ProtoService
+}
+
+type protoIndex struct {
+	messages map[string]msgEntry
+	enums    map[string]enumEntry
+	services map[string]svcEntry
+}
+
+func index(files map[string]*parser.ProtoFile) protoIndex {
+	idx := protoIndex{
+		messages: make(map[string]msgEntry),
+		enums:    make(map[string]enumEntry),
+		services: make(map[string]svcEntry),
+	}
+	for path, pf := range files {
+		if pf == nil {
+			continue
+		}
+		for _, m := range pf.AllMessages() {
+			idx.messages[qualify(pf.Package, m.FullName)] = msgEntry{path, m}
+		}
+		for _, e := range pf.AllEnums() {
+			idx.enums[qualify(pf.Package, e.FullName)] = enumEntry{path, e}
+		}
+		for _, s := range pf.Services {
+			idx.services[qualify(pf.Package, s.Name)] = svcEntry{path, pf.Package, s}
+		}
+	}
+	return idx
+}
+
+func qualify(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// parentRemoved reports whether name is nested in a message that was itself
+// removed, so only the outermost removal is reported.
+func parentRemoved(name string, old, new map[string]msgEntry) bool {
+	for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name, ".") {
+		name = name[:i]
+		if _, wasMsg := old[name]; wasMsg {
+			_, still := new[name]
+			return !still
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolve returns the fully qualified name of a type referenced from scope,
+// searching enclosing scopes innermost first like protoc does. Scalars and
+// unknown types are returned unchanged, map values are resolved in place.
+func (idx protoIndex) resolve(typ, scope string) string {
+	if strings.HasPrefix(typ, "map<") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(typ, "map<"), ">")
+		if k, v, ok := strings.Cut(inner, ","); ok {
+			return "map<" + strings.TrimSpace(k) + "," + idx.resolve(strings.TrimSpace(v), scope) + ">"
+		}
+		return typ
+	}
+	if strings.HasPrefix(typ, ".") {
+		return typ[1:]
+	}
+	for {
+		name := qualify(scope, typ)
+		if _, ok := idx.messages[name]; ok {
+			return name
+		}
+		if _, ok := idx.enums[name]; ok {
+			return name
+		}
+		if scope == "" {
+			return typ
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// wireGroups lists scalar types that share a wire encoding, so a change
+// within a group keeps old binaries decoding (possibly with truncation).
+var wireGroups = [][]string{
+	{"int32", "uint32", "int64", "uint64", "bool"},
+	{"sint32", "sint64"},
+	{"fixed32", "sfixed32"},
+	{"fixed64", "sfixed64"},
+	{"string", "bytes"},
+}
+
+func wireCompatible(a, b string) bool {
+	for _, g := range wireGroups {
+		var hasA, hasB bool
+		for _, t := range g {
+			hasA = hasA || t == a
+			hasB = hasB || t == b
+		}
+		if hasA && hasB {
+			return true
+		}
+	}
+	return false
+}
+
+func labelName(l string) string {
+	if l == "" {
+		return "singular"
+	}
+	return l
+}
@@ -0,0 +1,150 @@
+package protodiff
+
+import (
+	"testing"
+
+	"github.com/goscope/internal/parser"
+)
+
+const baseProto = `syntax = "proto3";
+package users.v1;
+
+message User {
+  string id = 1;
+  string email = 2;
+  int32 age = 3;
+  repeated string tags = 4;
+  string nickname = 5;
+  message Address { string city = 1; }
+  Address address = 6;
+}
+
+message Legacy { string x = 1; message Inner { string y = 1; } }
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1;
+  STATUS_BANNED = 2;
+  STATUS_DELETED = 3;
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc Watch(GetUserRequest) returns (stream User);
+  rpc Delete(GetUserRequest) returns (User);
+}
+
+message GetUserRequest { string id = 1; }
+`
+
+const headProto = `syntax = "proto3";
+package users.v1;
+
+message User {
+  reserved 3;
+  string id = 1;
+  bytes email = 2;
+  string tags = 4;
+  string nickname = 7;
+  message Address { string town = 1; }
+  .users.v1.User.Address address = 6;
+}
+
+enum Status {
+  reserved 3;
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 5;
+  STATUS_SUSPENDED = 2;
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (GetUserResponse);
+  rpc Watch(GetUserRequest) returns (User);
+}
+
+message GetUserRequest { string id = 1; }
+message GetUserResponse { User user = 1; }
+`
+
+func parse(t *testing.T, src string) map[string]*parser.ProtoFile {
+	t.Helper()
+	pf, err := parser.ParseProto([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseProto: %v", err)
+	}
+	return map[string]*parser.ProtoFile{"proto/users.proto": pf}
+}
+
+func TestCompare(t *testing.T) {
+	changes := Compare(parse(t, baseProto), parse(t, headProto))
+
+	got := make(map[string]Change)
+	for _, c := range changes {
+		got[c.Kind+" "+c.Element] = c
+	}
+	want := map[string]string{
+		"field-type-changed users.v1.User.email":              SeverityWarning,
+		"field-removed users.v1.User.age":                     SeverityWarning,
+		"field-label-changed users.v1.User.tags":              SeverityBreaking,
+		"field-renumbered users.v1.User.nickname":             SeverityBreaking,
+		"field-renamed users.v1.User.Address.city":            SeverityWarning,
+		"message-removed users.v1.Legacy":                     SeverityBreaking,
+		"enum-value-renumbered users.v1.Status.STATUS_ACTIVE": SeverityBreaking,
+		"enum-value-removed users.v1.Status.STATUS_DELETED":   SeverityWarning,
+		"rpc-type-changed users.v1.UserService.GetUser":       SeverityBreaking,
+		"rpc-streaming-changed users.v1.UserService.Watch":    SeverityBreaking,
+		"rpc-removed users.v1.UserService.Delete":             SeverityBreaking,
+	}
+	for key, sev := range want {
+		c, ok := got[key]
+		if !ok {
+			t.Errorf("missing change %q", key)
+			continue
+		}
+		if c.Severity != sev {
+			t.Errorf("%s: severity = %s, want %s", key, c.Severity, sev)
+		}
+		if c.File != "proto/users.proto" || c.Line == 0 {
+			t.Errorf("%s: location = %s:%d", key, c.File, c.Line)
+		}
+	}
+	if len(changes) != len(want) {
+		for _, c := range changes {
+			t.Logf("%s %s %s", c.Severity, c.Kind, c.Element)
+		}
+		t.Errorf("got %d changes, want %d", len(changes), len(want))
+	}
+	if changes[0].Severity != SeverityBreaking {
+		t.Errorf("changes not sorted by severity: first is %s", changes[0].Severity)
+	}
+}
+
+func TestCompare_MovedBetweenFiles(t *testing.T) {
+	old := map[string]*parser.ProtoFile{}
+	pf, _ := parser.ParseProto([]byte(`syntax = "proto3"; package a; message M { int64 id = 1; }`))
+	old["a.proto"] = pf
+	pf, _ = parser.ParseProto([]byte(`syntax = "proto3"; package a; message M { int64 id = 1; }`))
+	moved := map[string]*parser.ProtoFile{"b.proto": pf}
+
+	if changes := Compare(old, moved); len(changes) != 0 {
+		t.Errorf("moving a message between files reported %v", changes)
+	}
+}
+
+func TestWireCompatible(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"int32", "int64", true},
+		{"string", "bytes", true},
+		{"fixed32", "sfixed32", true},
+		{"int32", "sint32", false},
+		{"string", "User", false},
+	}
+	for _, tt := range tests {
+		if got := wireCompatible(tt.a, tt.b); got != tt.want {
+			t.Errorf("wireCompatible(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
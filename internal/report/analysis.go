@@ -0,0 +1,120 @@
+package report
+
+import (
+	"github.com/goscope/internal/cache"
+	"github.com/goscope/internal/config"
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/osv"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/protodiff"
+	"github.com/goscope/internal/scanner"
+)
+
+// Analysis is everything goscope computed for one codebase. It is the input
+// of both the HTML report and the JSON export.
+type Analysis struct {
+	Version        string // goscope version that produced the analysis
+	ProjectName    string
+	Root           string
+	Scan           *scanner.ScanResult
+	Files          []*parser.ParsedFile
+	Technologies   []string // from go.mod, docker-compose.yml and Makefile
+	DockerServices []string
+
+	Graph    *graph.DependencyGraph
+	Packages *graph.PackageGraph
+	Services *graph.ServiceGraph
+	GRPC     *graph.GRPCCoverage // proto services matched to their Go servers and clients
+	Calls    []graph.ServiceCall // runtime calls between microservices
+
+	Branch      string
+	AuthorStats map[string]*gitpkg.AuthorStats
+	Churn       []gitpkg.FileChurnStat
+	Tags        gitpkg.TagStats
+	Commits     gitpkg.CommitStats
+	Branches    gitpkg.BranchStats
+
+	ProtoDiff *protodiff.Diff // nil unless a base revision was given
+	Vulns     *osv.Report     // nil unless an OSV database is configured
+	Licenses  *license.Report // licenses of the required modules
+
+	TypeCheck bool         // give anti-pattern checks type information
+	Cache     *cache.Cache // results of earlier runs; nil when caching is disabled
+	Workers   int          // packages checked at once by the anti-pattern runner
+
+	HotspotCount int           // rows in the hot zones table; 0 means the default
+	Limits       config.Limits // section sizes; zero fields mean the defaults
+}
+
+// scan returns a.Scan, or an empty result when the analysis has none.
+func (a *Analysis) scan() *scanner.ScanResult {
+	if a.Scan == nil {
+		return &scanner.ScanResult{}
+	}
+	return a.Scan
+}
+
+// checkOptions returns the anti-pattern runner options for a.
+func (a *Analysis) checkOptions() CheckOptions {
+	return CheckOptions{
+		GitRepos:  a.scan().GitRepos,
+		Resolver:  a.scan().Resolver(),
+		TypeCheck: a.TypeCheck,
+		Root:      a.Root,
+		Cache:     a.Cache,
+		Workers:   a.Workers,
+
+		MaxViolations: a.limits().FindingsPerCheck,
+	}
+}
+
+// limits returns a.Limits with unset fields taken from the default config.
+func (a *Analysis) limits() config.Limits {
+	l, def := a.Limits, config.DefaultConfig().Limits
+	if l.TeamRows <= 0 {
+		l.TeamRows = def.TeamRows
+	}
+	if l.LongestFunctions <= 0 {
+		l.LongestFunctions = def.LongestFunctions
+	}
+	if l.FindingsPerCheck <= 0 {
+		l.FindingsPerCheck = def.FindingsPerCheck
+	}
+	if l.TypeRefDecls <= 0 {
+		l.TypeRefDecls = def.TypeRefDecls
+	}
+	return l
+}
+
+// hotspotCount returns how many hot zones the report lists.
+func (a *Analysis) hotspotCount() int {
+	if a.HotspotCount > 0 {
+		return a.HotspotCount
+	}
+	return config.DefaultConfig().HotspotCount
+}
+
+// techSet returns every technology in use: the detected ones plus those
+// implied by imports, proto files and non-Go services.
+func (a *Analysis) techSet() map[string]bool {
+	techSet := make(map[string]bool)
+	for _, t := range a.Technologies {
+		techSet[t] = true
+	}
+	techSet["Go"] = true
+	for _, f := range a.Files {
+		for _, imp := range f.Imports {
+			detectTechFromImport(imp, techSet)
+		}
+		if f.FileType == "proto" {
+			techSet["Protocol Buffers"] = true
+			techSet["gRPC"] = true
+		}
+	}
+	for _, fs := range a.scan().ForeignServices {
+		techSet[fs.Language] = true
+	}
+	return techSet
+}
@@ -1,16 +1,22 @@
 package report
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"github.com/goscope/internal/cache"
 	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/gomod"
 	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/pool"
 )
 
+// apMaxViolations is how many violations of a check a run reports unless
+// CheckOptions.MaxViolations or All says otherwise.
 const apMaxViolations = 10
 
 // priority values — used for sorting and badge colour.
@@ -21,17 +27,20 @@ const (
 )
 
 type apViolation struct {
-	File    string
+	File    string // shortened for display
+	Path    string // full file path
 	Line    int
 	Snippet string
 	Author  string
 }
 
 type apCheck struct {
+	ID          string // stable kebab-case identifier, e.g. "defer-in-loop"
 	Name        string
 	Description string
 	Priority    string // apHigh / apMedium / apLow
 	Detect      func(f *parser.ParsedFile, lines []string) []apViolation
+	DetectAST   func(s *apSource) []apViolation // used instead of Detect when set
 }
 
 type apResult struct {
@@ -42,40 +51,30 @@ type apResult struct {
 // ── shared regexes ────────────────────────────────────────────────────────────
 
 var (
-	reIgnoredErr      = regexp.MustCompile(`^\s*_\s*=\s*[\w.]+\(`)
-	reErrNotWrapped   = regexp.MustCompile(`errors\.New\(\w[\w.]*\.Error\(\)\)`)
-	rePanicCall       = regexp.MustCompile(`\bpanic\(`)
-	reTypeAssertNook  = regexp.MustCompile(`:=\s*\w[\w.()\[\]]*\.\(([A-Za-z*][\w.*]*)\)\s*(?:$|//)`)
-	reForLine         = regexp.MustCompile(`\bfor\b.*\{`)
-	reForRangeLine    = regexp.MustCompile(`\bfor\b.+:=\s*range\b`)
-	reDeferLine       = regexp.MustCompile(`^\s*defer\s`)
-	reDeferClose      = regexp.MustCompile(`^\s*defer\s+\w[\w.]*\.(?:Close|Flush|Sync)\s*\(\s*\)\s*(?:$|//)`)
-	reChanBuf         = regexp.MustCompile(`make\(\s*chan\b[^,)]+,\s*(\d+)`)
-	reTimeSleep       = regexp.MustCompile(`\btime\.Sleep\b`)
-	rePkgUnderscore   = regexp.MustCompile(`^package\s+[a-z]+_[a-z]`)
-	reInitFunc        = regexp.MustCompile(`^\s*func\s+init\s*\(\s*\)`)
-	reFmtSprintfInt   = regexp.MustCompile(`fmt\.Sprintf\s*\(\s*"%d"\s*,`)
-	reHardSecret      = regexp.MustCompile(`(?i)(password|passwd|secret|apikey|api_key|authkey)\s*[:=]+\s*"([^"]{8,})"`)
-	reSQLConcat       = regexp.MustCompile(`\.(Query|Exec|QueryRow|QueryContext|ExecContext)\s*\([^)]*\+`)
-	reSQLSprintf      = regexp.MustCompile(`\.(Query|Exec|QueryRow|QueryContext|ExecContext)\s*\(\s*fmt\.Sprintf`)
-	reMathRandImport  = regexp.MustCompile(`"math/rand"`)
-	reBytesInLoop     = regexp.MustCompile(`\[\]byte\(`)
-	reRowsErrCheck    = regexp.MustCompile(`rows\.Err\(\)`)
-	reRowsClose       = regexp.MustCompile(`rows\.(?:Close|close)\(\)`)
-	reRowsNext        = regexp.MustCompile(`\.Next\(\)`)
-	reHTTPClientCall  = regexp.MustCompile(`\b(http\.(Get|Post|Put|Delete|Head)|\.Do\(|\.Get\(|\.Post\()`)
-	reBodyClose       = regexp.MustCompile(`defer.*\.Body\.Close\(\)`)
-	reGoFunc          = regexp.MustCompile(`\bgo\s+func\b`)
-	reNakedReturn     = regexp.MustCompile(`^\s*return\s*(?://.*)?$`)
-	rePtrToIface      = regexp.MustCompile(`\*interface\{`)
-	reMakeNoCapacity  = regexp.MustCompile(`make\(\s*\[\][\w\[\]*]+,\s*0\s*\)`)
-	reVarSlice        = regexp.MustCompile(`^\s*var\s+(\w+)\s+\[\]`)
-	reAppend          = regexp.MustCompile(`\bappend\(`)
-	reErrDecl         = regexp.MustCompile(`,\s*err\s*:=|^\s*err\s*:=`)
-	reTypeStructStart = regexp.MustCompile(`^type\s+(\w+)\s+struct\b`)
-	reFieldMutex      = regexp.MustCompile(`\bsync\.(RW)?Mutex\b`)
-	reTestErrAssign   = regexp.MustCompile(`,\s*err\s*:=|^\s*err\s*:=`)
-	reTestErrCheck    = regexp.MustCompile(`err\s*!=\s*nil|require\.NoError|assert\.NoError|t\.Fatal|t\.Error\s*\(\s*err`)
+	reIgnoredErr     = regexp.MustCompile(`^\s*_\s*=\s*[\w.]+\(`)
+	reErrNotWrapped  = regexp.MustCompile(`errors\.New\(\w[\w.]*\.Error\(\)\)`)
+	rePanicCall      = regexp.MustCompile(`\bpanic\(`)
+	reTypeAssertNook = regexp.MustCompile(`:=\s*\w[\w.()\[\]]*\.\(([A-Za-z*][\w.*]*)\)\s*(?:$|//)`)
+	reForLine        = regexp.MustCompile(`\bfor\b.*\{`)
+	reDeferClose     = regexp.MustCompile(`^\s*defer\s+\w[\w.]*\.(?:Close|Flush|Sync)\s*\(\s*\)\s*(?:$|//)`)
+	reChanBuf        = regexp.MustCompile(`make\(\s*chan\b[^,)]+,\s*(\d+)`)
+	reTimeSleep      = regexp.MustCompile(`\btime\.Sleep\b`)
+	rePkgUnderscore  = regexp.MustCompile(`^package\s+[a-z]+_[a-z]`)
+	reInitFunc       = regexp.MustCompile(`^\s*func\s+init\s*\(\s*\)`)
+	reFmtSprintfInt  = regexp.MustCompile(`fmt\.Sprintf\s*\(\s*"%d"\s*,`)
+	reHardSecret     = regexp.MustCompile(`(?i)(password|passwd|secret|apikey|api_key|authkey)\s*[:=]+\s*"([^"]{8,})"`)
+	reSQLConcat      = regexp.MustCompile(`\.(Query|Exec|QueryRow|QueryContext|ExecContext)\s*\([^)]*\+`)
+	reSQLSprintf     = regexp.MustCompile(`\.(Query|Exec|QueryRow|QueryContext|ExecContext)\s*\(\s*fmt\.Sprintf`)
+	reMathRandImport = regexp.MustCompile(`"math/rand"`)
+	reBytesInLoop    = regexp.MustCompile(`\[\]byte\(`)
+	reNakedReturn    = regexp.MustCompile(`^\s*return\s*(?://.*)?$`)
+	rePtrToIface     = regexp.MustCompile(`\*interface\{`)
+	reMakeNoCapacity = regexp.MustCompile(`make\(\s*\[\][\w\[\]*]+,\s*0\s*\)`)
+	reVarSlice       = regexp.MustCompile(`^\s*var\s+(\w+)\s+\[\]`)
+	reAppend         = regexp.MustCompile(`\bappend\(`)
+	reErrDecl        = regexp.MustCompile(`,\s*err\s*:=|^\s*err\s*:=`)
+	reTestErrAssign  = regexp.MustCompile(`,\s*err\s*:=|^\s*err\s*:=`)
+	reTestErrCheck   = regexp.MustCompile(`err\s*!=\s*nil|require\.NoError|assert\.NoError|t\.Fatal|t\.Error\s*\(\s*err`)
 )
 
 // ── helpers ───────────────────────────────────────────────────────────────────
@@ -145,9 +144,6 @@ func checkHardcodedSecret(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if !skip {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -158,9 +154,6 @@ func checkSQLInjection(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reSQLConcat.MatchString(line) || reSQLSprintf.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -189,9 +182,6 @@ func checkPanic(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if rePanicCall.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -205,123 +195,6 @@ func checkTypeAssertNook(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if reTypeAssertNook.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
-		}
-	}
-	return out
-}
-
-func checkUnclosedResponseBody(f *parser.ParsedFile, lines []string) []apViolation {
-	var out []apViolation
-	for i, line := range lines {
-		if !reHTTPClientCall.MatchString(line) || !strings.Contains(line, ":=") {
-			continue
-		}
-		hasClose := false
-		for j := i; j < len(lines) && j < i+6; j++ {
-			if reBodyClose.MatchString(lines[j]) {
-				hasClose = true
-				break
-			}
-		}
-		if !hasClose {
-			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
-		}
-	}
-	return out
-}
-
-func checkLoopVarGoroutine(f *parser.ParsedFile, lines []string) []apViolation {
-	var out []apViolation
-	for i, line := range lines {
-		if !reForRangeLine.MatchString(line) {
-			continue
-		}
-		// Look for go func() within the next 20 lines
-		for j := i + 1; j < len(lines) && j < i+20; j++ {
-			jLine := lines[j]
-			if !reGoFunc.MatchString(jLine) {
-				continue
-			}
-			// Check that the loop variable isn't re-declared (v := v pattern)
-			hasCapture := true
-			for k := j; k < len(lines) && k < j+10; k++ {
-				// v := v pattern indicates correct capture
-				if regexp.MustCompile(`\w+\s*:=\s*\w+`).MatchString(lines[k]) &&
-					strings.Count(lines[k], ":=") == 1 {
-					parts := strings.SplitN(lines[k], ":=", 2)
-					lhs := strings.TrimSpace(parts[0])
-					rhs := strings.TrimSpace(parts[1])
-					if lhs == rhs {
-						hasCapture = false
-						break
-					}
-				}
-			}
-			if hasCapture {
-				out = append(out, viol(f, j, lines))
-				if len(out) >= apMaxViolations {
-					break
-				}
-			}
-			break
-		}
-		if len(out) >= apMaxViolations {
-			break
-		}
-	}
-	return out
-}
-
-func checkCopyMutex(f *parser.ParsedFile, lines []string) []apViolation {
-	// Step 1: collect struct type names that embed sync.Mutex/RWMutex
-	mutexTypes := make(map[string]bool)
-	var curType string
-	structDepth := 0
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if m := reTypeStructStart.FindStringSubmatch(trimmed); m != nil {
-			curType = m[1]
-			structDepth = 1
-			continue
-		}
-		if structDepth > 0 {
-			structDepth += strings.Count(line, "{") - strings.Count(line, "}")
-			if reFieldMutex.MatchString(line) && curType != "" {
-				mutexTypes[curType] = true
-			}
-			if structDepth <= 0 {
-				structDepth = 0
-				curType = ""
-			}
-		}
-	}
-	if len(mutexTypes) == 0 {
-		return nil
-	}
-
-	// Step 2: flag function signatures that accept mutex-containing types by value
-	var out []apViolation
-	for i, line := range lines {
-		if !strings.Contains(line, "func ") {
-			continue
-		}
-		for typeName := range mutexTypes {
-			reByVal := regexp.MustCompile(`[^*\w]` + regexp.QuoteMeta(typeName) + `[\s,)]`)
-			reByPtr := regexp.MustCompile(`\*` + regexp.QuoteMeta(typeName) + `\b`)
-			if reByVal.MatchString(line) && !reByPtr.MatchString(line) {
-				out = append(out, viol(f, i, lines))
-				break
-			}
-		}
-		if len(out) >= apMaxViolations {
-			break
 		}
 	}
 	return out
@@ -337,9 +210,6 @@ func checkIgnoredErrors(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if reIgnoredErr.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -350,9 +220,6 @@ func checkErrorNotWrapped(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reErrNotWrapped.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -380,9 +247,6 @@ func checkErrShadowing(f *parser.ParsedFile, lines []string) []apViolation {
 				break
 			}
 		}
-		if len(out) >= apMaxViolations {
-			break
-		}
 	}
 	return out
 }
@@ -392,143 +256,6 @@ func checkUncheckedClose(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reDeferClose.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
-		}
-	}
-	return out
-}
-
-func checkDeferInLoop(f *parser.ParsedFile, lines []string) []apViolation {
-	var out []apViolation
-	forDepth := 0
-	braceStack := []int{}
-
-	for i, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), "//") {
-			continue
-		}
-		opens := strings.Count(line, "{")
-		closes := strings.Count(line, "}")
-		isFor := reForLine.MatchString(line)
-		if isFor && opens > closes {
-			braceStack = append(braceStack, 0)
-			forDepth++
-		}
-		if forDepth > 0 && reDeferLine.MatchString(line) {
-			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
-		}
-		if closes > opens && forDepth > 0 {
-			forDepth--
-			if len(braceStack) > 0 {
-				braceStack = braceStack[:len(braceStack)-1]
-			}
-		}
-	}
-	return out
-}
-
-func checkSQLRowsErr(f *parser.ParsedFile, lines []string) []apViolation {
-	type funcBlock struct {
-		start   int
-		hasNext bool
-		hasErr  bool
-	}
-	var blocks []funcBlock
-	var cur *funcBlock
-	depth := 0
-
-	for i, line := range lines {
-		opens := strings.Count(line, "{")
-		closes := strings.Count(line, "}")
-		if strings.Contains(line, "func ") && opens > closes {
-			b := funcBlock{start: i}
-			blocks = append(blocks, b)
-			cur = &blocks[len(blocks)-1]
-			depth = 1
-			continue
-		}
-		if cur != nil {
-			if reRowsNext.MatchString(line) {
-				cur.hasNext = true
-			}
-			if reRowsErrCheck.MatchString(line) {
-				cur.hasErr = true
-			}
-			depth += opens - closes
-			if depth <= 0 {
-				cur = nil
-				depth = 0
-			}
-		}
-	}
-
-	var out []apViolation
-	for _, b := range blocks {
-		if b.hasNext && !b.hasErr {
-			out = append(out, apViolation{
-				File:    apDisplayPath(f.FilePath),
-				Line:    b.start + 1,
-				Snippet: apSnippet(lines[b.start]),
-			})
-			if len(out) >= apMaxViolations {
-				break
-			}
-		}
-	}
-	return out
-}
-
-func checkRowsClose(f *parser.ParsedFile, lines []string) []apViolation {
-	type funcBlock struct {
-		start    int
-		hasNext  bool
-		hasClose bool
-	}
-	var blocks []funcBlock
-	var cur *funcBlock
-	depth := 0
-
-	for i, line := range lines {
-		opens := strings.Count(line, "{")
-		closes := strings.Count(line, "}")
-		if strings.Contains(line, "func ") && opens > closes {
-			b := funcBlock{start: i}
-			blocks = append(blocks, b)
-			cur = &blocks[len(blocks)-1]
-			depth = 1
-			continue
-		}
-		if cur != nil {
-			if reRowsNext.MatchString(line) {
-				cur.hasNext = true
-			}
-			if reRowsClose.MatchString(line) {
-				cur.hasClose = true
-			}
-			depth += opens - closes
-			if depth <= 0 {
-				cur = nil
-				depth = 0
-			}
-		}
-	}
-
-	var out []apViolation
-	for _, b := range blocks {
-		if b.hasNext && !b.hasClose {
-			out = append(out, apViolation{
-				File:    apDisplayPath(f.FilePath),
-				Line:    b.start + 1,
-				Snippet: apSnippet(lines[b.start]),
-			})
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -552,9 +279,6 @@ func checkTestErrorCheck(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if !hasCheck {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -568,9 +292,6 @@ func checkTimeSleepSync(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reTimeSleep.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -584,9 +305,6 @@ func checkTimeSleepInTests(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reTimeSleep.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -604,9 +322,6 @@ func checkBigChannelBuffer(f *parser.ParsedFile, lines []string) []apViolation {
 			continue
 		}
 		out = append(out, viol(f, i, lines))
-		if len(out) >= apMaxViolations {
-			break
-		}
 	}
 	return out
 }
@@ -616,9 +331,6 @@ func checkNakedReturn(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reNakedReturn.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -632,9 +344,6 @@ func checkPtrToInterface(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if rePtrToIface.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -662,9 +371,6 @@ func checkSlicePrealloc(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if hasLoop && hasAppend {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -687,9 +393,6 @@ func checkInitFunc(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reInitFunc.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -700,9 +403,6 @@ func checkFmtSprintfInt(f *parser.ParsedFile, lines []string) []apViolation {
 	for i, line := range lines {
 		if reFmtSprintfInt.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 	}
 	return out
@@ -722,9 +422,6 @@ func checkBytesInLoop(f *parser.ParsedFile, lines []string) []apViolation {
 		}
 		if forDepth > 0 && reBytesInLoop.MatchString(line) {
 			out = append(out, viol(f, i, lines))
-			if len(out) >= apMaxViolations {
-				break
-			}
 		}
 		net := closes - opens
 		if net > 0 && forDepth > 0 {
@@ -743,79 +440,92 @@ func goAntipatternChecks() []apCheck {
 	return []apCheck{
 		// ── HIGH ──────────────────────────────────────────────────────────────
 		{
+			ID:          "hardcoded-secret",
 			Name:        "Hardcoded Secrets",
 			Priority:    apHigh,
 			Description: "Hardcoding passwords, secrets, or API keys embeds credentials into version history permanently. Load secrets from environment variables or a secrets manager at runtime. Any credential found here must be rotated immediately — consider it compromised.",
 			Detect:      checkHardcodedSecret,
 		},
 		{
+			ID:          "sql-injection",
 			Name:        "SQL Injection via String Concatenation",
 			Priority:    apHigh,
 			Description: "Building SQL queries with `+` or `fmt.Sprintf` allows attackers to inject arbitrary SQL. Always use parameterized queries: `db.Query(\"SELECT ... WHERE id = $1\", id)`. This is one of the most critical security vulnerabilities.",
 			Detect:      checkSQLInjection,
 		},
 		{
+			ID:          "math-rand",
 			Name:        "math/rand Used (Use crypto/rand for Secrets)",
 			Priority:    apHigh,
 			Description: "`math/rand` produces predictable pseudo-random numbers. Never use it for security-sensitive values (tokens, keys, nonces, session IDs). Use `crypto/rand` for anything that must be cryptographically unpredictable.",
 			Detect:      checkMathRand,
 		},
 		{
+			ID:          "panic",
 			Name:        "panic() in Business Logic",
 			Priority:    apHigh,
 			Description: "Reserve `panic` for truly unrecoverable initialization failures. Business logic must return an `error` so callers can handle it gracefully. A `panic` in a goroutine crashes the entire process.",
 			Detect:      checkPanic,
 		},
 		{
+			ID:          "unchecked-type-assertion",
 			Name:        "Type Assertion Without ok",
 			Priority:    apHigh,
 			Description: "`x := iface.(MyType)` panics if the interface holds a different concrete type. Always use the two-value form: `x, ok := iface.(MyType)` and check `ok` before using `x`.",
 			Detect:      checkTypeAssertNook,
 		},
 		{
+			ID:          "unclosed-response-body",
 			Name:        "Unclosed HTTP Response Body",
 			Priority:    apHigh,
 			Description: "Every HTTP response body must be closed to return the underlying connection to the pool. Add `defer resp.Body.Close()` immediately after checking the error from `http.Get` / `client.Do`. Missing this leaks connections and file descriptors.",
-			Detect:      checkUnclosedResponseBody,
+			DetectAST:   checkUnclosedResponseBody,
 		},
 		{
+			ID:          "loop-var-goroutine",
 			Name:        "Loop Variable Captured in Goroutine",
 			Priority:    apHigh,
 			Description: "A goroutine that closes over a `for … range` loop variable reads the variable's value at runtime, not at launch time. By then the loop may have advanced. Capture it explicitly: `v := v` before the `go func()`. Note: fixed in Go 1.22+ per-iteration semantics.",
-			Detect:      checkLoopVarGoroutine,
+			DetectAST:   checkLoopVarGoroutine,
 		},
 		{
+			ID:          "copy-mutex",
 			Name:        "Copying sync.Mutex",
 			Priority:    apHigh,
 			Description: "Passing a struct containing `sync.Mutex` (or `sync.RWMutex`) by value copies the lock state and silently breaks locking — the copy and the original have independent lock counts. Always pass such structs by pointer. `go vet` catches this as `copylocks`.",
-			Detect:      checkCopyMutex,
+			DetectAST:   checkCopyMutex,
 		},
 		// ── MEDIUM ────────────────────────────────────────────────────────────
 		{
+			ID:          "error-not-wrapped",
 			Name:        "Error Not Wrapped With %w",
 			Priority:    apMedium,
 			Description: "`errors.New(err.Error())` converts the error to a plain string and breaks the error chain, making `errors.Is` / `errors.As` useless for callers. Use `fmt.Errorf(\"context: %w\", err)` to preserve the original error.",
 			Detect:      checkErrorNotWrapped,
 		},
 		{
+			ID:          "defer-in-loop",
 			Name:        "defer Inside a Loop",
 			Priority:    apMedium,
 			Description: "A `defer` inside a `for` loop does not execute per iteration — it queues up and runs only when the enclosing function returns. This accumulates file handles, database connections, or locks for the entire loop duration. Call `Close()` explicitly inside the loop body.",
-			Detect:      checkDeferInLoop,
+			DetectAST:   checkDeferInLoop,
 		},
 		{
+			ID:          "sql-rows-err",
 			Name:        "SQL Rows — Missing rows.Err() Check",
 			Priority:    apMedium,
 			Description: "After `for rows.Next() { ... }`, always call `if err := rows.Err(); err != nil { ... }`. Network interruptions or context cancellations during iteration are only surfaced through `rows.Err()` — `rows.Next()` returning false is not sufficient.",
-			Detect:      checkSQLRowsErr,
+			DetectAST:   checkSQLRowsErr,
 		},
 		{
+			ID:          "sql-rows-close",
 			Name:        "SQL Rows — rows.Close() Not Called",
 			Priority:    apMedium,
 			Description: "Every `sql.Rows` value must have `rows.Close()` called to release the database connection. Omitting it holds the connection open for the lifetime of the enclosing function. Use `defer rows.Close()` immediately after checking the error from `db.Query`.",
-			Detect:      checkRowsClose,
+			DetectAST:   checkRowsClose,
 		},
 		{
+			ID:          "sleep-sync",
 			Name:        "time.Sleep for Goroutine Synchronization",
 			Priority:    apMedium,
 			Description: "`time.Sleep` is not a reliable synchronization primitive — it creates flaky, timing-dependent code that fails under load or on slow CI machines. Use `sync.WaitGroup`, channels, or `sync/atomic` to coordinate goroutines.",
@@ -823,54 +533,63 @@ func goAntipatternChecks() []apCheck {
 		},
 		// ── LOW ───────────────────────────────────────────────────────────────
 		{
+			ID:          "large-chan-buffer",
 			Name:        "Large Channel Buffer",
 			Priority:    apLow,
 			Description: "A channel buffer larger than 1 usually hides a concurrency design problem. Buffers of 0 (synchronous handoff) or 1 (one-item decoupling) are almost always the right choice. Larger buffers often mask missing backpressure or rate-limiting.",
 			Detect:      checkBigChannelBuffer,
 		},
 		{
+			ID:          "sleep-in-test",
 			Name:        "time.Sleep in Tests",
 			Priority:    apLow,
 			Description: "Tests that sleep are slow and flaky. Use `sync.WaitGroup`, channels with `select`/timeout, or `testify/assert.Eventually` to wait for asynchronous conditions deterministically instead of sleeping for an arbitrary duration.",
 			Detect:      checkTimeSleepInTests,
 		},
 		{
+			ID:          "naked-return",
 			Name:        "Naked Returns",
 			Priority:    apLow,
 			Description: "Bare `return` in a function with named return values silently returns whatever the named variables hold at that point. In functions longer than a few lines this makes it impossible to tell at the call site what is being returned. Always return values explicitly.",
 			Detect:      checkNakedReturn,
 		},
 		{
+			ID:          "pointer-to-interface",
 			Name:        "Pointer to Interface",
 			Priority:    apLow,
 			Description: "An interface value already can hold a pointer internally. Taking the address of an interface (`*interface{}`) adds a level of indirection with no benefit, complicates nil checks, and prevents the compiler from using the interface's dynamic dispatch correctly.",
 			Detect:      checkPtrToInterface,
 		},
 		{
+			ID:          "slice-prealloc",
 			Name:        "Missing Slice Pre-allocation",
 			Priority:    apLow,
 			Description: "`var s []T` or `make([]T, 0)` without a capacity causes the runtime to reallocate and copy the backing array as the slice grows (typically doubling at 1, 2, 4, 8 … elements). If the final length is known or estimable, use `make([]T, 0, expectedLen)` to allocate once.",
 			Detect:      checkSlicePrealloc,
 		},
 		{
+			ID:          "package-underscore",
 			Name:        "Package Name Contains Underscore",
 			Priority:    apLow,
 			Description: "Go package names should be short, lowercase, and without underscores. Underscores are a sign the package could be split or renamed. Prefer `httputil` over `http_util`. The Go naming convention is documented in Effective Go.",
 			Detect:      checkPackageUnderscore,
 		},
 		{
+			ID:          "init-func",
 			Name:        "init() Function",
 			Priority:    apLow,
 			Description: "`init()` runs automatically at package load time in an implicit, hard-to-control order. This makes unit testing harder and creates hidden dependencies between packages. Prefer explicit initialization functions called from `main()` or dependency-injection constructors.",
 			Detect:      checkInitFunc,
 		},
 		{
+			ID:          "sprintf-int",
 			Name:        "fmt.Sprintf for Integer → String",
 			Priority:    apLow,
 			Description: "`fmt.Sprintf(\"%d\", n)` involves reflection and allocates more than necessary. Use `strconv.Itoa(n)` (for int) or `strconv.FormatInt(n, 10)` (for int64) — they are faster and allocation-efficient, especially in hot paths.",
 			Detect:      checkFmtSprintfInt,
 		},
 		{
+			ID:          "bytes-in-loop",
 			Name:        "[]byte Conversion in Loop",
 			Priority:    apLow,
 			Description: "Each `[]byte(str)` call allocates and copies the string's bytes. Inside a loop this generates O(n) garbage. Cache the converted slice before the loop, or restructure the code to use `strings` package functions or a reused `[]byte` buffer.",
@@ -881,47 +600,204 @@ func goAntipatternChecks() []apCheck {
 
 // ── runner ────────────────────────────────────────────────────────────────────
 
-func runAntipatterns(files []*parser.ParsedFile, gitRepos []string) []apResult {
+// CheckOptions configures an anti-pattern run.
+type CheckOptions struct {
+	GitRepos  []string        // repositories used to blame violations
+	Resolver  *gomod.Resolver // local modules, for each file's go version and import path
+	TypeCheck bool            // type-check packages so checks can use type information
+
+	Root     string    // analysis root; baseline paths are relative to it
+	Baseline *Baseline // findings matching an entry are dropped
+	All      bool      // keep every violation instead of the first few per check
+
+	MaxViolations int // violations kept per check unless All; 0 means apMaxViolations
+
+	Cache *cache.Cache // reuses per-package results and blame of earlier runs; may be nil
+
+	Workers  int                   // packages checked and files blamed at once; below 1 means one
+	Context  context.Context       // stops the run early when done; nil means never
+	Progress func(done, total int) // called as packages finish; may be nil
+}
+
+func (o CheckOptions) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// runAntipatterns checks packages concurrently but filters and merges their
+// violations in file order, so the result does not depend on Workers. When
+// the context is cancelled the results are incomplete.
+func runAntipatterns(files []*parser.ParsedFile, opts CheckOptions) []apResult {
 	checks := goAntipatternChecks()
 	results := make([]apResult, len(checks))
 	for i, ch := range checks {
 		results[i].Check = ch
 	}
-	blameCache := make(map[string]map[int]string)
-	for _, f := range files {
-		if f.FileType == "proto" || strings.HasSuffix(f.FilePath, ".pb.go") {
-			continue
-		}
-		data, err := os.ReadFile(f.FilePath)
-		if err != nil {
-			continue
-		}
-		lines := strings.Split(string(data), "\n")
-		for i := range results {
-			vs := results[i].Check.Detect(f, lines)
-			if len(vs) > 0 && len(gitRepos) > 0 {
-				blame, ok := blameCache[f.FilePath]
-				if !ok {
-					blame = gitpkg.BlameAuthors(gitRepos, f.FilePath)
-					blameCache[f.FilePath] = blame
-				}
-				for j := range vs {
-					if blame != nil {
-						vs[j].Author = blame[vs[j].Line]
+	loader := newAPLoader(opts)
+	pkgs := groupByDir(files)
+	var done atomic.Int64
+	detected, _ := pool.Map(opts.context(), opts.Workers, len(pkgs), func(i int) []apFileResult {
+		frs := loader.detect(pkgs[i], checks)
+		if opts.Progress != nil {
+			opts.Progress(int(done.Add(1)), len(pkgs))
+		}
+		return frs
+	})
+
+	known := opts.Baseline.counts()
+	for _, frs := range detected {
+		for _, fr := range frs {
+			for i, ch := range checks {
+				for _, v := range fr.Violations[i] {
+					if fr.Ignored[v.Line][ch.ID] {
+						continue
+					}
+					if e := baselineEntry(ch.ID, v.Path, v.Snippet, opts.Root); known[e] > 0 {
+						known[e]--
+						continue
 					}
+					results[i].Violations = append(results[i].Violations, v)
 				}
 			}
-			results[i].Violations = append(results[i].Violations, vs...)
 		}
 	}
+	limit := opts.MaxViolations
+	if limit <= 0 {
+		limit = apMaxViolations
+	}
 	for i := range results {
-		if len(results[i].Violations) > apMaxViolations {
-			results[i].Violations = results[i].Violations[:apMaxViolations]
+		if !opts.All && len(results[i].Violations) > limit {
+			results[i].Violations = results[i].Violations[:limit]
 		}
 	}
+	if len(opts.GitRepos) > 0 {
+		blameViolations(files, results, opts)
+	}
 	return results
 }
 
+// blameViolations sets the author of every reported violation, running git
+// blame once per file.
+func blameViolations(files []*parser.ParsedFile, results []apResult, opts CheckOptions) {
+	byPath := make(map[string]*parser.ParsedFile, len(files))
+	for _, f := range files {
+		byPath[f.FilePath] = f
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		for _, v := range r.Violations {
+			if !seen[v.Path] && byPath[v.Path] != nil {
+				seen[v.Path] = true
+				paths = append(paths, v.Path)
+			}
+		}
+	}
+	blame := newAPBlamer(opts)
+	authors, _ := pool.Map(opts.context(), opts.Workers, len(paths), func(i int) map[int]string {
+		return blame.authors(byPath[paths[i]])
+	})
+	byFile := make(map[string]map[int]string, len(paths))
+	for i, p := range paths {
+		byFile[p] = authors[i]
+	}
+	for _, r := range results {
+		for j := range r.Violations {
+			v := &r.Violations[j]
+			v.Author = byFile[v.Path][v.Line]
+		}
+	}
+}
+
+// apBlamer runs git blame for a file, caching the result on disk under the
+// file contents and the HEAD commit of every repository holding it. It is
+// safe for concurrent use.
+type apBlamer struct {
+	opts  CheckOptions
+	heads map[string]string // repo → HEAD commit, "" when unresolvable
+}
+
+func newAPBlamer(opts CheckOptions) *apBlamer {
+	b := &apBlamer{opts: opts, heads: make(map[string]string)}
+	if opts.Cache != nil {
+		for _, repo := range opts.GitRepos {
+			b.heads[repo], _ = gitpkg.ResolveRevision(repo, "HEAD")
+		}
+	}
+	return b
+}
+
+// authors maps f's 1-based line numbers to the author of their last change.
+func (b *apBlamer) authors(f *parser.ParsedFile) map[int]string {
+	key := ""
+	if f.ContentHash != "" && b.opts.Cache != nil {
+		parts := []string{"blame", f.FilePath, f.ContentHash}
+		for _, repo := range b.opts.GitRepos {
+			if strings.HasPrefix(f.FilePath, repo) {
+				parts = append(parts, repo, b.heads[repo])
+			}
+		}
+		key = cache.Key(parts...)
+	}
+	var authors map[int]string
+	if key == "" || !b.opts.Cache.Get(key, &authors) {
+		authors = gitpkg.BlameAuthors(b.opts.context(), b.opts.GitRepos, f.FilePath)
+		if key != "" && b.opts.context().Err() == nil {
+			b.opts.Cache.Put(key, authors)
+		}
+	}
+	return authors
+}
+
+// Finding is a single anti-pattern violation flattened for non-HTML consumers
+// such as the `goscope check` command.
+type Finding struct {
+	ID       string `json:"id"`
+	Check    string `json:"check"`
+	Priority string `json:"priority"`
+	File     string `json:"file"` // last path elements, for display
+	Path     string `json:"path"` // full file path
+	Line     int    `json:"line"`
+	Snippet  string `json:"snippet"`
+	Author   string `json:"author,omitempty"`
+}
+
+// Priority levels reported in Finding.Priority.
+const (
+	PriorityHigh   = apHigh
+	PriorityMedium = apMedium
+	PriorityLow    = apLow
+)
+
+// Findings runs all anti-pattern checks and returns their violations ordered
+// by priority (HIGH first), keeping the registry order within a priority.
+func Findings(files []*parser.ParsedFile, opts CheckOptions) []Finding {
+	results := runAntipatterns(files, opts)
+	var out []Finding
+	for _, pri := range []string{apHigh, apMedium, apLow} {
+		for _, r := range results {
+			if r.Check.Priority != pri {
+				continue
+			}
+			for _, v := range r.Violations {
+				out = append(out, Finding{
+					ID:       r.Check.ID,
+					Check:    r.Check.Name,
+					Priority: r.Check.Priority,
+					File:     v.File,
+					Path:     v.Path,
+					Line:     v.Line,
+					Snippet:  v.Snippet,
+					Author:   v.Author,
+				})
+			}
+		}
+	}
+	return out
+}
+
 // ── HTML builder ──────────────────────────────────────────────────────────────
 
 func apPriorityBadge(p string) string {
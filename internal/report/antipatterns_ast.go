@@ -0,0 +1,796 @@
+package report
+
+import (
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goscope/internal/cache"
+	"github.com/goscope/internal/parser"
+)
+
+// apSource is the input of AST-based checks: one Go file with its syntax
+// tree and, when type checking is enabled and succeeds, type information
+// for the enclosing package.
+type apSource struct {
+	File      *parser.ParsedFile
+	Lines     []string
+	Fset      *token.FileSet
+	AST       *ast.File
+	Info      *types.Info // nil when type information is unavailable
+	GoVersion string      // go directive of the enclosing module, "" if unknown
+}
+
+// violation returns the violation reported at pos.
+func (s *apSource) violation(pos token.Pos) apViolation {
+	return viol(s.File, s.Fset.Position(pos).Line-1, s.Lines)
+}
+
+// object identifies the variable id refers to: its types.Object when type
+// information is available, otherwise the parser's scope object. Two
+// identifiers refer to the same variable when their objects are equal.
+func (s *apSource) object(id *ast.Ident) any {
+	if s.Info != nil {
+		if obj := s.Info.ObjectOf(id); obj != nil {
+			return obj
+		}
+	}
+	if id.Obj != nil {
+		return id.Obj
+	}
+	return nil
+}
+
+// importName returns the name under which the file imports path, or "" if
+// it does not.
+func (s *apSource) importName(path string) string {
+	for _, imp := range s.AST.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p != path {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
+
+// isNamed reports whether t, or the type it points to, is the named type
+// pkgPath.name.
+func isNamed(t types.Type, pkgPath, name string) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, ok := t.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	return n.Obj().Pkg().Path() == pkgPath && n.Obj().Name() == name
+}
+
+// isPkgCall reports whether call is pkg.<one of names>(...), with pkg the
+// local name of an import.
+func isPkgCall(call *ast.CallExpr, pkg string, names ...string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || pkg == "" {
+		return false
+	}
+	if id, ok := sel.X.(*ast.Ident); !ok || id.Name != pkg {
+		return false
+	}
+	for _, n := range names {
+		if sel.Sel.Name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// methodCall returns the receiver identifier and method name of a call of
+// the form x.Method(...).
+func methodCall(call *ast.CallExpr) (*ast.Ident, string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, ""
+	}
+	id, _ := sel.X.(*ast.Ident)
+	return id, sel.Sel.Name
+}
+
+// funcBodies calls fn for the body of every function declaration and
+// function literal in the file.
+func funcBodies(file *ast.File, fn func(body *ast.BlockStmt)) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				fn(n.Body)
+			}
+		case *ast.FuncLit:
+			fn(n.Body)
+		}
+		return true
+	})
+}
+
+// inspectSkippingFuncLits walks n like ast.Inspect without descending into
+// function literals, whose statements run in a different function.
+func inspectSkippingFuncLits(n ast.Node, fn func(ast.Node) bool) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		return fn(n)
+	})
+}
+
+// escapes reports whether the variable obj leaves body: it is returned,
+// passed to a function other than as a method receiver, stored in a
+// composite literal or assigned to another variable. Ownership of a
+// resource that escapes belongs to someone else. obj must not be nil.
+func (s *apSource) escapes(body *ast.BlockStmt, obj any) bool {
+	is := func(e ast.Expr) bool {
+		id, ok := ast.Unparen(e).(*ast.Ident)
+		return ok && s.object(id) == obj
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				found = found || is(r)
+			}
+		case *ast.CallExpr:
+			for _, a := range n.Args {
+				found = found || is(a)
+			}
+		case *ast.CompositeLit:
+			for _, e := range n.Elts {
+				if kv, ok := e.(*ast.KeyValueExpr); ok {
+					e = kv.Value
+				}
+				found = found || is(e)
+			}
+		case *ast.AssignStmt:
+			for _, r := range n.Rhs {
+				found = found || is(r)
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// calledMethod reports whether body calls method on the variable obj.
+func (s *apSource) calledMethod(body *ast.BlockStmt, obj any, method string) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if id, name := methodCall(call); id != nil && name == method && s.object(id) == obj {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// goVersionAtLeast reports whether the go directive v is at least
+// major.minor. An empty or malformed version is treated as old.
+func goVersionAtLeast(v string, major, minor int) bool {
+	parts := strings.SplitN(strings.TrimPrefix(v, "go"), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	vMajor, err1 := strconv.Atoi(parts[0])
+	vMinor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return vMajor > major || vMajor == major && vMinor >= minor
+}
+
+// ── AST checks ────────────────────────────────────────────────────────────────
+
+// checkLoopVarGoroutine flags `go func() { … }()` statements whose
+// function literal reads a variable declared by an enclosing for or range
+// clause. Variables passed as arguments or re-declared inside the loop are
+// not captures. Modules on Go 1.22+ get per-iteration variables and are
+// skipped.
+func checkLoopVarGoroutine(s *apSource) []apViolation {
+	if goVersionAtLeast(s.GoVersion, 1, 22) {
+		return nil
+	}
+	var out []apViolation
+	reported := make(map[token.Pos]bool)
+	ast.Inspect(s.AST, func(n ast.Node) bool {
+		var vars []*ast.Ident
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, e := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+						vars = append(vars, id)
+					}
+				}
+			}
+			body = n.Body
+		case *ast.ForStmt:
+			if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+				for _, e := range init.Lhs {
+					if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+						vars = append(vars, id)
+					}
+				}
+			}
+			body = n.Body
+		default:
+			return true
+		}
+		if len(vars) == 0 {
+			return true
+		}
+		loopVars := make(map[any]bool, len(vars))
+		for _, id := range vars {
+			if obj := s.object(id); obj != nil {
+				loopVars[obj] = true
+			}
+		}
+		ast.Inspect(body, func(n ast.Node) bool {
+			g, ok := n.(*ast.GoStmt)
+			if !ok || reported[g.Pos()] {
+				return true
+			}
+			lit, ok := g.Call.Fun.(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+			captured := false
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && loopVars[s.object(id)] {
+					captured = true
+				}
+				return !captured
+			})
+			if captured {
+				reported[g.Pos()] = true
+				out = append(out, s.violation(g.Pos()))
+			}
+			return true
+		})
+		return true
+	})
+	return out
+}
+
+// checkCopyMutex flags receivers and parameters that take a struct
+// containing a sync.Mutex or sync.RWMutex by value. With type information
+// lock-containing types are found across the package and through other
+// packages' types; without it, only structs declared in the same file are
+// known.
+func checkCopyMutex(s *apSource) []apViolation {
+	containsLock := s.lockTypesInFile()
+	byValue := func(expr ast.Expr) bool {
+		if s.Info != nil {
+			if t := s.Info.TypeOf(expr); t != nil {
+				return hasLock(t, make(map[types.Type]bool))
+			}
+		}
+		if id, ok := expr.(*ast.Ident); ok {
+			return containsLock[id.Name]
+		}
+		return s.isSyncLock(expr)
+	}
+
+	var out []apViolation
+	check := func(fields ...*ast.FieldList) {
+		for _, fl := range fields {
+			if fl == nil {
+				continue
+			}
+			for _, field := range fl.List {
+				if byValue(field.Type) {
+					out = append(out, s.violation(field.Pos()))
+				}
+			}
+		}
+	}
+	ast.Inspect(s.AST, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			check(n.Recv, n.Type.Params)
+		case *ast.FuncLit:
+			check(n.Type.Params)
+		}
+		return true
+	})
+	return out
+}
+
+// lockTypesInFile returns the names of struct types declared in the file
+// that contain a sync.Mutex or sync.RWMutex, directly, through an embedded
+// or named field, or through another such struct.
+func (s *apSource) lockTypesInFile() map[string]bool {
+	structs := make(map[string]*ast.StructType)
+	for _, d := range s.AST.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok {
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = st
+				}
+			}
+		}
+	}
+	locks := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for name, st := range structs {
+			if locks[name] {
+				continue
+			}
+			for _, f := range st.Fields.List {
+				if id, ok := f.Type.(*ast.Ident); ok && locks[id.Name] || s.isSyncLock(f.Type) {
+					locks[name] = true
+				}
+			}
+			changed = changed || locks[name]
+		}
+	}
+	return locks
+}
+
+// isSyncLock reports whether expr is sync.Mutex or sync.RWMutex.
+func (s *apSource) isSyncLock(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "Mutex" && sel.Sel.Name != "RWMutex") {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == s.importName("sync")
+}
+
+// hasLock reports whether a value of type t contains a sync.Mutex or
+// sync.RWMutex. Pointers, slices, maps and channels hold references and
+// are not followed.
+func hasLock(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if n, ok := t.(*types.Named); ok {
+		if obj := n.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "sync" &&
+			(obj.Name() == "Mutex" || obj.Name() == "RWMutex") {
+			return true
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if hasLock(u.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	case *types.Array:
+		return hasLock(u.Elem(), seen)
+	}
+	return false
+}
+
+// checkDeferInLoop flags defer statements executed by a for or range
+// loop body. Defers inside a function literal called from the loop run per
+// call and are fine.
+func checkDeferInLoop(s *apSource) []apViolation {
+	var out []apViolation
+	var walk func(n ast.Node, inLoop bool)
+	walk = func(n ast.Node, inLoop bool) {
+		ast.Inspect(n, func(c ast.Node) bool {
+			if c == n {
+				return true
+			}
+			switch c := c.(type) {
+			case *ast.FuncLit:
+				walk(c.Body, false)
+				return false
+			case *ast.ForStmt:
+				walk(c.Body, true)
+				return false
+			case *ast.RangeStmt:
+				walk(c.Body, true)
+				return false
+			case *ast.DeferStmt:
+				if inLoop {
+					out = append(out, s.violation(c.Pos()))
+				}
+			}
+			return true
+		})
+	}
+	walk(s.AST, false)
+	return out
+}
+
+// checkUnclosedResponseBody flags HTTP responses whose body is never
+// closed in the function that received them. A response that is returned
+// or handed to another function is assumed to be closed there.
+func checkUnclosedResponseBody(s *apSource) []apViolation {
+	httpName := s.importName("net/http")
+	if s.Info == nil && httpName == "" {
+		return nil
+	}
+	isResponseCall := func(call *ast.CallExpr) bool {
+		if s.Info != nil {
+			if tv, ok := s.Info.Types[call]; ok && tv.Type != nil {
+				if tuple, ok := tv.Type.(*types.Tuple); ok && tuple.Len() > 0 {
+					return isNamed(tuple.At(0).Type(), "net/http", "Response")
+				}
+				return isNamed(tv.Type, "net/http", "Response")
+			}
+		}
+		if isPkgCall(call, httpName, "Get", "Post", "PostForm", "Head") {
+			return true
+		}
+		// Without types, trust only receivers that look like HTTP clients.
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		switch sel.Sel.Name {
+		case "Do", "Get", "Post", "PostForm", "Head":
+		default:
+			return false
+		}
+		switch x := sel.X.(type) {
+		case *ast.Ident:
+			return strings.Contains(strings.ToLower(x.Name), "client")
+		case *ast.SelectorExpr:
+			return strings.Contains(strings.ToLower(x.Sel.Name), "client")
+		}
+		return false
+	}
+
+	var out []apViolation
+	funcBodies(s.AST, func(body *ast.BlockStmt) {
+		inspectSkippingFuncLits(body, func(n ast.Node) bool {
+			as, ok := n.(*ast.AssignStmt)
+			if !ok || len(as.Rhs) != 1 || len(as.Lhs) == 0 {
+				return true
+			}
+			call, ok := ast.Unparen(as.Rhs[0]).(*ast.CallExpr)
+			if !ok || !isResponseCall(call) {
+				return true
+			}
+			resp, ok := as.Lhs[0].(*ast.Ident)
+			if !ok {
+				return true // stored in a field or element
+			}
+			if resp.Name == "_" {
+				out = append(out, s.violation(as.Pos()))
+				return true
+			}
+			obj := s.object(resp)
+			if obj != nil && !s.closesBody(body, obj) && !s.escapes(body, obj) {
+				out = append(out, s.violation(as.Pos()))
+			}
+			return true
+		})
+	})
+	return out
+}
+
+// closesBody reports whether body calls obj.Body.Close().
+func (s *apSource) closesBody(body *ast.BlockStmt, obj any) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return !found
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Close" {
+			return true
+		}
+		inner, ok := sel.X.(*ast.SelectorExpr)
+		if !ok || inner.Sel.Name != "Body" {
+			return true
+		}
+		if id, ok := inner.X.(*ast.Ident); ok && s.object(id) == obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// sqlRows finds `for rows.Next()` loops over database rows in every
+// function and calls fn with the function body, the loop and the rows
+// variable. Rows are recognized by type (*sql.Rows, or any type with Next,
+// Err and Close methods) or, without type information, by being assigned
+// from a Query* method call in the same function.
+func (s *apSource) sqlRows(fn func(body *ast.BlockStmt, loop *ast.ForStmt, rows any)) {
+	funcBodies(s.AST, func(body *ast.BlockStmt) {
+		fromQuery := make(map[any]bool)
+		inspectSkippingFuncLits(body, func(n ast.Node) bool {
+			as, ok := n.(*ast.AssignStmt)
+			if !ok || len(as.Rhs) != 1 || len(as.Lhs) == 0 {
+				return true
+			}
+			call, ok := ast.Unparen(as.Rhs[0]).(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !strings.HasPrefix(sel.Sel.Name, "Query") || strings.HasPrefix(sel.Sel.Name, "QueryRow") {
+				return true
+			}
+			if id, ok := as.Lhs[0].(*ast.Ident); ok && s.object(id) != nil {
+				fromQuery[s.object(id)] = true
+			}
+			return true
+		})
+		isRows := func(id *ast.Ident) bool {
+			if s.Info != nil {
+				if t := s.Info.TypeOf(id); t != nil {
+					return isNamed(t, "database/sql", "Rows") || hasMethods(t, "Next", "Err", "Close")
+				}
+			}
+			return fromQuery[s.object(id)]
+		}
+		inspectSkippingFuncLits(body, func(n ast.Node) bool {
+			loop, ok := n.(*ast.ForStmt)
+			if !ok {
+				return true
+			}
+			call, ok := loop.Cond.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if id, name := methodCall(call); id != nil && name == "Next" && s.object(id) != nil && isRows(id) {
+				fn(body, loop, s.object(id))
+			}
+			return true
+		})
+	})
+}
+
+// hasMethods reports whether the method set of t (or *t) has every name.
+func hasMethods(t types.Type, names ...string) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		if _, ok := t.Underlying().(*types.Interface); !ok {
+			t = types.NewPointer(t)
+		}
+	}
+	ms := types.NewMethodSet(t)
+	for _, n := range names {
+		if ms.Lookup(nil, n) == nil {
+			// Lookup with a nil package only finds exported methods.
+			return false
+		}
+	}
+	return true
+}
+
+// checkSQLRowsErr flags `for rows.Next()` loops over database rows in
+// functions that never call rows.Err() and do not hand the rows elsewhere.
+func checkSQLRowsErr(s *apSource) []apViolation {
+	var out []apViolation
+	s.sqlRows(func(body *ast.BlockStmt, loop *ast.ForStmt, rows any) {
+		if !s.calledMethod(body, rows, "Err") && !s.escapes(body, rows) {
+			out = append(out, s.violation(loop.Pos()))
+		}
+	})
+	return out
+}
+
+// checkRowsClose flags `for rows.Next()` loops over database rows in
+// functions that never call rows.Close() and do not hand the rows elsewhere.
+func checkRowsClose(s *apSource) []apViolation {
+	var out []apViolation
+	s.sqlRows(func(body *ast.BlockStmt, loop *ast.ForStmt, rows any) {
+		if !s.calledMethod(body, rows, "Close") && !s.escapes(body, rows) {
+			out = append(out, s.violation(loop.Pos()))
+		}
+	})
+	return out
+}
+
+// apLoader reads and parses the files of one package at a time for the
+// anti-pattern runner, type-checking them when enabled.
+type apLoader struct {
+	opts     CheckOptions
+	importer types.Importer
+}
+
+func newAPLoader(opts CheckOptions) *apLoader {
+	l := &apLoader{opts: opts}
+	if opts.TypeCheck {
+		// Shared so each dependency is imported once per run.
+		l.importer = &lockedImporter{imp: importer.Default()}
+	}
+	return l
+}
+
+// lockedImporter serializes imports; the gc importer is not safe for
+// concurrent use.
+type lockedImporter struct {
+	mu  sync.Mutex
+	imp types.Importer
+}
+
+func (li *lockedImporter) Import(path string) (*types.Package, error) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	return li.imp.Import(path)
+}
+
+// load returns a source for every checkable file of files, which must all
+// be in one directory. Generated files (protobuf output and anything with a
+// "Code generated ... DO NOT EDIT." header) are parsed for type checking but
+// not returned, since nobody fixes them by hand. Files that do not parse
+// keep a nil AST so line-based checks still run on them.
+func (l *apLoader) load(files []*parser.ParsedFile) []*apSource {
+	fset := token.NewFileSet()
+	var out []*apSource
+	byPkg := make(map[string][]*ast.File)
+	var pkgNames []string
+	for _, f := range files {
+		if f.FileType == "proto" {
+			continue
+		}
+		data, err := os.ReadFile(f.FilePath)
+		if err != nil {
+			continue
+		}
+		file, err := goparser.ParseFile(fset, f.FilePath, data, goparser.ParseComments)
+		if err != nil {
+			file = nil
+		} else {
+			if _, ok := byPkg[file.Name.Name]; !ok {
+				pkgNames = append(pkgNames, file.Name.Name)
+			}
+			byPkg[file.Name.Name] = append(byPkg[file.Name.Name], file)
+		}
+		if f.Generated || strings.HasSuffix(f.FilePath, ".pb.go") {
+			continue
+		}
+		src := &apSource{File: f, Lines: strings.Split(string(data), "\n"), Fset: fset, AST: file}
+		if m := l.opts.Resolver.ModuleForDir(filepath.Dir(f.FilePath)); m != nil {
+			src.GoVersion = m.GoVersion
+		}
+		out = append(out, src)
+	}
+	if l.importer == nil || len(out) == 0 {
+		return out
+	}
+
+	dir := filepath.Dir(files[0].FilePath)
+	path := l.opts.Resolver.ImportPath(dir)
+	if path == "" {
+		path = dir
+	}
+	infos := make(map[*ast.File]*types.Info)
+	for _, name := range pkgNames {
+		info := &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		pkgPath := path
+		if strings.HasSuffix(name, "_test") {
+			pkgPath += "_test"
+		}
+		// Errors are expected (unresolvable imports, missing files) and
+		// only leave some expressions untyped.
+		conf := types.Config{Importer: l.importer, Error: func(error) {}}
+		conf.Check(pkgPath, fset, byPkg[name], info)
+		for _, f := range byPkg[name] {
+			infos[f] = info
+		}
+	}
+	for _, src := range out {
+		if src.AST != nil {
+			src.Info = infos[src.AST]
+		}
+	}
+	return out
+}
+
+// apFileResult is the output of every check on one file, before
+// suppression, baseline filtering and blame. Violations is indexed like the
+// check registry.
+type apFileResult struct {
+	Path       string
+	Ignored    map[int]map[string]bool // see ignoreDirectives
+	Violations [][]apViolation
+}
+
+// detect runs checks on the files of one package, or returns the result of
+// an earlier run when no file changed.
+func (l *apLoader) detect(files []*parser.ParsedFile, checks []apCheck) []apFileResult {
+	key := l.cacheKey(files, checks)
+	var out []apFileResult
+	if key != "" && l.opts.Cache.Get(key, &out) {
+		return out
+	}
+	for _, src := range l.load(files) {
+		fr := apFileResult{
+			Path:       src.File.FilePath,
+			Ignored:    ignoreDirectives(src.Lines),
+			Violations: make([][]apViolation, len(checks)),
+		}
+		for i, ch := range checks {
+			var vs []apViolation
+			if ch.DetectAST != nil {
+				if src.AST != nil {
+					vs = ch.DetectAST(src)
+				}
+			} else {
+				vs = ch.Detect(src.File, src.Lines)
+			}
+			for j := range vs {
+				vs[j].Path = src.File.FilePath
+			}
+			fr.Violations[i] = vs
+		}
+		out = append(out, fr)
+	}
+	if key != "" {
+		l.opts.Cache.Put(key, out)
+	}
+	return out
+}
+
+// cacheKey identifies the results of checks on files, or returns "" when
+// they cannot be cached. Type-checked results also depend on other
+// packages and are never cached.
+func (l *apLoader) cacheKey(files []*parser.ParsedFile, checks []apCheck) string {
+	if l.opts.Cache == nil || l.opts.TypeCheck || len(files) == 0 {
+		return ""
+	}
+	parts := []string{"antipatterns"}
+	for _, ch := range checks {
+		parts = append(parts, ch.ID)
+	}
+	goVersion := ""
+	if m := l.opts.Resolver.ModuleForDir(filepath.Dir(files[0].FilePath)); m != nil {
+		goVersion = m.GoVersion
+	}
+	parts = append(parts, goVersion)
+	for _, f := range files {
+		if f.ContentHash == "" {
+			return ""
+		}
+		parts = append(parts, f.FilePath, f.ContentHash)
+	}
+	return cache.Key(parts...)
+}
+
+// groupByDir splits files by directory, keeping the order in which
+// directories first appear.
+func groupByDir(files []*parser.ParsedFile) [][]*parser.ParsedFile {
+	index := make(map[string]int)
+	var groups [][]*parser.ParsedFile
+	for _, f := range files {
+		dir := filepath.Dir(f.FilePath)
+		i, ok := index[dir]
+		if !ok {
+			i = len(groups)
+			index[dir] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], f)
+	}
+	return groups
+}
@@ -176,25 +176,39 @@ func classifyGoFile(f *parser.ParsedFile) string {
 	return LayerOther
 }
 
-func buildArchLayersHTML(files []*parser.ParsedFile) string {
-	type layerBucket struct {
-		FileCount int
-		LineCount int
-	}
-	buckets := make(map[string]*layerBucket)
+// layerStat is the size of one architectural layer.
+type layerStat struct {
+	Layer     string
+	FileCount int
+	LineCount int
+}
+
+// archLayerStats groups files by layer, in layerOrder, skipping empty layers.
+func archLayerStats(files []*parser.ParsedFile) []layerStat {
+	buckets := make(map[string]*layerStat)
 	for _, f := range files {
 		layer := classifyGoFile(f)
 		b := buckets[layer]
 		if b == nil {
-			b = &layerBucket{}
+			b = &layerStat{Layer: layer}
 			buckets[layer] = b
 		}
 		b.FileCount++
 		b.LineCount += f.LineCount
 	}
+	var out []layerStat
+	for _, layer := range layerOrder {
+		if b := buckets[layer]; b != nil {
+			out = append(out, *b)
+		}
+	}
+	return out
+}
 
+func buildArchLayersHTML(files []*parser.ParsedFile) string {
+	layers := archLayerStats(files)
 	maxLines := 1
-	for _, b := range buckets {
+	for _, b := range layers {
 		if b.LineCount > maxLines {
 			maxLines = b.LineCount
 		}
@@ -202,11 +216,8 @@ func buildArchLayersHTML(files []*parser.ParsedFile) string {
 
 	var sb strings.Builder
 	sb.WriteString(`<div class="arch-layers">`)
-	for _, layer := range layerOrder {
-		b := buckets[layer]
-		if b == nil {
-			continue
-		}
+	for _, b := range layers {
+		layer := b.Layer
 		icon := layerIcons[layer]
 		pct := b.LineCount * 100 / maxLines
 		if pct < 4 {
@@ -235,20 +246,30 @@ func detectGoComponents(files []*parser.ParsedFile, techSet map[string]bool, tot
 	}
 
 	// HTTP frameworks
+	routes := routeCounts(files)
+	withRoutes := func(s, framework string) string {
+		if n := routes[framework]; n > 0 {
+			s += fmt.Sprintf(" · %d routes", n)
+		}
+		return s
+	}
 	if techSet["Gin"] {
-		add("Gin", "🌐", "Gin HTTP server")
+		add("Gin", "🌐", withRoutes("Gin HTTP server", parser.FrameworkGin))
 	}
 	if techSet["Echo"] {
-		add("Echo", "🌐", "Echo HTTP server")
+		add("Echo", "🌐", withRoutes("Echo HTTP server", parser.FrameworkEcho))
 	}
 	if techSet["Fiber"] {
-		add("Fiber", "🌐", "Fiber HTTP server")
+		add("Fiber", "🌐", withRoutes("Fiber HTTP server", parser.FrameworkFiber))
 	}
 	if techSet["Chi"] {
-		add("Chi", "🌐", "Chi router")
+		add("Chi", "🌐", withRoutes("Chi router", parser.FrameworkChi))
 	}
 	if techSet["Gorilla Mux"] {
-		add("Gorilla Mux", "🌐", "Gorilla Mux router")
+		add("Gorilla Mux", "🌐", withRoutes("Gorilla Mux router", parser.FrameworkGorilla))
+	}
+	if routes[parser.FrameworkNetHTTP] > 0 {
+		add("net/http", "🌐", withRoutes("net/http ServeMux", parser.FrameworkNetHTTP))
 	}
 
 	// gRPC
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultBaselinePath is the baseline file name, looked up in the analysis
+// root.
+const DefaultBaselinePath = ".goscope-baseline.json"
+
+// baselineVersion is the version of the baseline file format.
+const baselineVersion = 1
+
+// Baseline is a set of accepted findings. A finding matches an entry when
+// check, file and normalized snippet agree, so entries survive lines moving
+// within a file. Each entry absorbs one finding; duplicates are recorded as
+// separate entries.
+type Baseline struct {
+	Version  int             `json:"version"`
+	Findings []BaselineEntry `json:"findings"`
+	Cycles   [][]string      `json:"cycles,omitempty"` // accepted package cycles, members relative to the root
+}
+
+// BaselineEntry is the fingerprint of one accepted finding.
+type BaselineEntry struct {
+	Check   string `json:"check"`   // check ID
+	File    string `json:"file"`    // slash-separated, relative to the analysis root
+	Snippet string `json:"snippet"` // source line with whitespace collapsed
+}
+
+// NewBaseline records findings, with paths made relative to root.
+func NewBaseline(findings []Finding, root string) *Baseline {
+	b := &Baseline{Version: baselineVersion, Findings: []BaselineEntry{}}
+	for _, f := range findings {
+		b.Findings = append(b.Findings, baselineEntry(f.ID, f.Path, f.Snippet, root))
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Check != y.Check {
+			return x.Check < y.Check
+		}
+		return x.Snippet < y.Snippet
+	})
+	return b
+}
+
+// LoadBaseline reads a baseline file.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse baseline %s: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %s: unsupported version %d", path, b.Version)
+	}
+	return &b, nil
+}
+
+// Write saves b as indented JSON at path.
+func (b *Baseline) Write(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// counts returns how many findings each entry absorbs. It is nil-safe so
+// runs without a baseline need no special case.
+func (b *Baseline) counts() map[BaselineEntry]int {
+	if b == nil {
+		return nil
+	}
+	m := make(map[BaselineEntry]int, len(b.Findings))
+	for _, e := range b.Findings {
+		m[e]++
+	}
+	return m
+}
+
+func baselineEntry(check, path, snippet, root string) BaselineEntry {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return BaselineEntry{
+		Check:   check,
+		File:    filepath.ToSlash(path),
+		Snippet: strings.Join(strings.Fields(snippet), " "),
+	}
+}
+
+// reIgnoreDirective matches `//goscope:ignore <check-id> reason`.
+var reIgnoreDirective = regexp.MustCompile(`//goscope:ignore\s+([a-z0-9-]+)`)
+
+// ignoreDirectives maps 1-based line numbers to the check IDs suppressed on
+// them. A directive applies to its own line and, when it is the only thing
+// on its line, to the line below.
+func ignoreDirectives(lines []string) map[int]map[string]bool {
+	var out map[int]map[string]bool
+	for i, line := range lines {
+		if !strings.Contains(line, "//goscope:ignore") {
+			continue
+		}
+		for _, m := range reIgnoreDirective.FindAllStringSubmatch(line, -1) {
+			if out == nil {
+				out = make(map[int]map[string]bool)
+			}
+			targets := []int{i + 1}
+			if strings.HasPrefix(strings.TrimSpace(line), "//") {
+				targets = append(targets, i+2)
+			}
+			for _, n := range targets {
+				if out[n] == nil {
+					out[n] = make(map[string]bool)
+				}
+				out[n][m[1]] = true
+			}
+		}
+	}
+	return out
+}
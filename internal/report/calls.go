@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goscope/internal/graph"
+)
+
+// maxCallSites is the number of call sites listed per service call.
+const maxCallSites = 3
+
+// protocolLabels joins the display names of call protocols: "gRPC · HTTP".
+func protocolLabels(protocols []string) string {
+	labels := make([]string, len(protocols))
+	for i, p := range protocols {
+		switch p {
+		case graph.CallGRPC:
+			labels[i] = "gRPC"
+		default:
+			labels[i] = strings.ToUpper(p)
+		}
+	}
+	return strings.Join(labels, " · ")
+}
+
+// buildServiceCallsHTML lists the calls between microservices drawn on the
+// architecture graph with where each was found. Returns "" when there are
+// none.
+func buildServiceCallsHTML(calls []graph.ServiceCall, root string) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<h3 style="margin-top:24px">Service Calls</h3>`)
+	sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+	sb.WriteString(`<thead><tr><th>Caller</th><th>Callee</th><th>Protocol</th><th>Found in</th></tr></thead><tbody>`)
+	for _, c := range calls {
+		var sites []string
+		for i, s := range c.Sites {
+			if i == maxCallSites {
+				sites = append(sites, fmt.Sprintf(`<span class="pkg-stats">+%d more</span>`, len(c.Sites)-maxCallSites))
+				break
+			}
+			sites = append(sites, fmt.Sprintf(`<div><span class="tag tag-tech" style="font-size:11px">%s</span> %s <span class="mono" style="font-size:12px">%s</span></div>`,
+				esc(s.Source), sourceLink(s.File, s.Line, root), esc(s.Detail)))
+		}
+		sb.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td><span class="rpc-mode">%s</span></td><td>%s</td></tr>`,
+			serviceLinks([]string{c.From}), serviceLinks([]string{c.To}), esc(protocolLabels(c.Protocols)), strings.Join(sites, "")))
+	}
+	sb.WriteString(`</tbody></table></div>`)
+	return sb.String()
+}
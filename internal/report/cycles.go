@@ -0,0 +1,86 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/parser"
+)
+
+// maxCyclesShown caps the number of cycles listed per graph.
+const maxCyclesShown = 10
+
+// buildCyclesHTML renders the dependency cycles of the package and file
+// graphs with a sample cycle path and the edges suggested to break each one.
+func buildCyclesHTML(g *graph.DependencyGraph, pg *graph.PackageGraph, fileMap map[string]*parser.ParsedFile) string {
+	var pkgCycles, fileCycles []graph.Cycle
+	if pg != nil {
+		pkgCycles = pg.Cycles()
+	}
+	if g != nil {
+		fileCycles = g.Cycles()
+	}
+
+	pkgLabel := func(id string) string {
+		if p := pg.Packages[id]; p != nil {
+			return p.Label()
+		}
+		return id
+	}
+	fileLabel := func(path string) string {
+		if f := fileMap[path]; f != nil {
+			return shortRelPath(path, f.MicroserviceName)
+		}
+		return path
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🔁 Dependency Cycles</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d package cycles · %d file cycles</p>`, len(pkgCycles), len(fileCycles)))
+	if len(pkgCycles) == 0 && len(fileCycles) == 0 {
+		sb.WriteString(`<div class="ap-summary"><span class="ap-pass-badge">✓ No dependency cycles</span></div></div>`)
+		return sb.String()
+	}
+	writeCycles(&sb, "Package cycles", "packages", pkgCycles, pkgLabel)
+	writeCycles(&sb, "File cycles", "files", fileCycles, fileLabel)
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+func writeCycles(sb *strings.Builder, title, unit string, cycles []graph.Cycle, label func(string) string) {
+	if len(cycles) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf(`<h3>%s</h3>`, title))
+	for i, c := range cycles {
+		if i == maxCyclesShown {
+			sb.WriteString(fmt.Sprintf(`<p class="subtitle">… and %d more</p>`, len(cycles)-maxCyclesShown))
+			break
+		}
+		sb.WriteString(`<div class="sub-card">`)
+		sb.WriteString(fmt.Sprintf(`<h3 class="sub-card-title">%d %s · %d edges</h3>`, len(c.Members), unit, len(c.Edges)))
+
+		sb.WriteString(`<div class="pkg-grid">`)
+		for _, m := range c.Members {
+			sb.WriteString(fmt.Sprintf(`<span class="tag tag-local mono">%s</span>`, esc(label(m))))
+		}
+		sb.WriteString(`</div>`)
+
+		if len(c.Path) > 0 {
+			steps := make([]string, len(c.Path))
+			for j, v := range c.Path {
+				steps[j] = esc(label(v))
+			}
+			sb.WriteString(fmt.Sprintf(`<p class="mono">%s</p>`, strings.Join(steps, " → ")))
+		}
+
+		sb.WriteString(`<p><b>Suggested break:</b> remove `)
+		breaks := make([]string, len(c.Break))
+		for j, e := range c.Break {
+			breaks[j] = fmt.Sprintf(`<span class="mono">%s → %s</span>`, esc(label(e[0])), esc(label(e[1])))
+		}
+		sb.WriteString(strings.Join(breaks, ", "))
+		sb.WriteString(`</p></div>`)
+	}
+}
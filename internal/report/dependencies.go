@@ -0,0 +1,149 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/scanner"
+)
+
+// jsonDependencies summarizes the requirements of all scanned modules.
+type jsonDependencies struct {
+	GoVersions []gomod.VersionUse `json:"goVersions"`
+	Drift      []gomod.Drift      `json:"drift"`
+}
+
+func dependenciesJSON(scan *scanner.ScanResult) jsonDependencies {
+	out := jsonDependencies{GoVersions: gomod.GoVersions(scan.Modules), Drift: gomod.VersionDrift(scan.Modules, scan.WorkReplaces()...)}
+	if out.Drift == nil {
+		out.Drift = []gomod.Drift{}
+	}
+	return out
+}
+
+// buildDependenciesHTML renders the Go toolchain versions of the scanned
+// modules, the dependencies they require at different versions and their
+// replace directives. Trees without go.mod requirements get no card.
+func buildDependenciesHTML(scan *scanner.ScanResult, root string) string {
+	deps := make(map[string]bool)
+	replaces := 0
+	for _, m := range scan.Modules {
+		for _, r := range m.Requires {
+			deps[r.Path] = true
+		}
+		replaces += len(m.Replaces)
+	}
+	if scan.Workspace != nil {
+		replaces += len(scan.Workspace.Replaces)
+	}
+	if len(deps) == 0 && replaces == 0 {
+		return ""
+	}
+	drift := gomod.VersionDrift(scan.Modules, scan.WorkReplaces()...)
+
+	// Name modules after the microservices they hold.
+	labels := make(map[string]string)
+	for _, g := range groupModules(scan) {
+		if len(g.Services) > 0 {
+			labels[g.Module.Path] = strings.Join(g.Services, ", ")
+		}
+	}
+	moduleTag := func(path string) string {
+		if l, ok := labels[path]; ok {
+			return fmt.Sprintf(`<span class="tag tag-local" style="font-size:11px" title="%s">%s</span>`, esc(path), esc(l))
+		}
+		return fmt.Sprintf(`<span class="tag tag-local mono" style="font-size:11px">%s</span>`, esc(path))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🧷 Go Dependencies</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d modules · %d distinct requirements · %d at more than one version · %d replace directives</p>`,
+		len(scan.Modules), len(deps), len(drift), replaces))
+
+	if gv := gomod.GoVersions(scan.Modules); len(gv) > 0 {
+		sb.WriteString(`<h3>Go versions</h3><div class="pkg-grid">`)
+		for _, v := range gv {
+			sb.WriteString(fmt.Sprintf(`<span class="tag tag-tech" title="%s">go %s <span class="bs-badge-right">%d</span></span>`,
+				esc(strings.Join(v.Modules, "\n")), esc(v.Version), len(v.Modules)))
+		}
+		sb.WriteString(`</div>`)
+		toolchains := make(map[string][]string)
+		for _, m := range scan.Modules {
+			if m.Toolchain != "" {
+				toolchains[m.Toolchain] = append(toolchains[m.Toolchain], m.Path)
+			}
+		}
+		if len(toolchains) > 0 {
+			names := make([]string, 0, len(toolchains))
+			for t := range toolchains {
+				names = append(names, t)
+			}
+			sort.Slice(names, func(i, j int) bool { return gomod.CompareVersions(names[i], names[j]) > 0 })
+			var parts []string
+			for _, t := range names {
+				parts = append(parts, fmt.Sprintf("%s (%d)", esc(t), len(toolchains[t])))
+			}
+			sb.WriteString(fmt.Sprintf(`<p class="subtitle">Toolchain directives: %s</p>`, strings.Join(parts, " · ")))
+		}
+	}
+
+	if len(drift) > 0 {
+		sb.WriteString(`<h3>Version drift</h3><div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>Dependency</th><th>Version</th><th>Required by</th></tr></thead><tbody>`)
+		for _, d := range drift {
+			for i, v := range d.Versions {
+				name := ""
+				if i == 0 {
+					name = fmt.Sprintf(`<td class="mono" rowspan="%d">%s</td>`, len(d.Versions), esc(d.Path))
+				}
+				var users []string
+				for _, m := range v.Modules {
+					users = append(users, moduleTag(m))
+				}
+				sb.WriteString(fmt.Sprintf(`<tr>%s<td class="mono">%s</td><td>%s</td></tr>`, name, esc(v.Version), strings.Join(users, " ")))
+			}
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+
+	if replaces > 0 {
+		sb.WriteString(`<h3>Replace directives</h3><div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>In</th><th>Replaces</th><th>With</th><th>Kind</th></tr></thead><tbody>`)
+		row := func(in string, r gomod.Replace) {
+			old := r.Old
+			if r.OldVersion != "" {
+				old += "@" + r.OldVersion
+			}
+			target, kind := r.New+"@"+r.NewVersion, "fork"
+			switch {
+			case r.Dir != "":
+				target, kind = r.New, "local"
+				if rel, err := filepath.Rel(root, r.Dir); err == nil {
+					target = filepath.ToSlash(rel)
+				}
+			case r.New == r.Old:
+				target, kind = r.NewVersion, "pinned"
+			}
+			sb.WriteString(fmt.Sprintf(`<tr><td>%s</td><td class="mono">%s</td><td class="mono">%s</td><td>%s</td></tr>`,
+				in, esc(old), esc(target), kind))
+		}
+		if scan.Workspace != nil {
+			for _, r := range scan.Workspace.Replaces {
+				row(`<span class="mono">go.work</span>`, r)
+			}
+		}
+		mods := append([]gomod.Module(nil), scan.Modules...)
+		sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+		for _, m := range mods {
+			for _, r := range m.Replaces {
+				row(moduleTag(m.Path), r)
+			}
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
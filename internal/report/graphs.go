@@ -3,7 +3,9 @@ package report
 import (
 	"os"
 	"sort"
+	"strings"
 
+	"github.com/goscope/internal/graph"
 	"github.com/goscope/internal/parser"
 	"github.com/goscope/internal/scanner"
 )
@@ -19,6 +21,8 @@ type gNode struct {
 type gLink struct {
 	Source string `json:"source"`
 	Target string `json:"target"`
+	Kind   string `json:"kind,omitempty"`  // "call" for service calls
+	Label  string `json:"label,omitempty"` // protocols of a service call
 }
 type gData struct {
 	Nodes []gNode `json:"nodes"`
@@ -29,7 +33,10 @@ func newGData() gData {
 	return gData{Nodes: make([]gNode, 0), Links: make([]gLink, 0)}
 }
 
-func buildArchitectureGraph(microservices []*MicroserviceSummary, techList []string, files []*parser.ParsedFile, foreignServices []scanner.ForeignService) gData {
+// buildArchitectureGraph links every microservice to the technologies it
+// uses and, with directed edges labelled by protocol, to the microservices
+// it calls.
+func buildArchitectureGraph(microservices []*MicroserviceSummary, techList []string, files []*parser.ParsedFile, foreignServices []scanner.ForeignService, calls []graph.ServiceCall) gData {
 	msTechs := make(map[string]map[string]bool)
 	for _, f := range files {
 		if f.MicroserviceName == "" {
@@ -56,9 +63,13 @@ func buildArchitectureGraph(microservices []*MicroserviceSummary, techList []str
 
 	for _, ms := range microservices {
 		score := float64(ms.TotalLines) / 1000.0
+		kind := "microservice"
+		if ms.Shared {
+			kind = "library"
+		}
 		nodes = append(nodes, gNode{
 			ID: "ms:" + ms.Name, Label: ms.Name, Sublabel: fmtNum(ms.TotalLines) + " loc",
-			Kind: "microservice", Score: score, Group: "ms",
+			Kind: kind, Score: score, Group: "ms",
 		})
 		for t := range msTechs[ms.Name] {
 			usedTechs[t] = true
@@ -93,6 +104,15 @@ func buildArchitectureGraph(microservices []*MicroserviceSummary, techList []str
 	for _, fs := range foreignServices {
 		links = append(links, gLink{Source: "ms:" + fs.Name, Target: "tech:" + fs.Language})
 	}
+	msNodes := make(map[string]bool)
+	for _, ms := range microservices {
+		msNodes[ms.Name] = true
+	}
+	for _, c := range calls {
+		if msNodes[c.From] && msNodes[c.To] {
+			links = append(links, gLink{Source: "ms:" + c.From, Target: "ms:" + c.To, Kind: "call", Label: protocolLabels(c.Protocols)})
+		}
+	}
 
 	if nodes == nil {
 		nodes = make([]gNode, 0)
@@ -235,32 +255,56 @@ func buildDeclGraph(ms *MicroserviceSummary, scores map[string]float64) gData {
 		}
 	}
 
-	// Strategy 3: Proto service → message edges
-	// Connect proto services to request/response messages by name matching
-	for _, src := range ad {
-		if src.kind != parser.DeclService {
-			continue
+	// Strategy 3: Proto structure — service → request/response messages,
+	// message → message/enum field types, resolved from the parsed proto model.
+	protoNodes := make(map[string]string) // proto full name -> node ID
+	for _, d := range ad {
+		if d.kind == parser.DeclMessage || d.kind == parser.DeclEnum {
+			if _, ok := protoNodes[d.name]; !ok {
+				protoNodes[d.name] = d.fp + "::" + d.name
+			}
 		}
-		cs := fileContents[src.fp]
-		if cs == "" {
+	}
+	linkProto := func(srcID, typ, pkg, scope string) {
+		ref := protoTypeRef(typ, pkg)
+		tgtID, ok := "", false
+		// Resolve relative names from the innermost scope outwards.
+		for s := scope; s != "" && !ok; {
+			tgtID, ok = protoNodes[s+"."+ref]
+			if i := strings.LastIndex(s, "."); i >= 0 {
+				s = s[:i]
+			} else {
+				s = ""
+			}
+		}
+		if !ok {
+			tgtID, ok = protoNodes[ref]
+		}
+		if !ok || tgtID == srcID {
+			return
+		}
+		ek := srcID + "->" + tgtID
+		if !seen[ek] {
+			outgoing[srcID] = append(outgoing[srcID], candidate{tgtID, nodeScores[tgtID]})
+			seen[ek] = true
+		}
+	}
+	for _, f := range ms.Files {
+		if f.Proto == nil {
 			continue
 		}
-		srcID := src.fp + "::" + src.name
-		for _, tgt := range ad {
-			if tgt.kind != parser.DeclMessage && tgt.kind != parser.DeclRPC {
-				continue
+		pkg := f.Proto.Package
+		for _, svc := range f.Proto.Services {
+			srcID := f.FilePath + "::" + svc.Name
+			for _, r := range svc.RPCs {
+				linkProto(srcID, r.InputType, pkg, "")
+				linkProto(srcID, r.OutputType, pkg, "")
 			}
-			if len(tgt.name) <= 4 {
-				continue
-			}
-			ek := srcID + "->" + tgt.fp + "::" + tgt.name
-			if seen[ek] {
-				continue
-			}
-			if matchGoTypeRef(cs, tgt.name) {
-				tgtID := tgt.fp + "::" + tgt.name
-				outgoing[srcID] = append(outgoing[srcID], candidate{tgtID, nodeScores[tgtID]})
-				seen[ek] = true
+		}
+		for _, m := range f.Proto.AllMessages() {
+			srcID := f.FilePath + "::" + m.FullName
+			for _, fld := range m.Fields {
+				linkProto(srcID, fld.Type, pkg, m.FullName)
 			}
 		}
 	}
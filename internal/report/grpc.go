@@ -0,0 +1,214 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/protodiff"
+)
+
+// protoTypeRef normalizes a proto field/RPC type reference for lookup:
+// strips a leading dot and the file's own package, and unwraps map values.
+func protoTypeRef(typ, pkg string) string {
+	if strings.HasPrefix(typ, "map<") {
+		if i := strings.LastIndex(typ, ","); i >= 0 {
+			typ = strings.TrimSpace(strings.TrimSuffix(typ[i+1:], ">"))
+		}
+	}
+	typ = strings.TrimPrefix(typ, ".")
+	if pkg != "" {
+		typ = strings.TrimPrefix(typ, pkg+".")
+	}
+	return typ
+}
+
+func rpcModeBadge(r parser.ProtoRPC) string {
+	mode := r.StreamingMode()
+	cls := "rpc-mode"
+	if mode != "unary" {
+		cls += " rpc-mode-stream"
+	}
+	return fmt.Sprintf(`<span class="%s">%s</span>`, cls, mode)
+}
+
+// serviceLinks links to the cards of the named microservices.
+func serviceLinks(names []string) string {
+	links := make([]string, len(names))
+	for i, ms := range names {
+		links[i] = fmt.Sprintf("<a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a>",
+			strings.ReplaceAll(ms, " ", "-"), esc(ms))
+	}
+	return strings.Join(links, " ")
+}
+
+// buildGRPCCoverageHTML lists the Go implementations of a proto service and
+// the microservices that register and call it.
+func buildGRPCCoverageHTML(s *graph.GRPCService, root string) string {
+	var sb strings.Builder
+	if len(s.Implementations) == 0 {
+		sb.WriteString(`<p class="pkg-stats">No Go implementation found</p>`)
+	}
+	for _, impl := range s.Implementations {
+		var notes []string
+		if impl.Embeds != "" {
+			notes = append(notes, "embeds "+esc(impl.Embeds))
+		}
+		if impl.Registered {
+			notes = append(notes, "registered")
+		}
+		if len(impl.Missing) > 0 {
+			notes = append(notes, fmt.Sprintf("%d of %d RPCs missing", len(impl.Missing), len(s.RPCs)))
+		}
+		sb.WriteString(fmt.Sprintf(`<p class="pkg-stats">Implemented by <span class="mono">%s</span> %s %s · %s</p>`,
+			esc(impl.Type), serviceLinks([]string{impl.Microservice}), sourceLink(impl.File, impl.Line, root), strings.Join(notes, " · ")))
+	}
+	if len(s.Registered) > 0 {
+		sb.WriteString(`<p class="pkg-stats">Registered in ` + serviceLinks(s.Registered) + `</p>`)
+	}
+	if len(s.Clients) > 0 {
+		sb.WriteString(`<p class="pkg-stats">Clients ` + serviceLinks(s.Clients) + `</p>`)
+	}
+	return sb.String()
+}
+
+// buildGRPCHTML renders every proto service with its RPCs, request/response
+// types and streaming mode and, when cov is set, the Go types implementing
+// it, the microservices serving each RPC and the clients of the service.
+// Returns "" when no services were found.
+func buildGRPCHTML(files []*parser.ParsedFile, cov *graph.GRPCCoverage, root string) string {
+	type svcEntry struct {
+		svc  parser.ProtoService
+		file *parser.ParsedFile
+	}
+	var entries []svcEntry
+	for _, f := range files {
+		if f.Proto == nil {
+			continue
+		}
+		for _, s := range f.Proto.Services {
+			entries = append(entries, svcEntry{s, f})
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].file.MicroserviceName != entries[j].file.MicroserviceName {
+			return entries[i].file.MicroserviceName < entries[j].file.MicroserviceName
+		}
+		return entries[i].svc.Name < entries[j].svc.Name
+	})
+
+	totalRPCs, streaming := 0, 0
+	for _, e := range entries {
+		for _, r := range e.svc.RPCs {
+			totalRPCs++
+			if r.ClientStreaming || r.ServerStreaming {
+				streaming++
+			}
+		}
+	}
+
+	coverage := make(map[[2]string]*graph.GRPCService)
+	unimplemented := ""
+	if cov != nil {
+		for _, s := range cov.Services {
+			coverage[[2]string{s.Proto, s.Name}] = s
+		}
+		unimplemented = fmt.Sprintf(" · %d unimplemented", cov.Unimplemented())
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>📡 gRPC APIs</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d services · %d RPCs · %d streaming%s</p>`, len(entries), totalRPCs, streaming, unimplemented))
+	for _, e := range entries {
+		ms := e.file.MicroserviceName
+		if ms == "" {
+			ms = "root"
+		}
+		pkg := e.file.Proto.Package
+		title := e.svc.Name
+		if pkg != "" {
+			title = pkg + "." + e.svc.Name
+		}
+		depr := ""
+		if e.svc.Deprecated {
+			depr = ` <span class="bs-badge">deprecated</span>`
+		}
+		sb.WriteString(`<div class="sub-card">`)
+		sb.WriteString(fmt.Sprintf(
+			`<h3 class="sub-card-title">🔴 %s%s <a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a> <span class="pkg-stats">%s</span></h3>`,
+			esc(title), depr, strings.ReplaceAll(ms, " ", "-"), esc(ms), esc(e.file.FileName()),
+		))
+		cs := coverage[[2]string{e.file.FilePath, e.svc.Name}]
+		if cs != nil {
+			sb.WriteString(buildGRPCCoverageHTML(cs, root))
+		}
+		sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+		if cs != nil {
+			sb.WriteString(`<thead><tr><th>RPC</th><th>Request</th><th>Response</th><th>Mode</th><th>Served by</th></tr></thead><tbody>`)
+		} else {
+			sb.WriteString(`<thead><tr><th>RPC</th><th>Request</th><th>Response</th><th>Mode</th></tr></thead><tbody>`)
+		}
+		for i, r := range e.svc.RPCs {
+			name := esc(r.Name)
+			if r.Deprecated {
+				name = `<s>` + name + `</s> <span class="bs-badge">deprecated</span>`
+			}
+			servedBy := ""
+			if cs != nil && i < len(cs.RPCs) {
+				switch m := cs.RPCs[i]; {
+				case m.Unimplemented:
+					servedBy = `<td><span class="pd-sev pd-breaking">unimplemented</span></td>`
+				case len(m.ServedBy) == 0:
+					servedBy = `<td>—</td>`
+				default:
+					servedBy = `<td>` + serviceLinks(m.ServedBy) + `</td>`
+				}
+			}
+			sb.WriteString(fmt.Sprintf(
+				`<tr><td class="mono">%s</td><td class="mono">%s</td><td class="mono">%s</td><td>%s</td>%s</tr>`,
+				name, esc(r.InputType), esc(r.OutputType), rpcModeBadge(r), servedBy,
+			))
+		}
+		sb.WriteString(`</tbody></table></div></div>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// buildProtoDiffHTML lists proto breaking changes between the base revision
+// and the working tree. Returns "" when no comparison was requested.
+func buildProtoDiffHTML(d *protodiff.Diff) string {
+	if d == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🧬 Proto Breaking Changes</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%s → %s · %d proto files · %d changes</p>`,
+		esc(d.Base), esc(d.Head), d.Files, len(d.Changes)))
+	if len(d.Changes) == 0 {
+		sb.WriteString(`<div class="ap-summary"><span class="ap-pass-badge">✓ No breaking changes</span></div></div>`)
+		return sb.String()
+	}
+	breaking := d.Breaking()
+	sb.WriteString(fmt.Sprintf(`<div class="ap-summary"><span class="ap-fail-badge">%d breaking</span><span class="ap-check-count">%d warnings</span></div>`,
+		breaking, len(d.Changes)-breaking))
+	sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+	sb.WriteString(`<thead><tr><th>Severity</th><th>Change</th><th>Element</th><th>Location</th></tr></thead><tbody>`)
+	for _, c := range d.Changes {
+		cls := "pd-warning"
+		if c.Severity == protodiff.SeverityBreaking {
+			cls = "pd-breaking"
+		}
+		sb.WriteString(fmt.Sprintf(
+			`<tr><td><span class="pd-sev %s">%s</span></td><td>%s</td><td class="mono">%s</td><td class="mono">%s:%d</td></tr>`,
+			cls, c.Severity, esc(c.Message), esc(c.Element), esc(c.File), c.Line,
+		))
+	}
+	sb.WriteString(`</tbody></table></div></div>`)
+	return sb.String()
+}
@@ -1,9 +1,27 @@
 package report
 
 import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/osv"
 	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/protodiff"
+	"github.com/goscope/internal/scanner"
 )
 
 func TestMatchGoTypeRef(t *testing.T) {
@@ -252,3 +270,999 @@ func TestDetectTechFromImport(t *testing.T) {
 		}
 	}
 }
+
+func TestProtoTypeRef(t *testing.T) {
+	tests := []struct {
+		typ, pkg, want string
+	}{
+		{"Invoice", "billing.v1", "Invoice"},
+		{".billing.v1.Invoice", "billing.v1", "Invoice"},
+		{"billing.v1.Invoice.LineItem", "billing.v1", "Invoice.LineItem"},
+		{"map<string, Money>", "billing.v1", "Money"},
+		{"google.protobuf.Timestamp", "billing.v1", "google.protobuf.Timestamp"},
+	}
+	for _, tt := range tests {
+		if got := protoTypeRef(tt.typ, tt.pkg); got != tt.want {
+			t.Errorf("protoTypeRef(%q, %q) = %q, want %q", tt.typ, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func testGRPCCoverage() *graph.GRPCCoverage {
+	return &graph.GRPCCoverage{Services: []*graph.GRPCService{{
+		Name: "Users", Package: "users.v1", Proto: "/code/proto/users.proto", Microservice: "proto",
+		Implementations: []graph.GRPCImpl{{Type: "server.usersServer", File: "/code/users/server.go", Line: 7,
+			Microservice: "users", Embeds: "pb.UnimplementedUsersServer", Registered: true, Missing: []string{"Watch"}}},
+		Registered: []string{"users"},
+		Clients:    []string{"gateway"},
+		RPCs: []graph.GRPCMethod{
+			{Name: "Get", ServedBy: []string{"users"}},
+			{Name: "Watch", ServedBy: []string{}, Unimplemented: true},
+		},
+	}}}
+}
+
+func TestBuildGRPCHTML(t *testing.T) {
+	if got := buildGRPCHTML(nil, nil, "/code"); got != "" {
+		t.Errorf("buildGRPCHTML(nil) = %q, want empty", got)
+	}
+	src := "syntax = \"proto3\";\nservice Users { rpc Watch(Req) returns (stream Event); rpc Get(Req) returns (Req); }\n"
+	pf := parser.ParseProtoSource("/code/proto/users.proto", "proto", []byte(src))
+	html := buildGRPCHTML([]*parser.ParsedFile{pf}, nil, "/code")
+	for _, want := range []string{"Users", "Watch", "server-stream"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildGRPCHTML missing %q", want)
+		}
+	}
+	if strings.Contains(html, "Served by") {
+		t.Error("buildGRPCHTML without coverage has a Served by column")
+	}
+
+	files := []*parser.ParsedFile{pf,
+		{FilePath: "/code/users/server.go", MicroserviceName: "users", FileType: "go", PackageName: "server",
+			Declarations: []parser.Declaration{
+				{Name: "usersServer", Kind: parser.DeclStruct, Line: 7, Fields: []parser.StructField{
+					{Name: "UnimplementedUsersServer", Type: "pb.UnimplementedUsersServer", Embedded: true},
+				}},
+				{Name: "Get", Kind: parser.DeclFunc, Receiver: "*usersServer"},
+			}},
+		{FilePath: "/code/gateway/main.go", MicroserviceName: "gateway", FileType: "go",
+			GRPCClients: []parser.GRPCRef{{Service: "Users"}}},
+	}
+	html = buildGRPCHTML(files, graph.BuildGRPCCoverage(files), "/code")
+	for _, want := range []string{
+		"2 RPCs · 1 streaming · 1 unimplemented",
+		`<span class="mono">server.usersServer</span>`,
+		"embeds pb.UnimplementedUsersServer · 1 of 2 RPCs missing",
+		`users/server.go:7</a>`,
+		`Clients <a href='#ms-gateway'`,
+		`<td><span class="pd-sev pd-breaking">unimplemented</span></td>`,
+		`<td><a href='#ms-users' class='tag tag-local pkg-link-inline' style='font-size:11px'>users</a></td></tr>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildGRPCHTML with coverage missing %q", want)
+		}
+	}
+}
+
+func TestBuildProtoDiffHTML(t *testing.T) {
+	if got := buildProtoDiffHTML(nil); got != "" {
+		t.Errorf("buildProtoDiffHTML(nil) = %q, want empty", got)
+	}
+	d := &protodiff.Diff{Base: "v1.0.0", Head: "working tree", Changes: []protodiff.Change{
+		{Severity: protodiff.SeverityBreaking, Kind: protodiff.KindFieldRemoved, File: "proto/users.proto",
+			Line: 4, Element: "users.v1.User.email", Message: "field email = 2 was removed"},
+	}}
+	html := buildProtoDiffHTML(d)
+	for _, want := range []string{"v1.0.0", "1 breaking", "users.v1.User.email", "proto/users.proto:4"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildProtoDiffHTML missing %q", want)
+		}
+	}
+}
+
+func TestBuildPackagesHTML(t *testing.T) {
+	if got := buildPackagesHTML(nil); got != "" {
+		t.Errorf("buildPackagesHTML(nil) = %q, want empty", got)
+	}
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/users/main.go", MicroserviceName: "users", FileType: "go", Imports: []string{"github.com/acme/users/store"}},
+		{FilePath: "/code/users/store/store.go", MicroserviceName: "users", FileType: "go", Imports: []string{"github.com/lib/pq"}},
+	}
+	pg := graph.BuildPackageGraph(files, gomod.NewResolver([]gomod.Module{{Path: "github.com/acme/users", Dir: "/code/users"}}))
+	html := buildPackagesHTML(pg)
+	for _, want := range []string{"2 local packages in 1 modules", "github.com/acme/users/store", "github.com/lib/pq"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildPackagesHTML missing %q", want)
+		}
+	}
+}
+
+func TestBuildCyclesHTML(t *testing.T) {
+	g := graph.New()
+	for _, v := range []string{"/code/svc/a.go", "/code/svc/b.go"} {
+		g.AddVertex(v)
+	}
+	if html := buildCyclesHTML(g, nil, nil); !strings.Contains(html, "No dependency cycles") {
+		t.Errorf("acyclic graph should report no cycles, got %q", html)
+	}
+	g.AddEdge("/code/svc/a.go", "/code/svc/b.go")
+	g.AddEdge("/code/svc/b.go", "/code/svc/a.go")
+	fileMap := map[string]*parser.ParsedFile{
+		"/code/svc/a.go": {FilePath: "/code/svc/a.go", MicroserviceName: "svc"},
+		"/code/svc/b.go": {FilePath: "/code/svc/b.go", MicroserviceName: "svc"},
+	}
+	html := buildCyclesHTML(g, nil, fileMap)
+	for _, want := range []string{"1 file cycles", "2 files", "Suggested break", "a.go → b.go"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildCyclesHTML missing %q", want)
+		}
+	}
+}
+
+func TestBuildServiceCouplingHTML(t *testing.T) {
+	if got := buildServiceCouplingHTML(nil); !strings.Contains(got, "No dependencies") {
+		t.Errorf("buildServiceCouplingHTML(nil) = %q", got)
+	}
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/orders/main.go", MicroserviceName: "orders", FileType: "go", Imports: []string{"github.com/acme/users/api"}},
+		{FilePath: "/code/users/api/api.go", MicroserviceName: "users", FileType: "go",
+			Declarations: []parser.Declaration{{Name: "Store", Kind: parser.DeclInterface}}},
+	}
+	resolver := gomod.NewResolver([]gomod.Module{
+		{Path: "github.com/acme/orders", Dir: "/code/orders"},
+		{Path: "github.com/acme/users", Dir: "/code/users"},
+	})
+	sg := graph.BuildServiceGraph(files, graph.BuildPackageGraph(files, resolver))
+	html := buildServiceCouplingHTML(sg)
+	for _, want := range []string{"2 services · 1 dependencies", "(import)", "<svg", "users: I=0.00 A=1.00 D=0.00"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildServiceCouplingHTML missing %q", want)
+		}
+	}
+}
+
+func testServiceCalls() []graph.ServiceCall {
+	return []graph.ServiceCall{{From: "orders", To: "users", Protocols: []string{graph.CallGRPC, graph.CallHTTP}, Sites: []graph.CallSite{
+		{Protocol: graph.CallGRPC, Source: graph.SourceClient, File: "/code/orders/main.go", Line: 9, Detail: "NewUserServiceClient"},
+		{Protocol: graph.CallHTTP, Source: graph.SourceConfig, File: "/code/docker-compose.yml", Line: 5, Detail: "USERS_URL=http://users:8080"},
+	}}}
+}
+
+func TestBuildServiceCallsHTML(t *testing.T) {
+	if got := buildServiceCallsHTML(nil, "/code"); got != "" {
+		t.Errorf("buildServiceCallsHTML(nil) = %q, want empty", got)
+	}
+	html := buildServiceCallsHTML(testServiceCalls(), "/code")
+	for _, want := range []string{
+		"<a href='#ms-orders'", "<a href='#ms-users'", "gRPC · HTTP",
+		`orders/main.go:9</a> <span class="mono" style="font-size:12px">NewUserServiceClient</span>`,
+		"USERS_URL=http://users:8080",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildServiceCallsHTML missing %q", want)
+		}
+	}
+
+	microservices := []*MicroserviceSummary{{Name: "orders"}, {Name: "users"}}
+	calls := append(testServiceCalls(), graph.ServiceCall{From: "orders", To: "billing", Protocols: []string{graph.CallTCP}})
+	g := buildArchitectureGraph(microservices, nil, nil, nil, calls)
+	if len(g.Links) != 1 || g.Links[0] != (gLink{Source: "ms:orders", Target: "ms:users", Kind: "call", Label: "gRPC · HTTP"}) {
+		t.Errorf("architecture links = %+v", g.Links)
+	}
+}
+
+func TestJSONDocumentMatchesSchema(t *testing.T) {
+	files := []*parser.ParsedFile{
+		{FilePath: "/code/orders/main.go", MicroserviceName: "orders", FileType: "go", PackageName: "main",
+			Imports: []string{"github.com/acme/users/api"}, LineCount: 12},
+		{FilePath: "/code/users/api/api.go", MicroserviceName: "users", FileType: "go", PackageName: "api",
+			Declarations: []parser.Declaration{{Name: "Store", Kind: parser.DeclInterface, Exported: true, Line: 3}}, LineCount: 8},
+	}
+	scan := testModuleScan()
+	resolver := scan.Resolver()
+	g := graph.New()
+	g.Build(files, resolver)
+	g.Analyze()
+	pg := graph.BuildPackageGraph(files, resolver)
+	a := &Analysis{
+		Version:     "test",
+		ProjectName: "code",
+		Root:        "/code",
+		Scan:        scan,
+		Files:       files,
+		Graph:       g,
+		Packages:    pg,
+		Services:    graph.BuildServiceGraph(files, pg),
+		GRPC:        testGRPCCoverage(),
+		Calls:       testServiceCalls(),
+		Branch:      "main",
+		AuthorStats: map[string]*gitpkg.AuthorStats{"ann": {FilesModified: 2, TotalCommits: 3}},
+		ProtoDiff:   &protodiff.Diff{Base: "v1", Head: "HEAD"},
+		Vulns:       testVulns(),
+		Licenses:    testLicenses(),
+	}
+
+	data, err := json.Marshal(buildJSONDocument(a, time.Unix(0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["schemaVersion"] != float64(SchemaVersion) {
+		t.Errorf("schemaVersion = %v, want %d", doc["schemaVersion"], SchemaVersion)
+	}
+	if got := len(doc["graph"].(map[string]any)["edges"].([]any)); got != 1 {
+		t.Errorf("graph edges = %d, want 1", got)
+	}
+
+	raw, err := os.ReadFile(filepath.Join("..", "..", "schema", "analysis.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	for _, e := range validateSchema(schema, schema, doc, "$") {
+		t.Error(e)
+	}
+}
+
+func TestCheckDocumentMatchesSchema(t *testing.T) {
+	load := func(name string) map[string]any {
+		raw, err := os.ReadFile(filepath.Join("..", "..", "schema", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			t.Fatalf("%s is not valid JSON: %v", name, err)
+		}
+		return schema
+	}
+	schema, analysis := load("check.schema.json"), load("analysis.schema.json")
+	// Both documents describe findings and vulnerabilities the same way.
+	for _, key := range []string{"findings", "vulnerabilities"} {
+		got, _ := json.Marshal(schema["properties"].(map[string]any)[key])
+		want, _ := json.Marshal(analysis["properties"].(map[string]any)[key])
+		if string(got) != string(want) {
+			t.Errorf("check schema %s differs from the analysis schema", key)
+		}
+	}
+
+	findings := []Finding{{ID: "panic", Check: "panic in library code", Priority: PriorityHigh, File: "orders/main.go", Path: "/code/orders/main.go", Line: 7, Snippet: "panic(err)"}}
+	for _, vulns := range []*osv.Report{nil, testVulns()} {
+		data, err := json.Marshal(buildCheckDocument(findings, vulns))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := doc["vulnerabilities"]; ok != (vulns != nil) {
+			t.Errorf("vulnerabilities present = %v, want %v", ok, vulns != nil)
+		}
+		for _, e := range validateSchema(schema, schema, doc, "$") {
+			t.Error(e)
+		}
+	}
+	data, _ := json.Marshal(buildCheckDocument(nil, nil))
+	if string(data) != `{"findings":[]}` {
+		t.Errorf("empty check document = %s", data)
+	}
+}
+
+// validateSchema checks v against the subset of JSON Schema used by
+// schema/analysis.schema.json and returns one message per violation.
+func validateSchema(root, s map[string]any, v any, path string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		def := strings.TrimPrefix(ref, "#/$defs/")
+		return validateSchema(root, root["$defs"].(map[string]any)[def].(map[string]any), v, path)
+	}
+	var errs []string
+	if c, ok := s["const"]; ok && c != v {
+		errs = append(errs, fmt.Sprintf("%s: %v, want %v", path, v, c))
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v not in %v", path, v, enum))
+		}
+	}
+	if typ, ok := s["type"]; ok {
+		types := []any{typ}
+		if list, ok := typ.([]any); ok {
+			types = list
+		}
+		matched := false
+		for _, t := range types {
+			matched = matched || jsonTypeMatches(t.(string), v)
+		}
+		if !matched {
+			return append(errs, fmt.Sprintf("%s: %T is not %v", path, v, typ))
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		for _, r := range asSlice(s["required"]) {
+			if _, ok := v[r.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing %q", path, r))
+			}
+		}
+		for k, val := range v {
+			if ps, ok := props[k].(map[string]any); ok {
+				errs = append(errs, validateSchema(root, ps, val, path+"."+k)...)
+			} else if ap, ok := s["additionalProperties"].(map[string]any); ok {
+				errs = append(errs, validateSchema(root, ap, val, path+"."+k)...)
+			} else if s["additionalProperties"] == false {
+				errs = append(errs, fmt.Sprintf("%s: unexpected property %q", path, k))
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+func jsonTypeMatches(typ string, v any) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+// testModuleScan is a two-module workspace in /code.
+func testModuleScan() *scanner.ScanResult {
+	return &scanner.ScanResult{
+		Microservices: map[string][]string{
+			"orders": {"/code/orders/main.go"},
+			"users":  {"/code/users/api/api.go"},
+		},
+		Modules: []gomod.Module{
+			{Path: "github.com/acme/orders", Dir: "/code/orders", GoVersion: "1.22", Workspace: true,
+				Requires: []gomod.Require{{Path: "github.com/acme/users", Version: "v0.0.0"}, {Path: "golang.org/x/sys", Version: "v0.20.0", Indirect: true}},
+				Replaces: []gomod.Replace{{Old: "github.com/acme/users", New: "../users", Dir: "/code/users"}}},
+			{Path: "github.com/acme/users", Dir: "/code/users", GoVersion: "1.21", Toolchain: "go1.22.3", Workspace: true,
+				Requires: []gomod.Require{{Path: "golang.org/x/sys", Version: "v0.18.0"}, {Path: "google.golang.org/grpc", Version: "v1.64.0"}},
+				Replaces: []gomod.Replace{{Old: "google.golang.org/grpc", New: "github.com/acme/grpc", NewVersion: "v1.64.1"}}},
+		},
+		ModuleFiles: map[string][]string{
+			"github.com/acme/orders": {"/code/orders/main.go"},
+			"github.com/acme/users":  {"/code/users/api/api.go"},
+		},
+		Workspace: &gomod.Workspace{GoVersion: "1.22", Use: []string{"/code/orders", "/code/users"}},
+	}
+}
+
+func TestBuildModulesHTML(t *testing.T) {
+	if got := buildModulesHTML(&scanner.ScanResult{Modules: []gomod.Module{{Path: "m"}}}, "/code"); got != "" {
+		t.Errorf("single module: got %q, want empty", got)
+	}
+	html := buildModulesHTML(testModuleScan(), "/code")
+	for _, want := range []string{
+		"2 modules · go.work (go 1.22) uses 2",
+		"github.com/acme/orders <span class=\"tag tag-tech\"",
+		"#ms-orders",
+		"1 direct · 1 indirect",
+		"github.com/acme/users → users",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildModulesHTML missing %q", want)
+		}
+	}
+}
+
+func TestBuildDependenciesHTML(t *testing.T) {
+	if got := buildDependenciesHTML(&scanner.ScanResult{Modules: []gomod.Module{{Path: "m", GoVersion: "1.22"}}}, "/code"); got != "" {
+		t.Errorf("no requirements: got %q, want empty", got)
+	}
+	html := buildDependenciesHTML(testModuleScan(), "/code")
+	for _, want := range []string{
+		"2 modules · 3 distinct requirements · 1 at more than one version · 2 replace directives",
+		"go 1.22",
+		"Toolchain directives: go1.22.3 (1)",
+		`<td class="mono" rowspan="2">golang.org/x/sys</td><td class="mono">v0.20.0</td>`,
+		`title="github.com/acme/users">users</span>`,
+		`<td class="mono">users</td><td>local</td>`,
+		`<td class="mono">github.com/acme/grpc@v1.64.1</td><td>fork</td>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildDependenciesHTML missing %q", want)
+		}
+	}
+}
+
+func testVulns() *osv.Report {
+	return &osv.Report{Database: "/mirror/all.zip", Entries: 2, Checked: 3, Vulnerabilities: []osv.Vulnerability{{
+		ID: "GO-2023-2153", Aliases: []string{"CVE-2023-44487"}, Summary: "HTTP/2 Rapid Reset", Severity: osv.SeverityHigh, Score: 7.5,
+		URL: "https://pkg.go.dev/vuln/GO-2023-2153", Package: "google.golang.org/grpc", Version: "v1.56.0", Fixed: "v1.56.3",
+		RequiredBy: []osv.Requirer{
+			{Module: "github.com/acme/orders", GoMod: "/code/orders/go.mod", Line: 5},
+			{Module: "github.com/acme/tools", GoMod: "/code/tools/go.mod", Line: 4},
+		},
+	}, {
+		ID: "GHSA-xxxx", Summary: "Memory exhaustion", Severity: osv.SeverityUnknown,
+		Package: "golang.org/x/net", Version: "v0.16.0",
+		RequiredBy: []osv.Requirer{{Module: "github.com/acme/users", GoMod: "/code/users/go.mod", Line: 3}},
+	}}}
+}
+
+func TestBuildVulnsHTML(t *testing.T) {
+	if got := buildVulnsHTML(nil, testModuleScan()); got != "" {
+		t.Errorf("no database: got %q, want empty", got)
+	}
+	if got := buildVulnsHTML(&osv.Report{Checked: 4}, testModuleScan()); !strings.Contains(got, "No known vulnerabilities") {
+		t.Errorf("clean report: got %q", got)
+	}
+	html := buildVulnsHTML(testVulns(), testModuleScan())
+	for _, want := range []string{
+		"3 requirements checked against 2 OSV entries in /mirror/all.zip",
+		"1 high · 1 unknown",
+		`<span class="pd-sev pd-breaking">HIGH 7.5</span>`,
+		`<a href="https://pkg.go.dev/vuln/GO-2023-2153"`,
+		"google.golang.org/grpc@v1.56.0</td><td class=\"mono\">v1.56.3",
+		"#ms-orders",
+		">github.com/acme/tools</span>", // a module outside the scan
+		"no fix",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildVulnsHTML missing %q", want)
+		}
+	}
+}
+
+func testLicenses() *license.Report {
+	return &license.Report{ModuleCache: "/go/pkg/mod", Deny: []string{"GPL"}, Missing: 1, Modules: []license.Module{
+		{Path: "github.com/acme/gpl", Version: "v1.0.0", Licenses: []string{"GPL-3.0"}, Denied: true, RequiredBy: []string{"github.com/acme/orders"}},
+		{Path: "github.com/acme/odd", Version: "v0.1.0", Licenses: []string{license.Unknown}, RequiredBy: []string{"github.com/acme/users"}},
+		{Path: "github.com/acme/gone", Version: "v2.0.0", Licenses: []string{}, RequiredBy: []string{"github.com/acme/tools"}},
+		{Path: "golang.org/x/sys", Version: "v0.20.0", Licenses: []string{"BSD-3-Clause"},
+			RequiredBy: []string{"github.com/acme/orders", "github.com/acme/users"}},
+	}}
+}
+
+func TestBuildLicensesHTML(t *testing.T) {
+	if got := buildLicensesHTML(&license.Report{}, testModuleScan()); got != "" {
+		t.Errorf("no requirements: got %q, want empty", got)
+	}
+	got := groupLicenses(testLicenses(), testModuleScan())
+	if len(got) != 3 || got[0].Name != "github.com/acme/tools" || got[1].Name != "orders" || got[2].Name != "users" {
+		t.Fatalf("groupLicenses = %+v", got)
+	}
+	if o := got[1]; o.Licenses["GPL-3.0"] != 1 || o.Licenses["BSD-3-Clause"] != 1 || len(o.Denied) != 1 || o.Missing != 0 {
+		t.Errorf("orders = %+v", o)
+	}
+	if got[0].Missing != 1 {
+		t.Errorf("tools = %+v, want 1 missing", got[0])
+	}
+	html := buildLicensesHTML(testLicenses(), testModuleScan())
+	for _, want := range []string{
+		"4 required modules · 3 found in vendor/ or /go/pkg/mod · 1 not found",
+		"denied: GPL",
+		"1 modules under denied licenses",
+		`<span class="tag pd-breaking" style="font-size:11px">GPL-3.0 <b>1</b></span>`,
+		`<span class="tag tag-foreign" style="font-size:11px">unknown <b>1</b></span>`,
+		"#ms-orders",
+		"<td class=\"mono\">github.com/acme/gpl@v1.0.0</td><td>GPL-3.0</td>",
+		"<td class=\"mono\">github.com/acme/odd@v0.1.0</td><td>unknown</td>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildLicensesHTML missing %q", want)
+		}
+	}
+}
+
+func TestBuildRoutesHTML(t *testing.T) {
+	if got := buildRoutesHTML(nil, "/src"); got != "" {
+		t.Errorf("no routes: got %q, want empty", got)
+	}
+	router := &parser.ParsedFile{FilePath: "/src/orders/api/router.go", MicroserviceName: "orders",
+		Declarations: []parser.Declaration{{Name: "health", Kind: parser.DeclFunc, Line: 30}},
+		Routes: []parser.HTTPRoute{
+			{Framework: parser.FrameworkGin, Method: "POST", Path: "/orders", Handler: "h.Create", Middleware: []string{"gin.Recovery()", "auth"}, Line: 12},
+			{Framework: parser.FrameworkGin, Method: "GET", Path: "/healthz", Handler: "health", Line: 11},
+			{Framework: parser.FrameworkGin, Method: "GET", Path: "/debug", Handler: "func literal", Line: 14},
+			{Framework: parser.FrameworkGin, Method: "DELETE", Path: "/orders/:id", Handler: "h.Gone", Line: 13},
+		}}
+	handlers := &parser.ParsedFile{FilePath: "/src/orders/api/handlers.go", MicroserviceName: "orders",
+		Declarations: []parser.Declaration{{Name: "Create", Kind: parser.DeclFunc, Receiver: "*Handler", Line: 42}}}
+	users := &parser.ParsedFile{FilePath: "/src/users/main.go", MicroserviceName: "users",
+		Routes: []parser.HTTPRoute{{Framework: parser.FrameworkNetHTTP, Method: "GET", Path: "/users/{id}", Handler: "getUser", Line: 20}}}
+
+	html := buildRoutesHTML([]*parser.ParsedFile{router, handlers, users}, "/src")
+	for _, want := range []string{
+		"5 endpoints · 2 services · gin 4 · net/http 1",
+		"#ms-orders", "#ms-users",
+		`gin.Recovery() → auth`,
+		`>orders/api/handlers.go:42</a>`,  // method in another file of the service
+		`>orders/api/router.go:30</a>`,    // function in the registering file
+		`>orders/api/router.go:14</a>`,    // inline handler
+		`>orders/api/router.go:13</a>`,    // unresolved handler: the registration
+		`href="file:///src/users/main.go"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("buildRoutesHTML missing %q", want)
+		}
+	}
+	if strings.Index(html, "/debug") > strings.Index(html, "/healthz") || strings.Index(html, "/healthz") > strings.Index(html, "/orders<") {
+		t.Error("routes not sorted by path")
+	}
+
+	comps := detectGoComponents([]*parser.ParsedFile{router, users}, map[string]bool{"Gin": true}, 0, 0)
+	var summaries []string
+	for _, c := range comps {
+		summaries = append(summaries, c.Summary)
+	}
+	if got := strings.Join(summaries, ", "); !strings.Contains(got, "Gin HTTP server · 4 routes") || !strings.Contains(got, "net/http ServeMux · 1 routes") {
+		t.Errorf("components = %s", got)
+	}
+}
+
+func TestWriteSARIFVulnerabilities(t *testing.T) {
+	var buf strings.Builder
+	if err := WriteSARIF(&buf, nil, testVulns(), "/code", "v1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID         string         `json:"id"`
+						Properties map[string]any `json:"properties"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	run := log.Runs[0]
+	if n := len(run.Tool.Driver.Rules); n != len(goAntipatternChecks())+2 {
+		t.Errorf("got %d rules, want the checks plus 2 vulnerabilities", n)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want one per requiring go.mod", len(run.Results))
+	}
+	r := run.Results[1]
+	rule := run.Tool.Driver.Rules[r.RuleIndex]
+	loc := r.Locations[0].PhysicalLocation
+	if r.RuleID != "GO-2023-2153" || rule.ID != r.RuleID || r.Level != "error" ||
+		loc.ArtifactLocation.URI != "tools/go.mod" || loc.Region.StartLine != 4 {
+		t.Errorf("result = %+v, rule %s", r, rule.ID)
+	}
+	if rule.Properties["security-severity"] != "7.5" {
+		t.Errorf("rule properties = %v", rule.Properties)
+	}
+	if run.Results[2].Level != "note" {
+		t.Errorf("UNKNOWN severity level = %q, want note", run.Results[2].Level)
+	}
+}
+
+func TestAntipatternCheckIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, ch := range goAntipatternChecks() {
+		if ch.ID == "" || strings.ToLower(ch.ID) != ch.ID || strings.ContainsAny(ch.ID, " _") {
+			t.Errorf("check %q has invalid ID %q", ch.Name, ch.ID)
+		}
+		if seen[ch.ID] {
+			t.Errorf("duplicate check ID %q", ch.ID)
+		}
+		seen[ch.ID] = true
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	findings := []Finding{
+		{ID: "defer-in-loop", Check: "defer Inside a Loop", Priority: PriorityMedium, File: "code/svc/a.go", Path: "/code/svc/a.go", Line: 12, Snippet: "defer f.Close()", Author: "ann"},
+		{ID: "panic", Check: "panic() in Business Logic", Priority: PriorityHigh, File: "elsewhere/b.go", Path: "/elsewhere/b.go", Line: 3},
+	}
+	var buf strings.Builder
+	if err := WriteSARIF(&buf, findings, nil, "/code", "v1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Version string `json:"version"`
+					Rules   []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+							Snippet   struct {
+								Text string `json:"text"`
+							} `json:"snippet"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				Properties map[string]string `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version %q with %d runs, want 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(goAntipatternChecks()) || run.Tool.Driver.Version != "v1.2.3" {
+		t.Errorf("driver: %d rules version %q", len(run.Tool.Driver.Rules), run.Tool.Driver.Version)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+
+	r := run.Results[0]
+	if rule := run.Tool.Driver.Rules[r.RuleIndex]; rule.ID != "defer-in-loop" || rule.DefaultConfiguration.Level != "warning" {
+		t.Errorf("ruleIndex %d points at %+v", r.RuleIndex, rule)
+	}
+	loc := r.Locations[0].PhysicalLocation
+	if r.Level != "warning" || loc.ArtifactLocation.URI != "svc/a.go" || loc.ArtifactLocation.URIBaseID != "SRCROOT" ||
+		loc.Region.StartLine != 12 || loc.Region.Snippet.Text != "defer f.Close()" || r.Properties["author"] != "ann" {
+		t.Errorf("first result = %+v", r)
+	}
+	if r := run.Results[1]; r.Level != "error" || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "file:///elsewhere/b.go" {
+		t.Errorf("second result = %+v", r)
+	}
+}
+
+// apSourceFor parses src as a.go for the AST-based checks. With typed set
+// the file is type-checked against the installed standard library.
+func apSourceFor(t *testing.T, src, goVersion string, typed bool) *apSource {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "a.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &apSource{
+		File:      &parser.ParsedFile{FilePath: "a.go"},
+		Lines:     strings.Split(src, "\n"),
+		Fset:      fset,
+		AST:       file,
+		GoVersion: goVersion,
+	}
+	if typed {
+		s.Info = &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		conf := types.Config{Importer: importer.Default()}
+		if _, err := conf.Check("a", fset, []*ast.File{file}, s.Info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestASTAntipatternChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     func(*apSource) []apViolation
+		goVersion string
+		src       string
+		want      []int // violation lines
+	}{
+		{"loop var captured", checkLoopVarGoroutine, "1.21", `package a
+func f(xs []int) {
+	for _, x := range xs {
+		go func() { println(x) }()
+	}
+}`, []int{4}},
+		{"loop var shadowed", checkLoopVarGoroutine, "1.21", `package a
+func f(xs []int) {
+	for _, x := range xs {
+		x := x
+		go func() { println(x) }()
+	}
+}`, nil},
+		{"loop var passed as argument", checkLoopVarGoroutine, "1.21", `package a
+func f(xs []int) {
+	for i := 0; i < len(xs); i++ {
+		go func(i int) { println(xs[i]) }(i)
+	}
+}`, nil},
+		{"loop var on go1.22", checkLoopVarGoroutine, "1.22", `package a
+func f(xs []int) {
+	for _, x := range xs {
+		go func() { println(x) }()
+	}
+}`, nil},
+
+		{"mutex by value", checkCopyMutex, "", `package a
+import "sync"
+type inner struct{ mu sync.Mutex }
+type T struct{ in inner }
+func (t T) Get() {}
+func use(m sync.RWMutex) {}
+func ptr(t *T) {}
+func (t *T) Set() {}
+func ret() T { return T{} }`, []int{5, 6}},
+
+		{"defer in loop", checkDeferInLoop, "", `package a
+func f(names []string) {
+	for _, n := range names {
+		defer println(n)
+	}
+}`, []int{4}},
+		{"defer in func literal in loop", checkDeferInLoop, "", `package a
+func f(names []string) {
+	for _, n := range names {
+		func() {
+			defer println(n)
+		}()
+	}
+	defer println()
+}`, nil},
+
+		{"response body not closed", checkUnclosedResponseBody, "", `package a
+import "net/http"
+func f(client *http.Client, req *http.Request) {
+	resp, _ := client.Do(req)
+	println(resp.StatusCode)
+	_, _ = http.Get("x")
+}`, []int{4, 6}},
+		{"response body closed or handed off", checkUnclosedResponseBody, "", `package a
+import "net/http"
+func f(u string) (*http.Response, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	println(resp.StatusCode)
+
+	r2, _ := http.Head(u)
+	consume(r2)
+
+	v, _ := cache.Get(u)
+	println(v)
+
+	defer resp.Body.Close()
+	return http.Post(u, "", nil)
+}`, nil},
+
+		{"rows without Err and Close", checkSQLRowsErr, "", `package a
+func f(db DB) {
+	rows, _ := db.QueryContext(ctx, "q")
+	for rows.Next() {
+	}
+}`, []int{4}},
+		{"rows.Err after loop", checkSQLRowsErr, "", `package a
+func f(db DB, sc Scanner) error {
+	rows, _ := db.Query("q")
+	defer rows.Close()
+	for rows.Next() {
+	}
+	for sc.Next() {
+	}
+	row := db.QueryRow("q")
+	for row.Next() {
+	}
+	return rows.Err()
+}`, nil},
+		{"rows never closed", checkRowsClose, "", `package a
+func f(db DB) error {
+	rows, _ := db.Query("q")
+	for rows.Next() {
+	}
+	return rows.Err()
+}`, []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, v := range tt.check(apSourceFor(t, tt.src, tt.goVersion, false)) {
+				got = append(got, v.Line)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("violations on lines %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestASTAntipatternChecksTyped(t *testing.T) {
+	if testing.Short() {
+		t.Skip("imports the standard library")
+	}
+	src := `package a
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+)
+
+type counter struct {
+	sync.Mutex
+	n int
+}
+
+type holder struct{ c counter }
+
+func byValue(h holder) {}
+
+func fetch(api *http.Client, req *http.Request) {
+	r, _ := api.Do(req)
+	println(r.StatusCode)
+}
+
+type iter struct{}
+
+func (iter) Next() bool { return false }
+
+func read(db *sql.DB, it iter) {
+	rs, _ := db.Query("q")
+	defer rs.Close()
+	for rs.Next() {
+	}
+	for it.Next() {
+	}
+}
+`
+	s := apSourceFor(t, src, "1.21", true)
+	for _, tt := range []struct {
+		check func(*apSource) []apViolation
+		want  int
+	}{
+		{checkCopyMutex, 16},            // lock embedded one struct down
+		{checkUnclosedResponseBody, 19}, // client not named "client"
+		{checkSQLRowsErr, 30},           // iter has no Err method
+	} {
+		vs := tt.check(s)
+		if len(vs) != 1 || vs[0].Line != tt.want {
+			t.Errorf("%v, want one violation on line %d", vs, tt.want)
+		}
+	}
+}
+
+func TestFindingsSuppressionAndBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	write := func(src string) []*parser.ParsedFile {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return []*parser.ParsedFile{{FilePath: path, FileType: "go"}}
+	}
+	ids := func(fs []Finding) []string {
+		var out []string
+		for _, f := range fs {
+			out = append(out, fmt.Sprintf("%s:%d", f.ID, f.Line))
+		}
+		return out
+	}
+
+	files := write(`package a
+
+func f(xs []func()) {
+	for _, x := range xs {
+		defer x()
+	}
+	//goscope:ignore panic unreachable by construction
+	panic("no")
+}
+
+func g(xs []func()) {
+	for _, x := range xs {
+		defer   x() //goscope:ignore sleep-sync wrong check, still reported
+	}
+}
+`)
+	got := Findings(files, CheckOptions{Root: dir})
+	if want := []string{"defer-in-loop:5", "defer-in-loop:13"}; fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Fatalf("findings %v, want %v", ids(got), want)
+	}
+
+	basePath := filepath.Join(dir, DefaultBaselinePath)
+	if err := NewBaseline(got[:1], dir).Write(basePath); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Findings) != 1 || b.Findings[0] != (BaselineEntry{Check: "defer-in-loop", File: "a.go", Snippet: "defer x()"}) {
+		t.Fatalf("baseline %+v", b.Findings)
+	}
+
+	// The baselined line moved down; one of the two identical defers is
+	// still accepted, the other is new.
+	files = write(`package a
+
+// f runs xs.
+func f(xs []func()) {
+	for _, x := range xs {
+		defer x()
+	}
+}
+
+func g(xs []func()) {
+	for _, x := range xs {
+		defer   x()
+	}
+}
+`)
+	got = Findings(files, CheckOptions{Root: dir, Baseline: b})
+	if want := []string{"defer-in-loop:12"}; fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Errorf("findings with baseline %v, want %v", ids(got), want)
+	}
+
+	got = Findings(files, CheckOptions{Root: dir, MaxViolations: 1})
+	if want := []string{"defer-in-loop:6"}; fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Errorf("findings with MaxViolations 1 %v, want %v", ids(got), want)
+	}
+}
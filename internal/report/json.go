@@ -0,0 +1,352 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	gitpkg "github.com/goscope/internal/git"
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/graph"
+	"github.com/goscope/internal/osv"
+	"github.com/goscope/internal/parser"
+	"github.com/goscope/internal/protodiff"
+	"github.com/goscope/internal/scanner"
+)
+
+// SchemaVersion is the version of the document written by WriteJSON and
+// described by schema/analysis.schema.json. It is bumped only for
+// incompatible changes; new fields may appear without a bump.
+const SchemaVersion = 1
+
+// jsonDocument is the top-level JSON export. Lists are sorted so two runs
+// over the same tree produce the same document apart from generatedAt.
+type jsonDocument struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	Generator     string               `json:"generator"`
+	GeneratedAt   string               `json:"generatedAt"` // RFC 3339
+	Project       jsonProject          `json:"project"`
+	Scan          jsonScan             `json:"scan"`
+	Files         []*parser.ParsedFile `json:"files"`
+	Graph         jsonGraph            `json:"graph"`
+	Packages      jsonPackageGraph     `json:"packages"`
+	Services      jsonServiceGraph     `json:"services"`
+	GRPC          *graph.GRPCCoverage  `json:"grpc,omitempty"`
+	Calls         []graph.ServiceCall  `json:"calls,omitempty"`
+	Git           jsonGit              `json:"git"`
+	Architecture  jsonArchitecture     `json:"architecture"`
+	Dependencies  jsonDependencies     `json:"dependencies"`
+	Findings      []Finding            `json:"findings"`
+	ProtoDiff     *protodiff.Diff      `json:"protoDiff,omitempty"`
+	Vulns         *osv.Report          `json:"vulnerabilities,omitempty"`
+	Licenses      *jsonLicenses        `json:"licenses,omitempty"`
+}
+
+type jsonProject struct {
+	Name string `json:"name"`
+	Root string `json:"root"`
+}
+
+type jsonScan struct {
+	ServicesRoot    string                   `json:"servicesRoot"`
+	RootSubdirs     []string                 `json:"rootSubdirs"`
+	GitRepos        []string                 `json:"gitRepos"`
+	Modules         []gomod.Module           `json:"modules"`
+	Workspace       *gomod.Workspace         `json:"workspace,omitempty"`
+	Microservices   []jsonMicroservice       `json:"microservices"`
+	ForeignServices []scanner.ForeignService `json:"foreignServices"`
+}
+
+type jsonMicroservice struct {
+	Name    string   `json:"name"`
+	Files   []string `json:"files"`
+	Modules []string `json:"modules,omitempty"` // paths of the modules holding the files
+	Shared  bool     `json:"shared,omitempty"`  // a shared library from the config's service map
+}
+
+// jsonGraph is the file dependency graph.
+type jsonGraph struct {
+	Vertices []jsonVertex  `json:"vertices"`
+	Edges    [][2]string   `json:"edges"`
+	Cycles   []graph.Cycle `json:"cycles"`
+}
+
+type jsonVertex struct {
+	ID        string  `json:"id"`
+	PageRank  float64 `json:"pageRank"`
+	InDegree  int     `json:"inDegree"`
+	OutDegree int     `json:"outDegree"`
+}
+
+type jsonPackageGraph struct {
+	Packages []*graph.Package  `json:"packages"`
+	Edges    []jsonPackageEdge `json:"edges"`
+	Cycles   []graph.Cycle     `json:"cycles"`
+}
+
+type jsonPackageEdge struct {
+	From string         `json:"from"`
+	To   string         `json:"to"`
+	Kind graph.EdgeKind `json:"kind"`
+}
+
+type jsonServiceGraph struct {
+	Metrics      []*graph.ServiceMetrics `json:"metrics"`
+	Dependencies []*graph.ServiceDep     `json:"dependencies"`
+}
+
+type jsonGit struct {
+	Branch   string                 `json:"branch"`
+	Authors  []jsonAuthor           `json:"authors"`
+	Churn    []gitpkg.FileChurnStat `json:"churn"`
+	Tags     gitpkg.TagStats        `json:"tags"`
+	Commits  gitpkg.CommitStats     `json:"commits"`
+	Branches gitpkg.BranchStats     `json:"branches"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	gitpkg.AuthorStats
+}
+
+type jsonArchitecture struct {
+	Layers         []jsonLayer     `json:"layers"`
+	Components     []jsonComponent `json:"components"`
+	Technologies   []string        `json:"technologies"`
+	DockerServices []string        `json:"dockerServices"`
+}
+
+type jsonLayer struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Lines int    `json:"lines"`
+}
+
+type jsonComponent struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+}
+
+// WriteJSON writes a as an indented JSON document. It runs the anti-pattern
+// checks, so it costs about as much as Generate.
+func WriteJSON(a *Analysis, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildJSONDocument(a, time.Now()))
+}
+
+// checkDocument is the output of `goscope check --format json`, described
+// by schema/check.schema.json.
+type checkDocument struct {
+	Findings []Finding   `json:"findings"`
+	Vulns    *osv.Report `json:"vulnerabilities,omitempty"`
+}
+
+// WriteCheckJSON writes the findings of `goscope check` and, when an OSV
+// database was checked, the vulnerable dependencies as an indented JSON
+// document.
+func WriteCheckJSON(w io.Writer, findings []Finding, vulns *osv.Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildCheckDocument(findings, vulns))
+}
+
+func buildCheckDocument(findings []Finding, vulns *osv.Report) *checkDocument {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	return &checkDocument{Findings: findings, Vulns: vulns}
+}
+
+func buildJSONDocument(a *Analysis, now time.Time) *jsonDocument {
+	scan := a.scan()
+	doc := &jsonDocument{
+		SchemaVersion: SchemaVersion,
+		Generator:     "goscope " + a.Version,
+		GeneratedAt:   now.UTC().Format(time.RFC3339),
+		Project:       jsonProject{Name: a.ProjectName, Root: a.Root},
+		Scan: jsonScan{
+			ServicesRoot:    scan.ServicesRoot,
+			RootSubdirs:     orEmpty(scan.RootSubdirs),
+			GitRepos:        orEmpty(scan.GitRepos),
+			Modules:         scan.Modules,
+			Workspace:       scan.Workspace,
+			ForeignServices: scan.ForeignServices,
+		},
+		Files:        a.Files,
+		Graph:        fileGraphJSON(a.Graph),
+		Packages:     packageGraphJSON(a.Packages),
+		Services:     serviceGraphJSON(a.Services),
+		GRPC:         a.GRPC,
+		Calls:        a.Calls,
+		Git:          gitJSON(a),
+		Dependencies: dependenciesJSON(scan),
+		Findings:     Findings(a.Files, a.checkOptions()),
+		ProtoDiff:    a.ProtoDiff,
+		Vulns:        a.Vulns,
+	}
+	if a.Licenses != nil {
+		doc.Licenses = &jsonLicenses{Report: a.Licenses, Services: groupLicenses(a.Licenses, scan)}
+	}
+	if doc.Scan.Modules == nil {
+		doc.Scan.Modules = []gomod.Module{}
+	}
+	if doc.Scan.ForeignServices == nil {
+		doc.Scan.ForeignServices = []scanner.ForeignService{}
+	}
+	if doc.Files == nil {
+		doc.Files = []*parser.ParsedFile{}
+	}
+	if doc.Findings == nil {
+		doc.Findings = []Finding{}
+	}
+
+	msNames := make([]string, 0, len(scan.Microservices))
+	for name := range scan.Microservices {
+		msNames = append(msNames, name)
+	}
+	sort.Strings(msNames)
+	modulesOf := make(map[string][]string)
+	for _, g := range groupModules(scan) {
+		for _, ms := range g.Services {
+			modulesOf[ms] = append(modulesOf[ms], g.Module.Path)
+		}
+	}
+	doc.Scan.Microservices = []jsonMicroservice{}
+	for _, name := range msNames {
+		files := append([]string(nil), scan.Microservices[name]...)
+		sort.Strings(files)
+		doc.Scan.Microservices = append(doc.Scan.Microservices, jsonMicroservice{Name: name, Files: files, Modules: modulesOf[name], Shared: scan.Shared[name]})
+	}
+
+	doc.Architecture = architectureJSON(a)
+	return doc
+}
+
+func fileGraphJSON(g *graph.DependencyGraph) jsonGraph {
+	out := jsonGraph{Vertices: []jsonVertex{}, Edges: [][2]string{}, Cycles: []graph.Cycle{}}
+	if g == nil {
+		return out
+	}
+	for v := range g.Vertices {
+		out.Vertices = append(out.Vertices, jsonVertex{
+			ID:        v,
+			PageRank:  g.PageRankScores[v],
+			InDegree:  g.InDegree(v),
+			OutDegree: g.OutDegree(v),
+		})
+	}
+	sort.Slice(out.Vertices, func(i, j int) bool { return out.Vertices[i].ID < out.Vertices[j].ID })
+	out.Edges = sortedEdges(g.Edges)
+	if c := g.Cycles(); c != nil {
+		out.Cycles = c
+	}
+	return out
+}
+
+func packageGraphJSON(pg *graph.PackageGraph) jsonPackageGraph {
+	out := jsonPackageGraph{Packages: []*graph.Package{}, Edges: []jsonPackageEdge{}, Cycles: []graph.Cycle{}}
+	if pg == nil {
+		return out
+	}
+	for _, p := range pg.Packages {
+		out.Packages = append(out.Packages, p)
+	}
+	sort.Slice(out.Packages, func(i, j int) bool { return out.Packages[i].ID < out.Packages[j].ID })
+	for _, e := range sortedEdges(pg.Edges) {
+		out.Edges = append(out.Edges, jsonPackageEdge{From: e[0], To: e[1], Kind: pg.EdgeKinds[e]})
+	}
+	if c := pg.Cycles(); c != nil {
+		out.Cycles = c
+	}
+	return out
+}
+
+func serviceGraphJSON(sg *graph.ServiceGraph) jsonServiceGraph {
+	out := jsonServiceGraph{Metrics: []*graph.ServiceMetrics{}, Dependencies: []*graph.ServiceDep{}}
+	if sg == nil {
+		return out
+	}
+	out.Metrics = sg.SortedMetrics()
+	for _, e := range sortedEdges(sg.Edges) {
+		out.Dependencies = append(out.Dependencies, sg.Deps[e])
+	}
+	return out
+}
+
+func gitJSON(a *Analysis) jsonGit {
+	out := jsonGit{
+		Branch:   a.Branch,
+		Authors:  []jsonAuthor{},
+		Churn:    a.Churn,
+		Tags:     a.Tags,
+		Commits:  a.Commits,
+		Branches: a.Branches,
+	}
+	for name, st := range a.AuthorStats {
+		out.Authors = append(out.Authors, jsonAuthor{Name: name, AuthorStats: *st})
+	}
+	sort.Slice(out.Authors, func(i, j int) bool {
+		if out.Authors[i].TotalCommits != out.Authors[j].TotalCommits {
+			return out.Authors[i].TotalCommits > out.Authors[j].TotalCommits
+		}
+		return out.Authors[i].Name < out.Authors[j].Name
+	})
+	if out.Churn == nil {
+		out.Churn = []gitpkg.FileChurnStat{}
+	}
+	return out
+}
+
+func architectureJSON(a *Analysis) jsonArchitecture {
+	out := jsonArchitecture{
+		Layers:         []jsonLayer{},
+		Components:     []jsonComponent{},
+		Technologies:   []string{},
+		DockerServices: orEmpty(a.DockerServices),
+	}
+	for _, l := range archLayerStats(a.Files) {
+		out.Layers = append(out.Layers, jsonLayer{Name: l.Layer, Files: l.FileCount, Lines: l.LineCount})
+	}
+
+	var services, rpcs int
+	for _, f := range a.Files {
+		for _, d := range f.Declarations {
+			switch d.Kind {
+			case parser.DeclService:
+				services++
+			case parser.DeclRPC:
+				rpcs++
+			}
+		}
+	}
+	techSet := a.techSet()
+	for _, c := range detectGoComponents(a.Files, techSet, services, rpcs) {
+		out.Components = append(out.Components, jsonComponent{Name: c.Name, Summary: c.Summary})
+	}
+	for t := range techSet {
+		out.Technologies = append(out.Technologies, t)
+	}
+	sort.Strings(out.Technologies)
+	return out
+}
+
+func sortedEdges(edges [][2]string) [][2]string {
+	out := append([][2]string{}, edges...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i][0] != out[j][0] {
+			return out[i][0] < out[j][0]
+		}
+		return out[i][1] < out[j][1]
+	})
+	return out
+}
+
+// orEmpty keeps nil slices from being encoded as null.
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/license"
+	"github.com/goscope/internal/scanner"
+)
+
+// serviceLicenses is what one service ships: the licenses of the modules
+// its go.mod files require.
+type serviceLicenses struct {
+	Name     string         `json:"name"`
+	Licenses map[string]int `json:"licenses"`         // license -> required modules under it
+	Missing  int            `json:"missing"`          // required modules whose sources were not found
+	Denied   []string       `json:"denied,omitempty"` // path@version of required modules under denied licenses
+}
+
+// jsonLicenses is the licenses section of the JSON export.
+type jsonLicenses struct {
+	*license.Report
+	Services []serviceLicenses `json:"services"`
+}
+
+// groupLicenses attributes the modules of r to the microservices of the
+// requiring modules, sorted by service name. Requirements of a module that
+// holds no microservice are listed under the module path.
+func groupLicenses(r *license.Report, scan *scanner.ScanResult) []serviceLicenses {
+	servicesOf := make(map[string][]string)
+	for _, g := range groupModules(scan) {
+		servicesOf[g.Module.Path] = g.Services
+	}
+	byName := make(map[string]*serviceLicenses)
+	for _, m := range r.Modules {
+		seen := make(map[string]bool)
+		for _, req := range m.RequiredBy {
+			names := servicesOf[req]
+			if len(names) == 0 {
+				names = []string{req}
+			}
+			for _, name := range names {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				s := byName[name]
+				if s == nil {
+					s = &serviceLicenses{Name: name, Licenses: make(map[string]int)}
+					byName[name] = s
+				}
+				if len(m.Licenses) == 0 {
+					s.Missing++
+				}
+				for _, l := range m.Licenses {
+					s.Licenses[l]++
+				}
+				if m.Denied {
+					s.Denied = append(s.Denied, m.Path+"@"+m.Version)
+				}
+			}
+		}
+	}
+	out := make([]serviceLicenses, 0, len(byName))
+	for _, s := range byName {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// licenseChips renders license counts, most used first, with denied
+// licenses in red.
+func licenseChips(counts map[string]int, deny []string) string {
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if counts[ids[i]] != counts[ids[j]] {
+			return counts[ids[i]] > counts[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	var sb strings.Builder
+	for _, id := range ids {
+		cls := "tag-tech"
+		switch {
+		case license.Denied([]string{id}, deny):
+			cls = "pd-breaking"
+		case id == license.Unknown || id == license.None:
+			cls = "tag-foreign"
+		}
+		sb.WriteString(fmt.Sprintf(`<span class="tag %s" style="font-size:11px">%s <b>%d</b></span> `, cls, esc(id), counts[id]))
+	}
+	return sb.String()
+}
+
+// buildLicensesHTML renders the licenses every service ships and the
+// required modules under denied, unrecognized or missing licenses.
+func buildLicensesHTML(r *license.Report, scan *scanner.ScanResult) string {
+	if r == nil || len(r.Modules) == 0 {
+		return ""
+	}
+	totals := make(map[string]int)
+	for _, m := range r.Modules {
+		for _, l := range m.Licenses {
+			totals[l]++
+		}
+	}
+	denied := r.DeniedModules()
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>⚖️ Licenses</h2>`)
+	subtitle := fmt.Sprintf("%d required modules · %d found in vendor/ or %s", len(r.Modules), len(r.Modules)-r.Missing, esc(r.ModuleCache))
+	if r.Missing > 0 {
+		subtitle += fmt.Sprintf(" · %d not found (run <code>go mod download</code>)", r.Missing)
+	}
+	if len(r.Deny) > 0 {
+		subtitle += " · denied: " + esc(strings.Join(r.Deny, ", "))
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%s</p>`, subtitle))
+	if len(r.Deny) > 0 {
+		if len(denied) == 0 {
+			sb.WriteString(`<div class="ap-summary"><span class="ap-pass-badge">✓ No denied licenses</span></div>`)
+		} else {
+			sb.WriteString(fmt.Sprintf(`<div class="ap-summary"><span class="ap-fail-badge">%d modules under denied licenses</span></div>`, len(denied)))
+		}
+	}
+	sb.WriteString(`<div class="pkg-grid" style="margin-bottom:12px">` + licenseChips(totals, r.Deny) + `</div>`)
+
+	sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+	sb.WriteString(`<thead><tr><th>Service</th><th>Licenses shipped</th><th>Not found</th><th>Denied</th></tr></thead><tbody>`)
+	for _, s := range groupLicenses(r, scan) {
+		name := fmt.Sprintf(`<span class="mono">%s</span>`, esc(s.Name))
+		if _, ok := scan.Microservices[s.Name]; ok {
+			name = fmt.Sprintf("<a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a>",
+				strings.ReplaceAll(s.Name, " ", "-"), esc(s.Name))
+		}
+		missing := ""
+		if s.Missing > 0 {
+			missing = fmt.Sprint(s.Missing)
+		}
+		sb.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td class="mono" style="font-size:12px">%s</td></tr>`,
+			name, licenseChips(s.Licenses, r.Deny), missing, esc(strings.Join(s.Denied, ", "))))
+	}
+	sb.WriteString(`</tbody></table></div>`)
+
+	// Modules legal has to look at: denied, then unrecognized.
+	var review []license.Module
+	review = append(review, denied...)
+	for _, m := range r.Modules {
+		if !m.Denied && len(m.Licenses) > 0 && (m.Licenses[0] == license.Unknown || m.Licenses[0] == license.None) {
+			review = append(review, m)
+		}
+	}
+	if len(review) > 0 {
+		sb.WriteString(`<h3>Needs review</h3><div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>Module</th><th>License</th><th>Required by</th></tr></thead><tbody>`)
+		for _, m := range review {
+			sb.WriteString(fmt.Sprintf(`<tr><td class="mono">%s@%s</td><td>%s</td><td class="mono" style="font-size:12px">%s</td></tr>`,
+				esc(m.Path), esc(m.Version), esc(strings.Join(m.Licenses, ", ")), esc(strings.Join(m.RequiredBy, ", "))))
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
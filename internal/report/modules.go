@@ -0,0 +1,105 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/scanner"
+)
+
+// moduleGroup is a Go module with the microservices whose files it contains.
+type moduleGroup struct {
+	Module   gomod.Module
+	Services []string
+	Files    int
+}
+
+// groupModules assigns the microservices of scan to the modules holding
+// their files, sorted by module path. A service split over several modules
+// appears under each of them.
+func groupModules(scan *scanner.ScanResult) []moduleGroup {
+	serviceOf := make(map[string]string)
+	for ms, paths := range scan.Microservices {
+		for _, p := range paths {
+			serviceOf[p] = ms
+		}
+	}
+	groups := make([]moduleGroup, 0, len(scan.Modules))
+	for _, m := range scan.Modules {
+		g := moduleGroup{Module: m, Files: len(scan.ModuleFiles[m.Path])}
+		seen := make(map[string]bool)
+		for _, p := range scan.ModuleFiles[m.Path] {
+			if ms := serviceOf[p]; ms != "" && !seen[ms] {
+				seen[ms] = true
+				g.Services = append(g.Services, ms)
+			}
+		}
+		sort.Strings(g.Services)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Module.Path < groups[j].Module.Path })
+	return groups
+}
+
+// buildModulesHTML renders the modules of a multi-module tree: their go
+// version, the microservices they contain, their requirements and the
+// replace directives that point at local directories. Single-module trees
+// get no card.
+func buildModulesHTML(scan *scanner.ScanResult, root string) string {
+	if len(scan.Modules) < 2 {
+		return ""
+	}
+	relDir := func(dir string) string {
+		if rel, err := filepath.Rel(root, dir); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return dir
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🧩 Go Modules</h2>`)
+	subtitle := fmt.Sprintf("%d modules", len(scan.Modules))
+	if ws := scan.Workspace; ws != nil {
+		subtitle += fmt.Sprintf(" · go.work (go %s) uses %d", esc(ws.GoVersion), len(ws.Use))
+		if len(ws.Replaces) > 0 {
+			subtitle += fmt.Sprintf(" · %d workspace replaces", len(ws.Replaces))
+		}
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%s</p>`, subtitle))
+	sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+	sb.WriteString(`<thead><tr><th>Module</th><th>Directory</th><th>Go</th><th>Microservices</th><th>Files</th><th>Requires</th><th>Local replaces</th></tr></thead><tbody>`)
+	for _, g := range groupModules(scan) {
+		m := g.Module
+		name := esc(m.Path)
+		if m.Workspace {
+			name += ` <span class="tag tag-tech" style="font-size:11px">go.work</span>`
+		}
+		var services strings.Builder
+		for _, ms := range g.Services {
+			services.WriteString(fmt.Sprintf("<a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a> ",
+				strings.ReplaceAll(ms, " ", "-"), esc(ms)))
+		}
+		direct := 0
+		for _, r := range m.Requires {
+			if !r.Indirect {
+				direct++
+			}
+		}
+		var replaces []string
+		for _, r := range m.Replaces {
+			if r.Dir != "" {
+				replaces = append(replaces, esc(r.Old)+" → "+esc(relDir(r.Dir)))
+			}
+		}
+		sb.WriteString(fmt.Sprintf(
+			`<tr><td class="mono">%s</td><td class="mono">%s</td><td>%s</td><td>%s</td><td>%d</td><td>%d direct · %d indirect</td><td class="mono" style="font-size:12px">%s</td></tr>`,
+			name, esc(relDir(m.Dir)), esc(m.GoVersion), services.String(), g.Files,
+			direct, len(m.Requires)-direct, strings.Join(replaces, "<br>"),
+		))
+	}
+	sb.WriteString(`</tbody></table></div></div>`)
+	return sb.String()
+}
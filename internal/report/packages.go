@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goscope/internal/graph"
+)
+
+// buildPackagesHTML renders the package graph summary: edge counts by kind
+// and the most imported local and external packages.
+func buildPackagesHTML(pg *graph.PackageGraph) string {
+	if pg == nil || len(pg.Packages) == 0 {
+		return ""
+	}
+	local, external := 0, 0
+	modules := make(map[string]bool)
+	for _, p := range pg.Packages {
+		if p.External {
+			external++
+			continue
+		}
+		local++
+		if p.Module != "" {
+			modules[p.Module] = true
+		}
+	}
+	counts := pg.EdgeCounts()
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>📦 Packages</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d local packages in %d modules · %d external packages</p>`,
+		local, len(modules), external))
+	sb.WriteString(`<div class="bm-grid">`)
+	for _, c := range []struct {
+		kind  graph.EdgeKind
+		label string
+	}{
+		{graph.EdgeIntraService, "Intra-service imports"},
+		{graph.EdgeCrossService, "Cross-service imports"},
+		{graph.EdgeExternal, "External imports"},
+	} {
+		sb.WriteString(fmt.Sprintf(`<div class="bm-card"><div class="bm-value">%d</div><div class="bm-label">%s</div></div>`,
+			counts[c.kind], c.label))
+	}
+	sb.WriteString(`</div>`)
+
+	if top := pg.MostImported(10, false); len(top) > 0 {
+		sb.WriteString(`<h3>Most imported local packages</h3><div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>Package</th><th>Microservice</th><th>Same service</th><th>Other services</th></tr></thead><tbody>`)
+		for _, st := range top {
+			ms := st.Package.Microservice
+			sb.WriteString(fmt.Sprintf(
+				`<tr><td class="mono">%s</td><td><a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a></td><td>%d</td><td>%d</td></tr>`,
+				esc(st.Package.Label()), strings.ReplaceAll(ms, " ", "-"), esc(ms),
+				st.ByKind[graph.EdgeIntraService], st.ByKind[graph.EdgeCrossService],
+			))
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+
+	if top := pg.MostImported(10, true); len(top) > 0 {
+		sb.WriteString(`<h3>Most imported external packages</h3><div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>Package</th><th>Importing packages</th></tr></thead><tbody>`)
+		for _, st := range top {
+			sb.WriteString(fmt.Sprintf(`<tr><td class="mono">%s</td><td>%d</td></tr>`, esc(st.Package.Label()), st.Total))
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
@@ -10,13 +10,12 @@ import (
 	"time"
 
 	gitpkg "github.com/goscope/internal/git"
-	"github.com/goscope/internal/graph"
 	"github.com/goscope/internal/parser"
-	"github.com/goscope/internal/scanner"
 )
 
 type MicroserviceSummary struct {
 	Name           string
+	Shared         bool // a shared library from the config's service map
 	Files          []*parser.ParsedFile
 	TotalLines     int
 	Declarations   []parser.Declaration
@@ -56,23 +55,23 @@ func newMS(name string, files []*parser.ParsedFile) *MicroserviceSummary {
 	return ms
 }
 
-func Generate(
-	g *graph.DependencyGraph,
-	outputPath string,
-	files []*parser.ParsedFile,
-	branchName string,
-	authorStats map[string]*gitpkg.AuthorStats,
-	projectName string,
-	technologies []string,
-	dockerServices []string,
-	rootSubdirs []string,
-	foreignServices []scanner.ForeignService,
-	gitRepos []string,
-	churnStats []gitpkg.FileChurnStat,
-	tagStats gitpkg.TagStats,
-	commitStats gitpkg.CommitStats,
-	branchStats gitpkg.BranchStats,
-) error {
+// Generate renders a as a self-contained HTML report at outputPath.
+func Generate(a *Analysis, outputPath string) error {
+	g := a.Graph
+	files := a.Files
+	branchName := a.Branch
+	authorStats := a.AuthorStats
+	projectName := a.ProjectName
+	rootSubdirs := a.scan().RootSubdirs
+	foreignServices := a.scan().ForeignServices
+	churnStats := a.Churn
+	tagStats := a.Tags
+	commitStats := a.Commits
+	branchStats := a.Branches
+	pkgGraph := a.Packages
+	svcGraph := a.Services
+	protoDiff := a.ProtoDiff
+
 	fmt.Println("   Generating HTML sections...")
 
 	fileMap := make(map[string]*parser.ParsedFile)
@@ -146,7 +145,9 @@ func Generate(
 	}
 	var microservices []*MicroserviceSummary
 	for name, mf := range msFiles {
-		microservices = append(microservices, newMS(name, mf))
+		ms := newMS(name, mf)
+		ms.Shared = a.scan().Shared[name]
+		microservices = append(microservices, ms)
 	}
 	sort.Slice(microservices, func(i, j int) bool {
 		iGW := isAPIGateway(microservices[i].Name)
@@ -162,7 +163,13 @@ func Generate(
 		return microservices[i].TotalLines > microservices[j].TotalLines
 	})
 
-	totalMSCount := len(microservices) + len(foreignServices)
+	// Shared libraries are shown with the services but not counted as ones.
+	totalMSCount := len(foreignServices)
+	for _, ms := range microservices {
+		if !ms.Shared {
+			totalMSCount++
+		}
+	}
 
 	// ─── 1. Team ───
 	type authorEntry struct {
@@ -176,8 +183,8 @@ func Generate(
 	sort.Slice(teamEntries, func(i, j int) bool {
 		return teamEntries[i].Stats.FilesModified > teamEntries[j].Stats.FilesModified
 	})
-	if len(teamEntries) > 30 {
-		teamEntries = teamEntries[:30]
+	if limit := a.limits().TeamRows; len(teamEntries) > limit {
+		teamEntries = teamEntries[:limit]
 	}
 
 	var teamRows strings.Builder
@@ -275,24 +282,7 @@ func Generate(
 	}
 
 	// ─── 2. Tech Stack: Technologies ───
-	techSet := make(map[string]bool)
-	for _, t := range technologies {
-		techSet[t] = true
-	}
-	techSet["Go"] = true
-	for _, f := range files {
-		for _, imp := range f.Imports {
-			detectTechFromImport(imp, techSet)
-		}
-	}
-	if totalProtoFiles > 0 {
-		techSet["Protocol Buffers"] = true
-		techSet["gRPC"] = true
-	}
-	// Add foreign languages as technologies
-	for _, fs := range foreignServices {
-		techSet[fs.Language] = true
-	}
+	techSet := a.techSet()
 	var techList []string
 	for t := range techSet {
 		techList = append(techList, t)
@@ -320,11 +310,11 @@ func Generate(
 
 	// ─── 2b+. Anti-patterns ───
 	fmt.Println("   Running anti-pattern checks...")
-	apResults := runAntipatterns(files, gitRepos)
+	apResults := runAntipatterns(files, a.checkOptions())
 	apCardHTML := buildAntipatternHTML(apResults)
 
 	// ─── 2c. Architecture graph ───
-	archGraph := buildArchitectureGraph(microservices, techList, files, foreignServices)
+	archGraph := buildArchitectureGraph(microservices, techList, files, foreignServices, a.Calls)
 	archGraphJSON, _ := json.Marshal(archGraph)
 
 	// ─── 2c. Microservices grid ───
@@ -333,7 +323,10 @@ func Generate(
 		anchor := strings.ReplaceAll(ms.Name, " ", "-")
 		badge := fmt.Sprintf("%s loc", fmtNum(ms.TotalLines))
 		icon := "🔧"
-		if isAPIGateway(ms.Name) {
+		if ms.Shared {
+			icon = "📚"
+			badge = "library · " + badge
+		} else if isAPIGateway(ms.Name) {
 			icon = "🌐"
 		} else if isProtoMS(ms.Name) {
 			icon = "📡"
@@ -352,53 +345,7 @@ func Generate(
 		))
 	}
 
-	// ─── 3. Microservices penetration ───
-	allMSNames := make(map[string]bool)
-	for _, ms := range microservices {
-		allMSNames[ms.Name] = true
-	}
-	msImportedBy := make(map[string]map[string]bool)
-	for _, f := range files {
-		srcMS := f.MicroserviceName
-		if srcMS == "" {
-			continue
-		}
-		for _, imp := range f.Imports {
-			impLower := strings.ToLower(imp)
-			for targetMS := range allMSNames {
-				if targetMS == srcMS {
-					continue
-				}
-				if strings.Contains(impLower, strings.ToLower(targetMS)) {
-					if msImportedBy[targetMS] == nil {
-						msImportedBy[targetMS] = make(map[string]bool)
-					}
-					msImportedBy[targetMS][srcMS] = true
-				}
-			}
-		}
-	}
-	type penEntry struct {
-		Name       string
-		Count      int
-		Dependents []string
-	}
-	var penList []penEntry
-	for ms, deps := range msImportedBy {
-		if len(deps) >= 1 {
-			var dl []string
-			for d := range deps {
-				dl = append(dl, d)
-			}
-			sort.Strings(dl)
-			penList = append(penList, penEntry{ms, len(deps), dl})
-		}
-	}
-	sort.Slice(penList, func(i, j int) bool { return penList[i].Count > penList[j].Count })
-	if len(penList) > 20 {
-		penList = penList[:20]
-	}
-
+	// ─── 3. Service coupling + TODO/FIXME density ───
 	msTodos := make(map[string]int)
 	msFixmes := make(map[string]int)
 	for _, f := range files {
@@ -425,12 +372,12 @@ func Generate(
 		return todoList[i].Todos+todoList[i].Fixmes > todoList[j].Todos+todoList[j].Fixmes
 	})
 
-	// ─── 4. Hot Zones (top 10 by PageRank) ───
-	hotspots := g.GetTopHotspots(30) // fetch more, then filter
+	// ─── 4. Hot Zones (top files by PageRank) ───
+	hotspots := g.GetTopHotspots(len(g.Vertices)) // all, since some are filtered
 	var hotspotRows strings.Builder
 	hotspotCount := 0
 	for _, h := range hotspots {
-		if hotspotCount >= 10 {
+		if hotspotCount >= a.hotspotCount() {
 			break
 		}
 		fname := filepath.Base(h.Path)
@@ -467,8 +414,8 @@ func Generate(
 		}
 	}
 	sort.Slice(allFuncs, func(i, j int) bool { return allFuncs[i].LineCount > allFuncs[j].LineCount })
-	if len(allFuncs) > 20 {
-		allFuncs = allFuncs[:20]
+	if limit := a.limits().LongestFunctions; len(allFuncs) > limit {
+		allFuncs = allFuncs[:limit]
 	}
 
 	// ─── 5. Microservice sections ───
@@ -482,7 +429,9 @@ func Generate(
 
 		anchor := strings.ReplaceAll(ms.Name, " ", "-")
 		icon := "🔧"
-		if isAPIGateway(ms.Name) {
+		if ms.Shared {
+			icon = "📚"
+		} else if isAPIGateway(ms.Name) {
 			icon = "🌐"
 		} else if isProtoMS(ms.Name) {
 			icon = "📡"
@@ -579,16 +528,6 @@ g.d3Force('charge').strength(-120);g.d3Force('link').distance(50);g.d3Force('x',
 		}
 	}
 
-	// ─── Penetration rows ───
-	var penRows strings.Builder
-	for _, pe := range penList {
-		ds := strings.Join(pe.Dependents, ", ")
-		if len(ds) > 80 {
-			ds = ds[:80] + "…"
-		}
-		anchor := strings.ReplaceAll(pe.Name, " ", "-")
-		penRows.WriteString(fmt.Sprintf("<tr><td><a href='#ms-%s' class='pkg-link-inline'>%s</a></td><td class='mono'>%d</td><td style='color:var(--text3);font-size:12px'>%s</td></tr>\n", anchor, esc(pe.Name), pe.Count, esc(ds)))
-	}
 	var todoRows strings.Builder
 	for _, te := range todoList {
 		a := strings.ReplaceAll(te.Name, " ", "-")
@@ -728,8 +667,24 @@ h3{color:var(--text2);font-size:16px;margin:20px 0 8px 0;}
 .bm-grid{display:grid;grid-template-columns:repeat(auto-fit,minmax(140px,1fr));gap:10px;margin-bottom:16px;}
 .bm-card{background:var(--bg);border-radius:10px;padding:12px;text-align:center;}
 .bm-value{font-size:22px;font-weight:700;color:var(--accent);}
+.rpc-mode{font-family:'SF Mono',Menlo,monospace;font-size:11px;padding:1px 6px;border-radius:4px;background:var(--bg);color:var(--text3);}
+.rpc-mode-stream{background:#f3e5f5;color:#7b1fa2;}
+.pd-sev{padding:1px 6px;border-radius:4px;font-size:10px;font-weight:700;}
+.pd-breaking{background:#ffeaea;color:#c62828;}
+.pd-warning{background:#fff3e0;color:#e65100;}
+.vuln-critical{background:#c62828;color:#fff;}
+.vuln-low{background:#e3f2fd;color:#1565c0;}
+.vuln-unknown{background:rgba(0,0,0,0.06);color:var(--text2);}
+.coupling-layout{display:grid;grid-template-columns:1fr 300px;gap:16px;align-items:start;}
+.main-seq{max-width:100%%;height:auto;}
+.msq-ok{color:#34c759;fill:#34c759;}
+.msq-warn{color:#e65100;fill:#ff9500;}
+.msq-bad{color:var(--red);fill:#ff3b30;}
+.msq-zone{font-size:10px;fill:var(--text3);font-style:italic;}
+.msq-axis{font-size:11px;fill:var(--text3);}
+.msq-label{font-size:10px;fill:var(--text2);}
 .bm-label{font-size:11px;color:var(--text3);text-transform:uppercase;letter-spacing:0.04em;margin-top:2px;}
-@media(max-width:900px){.arch-cols{grid-template-columns:1fr 1fr;}.ap-cols{grid-template-columns:1fr;}}
+@media(max-width:900px){.arch-cols{grid-template-columns:1fr 1fr;}.ap-cols{grid-template-columns:1fr;}.coupling-layout{grid-template-columns:1fr;}}
 @media(max-width:768px){body{padding:8px;}.card{padding:14px;border-radius:12px;}.summary-grid{grid-template-columns:repeat(3,1fr);gap:6px;}.summary-card{padding:10px 4px;}.summary-card .num{font-size:18px;}.summary-card .label{font-size:9px;}h1{font-size:20px;}h2{font-size:17px;}.team-table,.file-table{font-size:12px;min-width:500px;}.pkg-grid{grid-template-columns:repeat(auto-fill,minmax(160px,1fr));}.pkg-graph-container,.arch-graph-container{height:300px;}.arch-cols{grid-template-columns:1fr;}}
 </style>
 <script src="https://unpkg.com/d3-force@3"></script>
@@ -769,10 +724,11 @@ h3{color:var(--text2);font-size:16px;margin:20px 0 8px 0;}
 </div></div>
 <h3 style="margin-top:24px">Architecture Graph</h3>
 <div id="arch-graph" class="arch-graph-container"></div>
+%s
 </div>
 
 <div class="card">
-<h2>🔗 Microservices Penetration</h2>
+<h2>🔗 Service Coupling</h2>
 %s
 <h3 style="margin-top:24px">📝 TODO / FIXME</h3>
 %s
@@ -782,6 +738,24 @@ h3{color:var(--text2);font-size:16px;margin:20px 0 8px 0;}
 
 %s
 
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+
 <div class="card">
 %s
 </div>
@@ -801,10 +775,10 @@ h3{color:var(--text2);font-size:16px;margin:20px 0 8px 0;}
 const d=%s;
 const el=document.getElementById('arch-graph');
 if(d.nodes.length>0&&el){
-const kc={'microservice':'#007aff','technology':'#34c759','foreign':'#ff9500'};
+const kc={'microservice':'#007aff','library':'#af52de','technology':'#34c759','foreign':'#ff9500'};
 const g=ForceGraph()(el).graphData(d)
 .nodeLabel(n=>n.label+'\n'+n.kind)
-.nodeVal(n=>n.kind==='microservice'||n.kind==='foreign'?10:5)
+.nodeVal(n=>n.kind==='microservice'||n.kind==='library'||n.kind==='foreign'?10:5)
 .nodeColor(n=>kc[n.kind]||'#999')
 .nodeCanvasObject((node,ctx,gs)=>{
 const r=node.kind==='technology'?5:7;
@@ -814,8 +788,17 @@ if(gs>0.3){
 ctx.font=(Math.max(10/gs,3))+'px -apple-system,sans-serif';
 ctx.textAlign='center';ctx.fillStyle=node.kind==='technology'?'#666':'#1d1d1f';
 ctx.fillText(node.label,node.x,node.y+r+12/gs);}})
-.linkColor(()=>'rgba(0,0,0,0.08)')
-.linkWidth(1.5)
+.linkColor(l=>l.kind==='call'?'rgba(0,122,255,0.5)':'rgba(0,0,0,0.08)')
+.linkWidth(l=>l.kind==='call'?2:1.5)
+.linkDirectionalArrowLength(l=>l.kind==='call'?6:0)
+.linkDirectionalArrowRelPos(1)
+.linkLabel(l=>l.label||'')
+.linkCanvasObjectMode(l=>l.kind==='call'?'after':undefined)
+.linkCanvasObject((l,ctx,gs)=>{
+if(l.kind!=='call'||gs<0.6)return;
+ctx.font=(Math.max(9/gs,2.5))+'px -apple-system,sans-serif';
+ctx.textAlign='center';ctx.textBaseline='middle';ctx.fillStyle='#007aff';
+ctx.fillText(l.label,(l.source.x+l.target.x)/2,(l.source.y+l.target.y)/2);})
 .width(el.offsetWidth).height(500)
 .onEngineStop(()=>g.zoomToFit(400,40));
 g.d3Force('charge').strength(-200);g.d3Force('link').distance(90);g.d3Force('x',d3.forceX().strength(0.12));g.d3Force('y',d3.forceY().strength(0.12));
@@ -883,16 +866,9 @@ g.d3Force('charge').strength(-200);g.d3Force('link').distance(90);g.d3Force('x',
 		techTags,
 		totalMSCount,
 		msGridHTML.String(),
-		// Penetration
-		func() string {
-			if len(penList) == 0 {
-				return ""
-			}
-			return fmt.Sprintf(`<div class="table-wrap"><table class="file-table">
-<thead><tr><th>Microservice</th><th>Used by</th><th>Dependent Microservices</th></tr></thead>
-<tbody>%s</tbody>
-</table></div>`, penRows.String())
-		}(),
+		buildServiceCallsHTML(a.Calls, a.Root),
+		// Service coupling
+		buildServiceCouplingHTML(svcGraph),
 		func() string {
 			if len(todoList) == 0 {
 				return `<p style="color:var(--text3)">No TODO or FIXME comments found.</p>`
@@ -929,6 +905,24 @@ g.d3Force('charge').strength(-200);g.d3Force('link').distance(90);g.d3Force('x',
 </table></div>
 </div>`, funcRows.String())
 		}(),
+		// Go modules
+		buildModulesHTML(a.scan(), a.Root),
+		// Dependency versions
+		buildDependenciesHTML(a.scan(), a.Root),
+		// Vulnerable dependencies
+		buildVulnsHTML(a.Vulns, a.scan()),
+		// Licenses
+		buildLicensesHTML(a.Licenses, a.scan()),
+		// Package graph
+		buildPackagesHTML(pkgGraph),
+		// Dependency cycles
+		buildCyclesHTML(g, pkgGraph, fileMap),
+		// gRPC APIs
+		buildGRPCHTML(files, a.GRPC, a.Root),
+		// HTTP routes
+		buildRoutesHTML(files, a.Root),
+		// Proto breaking changes
+		buildProtoDiffHTML(protoDiff),
 		// Anti-patterns card
 		apCardHTML,
 		// Microservice sections
@@ -948,4 +942,4 @@ g.d3Force('charge').strength(-200);g.d3Force('link').distance(90);g.d3Force('x',
 	return nil
 }
 
-// buildArchitectureGraph builds a graph with all microservices + technologies.
+// buildArchitectureGraph builds a graph with all microservices, technologies and service calls.
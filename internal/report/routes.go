@@ -0,0 +1,119 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// routeEntry is a route with the file that registers it and the
+// declaration of its handler, when one was found.
+type routeEntry struct {
+	parser.HTTPRoute
+	File        *parser.ParsedFile
+	HandlerFile *parser.ParsedFile
+	HandlerLine int
+}
+
+// routeCounts returns the number of routes per framework.
+func routeCounts(files []*parser.ParsedFile) map[string]int {
+	counts := make(map[string]int)
+	for _, f := range files {
+		for _, r := range f.Routes {
+			counts[r.Framework]++
+		}
+	}
+	return counts
+}
+
+// collectRoutes returns every route grouped by microservice, sorted by
+// path and method. Inline handlers point at the registration.
+func collectRoutes(files []*parser.ParsedFile) map[string][]routeEntry {
+	index := parser.NewFuncIndex(files)
+	out := make(map[string][]routeEntry)
+	for _, f := range files {
+		for _, r := range f.Routes {
+			e := routeEntry{HTTPRoute: r, File: f}
+			if r.Handler == parser.InlineHandler {
+				e.HandlerFile, e.HandlerLine = f, r.Line
+			} else if hf, d := index.Lookup(f, r.Handler); d != nil {
+				e.HandlerFile, e.HandlerLine = hf, d.Line
+			}
+			out[f.MicroserviceName] = append(out[f.MicroserviceName], e)
+		}
+	}
+	for _, routes := range out {
+		sort.SliceStable(routes, func(i, j int) bool {
+			if routes[i].Path != routes[j].Path {
+				return routes[i].Path < routes[j].Path
+			}
+			return routes[i].Method < routes[j].Method
+		})
+	}
+	return out
+}
+
+// sourceLink links to a line of a source file, shown relative to root.
+func sourceLink(path string, line int, root string) string {
+	display := path
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		display = filepath.ToSlash(rel)
+	}
+	return fmt.Sprintf(`<a href="%s" class="mono" style="font-size:12px">%s:%d</a>`, esc(fileURI(path)), esc(display), line)
+}
+
+// buildRoutesHTML lists the HTTP endpoints of every microservice with the
+// handler and middleware chain of each. Returns "" when no routes were found.
+func buildRoutesHTML(files []*parser.ParsedFile, root string) string {
+	byService := collectRoutes(files)
+	if len(byService) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(byService))
+	total := 0
+	for name, routes := range byService {
+		names = append(names, name)
+		total += len(routes)
+	}
+	sort.Strings(names)
+	counts := routeCounts(files)
+	frameworks := make([]string, 0, len(counts))
+	for fw, n := range counts {
+		frameworks = append(frameworks, fmt.Sprintf("%s %d", fw, n))
+	}
+	sort.Strings(frameworks)
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🛣️ HTTP Routes</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d endpoints · %d services · %s</p>`, total, len(names), esc(strings.Join(frameworks, " · "))))
+	for _, name := range names {
+		routes := byService[name]
+		ms := name
+		if ms == "" {
+			ms = "root"
+		}
+		sb.WriteString(`<div class="sub-card">`)
+		sb.WriteString(fmt.Sprintf(
+			`<h3 class="sub-card-title"><a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a> <span class="pkg-stats">%d endpoints</span></h3>`,
+			strings.ReplaceAll(ms, " ", "-"), esc(ms), len(routes),
+		))
+		sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+		sb.WriteString(`<thead><tr><th>Method</th><th>Path</th><th>Handler</th><th>Middleware</th><th>Source</th></tr></thead><tbody>`)
+		for _, r := range routes {
+			source := sourceLink(r.File.FilePath, r.Line, root)
+			if r.HandlerFile != nil {
+				source = sourceLink(r.HandlerFile.FilePath, r.HandlerLine, root)
+			}
+			sb.WriteString(fmt.Sprintf(
+				`<tr><td><span class="rpc-mode">%s</span></td><td class="mono">%s</td><td class="mono">%s</td><td class="mono" style="font-size:12px">%s</td><td>%s</td></tr>`,
+				esc(r.Method), esc(r.Path), esc(r.Handler), esc(strings.Join(r.Middleware, " → ")), source,
+			))
+		}
+		sb.WriteString(`</tbody></table></div></div>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
@@ -0,0 +1,232 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goscope/internal/osv"
+)
+
+// SARIF 2.1.0 log, reduced to the properties goscope fills in.
+// Spec: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]any     `json:"properties,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int           `json:"startLine"`
+	Snippet   *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifLevel maps a check priority to a SARIF result level.
+func sarifLevel(priority string) string {
+	switch priority {
+	case apHigh:
+		return "error"
+	case apMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// vulnLevel maps a vulnerability severity to a SARIF result level.
+func vulnLevel(severity string) string {
+	switch severity {
+	case osv.SeverityCritical, osv.SeverityHigh:
+		return "error"
+	case osv.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log with every anti-pattern
+// check as a rule. Each vulnerability in vulns, which may be nil, becomes a
+// rule with one result per go.mod requiring the affected version. File
+// locations are made relative to root, which is exposed as the %SRCROOT%
+// base URI.
+func WriteSARIF(w io.Writer, findings []Finding, vulns *osv.Report, root, version string) error {
+	checks := goAntipatternChecks()
+	rules := make([]sarifRule, len(checks))
+	ruleIndex := make(map[string]int, len(checks))
+	for i, ch := range checks {
+		ruleIndex[ch.ID] = i
+		rules[i] = sarifRule{
+			ID:                   ch.ID,
+			Name:                 ch.Name,
+			ShortDescription:     sarifMessage{Text: ch.Name},
+			FullDescription:      sarifMessage{Text: ch.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(ch.Priority)},
+			Properties:           map[string]any{"priority": ch.Priority},
+		}
+	}
+
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "goscope", Version: version, Rules: rules}},
+		Results: []sarifResult{},
+	}
+	if root != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{
+			"SRCROOT": {URI: fileURI(root) + "/"},
+		}
+	}
+	for _, f := range findings {
+		idx, ok := ruleIndex[f.ID]
+		if !ok {
+			continue
+		}
+		loc := sarifPhysicalLocation{
+			ArtifactLocation: artifactLocation(f.Path, root),
+			Region:           sarifRegion{StartLine: f.Line},
+		}
+		if f.Snippet != "" {
+			loc.Region.Snippet = &sarifMessage{Text: f.Snippet}
+		}
+		res := sarifResult{
+			RuleID:    f.ID,
+			RuleIndex: idx,
+			Level:     sarifLevel(f.Priority),
+			Message:   sarifMessage{Text: f.Check},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+		}
+		if f.Author != "" {
+			res.Properties = map[string]any{"author": f.Author}
+		}
+		run.Results = append(run.Results, res)
+	}
+	if vulns != nil {
+		for _, v := range vulns.Vulnerabilities {
+			idx, ok := ruleIndex[v.ID]
+			if !ok {
+				idx = len(rules)
+				ruleIndex[v.ID] = idx
+				rule := sarifRule{
+					ID:                   v.ID,
+					Name:                 "VulnerableDependency",
+					ShortDescription:     sarifMessage{Text: v.Summary},
+					FullDescription:      sarifMessage{Text: v.Summary},
+					DefaultConfiguration: sarifConfiguration{Level: vulnLevel(v.Severity)},
+					Properties:           map[string]any{"severity": v.Severity, "tags": []string{"security", "vulnerability"}},
+				}
+				if v.Score > 0 {
+					// Read by GitHub code scanning to rank security alerts.
+					rule.Properties["security-severity"] = strconv.FormatFloat(v.Score, 'f', 1, 64)
+				}
+				rules = append(rules, rule)
+			}
+			msg := v.Package + "@" + v.Version + " is affected by " + v.ID
+			if v.Fixed != "" {
+				msg += "; fixed in " + v.Fixed
+			}
+			for _, req := range v.RequiredBy {
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    v.ID,
+					RuleIndex: idx,
+					Level:     vulnLevel(v.Severity),
+					Message:   sarifMessage{Text: msg},
+					Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: artifactLocation(req.GoMod, root),
+						Region:           sarifRegion{StartLine: req.Line},
+					}}},
+					Properties: map[string]any{"module": req.Module},
+				})
+			}
+		}
+		run.Tool.Driver.Rules = rules
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// artifactLocation returns path relative to %SRCROOT% when it lies inside
+// root, or as an absolute file URI otherwise.
+func artifactLocation(path, root string) sarifArtifactLocation {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: "SRCROOT"}
+		}
+	}
+	return sarifArtifactLocation{URI: fileURI(path)}
+}
+
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
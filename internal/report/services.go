@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goscope/internal/graph"
+)
+
+// distanceClass buckets a main-sequence distance for coloring.
+func distanceClass(d float64) string {
+	switch {
+	case d < 0.3:
+		return "msq-ok"
+	case d < 0.6:
+		return "msq-warn"
+	default:
+		return "msq-bad"
+	}
+}
+
+// buildServiceCouplingHTML renders per-service coupling metrics and the
+// main-sequence chart. Returns a placeholder when no service depends on another.
+func buildServiceCouplingHTML(sg *graph.ServiceGraph) string {
+	if sg == nil || len(sg.Deps) == 0 {
+		return `<p style="color:var(--text3)">No dependencies between microservices found.</p>`
+	}
+	metrics := sg.SortedMetrics()
+
+	var rows strings.Builder
+	for _, m := range metrics {
+		var deps []string
+		for _, to := range m.Dependencies {
+			d := sg.Deps[[2]string{m.Name, to}]
+			deps = append(deps, fmt.Sprintf("%s <span style='color:var(--text3)'>(%s)</span>", esc(to), strings.Join(d.Kinds, ", ")))
+		}
+		anchor := strings.ReplaceAll(m.Name, " ", "-")
+		lib := ""
+		if m.Kind == graph.NodeLibrary {
+			lib = ` <span class="bs-badge">library</span>`
+		}
+		rows.WriteString(fmt.Sprintf(
+			"<tr><td><a href='#ms-%s' class='pkg-link-inline'>%s</a>%s</td><td class='mono'>%d</td><td class='mono'>%d</td><td class='mono'>%.2f</td><td class='mono'>%.2f</td><td class='mono %s'>%.2f</td><td style='font-size:12px'>%s</td></tr>\n",
+			anchor, esc(m.Name), lib, m.Ca, m.Ce, m.Instability, m.Abstractness, distanceClass(m.Distance), m.Distance, strings.Join(deps, ", "),
+		))
+	}
+
+	counts := fmt.Sprintf("%d services", len(metrics)-sg.Libraries())
+	if libs := sg.Libraries(); libs > 0 {
+		counts += fmt.Sprintf(" · %d shared libraries", libs)
+	}
+	return fmt.Sprintf(`<p class="subtitle">%s · %d dependencies, from resolved imports, shared proto packages and gRPC clients</p>
+<div class="coupling-layout">
+<div class="table-wrap"><table class="file-table">
+<thead><tr><th>Microservice</th><th title="Afferent coupling: services that depend on it">Ca</th><th title="Efferent coupling: services it depends on">Ce</th><th title="Instability Ce/(Ca+Ce)">I</th><th title="Abstractness: interfaces and proto services / all types">A</th><th title="Distance from the main sequence |A+I-1|">D</th><th>Depends on</th></tr></thead>
+<tbody>%s</tbody>
+</table></div>
+%s
+</div>`, counts, len(sg.Deps), rows.String(), mainSequenceSVG(metrics))
+}
+
+// mainSequenceSVG plots every service by instability (x) and abstractness
+// (y) against the A + I = 1 line.
+func mainSequenceSVG(metrics []*graph.ServiceMetrics) string {
+	const size, pad = 300.0, 30.0
+	x := func(v float64) float64 { return pad + v*(size-2*pad) }
+	y := func(v float64) float64 { return size - pad - v*(size-2*pad) }
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg class="main-seq" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f">`, size, size, size, size))
+	sb.WriteString(fmt.Sprintf(`<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="none" stroke="#ddd"/>`, pad, pad, size-2*pad, size-2*pad))
+	sb.WriteString(fmt.Sprintf(`<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#aaa" stroke-dasharray="4 4"/>`, x(0), y(1), x(1), y(0)))
+	sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="msq-zone">zone of pain</text>`, x(0.03), y(0.05)))
+	sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="msq-zone" text-anchor="end">zone of uselessness</text>`, x(0.97), y(0.93)))
+	sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="msq-axis" text-anchor="middle">Instability →</text>`, size/2, size-8))
+	sb.WriteString(fmt.Sprintf(`<text x="12" y="%.0f" class="msq-axis" text-anchor="middle" transform="rotate(-90 12 %.0f)">Abstractness →</text>`, size/2, size/2))
+	for _, m := range metrics {
+		cx, cy := x(m.Instability), y(m.Abstractness)
+		sb.WriteString(fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="5" class="%s"><title>%s: I=%.2f A=%.2f D=%.2f</title></circle>`,
+			cx, cy, distanceClass(m.Distance), esc(m.Name), m.Instability, m.Abstractness, m.Distance))
+		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" class="msq-label">%s</text>`, cx+7, cy+3, esc(m.Name)))
+	}
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
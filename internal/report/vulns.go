@@ -0,0 +1,100 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goscope/internal/osv"
+	"github.com/goscope/internal/scanner"
+)
+
+// vulnClass maps a severity to its badge class.
+func vulnClass(severity string) string {
+	switch severity {
+	case osv.SeverityCritical:
+		return "vuln-critical"
+	case osv.SeverityHigh:
+		return "pd-breaking"
+	case osv.SeverityMedium:
+		return "pd-warning"
+	case osv.SeverityLow:
+		return "vuln-low"
+	}
+	return "vuln-unknown"
+}
+
+// buildVulnsHTML renders the dependencies affected by entries of the
+// configured OSV database with the services that require them. It is
+// empty when no database is configured.
+func buildVulnsHTML(r *osv.Report, scan *scanner.ScanResult) string {
+	if r == nil {
+		return ""
+	}
+	servicesOf := make(map[string][]string)
+	for _, g := range groupModules(scan) {
+		servicesOf[g.Module.Path] = g.Services
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card"><h2>🛡️ Vulnerable Dependencies</h2>`)
+	sb.WriteString(fmt.Sprintf(`<p class="subtitle">%d requirements checked against %d OSV entries in %s</p>`,
+		r.Checked, r.Entries, esc(r.Database)))
+	if len(r.Vulnerabilities) == 0 {
+		sb.WriteString(`<div class="ap-summary"><span class="ap-pass-badge">✓ No known vulnerabilities</span></div></div>`)
+		return sb.String()
+	}
+	counts := r.Count()
+	var parts []string
+	for _, sev := range []string{osv.SeverityCritical, osv.SeverityHigh, osv.SeverityMedium, osv.SeverityLow, osv.SeverityUnknown} {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[sev], strings.ToLower(sev)))
+		}
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="ap-summary"><span class="ap-fail-badge">%d vulnerabilities</span><span class="ap-check-count">%s</span></div>`,
+		len(r.Vulnerabilities), strings.Join(parts, " · ")))
+	sb.WriteString(`<div class="table-wrap"><table class="file-table">`)
+	sb.WriteString(`<thead><tr><th>Severity</th><th>Advisory</th><th>Dependency</th><th>Fixed in</th><th>Affected services</th></tr></thead><tbody>`)
+	for _, v := range r.Vulnerabilities {
+		sev := v.Severity
+		if v.Score > 0 {
+			sev += fmt.Sprintf(" %.1f", v.Score)
+		}
+		id := esc(v.ID)
+		if v.URL != "" {
+			id = fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`, esc(v.URL), id)
+		}
+		if len(v.Aliases) > 0 {
+			id += fmt.Sprintf(`<br><span style="font-size:11px;color:var(--text3)">%s</span>`, esc(strings.Join(v.Aliases, ", ")))
+		}
+		fixed := esc(v.Fixed)
+		if fixed == "" {
+			fixed = `<span style="color:var(--text3)">no fix</span>`
+		}
+		seen := make(map[string]bool)
+		var services []string
+		for _, req := range v.RequiredBy {
+			names := servicesOf[req.Module]
+			if len(names) == 0 {
+				names = []string{req.Module}
+			}
+			for _, ms := range names {
+				if seen[ms] {
+					continue
+				}
+				seen[ms] = true
+				if _, ok := scan.Microservices[ms]; ok {
+					services = append(services, fmt.Sprintf("<a href='#ms-%s' class='tag tag-local pkg-link-inline' style='font-size:11px'>%s</a>",
+						strings.ReplaceAll(ms, " ", "-"), esc(ms)))
+				} else {
+					services = append(services, fmt.Sprintf(`<span class="tag tag-local mono" style="font-size:11px">%s</span>`, esc(ms)))
+				}
+			}
+		}
+		sb.WriteString(fmt.Sprintf(
+			`<tr><td><span class="pd-sev %s">%s</span></td><td class="mono">%s<div style="font-family:inherit;font-size:12px">%s</div></td><td class="mono">%s@%s</td><td class="mono">%s</td><td>%s</td></tr>`,
+			vulnClass(v.Severity), sev, id, esc(v.Summary), esc(v.Package), esc(v.Version), fixed, strings.Join(services, " "),
+		))
+	}
+	sb.WriteString(`</tbody></table></div></div>`)
+	return sb.String()
+}
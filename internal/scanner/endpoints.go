@@ -0,0 +1,245 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goscope/internal/parser"
+)
+
+// Endpoint is the address of a service found in configuration, e.g.
+// USER_SERVICE_URL=http://user-service:8080 in a docker-compose environment.
+type Endpoint struct {
+	Service string `json:"service"` // microservice or docker-compose service the configuration belongs to
+	Key     string `json:"key"`     // variable or key holding the address
+	Value   string `json:"value"`
+	Scheme  string `json:"scheme,omitempty"`
+	Host    string `json:"host"` // service name, see parser.ParseAddress
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+// maxConfigSize is the size above which a config file is not read.
+const maxConfigSize = 256 << 10
+
+var composeFiles = map[string]bool{
+	"docker-compose.yml": true, "docker-compose.yaml": true, "compose.yml": true, "compose.yaml": true,
+}
+
+// configKeyValue matches a key and value in YAML, .env, TOML, INI,
+// properties and JSON files and in docker-compose environment lists.
+var configKeyValue = regexp.MustCompile(`^\s*(?:-\s*)?(?:export\s+)?["']?([A-Za-z_][\w.-]*)["']?\s*[:=]\s*(.+?)\s*,?\s*$`)
+
+// ScanEndpoints finds the service addresses in the docker-compose files and
+// config files Scan collected under root, so the same exclusions and ignore
+// files apply: environment variables of compose services, and keys of .env,
+// YAML, TOML, INI, properties and config JSON files. A config file belongs
+// to the microservice owning the Go files around it; one that no single
+// microservice owns, such as a shared deploy/ directory, is skipped. It also
+// returns the compose services that are built from a microservice's
+// directory, mapped to that microservice.
+func ScanEndpoints(root string, scan *ScanResult) (endpoints []Endpoint, aliases map[string]string) {
+	owners := dirOwners(root, scan)
+	aliases = make(map[string]string)
+	if scan == nil {
+		return nil, aliases
+	}
+	for _, path := range scan.ConfigFiles {
+		if composeFiles[filepath.Base(path)] {
+			eps, builds := parseComposeEndpoints(path)
+			endpoints = append(endpoints, eps...)
+			for svc, dir := range builds {
+				if ms := ownerOf(owners, root, dir); ms != "" {
+					aliases[svc] = ms
+				}
+			}
+			continue
+		}
+		if ms := ownerOf(owners, root, filepath.Dir(path)); ms != "" {
+			endpoints = append(endpoints, parseConfigEndpoints(path, ms)...)
+		}
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].File != endpoints[j].File {
+			return endpoints[i].File < endpoints[j].File
+		}
+		return endpoints[i].Line < endpoints[j].Line
+	})
+	return endpoints, aliases
+}
+
+// isConfigFile reports whether a file name looks like service configuration.
+func isConfigFile(name string) bool {
+	lower := strings.ToLower(name)
+	switch filepath.Ext(lower) {
+	case ".yml", ".yaml", ".env", ".toml", ".ini", ".properties", ".conf":
+		return true
+	case ".json":
+		return strings.Contains(lower, "config") || strings.Contains(lower, "settings")
+	}
+	return strings.HasPrefix(lower, ".env")
+}
+
+// dirOwners maps every directory holding Go/proto files, and each of its
+// ancestors below root, to the microservices with files under it.
+func dirOwners(root string, scan *ScanResult) map[string]map[string]bool {
+	owners := make(map[string]map[string]bool)
+	if scan == nil {
+		return owners
+	}
+	for ms, files := range scan.Microservices {
+		for _, f := range files {
+			for dir := filepath.Dir(f); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+				if owners[dir] == nil {
+					owners[dir] = make(map[string]bool)
+				}
+				if owners[dir][ms] {
+					break
+				}
+				owners[dir][ms] = true
+			}
+		}
+	}
+	return owners
+}
+
+// ownerOf returns the microservice owning dir: the only one with files in
+// dir or, when it has none, in its nearest ancestor that has any. It
+// returns "" when several microservices share that directory.
+func ownerOf(owners map[string]map[string]bool, root, dir string) string {
+	for ; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		switch ms := owners[dir]; len(ms) {
+		case 0:
+			continue
+		case 1:
+			for name := range ms {
+				return name
+			}
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// parseComposeEndpoints returns the addresses in the environment of the
+// services of a docker-compose file, and the directory each service is
+// built from.
+func parseComposeEndpoints(path string) (endpoints []Endpoint, builds map[string]string) {
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) > maxConfigSize {
+		return nil, nil
+	}
+	builds = make(map[string]string)
+	dir := filepath.Dir(path)
+	// The services are the keys at the indentation of the first line
+	// below "services:", whatever width the file indents with.
+	servicesIndent, serviceIndent, service, section, sectionIndent := -1, -1, "", "", 0
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := countLeadingSpaces(line)
+		if trimmed == "services:" {
+			servicesIndent, serviceIndent, service = indent, -1, ""
+			continue
+		}
+		if servicesIndent < 0 || indent <= servicesIndent {
+			servicesIndent = -1
+			continue
+		}
+		if serviceIndent < 0 {
+			serviceIndent = indent
+		}
+		if indent == serviceIndent && strings.HasSuffix(trimmed, ":") {
+			service, section = strings.TrimSuffix(trimmed, ":"), ""
+			continue
+		}
+		if service == "" {
+			continue
+		}
+		if section != "" && (indent < sectionIndent || indent == sectionIndent && !strings.HasPrefix(trimmed, "-")) {
+			section = ""
+		}
+		m := configKeyValue.FindStringSubmatch(trimmed)
+		if section == "" {
+			key := strings.TrimSuffix(trimmed, ":")
+			switch {
+			case key == "environment" || key == "build":
+				section, sectionIndent = key, indent
+			case m != nil && m[1] == "build":
+				builds[service] = filepath.Join(dir, unquote(m[2]))
+			}
+			continue
+		}
+		if m == nil {
+			continue
+		}
+		switch section {
+		case "build":
+			if m[1] == "context" {
+				builds[service] = filepath.Join(dir, unquote(m[2]))
+			}
+		case "environment":
+			value := unquote(m[2])
+			if scheme, host, ok := parser.ParseAddress(value); ok {
+				endpoints = append(endpoints, Endpoint{
+					Service: service, Key: m[1], Value: value, Scheme: scheme, Host: host, File: path, Line: i + 1,
+				})
+			}
+		}
+	}
+	return endpoints, builds
+}
+
+// parseConfigEndpoints returns the addresses in a config file of
+// microservice ms. A Kubernetes env entry, "- name: KEY" followed by
+// "value: ...", is reported under KEY.
+func parseConfigEndpoints(path, ms string) []Endpoint {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxConfigSize {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var endpoints []Endpoint
+	lastName := ""
+	for i, line := range strings.Split(string(content), "\n") {
+		m := configKeyValue.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key, value := m[1], unquote(m[2])
+		if key == "name" {
+			lastName = value
+			continue
+		}
+		if key == "value" && lastName != "" {
+			key = lastName
+		}
+		lastName = ""
+		if key == "image" {
+			continue // registry:port/name:tag
+		}
+		if scheme, host, ok := parser.ParseAddress(value); ok {
+			endpoints = append(endpoints, Endpoint{
+				Service: ms, Key: key, Value: value, Scheme: scheme, Host: host, File: path, Line: i + 1,
+			})
+		}
+	}
+	return endpoints
+}
+
+// unquote strips matching quotes around a config value.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
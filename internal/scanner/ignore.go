@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// IgnoreFile lists gitignore-style patterns of paths goscope skips. Like
+// .gitignore, it applies to its directory and everything below.
+const IgnoreFile = ".goscopeignore"
+
+// ignoreRule is one pattern of a .gitignore-style file.
+type ignoreRule struct {
+	base     string   // directory of the pattern file, slash-separated and relative to the root; "" for the root
+	segments []string // pattern split on "/"; unanchored patterns start with "**"
+	dirOnly  bool     // trailing slash: only matches directories
+	negate   bool     // leading "!": re-includes what earlier rules excluded
+}
+
+// parseIgnoreRule parses one pattern line. ok is false for blank lines and
+// comments.
+func parseIgnoreRule(line, base string) (r ignoreRule, ok bool) {
+	line = strings.TrimRight(line, "\r ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	r.base = base
+	switch {
+	case strings.HasPrefix(line, "!"):
+		r.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\`):
+		line = line[1:] // escaped leading "#" or "!"
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but at the end anchors the pattern to base.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return r, false
+	}
+	r.segments = strings.Split(line, "/")
+	if !anchored {
+		r.segments = append([]string{"**"}, r.segments...)
+	}
+	return r, true
+}
+
+// match reports whether rel, a slash-separated path relative to the root,
+// matches the rule.
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path elements against pattern elements, where "**"
+// matches any number of elements and the others use path.Match syntax.
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for len(pat) > 0 && pat[0] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pat, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreList is an ordered set of rules. As in git, the last matching rule
+// decides, so rules of deeper pattern files must be added later.
+type ignoreList []ignoreRule
+
+// add appends the rules in patterns, which are relative to base.
+func (l *ignoreList) add(patterns []string, base string) {
+	for _, p := range patterns {
+		if r, ok := parseIgnoreRule(p, base); ok {
+			*l = append(*l, r)
+		}
+	}
+}
+
+// addFile appends the rules of the pattern file at path, if it exists.
+func (l *ignoreList) addFile(path, base string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	l.add(strings.Split(string(data), "\n"), base)
+}
+
+// matches reports whether the last rule matching rel is not a negation.
+func (l ignoreList) matches(rel string, isDir bool) bool {
+	matched := false
+	for _, r := range l {
+		if r.match(rel, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// covers reports whether rel or one of its parent directories is matched,
+// with rules for deeper paths overriding those for their parents. Include
+// lists use it so that a directory pattern selects the files below it.
+func (l ignoreList) covers(rel string) bool {
+	covered := false
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		isDir := i < len(parts)-1
+		for _, r := range l {
+			if r.match(prefix, isDir) {
+				covered = !r.negate
+			}
+		}
+	}
+	return covered
+}
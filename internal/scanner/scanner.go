@@ -1,31 +1,41 @@
 package scanner
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/goscope/internal/config"
+	"github.com/goscope/internal/gomod"
+	"github.com/goscope/internal/pool"
 )
 
 // ForeignService represents a non-Go microservice detected in the repo tree.
 type ForeignService struct {
-	Name      string
-	Language  string // "Python", "Java", "C#", etc.
-	Path      string
-	LineCount int
-	FileCount int
+	Name      string `json:"name"`
+	Language  string `json:"language"` // "Python", "Java", "C#", etc.
+	Path      string `json:"path"`
+	LineCount int    `json:"lineCount"`
+	FileCount int    `json:"fileCount"`
 }
 
 // ScanResult holds the scanned files grouped by microservice.
 type ScanResult struct {
 	Files           []string            // all Go/proto file paths
 	Microservices   map[string][]string // microservice name -> Go/proto file paths
+	Shared          map[string]bool     // microservices the config marks as shared libraries
 	RootSubdirs     []string            // first-level subdirectories of the root
 	GitRepos        []string            // paths to directories containing .git
 	ForeignServices []ForeignService    // non-Go services detected
 	ServicesRoot    string              // detected services root dir (e.g. "src", "services", or "")
+	Modules         []gomod.Module      // Go modules from go.mod files and the root go.work
+	ModuleFiles     map[string][]string // module path -> Go/proto file paths inside the module
+	Workspace       *gomod.Workspace    // the root go.work, nil when there is none
+	Skipped         int                 // Go/proto files left out beyond cfg.MaxFilesAnalyze
+	Ignored         int                 // Go/proto files left out by ignore files and include/exclude globs
+	ConfigFiles     []string            // docker-compose and config files, in walk order, see ScanEndpoints
 }
 
 // serviceContainerDirs are directory names that typically hold microservices inside them.
@@ -67,8 +77,14 @@ var langExtensions = map[string]string{
 	".dart":  "Dart",
 }
 
-// Scan walks the directory tree looking for Go/proto files and foreign services.
-func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
+// Scan walks the directory tree looking for Go/proto files and foreign
+// services, counting foreign source lines on cfg.Workers() goroutines. It
+// skips paths matched by .goscopeignore files, cfg.Exclude and, when
+// cfg.RespectGitignore is set, .gitignore files; when cfg.Include is set,
+// only matching Go/proto and config files are kept. Files covered by cfg.Services are
+// assigned to the configured service; the others to a detected one. It stops
+// with ctx.Err() when ctx is done.
+func Scan(ctx context.Context, rootPath string, cfg config.Config) (*ScanResult, error) {
 	rootPath, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
@@ -90,10 +106,18 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 		extSet["."+ext] = true
 	}
 
-	result := &ScanResult{Microservices: make(map[string][]string)}
+	result := &ScanResult{
+		Microservices: make(map[string][]string),
+		Shared:        make(map[string]bool),
+		ModuleFiles:   make(map[string][]string),
+	}
+	if ws, err := gomod.ParseWorkFile(filepath.Join(rootPath, "go.work")); err == nil {
+		result.Workspace = ws
+	}
 
 	// ── Phase 1: Discover service directories (up to 3 levels deep) ──
 	serviceDirs := discoverServiceDirs(rootPath, excludeSet)
+	serviceDirs = appendWorkspaceServiceDirs(rootPath, serviceDirs, result.Workspace)
 
 	// Determine services root for CLI output
 	if len(serviceDirs) > 0 {
@@ -146,10 +170,22 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 
 	// ── Phase 2: Walk and collect Go/proto files + detect foreign services ──
 	foreignStats := make(map[string]*ForeignService) // service dir path -> stats
+	var foreignFiles []string
+	var foreignOwners []*ForeignService
+
+	// Pattern files are read as their directory is entered, so rules of
+	// deeper files come later and take precedence.
+	var ignores, excludes, includes ignoreList
+	excludes.add(cfg.Exclude, "")
+	includes.add(cfg.Include, "")
+	services := newServiceRules(cfg.Services)
 
 	err = filepath.WalkDir(rootPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return nil
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 		name := d.Name()
 		if d.IsDir() && strings.HasPrefix(name, ".") {
@@ -158,16 +194,67 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 		if d.IsDir() && excludeSet[name] {
 			return filepath.SkipDir
 		}
+		rel, _ := filepath.Rel(rootPath, path)
+		rel = filepath.ToSlash(rel)
 		if d.IsDir() {
+			if rel != "." && (ignores.matches(rel, true) || excludes.matches(rel, true)) {
+				return filepath.SkipDir
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			if cfg.RespectGitignore {
+				ignores.addFile(filepath.Join(path, ".gitignore"), base)
+			}
+			ignores.addFile(filepath.Join(path, IgnoreFile), base)
+			return nil
+		}
+		if ignores.matches(rel, false) || excludes.matches(rel, false) {
+			if extSet[strings.ToLower(filepath.Ext(name))] {
+				result.Ignored++
+			}
 			return nil
 		}
 
 		ext := strings.ToLower(filepath.Ext(name))
 
-		// Go/proto files
+		// Go modules
+		if name == "go.mod" {
+			if mod, err := gomod.ParseModFile(path); err == nil {
+				result.Modules = append(result.Modules, *mod)
+			}
+			return nil
+		}
+
+		// Configuration that may hold service addresses; test fixtures do not.
+		if (composeFiles[name] || isConfigFile(name)) && !strings.Contains("/"+rel, "/testdata/") {
+			if len(includes) == 0 || includes.covers(rel) {
+				result.ConfigFiles = append(result.ConfigFiles, path)
+			}
+			return nil
+		}
+
+		// Go/proto files, in walk order up to the configured maximum
 		if extSet[ext] {
+			if len(includes) > 0 && !includes.covers(rel) {
+				result.Ignored++
+				return nil
+			}
+			if cfg.MaxFilesAnalyze > 0 && len(result.Files) >= cfg.MaxFilesAnalyze {
+				result.Skipped++
+				return nil
+			}
 			result.Files = append(result.Files, path)
-			ms := detectMicroservice(rootPath, path, serviceDirs)
+			var ms string
+			if svc, ok := assignService(services, rel); ok {
+				ms = svc.name
+				if svc.shared {
+					result.Shared[ms] = true
+				}
+			} else {
+				ms = detectMicroservice(rootPath, path, serviceDirs)
+			}
 			result.Microservices[ms] = append(result.Microservices[ms], path)
 			return nil
 		}
@@ -186,12 +273,25 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 				foreignStats[key] = fs
 			}
 			fs.FileCount++
-			lc := countFileLines(path)
-			fs.LineCount += lc
+			foreignFiles = append(foreignFiles, path)
+			foreignOwners = append(foreignOwners, fs)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	lineCounts, err := pool.Map(ctx, cfg.Workers(), len(foreignFiles), func(i int) int {
+		return countFileLines(foreignFiles[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, fs := range foreignOwners {
+		fs.LineCount += lineCounts[i]
+	}
 
 	// Filter foreign services: only keep dirs that have NO Go files (pure foreign)
 	for key, fs := range foreignStats {
@@ -209,6 +309,14 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 		return result.ForeignServices[i].LineCount > result.ForeignServices[j].LineCount
 	})
 
+	result.Modules = appendWorkspaceModules(result.Modules, result.Workspace)
+	resolver := result.Resolver()
+	for _, f := range result.Files {
+		if m := resolver.ModuleForDir(filepath.Dir(f)); m != nil {
+			result.ModuleFiles[m.Path] = append(result.ModuleFiles[m.Path], f)
+		}
+	}
+
 	// ── Phase 3: Filter out microservices with no real content ──
 	for ms, files := range result.Microservices {
 		if ms == "root" && len(files) <= 1 {
@@ -218,7 +326,74 @@ func Scan(rootPath string, cfg config.Config) (*ScanResult, error) {
 		// Keep all detected microservices that have files
 	}
 
-	return result, err
+	return result, nil
+}
+
+// Resolver returns an import path resolver for the scanned modules that
+// applies the replace directives of the root go.work.
+func (r *ScanResult) Resolver() *gomod.Resolver {
+	return gomod.NewResolver(r.Modules, r.WorkReplaces()...)
+}
+
+// WorkReplaces returns the replace directives of the root go.work, if any.
+func (r *ScanResult) WorkReplaces() []gomod.Replace {
+	if r.Workspace == nil {
+		return nil
+	}
+	return r.Workspace.Replaces
+}
+
+// appendWorkspaceModules marks the modules used by ws and adds those that
+// live outside the walked tree (e.g. `use ../shared`).
+func appendWorkspaceModules(mods []gomod.Module, ws *gomod.Workspace) []gomod.Module {
+	if ws == nil {
+		return mods
+	}
+	used := make(map[string]bool)
+	for _, dir := range ws.Use {
+		used[dir] = true
+	}
+	known := make(map[string]bool)
+	for i := range mods {
+		known[mods[i].Dir] = true
+		mods[i].Workspace = used[mods[i].Dir]
+	}
+	for _, dir := range ws.Use {
+		if known[dir] {
+			continue
+		}
+		if mod, err := gomod.ParseModFile(filepath.Join(dir, "go.mod")); err == nil {
+			mod.Workspace = true
+			mods = append(mods, *mod)
+			known[dir] = true
+		}
+	}
+	return mods
+}
+
+// appendWorkspaceServiceDirs adds the modules used by ws that lie inside the
+// root but outside every discovered service directory, so that a module
+// nested deeper than discovery looks is still its own service.
+func appendWorkspaceServiceDirs(rootPath string, dirs []string, ws *gomod.Workspace) []string {
+	if ws == nil {
+		return dirs
+	}
+	for _, use := range ws.Use {
+		if use == rootPath || !strings.HasPrefix(use, rootPath+string(filepath.Separator)) {
+			continue
+		}
+		covered := false
+		for _, sd := range dirs {
+			if use == sd || strings.HasPrefix(use, sd+string(filepath.Separator)) {
+				covered = true
+				break
+			}
+		}
+		if _, err := os.Stat(use); err == nil && !covered {
+			dirs = append(dirs, use)
+		}
+	}
+	return dirs
 }
 
 // discoverServiceDirs finds directories that look like microservices.
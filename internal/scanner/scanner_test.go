@@ -1,9 +1,12 @@
 package scanner
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/goscope/internal/config"
 )
 
 func setupTestTree(t *testing.T) string {
@@ -170,3 +173,292 @@ func TestCountFileLines_Missing(t *testing.T) {
 		t.Errorf("countFileLines missing file = %d, want 0", got)
 	}
 }
+
+func TestScanModules(t *testing.T) {
+	root := setupTestTree(t)
+	shared := t.TempDir()
+	os.WriteFile(filepath.Join(shared, "go.mod"), []byte("module github.com/acme/shared\n"), 0644)
+	// A workspace module nested deeper than service discovery looks.
+	events := filepath.Join(root, "libs", "platform", "events")
+	os.MkdirAll(events, 0755)
+	os.WriteFile(filepath.Join(events, "go.mod"), []byte("module github.com/acme/events\n"), 0644)
+	os.WriteFile(filepath.Join(events, "bus.go"), []byte("package events\n"), 0644)
+	os.WriteFile(filepath.Join(root, "go.work"), []byte("go 1.22\nuse (\n\t./api-gateway\n\t./libs/platform/events\n\t"+shared+"\n)\n"), 0644)
+
+	res, err := Scan(context.Background(), root, config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	inWorkspace := make(map[string]bool)
+	for _, m := range res.Modules {
+		got[m.Path] = m.Dir
+		inWorkspace[m.Path] = m.Workspace
+	}
+	want := map[string]string{
+		"api-gateway":            filepath.Join(root, "api-gateway"),
+		"payment":                filepath.Join(root, "src/payment-service"),
+		"github.com/acme/events": events,
+		"github.com/acme/shared": shared,
+	}
+	if res.Workspace == nil || len(res.Workspace.Use) != 3 {
+		t.Errorf("Workspace = %+v", res.Workspace)
+	}
+	if !inWorkspace["api-gateway"] || !inWorkspace["github.com/acme/shared"] || inWorkspace["payment"] {
+		t.Errorf("workspace modules = %v", inWorkspace)
+	}
+	if files := res.Microservices["events"]; len(files) != 1 {
+		t.Errorf("events service files = %v", files)
+	}
+	if files := res.ModuleFiles["github.com/acme/events"]; len(files) != 1 || files[0] != filepath.Join(events, "bus.go") {
+		t.Errorf("ModuleFiles[events] = %v", files)
+	}
+	if files := res.ModuleFiles["payment"]; len(files) != 1 {
+		t.Errorf("ModuleFiles[payment] = %v", files)
+	}
+	if len(got) != len(want) {
+		t.Errorf("Modules = %v, want %v", got, want)
+	}
+	for path, dir := range want {
+		if got[path] != dir {
+			t.Errorf("module %s dir = %q, want %q", path, got[path], dir)
+		}
+	}
+}
+
+func TestScanMaxFiles(t *testing.T) {
+	root := setupTestTree(t)
+	all, err := Scan(context.Background(), root, config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.MaxFilesAnalyze = 2
+	res, err := Scan(context.Background(), root, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 2 || res.Skipped != len(all.Files)-2 {
+		t.Errorf("got %d files, %d skipped; want 2 of %d", len(res.Files), res.Skipped, len(all.Files))
+	}
+	for i, f := range res.Files {
+		if f != all.Files[i] {
+			t.Errorf("Files[%d] = %s, want %s", i, f, all.Files[i])
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern, base, path string
+		isDir               bool
+		want                bool
+	}{
+		{"*.pb.go", "", "svc/api/user.pb.go", false, true},
+		{"mocks/", "", "svc/internal/mocks", true, true},
+		{"mocks/", "", "svc/mocks.go", false, false},
+		{"/gen", "", "gen", true, true},
+		{"/gen", "", "svc/gen", true, false},
+		{"internal/testdata", "svc", "svc/internal/testdata", true, true},
+		{"internal/testdata", "svc", "other/internal/testdata", true, false},
+		{"**/fixtures/*.go", "", "a/b/fixtures/x.go", false, true},
+		{"docs/**", "", "docs/a/b.go", false, true},
+		{"a?c.go", "", "abc.go", false, true},
+		{`\#notes.go`, "", "#notes.go", false, true},
+	}
+	for _, tt := range tests {
+		r, ok := parseIgnoreRule(tt.pattern, tt.base)
+		if !ok {
+			t.Fatalf("parseIgnoreRule(%q) not ok", tt.pattern)
+		}
+		if got := r.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q (base %q) match %q = %v, want %v", tt.pattern, tt.base, tt.path, got, tt.want)
+		}
+	}
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parseIgnoreRule(line, ""); ok {
+			t.Errorf("parseIgnoreRule(%q) should be skipped", line)
+		}
+	}
+
+	var l ignoreList
+	l.add([]string{"*.go", "!keep.go"}, "")
+	if !l.matches("x/drop.go", false) || l.matches("x/keep.go", false) {
+		t.Error("negation not applied")
+	}
+}
+
+func TestScanIgnores(t *testing.T) {
+	root := setupTestTree(t)
+	files := map[string]string{
+		".gitignore":                              "/api-gateway/gen/\n",
+		"api-gateway/gen/types.go":                "package gen",
+		"api-gateway/handler.go":                  "package main",
+		"api-gateway/handler_mock.go":             "package main",
+		"src/payment-service/.goscopeignore":      "testdata/\n",
+		"src/payment-service/testdata/fixture.go": "package testdata",
+		"src/payment-service/store.go":            "package main",
+		"src/payment-service/tools/tools.go":      "package tools",
+	}
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Exclude = []string{"*_mock.go"}
+	cfg.Include = []string{"api-gateway/", "src/", "!src/**/tools/"}
+	res, err := Scan(context.Background(), root, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, f := range res.Files {
+		rel, _ := filepath.Rel(root, f)
+		got[filepath.ToSlash(rel)] = true
+	}
+	want := []string{"api-gateway/handler.go", "api-gateway/main.go", "src/payment-service/main.go", "src/payment-service/store.go"}
+	if len(got) != len(want) {
+		t.Errorf("Files = %v, want %v", got, want)
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing %s in %v", w, got)
+		}
+	}
+	if res.Ignored != 3 { // mock, tools.go and proto/user.proto; ignored directories are not counted
+		t.Errorf("Ignored = %d, want 3", res.Ignored)
+	}
+
+	cfg = config.DefaultConfig()
+	cfg.RespectGitignore = false
+	res, err = Scan(context.Background(), root, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 8 {
+		t.Errorf("without .gitignore: %d files, want 8: %v", len(res.Files), res.Files)
+	}
+}
+
+func TestScanServiceMap(t *testing.T) {
+	root := setupTestTree(t)
+	files := []string{
+		"api-gateway/pkg/middleware/auth.go",
+		"billing/invoice/invoice.go",
+		"pkg/log/log.go",
+	}
+	for _, path := range files {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte("package x"), 0644)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Services = []config.Service{
+		{Name: "payments", Paths: []string{"src/payment-service", "billing/**/*.go"}},
+		{Name: "platform", Paths: []string{"pkg/"}, Shared: true},
+	}
+	res, err := Scan(context.Background(), root, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := make(map[string]string)
+	for ms, paths := range res.Microservices {
+		for _, p := range paths {
+			rel, _ := filepath.Rel(root, p)
+			owner[filepath.ToSlash(rel)] = ms
+		}
+	}
+	want := map[string]string{
+		"src/payment-service/main.go":        "payments",
+		"billing/invoice/invoice.go":         "payments",
+		"pkg/log/log.go":                     "platform",
+		"api-gateway/pkg/middleware/auth.go": "platform",
+		"api-gateway/main.go":                "api-gateway", // not covered: detected
+	}
+	for path, ms := range want {
+		if owner[path] != ms {
+			t.Errorf("%s assigned to %q, want %q", path, owner[path], ms)
+		}
+	}
+	if !res.Shared["platform"] || res.Shared["payments"] || len(res.Shared) != 1 {
+		t.Errorf("Shared = %v, want only platform", res.Shared)
+	}
+}
+
+func TestScanEndpoints(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"gateway/main.go":            "package main",
+		"gateway/config/config.yaml": "upstreams:\n  users: http://user-service:8080\n  docs: https://example.com\n",
+		"users/main.go":              "package main",
+		"users/.env":                 "export ORDERS_GRPC_ADDR=orders:9090\nDEBUG=true\n",
+		"users/deploy/k8s.yaml":      "env:\n  - name: BILLING_URL\n    value: \"http://billing.prod.svc.cluster.local\"\nimage: registry:5000/users:1\n",
+		"deploy/settings.json":       `{"users": "http://users:8080"}`,
+		"node_modules/x/config.yml":  "url: http://users:8080\n",
+		"users/testdata/config.yml":  "url: http://gateway:8080\n",
+		"gateway/local/config.yml":   "url: http://orders:9090\n",
+		".goscopeignore":             "gateway/local/\n",
+		"orders/main.go":             "package main",
+		"orders/compose.yaml": `services:
+    orders:
+        build: .
+        environment:
+            - USERS_URL=http://users:8080
+`,
+		"docker-compose.yml": `services:
+  gateway:
+    build: ./gateway
+    environment:
+      - USER_SERVICE_URL=http://user-service:8080
+      - LOG_LEVEL=debug
+  user-service:
+    build:
+      context: ./users
+    environment:
+      ORDERS_ADDR: "orders:9090"
+    ports:
+      - "8080:8080"
+volumes:
+  data:
+`,
+	}
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	scan, err := Scan(context.Background(), root, config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, aliases := ScanEndpoints(root, scan)
+	if aliases["gateway"] != "gateway" || aliases["user-service"] != "users" || aliases["orders"] != "orders" || len(aliases) != 3 {
+		t.Errorf("aliases = %v", aliases)
+	}
+	type ep struct{ service, key, scheme, host, file string }
+	want := []ep{
+		{"gateway", "USER_SERVICE_URL", "http", "user-service", "docker-compose.yml"},
+		{"user-service", "ORDERS_ADDR", "", "orders", "docker-compose.yml"},
+		{"gateway", "users", "http", "user-service", "gateway/config/config.yaml"},
+		{"orders", "USERS_URL", "http", "users", "orders/compose.yaml"},
+		{"users", "ORDERS_GRPC_ADDR", "", "orders", "users/.env"},
+		{"users", "BILLING_URL", "http", "billing", "users/deploy/k8s.yaml"},
+	}
+	var got []ep
+	for _, e := range endpoints {
+		rel, _ := filepath.Rel(root, e.File)
+		got = append(got, ep{e.Service, e.Key, e.Scheme, e.Host, filepath.ToSlash(rel)})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("endpoints = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("endpoint %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
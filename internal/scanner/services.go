@@ -0,0 +1,31 @@
+package scanner
+
+import "github.com/goscope/internal/config"
+
+// serviceRule is an entry of the config's explicit service map.
+type serviceRule struct {
+	name   string
+	shared bool
+	paths  ignoreList
+}
+
+func newServiceRules(defs []config.Service) []serviceRule {
+	rules := make([]serviceRule, 0, len(defs))
+	for _, d := range defs {
+		r := serviceRule{name: d.Name, shared: d.Shared}
+		r.paths.add(d.Paths, "")
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// assignService returns the first rule whose paths cover rel, a
+// slash-separated path relative to the root.
+func assignService(rules []serviceRule, rel string) (serviceRule, bool) {
+	for _, r := range rules {
+		if r.paths.covers(rel) {
+			return r, true
+		}
+	}
+	return serviceRule{}, false
+}
@@ -1,11 +1,12 @@
 package scanner
 
 import (
-	"bufio"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/goscope/internal/gomod"
 )
 
 // ScanDockerCompose reads docker-compose.yml from root and all subdirs.
@@ -105,12 +106,12 @@ func parseDockerCompose(path string) (services []string, technologies []string)
 	return
 }
 
+// scanGoMod maps the requirements of the go.mod at path to technologies.
 func scanGoMod(path string) []string {
-	f, err := os.Open(path)
+	mod, err := gomod.ParseModFile(path)
 	if err != nil {
 		return nil
 	}
-	defer f.Close()
 
 	techMap := map[string]string{
 		"github.com/jackc/pgx":                      "PostgreSQL",
@@ -162,11 +163,10 @@ func scanGoMod(path string) []string {
 
 	seen := make(map[string]bool)
 	var techs []string
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
+	for _, req := range mod.Requires {
 		for prefix, tech := range techMap {
-			if strings.HasPrefix(line, prefix) && !seen[tech] {
+			// Prefix match so major versions ("pgx/v5") count too.
+			if strings.HasPrefix(req.Path, prefix) && !seen[tech] {
 				techs = append(techs, tech)
 				seen[tech] = true
 			}